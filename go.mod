@@ -88,6 +88,7 @@ require (
 	github.com/prometheus/client_model v0.3.0
 	github.com/prometheus/common v0.39.0
 	github.com/prometheus/prometheus v1.8.2-0.20211011171444-354d8d2ecfac
+	github.com/rabbitmq/amqp091-go v1.5.0
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/russellhaering/goxmldsig v1.1.1
 	github.com/stretchr/testify v1.8.1
@@ -269,6 +270,9 @@ require (
 )
 
 require (
+	github.com/antonmedv/expr v1.12.7
+	github.com/eclipse/paho.mqtt.golang v1.4.2
+	github.com/mochi-co/mqtt v1.3.2
 	github.com/parca-dev/parca v0.15.0
 	k8s.io/apimachinery v0.25.3
 )
@@ -310,6 +314,7 @@ require (
 	github.com/opencontainers/go-digest v1.0.0 // indirect
 	github.com/opencontainers/image-spec v1.0.3-0.20211202183452-c5a74bcca799 // indirect
 	github.com/rivo/uniseg v0.3.4 // indirect
+	github.com/rs/xid v1.4.0 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/segmentio/asm v1.1.4 // indirect
 	github.com/shopspring/decimal v1.2.0 // indirect
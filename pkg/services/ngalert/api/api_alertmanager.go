@@ -335,6 +335,7 @@ func newTestReceiversResult(r *notifier.TestReceiversResult) apimodels.TestRecei
 			configs[jx].Status = config.Status
 			if config.Error != nil {
 				configs[jx].Error = config.Error.Error()
+				configs[jx].ErrorCategory = string(config.ErrorCategory)
 			}
 		}
 		v.Receivers[ix].Configs = configs
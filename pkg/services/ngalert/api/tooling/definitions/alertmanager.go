@@ -270,6 +270,9 @@ type TestReceiverConfigResult struct {
 	UID    string `json:"uid"`
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
+	// ErrorCategory is the category of the error: auth, rate_limited, network,
+	// bad_request or server_error.
+	ErrorCategory string `json:"error_category,omitempty"`
 }
 
 // swagger:parameters RouteCreateSilence RouteCreateGrafanaSilence
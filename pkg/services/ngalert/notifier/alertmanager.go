@@ -120,6 +120,9 @@ type Alertmanager struct {
 	silences *silence.Silences
 
 	receivers []*notify.Receiver
+	// notifiers are the notifiers of the receivers, which are closed when they are
+	// replaced by a new configuration.
+	notifiers []channels.NotificationChannel
 
 	// muteTimes is a map where the key is the name of the mute_time_interval
 	// and the value represents all configured time_interval(s)
@@ -127,6 +130,10 @@ type Alertmanager struct {
 
 	stageMetrics      *notify.Metrics
 	dispatcherMetrics *dispatch.DispatcherMetrics
+	channelMetrics    *ngchannels.Metrics
+
+	// destinationPolicy restricts the hosts that notifications are sent to.
+	destinationPolicy *ngchannels.DestinationPolicy
 
 	reloadConfigMtx sync.RWMutex
 	config          *apimodels.PostableUserConfig
@@ -138,6 +145,11 @@ type Alertmanager struct {
 
 func newAlertmanager(ctx context.Context, orgID int64, cfg *setting.Cfg, store AlertingStore, kvStore kvstore.KVStore,
 	peer ClusterPeer, decryptFn channels.GetDecryptedValueFn, ns notifications.Service, m *metrics.Alertmanager) (*Alertmanager, error) {
+	destinationPolicy, err := ngchannels.NewDestinationPolicy(cfg.UnifiedAlerting.Destinations.AllowedHosts, cfg.UnifiedAlerting.Destinations.DeniedHosts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification destinations: %w", err)
+	}
+
 	am := &Alertmanager{
 		Settings:            cfg,
 		stopc:               make(chan struct{}),
@@ -145,6 +157,7 @@ func newAlertmanager(ctx context.Context, orgID int64, cfg *setting.Cfg, store A
 		marker:              types.NewMarker(m.Registerer),
 		stageMetrics:        notify.NewMetrics(m.Registerer),
 		dispatcherMetrics:   dispatch.NewDispatcherMetrics(false, m.Registerer),
+		channelMetrics:      ngchannels.NewMetrics(m.Registerer),
 		Store:               store,
 		peer:                peer,
 		peerTimeout:         cfg.UnifiedAlerting.HAPeerTimeout,
@@ -152,6 +165,7 @@ func newAlertmanager(ctx context.Context, orgID int64, cfg *setting.Cfg, store A
 		NotificationService: ns,
 		orgID:               orgID,
 		decryptFn:           decryptFn,
+		destinationPolicy:   destinationPolicy,
 	}
 
 	am.fileStore = NewFileStore(am.orgID, kvStore, am.WorkingDirPath())
@@ -245,6 +259,10 @@ func (am *Alertmanager) StopAndWait() {
 	close(am.stopc)
 
 	am.wg.Wait()
+
+	for _, n := range am.notifiers {
+		ngchannels.Close(n)
+	}
 }
 
 // SaveAndApplyDefaultConfig saves the default configuration the database and applies the configuration to the Alertmanager.
@@ -401,7 +419,7 @@ func (am *Alertmanager) applyConfig(cfg *apimodels.PostableUserConfig, rawConfig
 	}
 
 	// Finally, build the integrations map using the receiver configuration and templates.
-	integrationsMap, err := am.buildIntegrationsMap(cfg.AlertmanagerConfig.Receivers, tmpl)
+	integrationsMap, notifiers, err := am.buildIntegrationsMap(cfg.AlertmanagerConfig.Receivers, tmpl)
 	if err != nil {
 		return fmt.Errorf("failed to build integration map: %w", err)
 	}
@@ -440,6 +458,13 @@ func (am *Alertmanager) applyConfig(cfg *apimodels.PostableUserConfig, rawConfig
 	}
 	am.receivers = receivers
 
+	// The old dispatcher is stopped, so the notifiers of the old configuration
+	// can be closed.
+	for _, n := range am.notifiers {
+		ngchannels.Close(n)
+	}
+	am.notifiers = notifiers
+
 	am.wg.Add(1)
 	go func() {
 		defer am.wg.Done()
@@ -463,30 +488,35 @@ func (am *Alertmanager) WorkingDirPath() string {
 }
 
 // buildIntegrationsMap builds a map of name to the list of Grafana integration notifiers off of a list of receiver config.
-func (am *Alertmanager) buildIntegrationsMap(receivers []*apimodels.PostableApiReceiver, templates *template.Template) (map[string][]*notify.Integration, error) {
+// It also returns all the notifiers, so they can be closed when they are replaced.
+func (am *Alertmanager) buildIntegrationsMap(receivers []*apimodels.PostableApiReceiver, templates *template.Template) (map[string][]*notify.Integration, []channels.NotificationChannel, error) {
 	integrationsMap := make(map[string][]*notify.Integration, len(receivers))
+	var notifiers []channels.NotificationChannel
 	for _, receiver := range receivers {
-		integrations, err := am.buildReceiverIntegrations(receiver, templates)
+		integrations, receiverNotifiers, err := am.buildReceiverIntegrations(receiver, templates)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		integrationsMap[receiver.Name] = integrations
+		notifiers = append(notifiers, receiverNotifiers...)
 	}
 
-	return integrationsMap, nil
+	return integrationsMap, notifiers, nil
 }
 
 // buildReceiverIntegrations builds a list of integration notifiers off of a receiver config.
-func (am *Alertmanager) buildReceiverIntegrations(receiver *apimodels.PostableApiReceiver, tmpl *template.Template) ([]*notify.Integration, error) {
+func (am *Alertmanager) buildReceiverIntegrations(receiver *apimodels.PostableApiReceiver, tmpl *template.Template) ([]*notify.Integration, []channels.NotificationChannel, error) {
 	var integrations []*notify.Integration
+	var notifiers []channels.NotificationChannel
 	for i, r := range receiver.GrafanaManagedReceivers {
 		n, err := am.buildReceiverIntegration(r, tmpl)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		integrations = append(integrations, notify.NewIntegration(n, n, r.Type, i))
+		notifiers = append(notifiers, n)
 	}
-	return integrations, nil
+	return integrations, notifiers, nil
 }
 
 func (am *Alertmanager) buildReceiverIntegration(r *apimodels.PostableGrafanaReceiver, tmpl *template.Template) (channels.NotificationChannel, error) {
@@ -529,7 +559,11 @@ func (am *Alertmanager) buildReceiverIntegration(r *apimodels.PostableGrafanaRec
 			Err:      fmt.Errorf("notifier %s is not supported", r.Type),
 		}
 	}
-	n, err := receiverFactory(factoryConfig)
+	receiverFactory = ngchannels.WithSecretsResolver(receiverFactory, ngchannels.FileSecretsResolver(am.Settings.UnifiedAlerting.NotifierSecretsDirectory))
+	receiverFactory = ngchannels.WithDestinationPolicy(receiverFactory, am.destinationPolicy)
+	// The queue wraps the metrics so that failures of queued notifications are counted.
+	receiverFactory = ngchannels.WithMetrics(receiverFactory, am.channelMetrics)
+	n, err := ngchannels.WithQueue(receiverFactory, am.channelMetrics)(factoryConfig)
 	if err != nil {
 		return nil, InvalidReceiverError{
 			Receiver: r,
@@ -2,11 +2,14 @@ package channels
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
 	"github.com/prometheus/alertmanager/types"
@@ -21,9 +24,33 @@ type AlertmanagerConfig struct {
 }
 
 type alertmanagerSettings struct {
-	URLs     []*url.URL
-	User     string
-	Password string
+	URLs           []*url.URL
+	User           string
+	Password       string
+	ConnectTimeout time.Duration
+	RequestTimeout time.Duration
+	// ResponseHeaderTimeout is the timeout for receiving the response headers.
+	ResponseHeaderTimeout time.Duration
+	// IdleConnTimeout is the time after which idle connections are closed.
+	IdleConnTimeout time.Duration
+	// ExpectedStatusCodes override the 2xx status codes that are considered successful.
+	ExpectedStatusCodes []int
+	// Resolver overrides the system resolver.
+	Resolver *net.Resolver
+	// MaxRedirects is the maximum number of redirects to follow.
+	MaxRedirects int
+	// RedirectHosts are the hosts that redirects are followed to.
+	RedirectHosts []string
+	// Trace logs the latency breakdown of requests.
+	Trace bool
+	// Host overrides the Host of requests.
+	Host string
+	// MaxBodyLogLen is the maximum number of bytes of response bodies that are logged.
+	MaxBodyLogLen int
+	// TLSConfig has the CA and client certificates.
+	TLSConfig *tls.Config
+	// SigV4 has the region, service and credentials to sign requests with.
+	SigV4 *sigV4Config
 }
 
 func AlertmanagerFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
@@ -65,14 +92,79 @@ func buildAlertmanagerNotifier(fc channels.FactoryConfig) (*AlertmanagerNotifier
 		return nil, errors.New("could not find url property in settings")
 	}
 	settings.Password = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "basicAuthPassword", settings.Password)
+	connectTimeout, requestTimeout, err := buildHTTPTimeouts(fc)
+	if err != nil {
+		return nil, err
+	}
+	responseHeaderTimeout, err := buildResponseHeaderTimeout(fc)
+	if err != nil {
+		return nil, err
+	}
+	idleConnTimeout, err := buildIdleConnTimeout(fc)
+	if err != nil {
+		return nil, err
+	}
+	expectedStatusCodes, err := buildExpectedStatusCodes(fc)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := buildHTTPResolver(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxRedirects, err := buildMaxRedirects(fc)
+	if err != nil {
+		return nil, err
+	}
+	redirectHosts, err := buildRedirectHosts(fc)
+	if err != nil {
+		return nil, err
+	}
+	trace, err := buildHTTPTrace(fc)
+	if err != nil {
+		return nil, err
+	}
+	host, err := buildHostHeader(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxBodyLogLen, err := buildMaxBodyLogLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := buildTLSConfig(fc)
+	if err != nil {
+		return nil, err
+	}
+	sigV4, err := buildSigV4Config(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
 
 	return &AlertmanagerNotifier{
 		Base:   channels.NewBase(fc.Config),
-		images: fc.ImageStore,
+		images: images,
 		settings: alertmanagerSettings{
-			URLs:     urls,
-			User:     settings.User,
-			Password: settings.Password,
+			URLs:                  urls,
+			User:                  settings.User,
+			Password:              settings.Password,
+			ConnectTimeout:        connectTimeout,
+			RequestTimeout:        requestTimeout,
+			ResponseHeaderTimeout: responseHeaderTimeout,
+			IdleConnTimeout:       idleConnTimeout,
+			ExpectedStatusCodes:   expectedStatusCodes,
+			Resolver:              resolver,
+			MaxRedirects:          maxRedirects,
+			RedirectHosts:         redirectHosts,
+			Trace:                 trace,
+			Host:                  host,
+			TLSConfig:             tlsConfig,
+			MaxBodyLogLen:         maxBodyLogLen,
+			SigV4:                 sigV4,
 		},
 		logger: fc.Logger,
 	}, nil
@@ -84,6 +176,7 @@ type AlertmanagerNotifier struct {
 	images   channels.ImageStore
 	settings alertmanagerSettings
 	logger   channels.Logger
+	clients  httpClients
 }
 
 // Notify sends alert notifications to Alertmanager.
@@ -114,9 +207,23 @@ func (n *AlertmanagerNotifier) Notify(ctx context.Context, as ...*types.Alert) (
 	)
 	for _, u := range n.settings.URLs {
 		if _, err := sendHTTPRequest(ctx, u, httpCfg{
-			user:     n.settings.User,
-			password: n.settings.Password,
-			body:     body,
+			user:                  n.settings.User,
+			password:              n.settings.Password,
+			body:                  body,
+			connectTimeout:        n.settings.ConnectTimeout,
+			requestTimeout:        n.settings.RequestTimeout,
+			responseHeaderTimeout: n.settings.ResponseHeaderTimeout,
+			idleConnTimeout:       n.settings.IdleConnTimeout,
+			expectedStatusCodes:   n.settings.ExpectedStatusCodes,
+			resolver:              n.settings.Resolver,
+			maxRedirects:          n.settings.MaxRedirects,
+			redirectHosts:         n.settings.RedirectHosts,
+			trace:                 n.settings.Trace,
+			host:                  n.settings.Host,
+			tlsConfig:             n.settings.TLSConfig,
+			maxBodyLogLen:         n.settings.MaxBodyLogLen,
+			sigV4:                 n.settings.SigV4,
+			clients:               &n.clients,
 		}, n.logger); err != nil {
 			n.logger.Warn("failed to send to Alertmanager", "error", err, "alertmanager", n.Name, "url", u.String())
 			lastErr = err
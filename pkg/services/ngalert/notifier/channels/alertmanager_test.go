@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
 	"github.com/prometheus/alertmanager/notify"
@@ -64,6 +65,61 @@ func TestNewAlertmanagerNotifier(t *testing.T) {
 			expectedInitError: "invalid url property in settings: parse \"://url/api/v1/alerts\": missing protocol scheme",
 			receiverName:      "Alertmanager",
 		},
+		{
+			name: "Error in initing: invalid request timeout",
+			settings: `{
+				"url": "https://alertmanager.com",
+				"request_timeout": "10"
+			}`,
+			expectedInitError: `invalid value for request_timeout: "10"`,
+			receiverName:      "Alertmanager",
+		},
+		{
+			name: "Error in initing: connect timeout greater than request timeout",
+			settings: `{
+				"url": "https://alertmanager.com",
+				"connect_timeout": "10s",
+				"request_timeout": "5s"
+			}`,
+			expectedInitError: "connect_timeout 10s must not be greater than request_timeout 5s",
+			receiverName:      "Alertmanager",
+		},
+		{
+			name: "Error in initing: invalid response header timeout",
+			settings: `{
+				"url": "https://alertmanager.com",
+				"response_header_timeout": "0s"
+			}`,
+			expectedInitError: `invalid value for response_header_timeout: "0s"`,
+			receiverName:      "Alertmanager",
+		},
+		{
+			name: "Error in initing: invalid expected status code",
+			settings: `{
+				"url": "https://alertmanager.com",
+				"expected_status_codes": "200,2xx"
+			}`,
+			expectedInitError: `invalid status code in expected_status_codes: "2xx"`,
+			receiverName:      "Alertmanager",
+		},
+		{
+			name: "Error in initing: invalid DNS server",
+			settings: `{
+				"url": "https://alertmanager.com",
+				"dns_server": "dns.example.com"
+			}`,
+			expectedInitError: `invalid value for dns_server: "dns.example.com", must be an IP address with an optional port`,
+			receiverName:      "Alertmanager",
+		},
+		{
+			name: "Error in initing: invalid max redirects",
+			settings: `{
+				"url": "https://alertmanager.com",
+				"max_redirects": -1
+			}`,
+			expectedInitError: `invalid value for max_redirects: "-1", must be a non-negative integer`,
+			receiverName:      "Alertmanager",
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -113,6 +169,8 @@ func TestAlertmanagerNotifier_Notify(t *testing.T) {
 		expectedError        string
 		sendHTTPRequestError error
 		receiverName         string
+		expConnectTimeout    time.Duration
+		expRequestTimeout    time.Duration
 	}{
 		{
 			name:     "Default config with one alert",
@@ -149,6 +207,24 @@ func TestAlertmanagerNotifier_Notify(t *testing.T) {
 					},
 				},
 			},
+		}, {
+			name: "Custom timeouts",
+			settings: `{
+				"url": "https://alertmanager.com",
+				"connect_timeout": "5s",
+				"request_timeout": "1m"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"__alert_rule_uid__": "rule uid", "alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				},
+			},
+			receiverName:      "Alertmanager",
+			expConnectTimeout: 5 * time.Second,
+			expRequestTimeout: time.Minute,
 		}, {
 			name: "Error sending to Alertmanager",
 			settings: `{
@@ -193,13 +269,19 @@ func TestAlertmanagerNotifier_Notify(t *testing.T) {
 			sn, err := buildAlertmanagerNotifier(fc)
 			require.NoError(t, err)
 
-			var body []byte
+			var (
+				body           []byte
+				connectTimeout time.Duration
+				requestTimeout time.Duration
+			)
 			origSendHTTPRequest := sendHTTPRequest
 			t.Cleanup(func() {
 				sendHTTPRequest = origSendHTTPRequest
 			})
 			sendHTTPRequest = func(ctx context.Context, url *url.URL, cfg httpCfg, logger channels.Logger) ([]byte, error) {
 				body = cfg.body
+				connectTimeout = cfg.connectTimeout
+				requestTimeout = cfg.requestTimeout
 				return nil, c.sendHTTPRequestError
 			}
 
@@ -216,6 +298,16 @@ func TestAlertmanagerNotifier_Notify(t *testing.T) {
 				expBody, err := json.Marshal(c.alerts)
 				require.NoError(t, err)
 				require.JSONEq(t, string(expBody), string(body))
+
+				expConnectTimeout, expRequestTimeout := c.expConnectTimeout, c.expRequestTimeout
+				if expConnectTimeout == 0 {
+					expConnectTimeout = defaultHTTPTimeout
+				}
+				if expRequestTimeout == 0 {
+					expRequestTimeout = defaultHTTPTimeout
+				}
+				require.Equal(t, expConnectTimeout, connectTimeout)
+				require.Equal(t, expRequestTimeout, requestTimeout)
 			}
 		})
 	}
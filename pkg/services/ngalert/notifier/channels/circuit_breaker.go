@@ -0,0 +1,152 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+)
+
+const (
+	// circuitBreakerDefaultTimeout is the default time that the circuit breaker stays
+	// open before a notification is sent again.
+	circuitBreakerDefaultTimeout = time.Minute
+	// circuitBreakerMaxTimeout is the longest that the circuit breaker can stay open, as
+	// notifications are retried until the deadline of the notification pipeline.
+	circuitBreakerMaxTimeout = 10 * time.Minute
+)
+
+// ErrCircuitOpen is returned for notifications that are not sent because the circuit
+// breaker of the notifier is open.
+var ErrCircuitOpen = errors.New("notification not sent as the circuit breaker is open")
+
+// circuitBreakerSettings are the settings of the circuit breaker of a notifier.
+type circuitBreakerSettings struct {
+	failures int
+	timeout  time.Duration
+}
+
+// buildCircuitBreaker returns the circuit_breaker_failures and circuit_breaker_timeout
+// settings of the notifier, or nil if it has no circuit breaker.
+func buildCircuitBreaker(fc channels.FactoryConfig) (*circuitBreakerSettings, error) {
+	var settings struct {
+		Failures int    `json:"circuit_breaker_failures,omitempty" yaml:"circuit_breaker_failures,omitempty"`
+		Timeout  string `json:"circuit_breaker_timeout,omitempty" yaml:"circuit_breaker_timeout,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.Failures < 0 {
+		return nil, fmt.Errorf("invalid value for circuit_breaker_failures: %d", settings.Failures)
+	}
+	if settings.Failures == 0 {
+		if settings.Timeout != "" {
+			return nil, errors.New("circuit_breaker_timeout requires circuit_breaker_failures")
+		}
+		return nil, nil
+	}
+	timeout := circuitBreakerDefaultTimeout
+	if settings.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(settings.Timeout)
+		if err != nil || timeout <= 0 || timeout > circuitBreakerMaxTimeout {
+			return nil, fmt.Errorf("invalid value for circuit_breaker_timeout: %q, must be a positive duration of at most %s", settings.Timeout, circuitBreakerMaxTimeout)
+		}
+	}
+	return &circuitBreakerSettings{failures: settings.Failures, timeout: timeout}, nil
+}
+
+// withCircuitBreaker wraps the factory so that notifiers with the circuit_breaker_failures
+// setting stop sending notifications for circuit_breaker_timeout once that many
+// notifications in a row failed as the service is unavailable, so a service that is
+// down is not sent a retry for each group. Notifications are rejected with ErrCircuitOpen
+// while the circuit breaker is open, and are retried by the notification pipeline.
+func withCircuitBreaker(factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		settings, err := buildCircuitBreaker(fc)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: err.Error(),
+				Cfg:    *fc.Config,
+			}
+		}
+		n, err := factory(fc)
+		if err != nil || settings == nil {
+			return n, err
+		}
+		return &circuitBreakerNotifier{
+			NotificationChannel: n,
+			log:                 fc.Logger,
+			clock:               clock.New(),
+			settings:            *settings,
+		}, nil
+	}
+}
+
+// circuitBreakerNotifier rejects notifications while the circuit breaker is open.
+type circuitBreakerNotifier struct {
+	channels.NotificationChannel
+	log      channels.Logger
+	clock    clock.Clock
+	settings circuitBreakerSettings
+
+	mtx sync.Mutex
+	// failures is the number of notifications in a row that failed as the service is
+	// unavailable. It is only reset by a notification that did not fail, so the circuit
+	// breaker opens again on the first failure once it was open.
+	failures  int
+	openUntil time.Time
+}
+
+func (cb *circuitBreakerNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	cb.mtx.Lock()
+	open := cb.clock.Now().Before(cb.openUntil)
+	cb.mtx.Unlock()
+	if open {
+		return true, ErrCircuitOpen
+	}
+
+	retry, err := cb.NotificationChannel.Notify(ctx, as...)
+
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+	if err == nil || !serviceUnavailable(retry, err) {
+		cb.failures = 0
+		return retry, err
+	}
+	cb.failures++
+	if cb.failures >= cb.settings.failures {
+		cb.log.Warn("opening circuit breaker as notifications failed", "failures", cb.failures, "timeout", cb.settings.timeout, "error", err)
+		cb.openUntil = cb.clock.Now().Add(cb.settings.timeout)
+	}
+	return retry, err
+}
+
+// serviceUnavailable returns true if the notification failed as the service is
+// unavailable, either as the notifier retries it or from its status code or network
+// error. Other failures, such as bad requests, show that the service is available.
+func serviceUnavailable(retry bool, err error) bool {
+	if retry {
+		return true
+	}
+	switch classifyNotifyError(err) {
+	case NotifyErrorNetwork, NotifyErrorRateLimited, NotifyErrorServer:
+		return true
+	default:
+		return false
+	}
+}
+
+func (cb *circuitBreakerNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, cb.NotificationChannel)
+}
+
+func (cb *circuitBreakerNotifier) Close() {
+	Close(cb.NotificationChannel)
+}
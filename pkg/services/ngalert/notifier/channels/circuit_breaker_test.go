@@ -0,0 +1,149 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+type countingNotifier struct {
+	fakeNotifier
+	calls int
+}
+
+func (n *countingNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	n.calls++
+	return n.retry, n.err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	newNotifier := func(t *testing.T, settings string) (*circuitBreakerNotifier, *countingNotifier, *clock.Mock) {
+		t.Helper()
+		next := &countingNotifier{}
+		n, err := withCircuitBreaker(func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+			return next, nil
+		})(channels.FactoryConfig{
+			Config: &channels.NotificationChannelConfig{
+				Name:     "circuit_breaker_testing",
+				Type:     "webhook",
+				Settings: json.RawMessage(settings),
+			},
+			Logger: &channels.FakeLogger{},
+		})
+		require.NoError(t, err)
+		cb, ok := n.(*circuitBreakerNotifier)
+		require.True(t, ok)
+		mock := clock.NewMock()
+		cb.clock = mock
+		return cb, next, mock
+	}
+	alert := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}}
+
+	t.Run("opens after the failures and closes after the timeout", func(t *testing.T) {
+		cb, next, mock := newNotifier(t, `{"circuit_breaker_failures": 2, "circuit_breaker_timeout": "30s"}`)
+		next.retry, next.err = true, errors.New("service unavailable")
+
+		for i := 0; i < 2; i++ {
+			_, err := cb.Notify(context.Background(), alert)
+			require.EqualError(t, err, "service unavailable")
+		}
+		retry, err := cb.Notify(context.Background(), alert)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		require.True(t, retry)
+		require.Equal(t, 2, next.calls)
+
+		// The circuit breaker opens again on the first failure once it was open.
+		mock.Add(30 * time.Second)
+		_, err = cb.Notify(context.Background(), alert)
+		require.EqualError(t, err, "service unavailable")
+		_, err = cb.Notify(context.Background(), alert)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		require.Equal(t, 3, next.calls)
+
+		mock.Add(30 * time.Second)
+		next.retry, next.err = false, nil
+		_, err = cb.Notify(context.Background(), alert)
+		require.NoError(t, err)
+		next.retry, next.err = true, errors.New("service unavailable")
+		_, err = cb.Notify(context.Background(), alert)
+		require.EqualError(t, err, "service unavailable")
+		require.Equal(t, 5, next.calls)
+	})
+
+	t.Run("failures that are not retried open it if the service is unavailable", func(t *testing.T) {
+		cb, next, _ := newNotifier(t, `{"circuit_breaker_failures": 1}`)
+		next.retry, next.err = false, httpStatusError{StatusCode: http.StatusServiceUnavailable}
+
+		_, err := cb.Notify(context.Background(), alert)
+		require.ErrorAs(t, err, &httpStatusError{})
+		_, err = cb.Notify(context.Background(), alert)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		require.Equal(t, 1, next.calls)
+	})
+
+	t.Run("failures that are not retried do not open it if the service is available", func(t *testing.T) {
+		cb, next, _ := newNotifier(t, `{"circuit_breaker_failures": 1}`)
+		next.retry, next.err = false, httpStatusError{StatusCode: http.StatusBadRequest}
+
+		for i := 0; i < 3; i++ {
+			_, err := cb.Notify(context.Background(), alert)
+			require.ErrorAs(t, err, &httpStatusError{})
+		}
+		require.Equal(t, 3, next.calls)
+		require.Equal(t, circuitBreakerDefaultTimeout, cb.settings.timeout)
+	})
+}
+
+func TestCircuitBreaker_Settings(t *testing.T) {
+	cases := []struct {
+		name         string
+		settings     string
+		expInitError string
+	}{{
+		name:     "No circuit breaker",
+		settings: `{}`,
+	}, {
+		name:         "Error in initialization, negative failures",
+		settings:     `{"circuit_breaker_failures": -1}`,
+		expInitError: `failed to validate receiver "circuit_breaker_testing" of type "webhook": invalid value for circuit_breaker_failures: -1`,
+	}, {
+		name:         "Error in initialization, timeout without failures",
+		settings:     `{"circuit_breaker_timeout": "1m"}`,
+		expInitError: `failed to validate receiver "circuit_breaker_testing" of type "webhook": circuit_breaker_timeout requires circuit_breaker_failures`,
+	}, {
+		name:         "Error in initialization, timeout is too long",
+		settings:     `{"circuit_breaker_failures": 3, "circuit_breaker_timeout": "1h"}`,
+		expInitError: `failed to validate receiver "circuit_breaker_testing" of type "webhook": invalid value for circuit_breaker_timeout: "1h", must be a positive duration of at most 10m0s`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			next := &countingNotifier{}
+			n, err := withCircuitBreaker(func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+				return next, nil
+			})(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "circuit_breaker_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				Logger: &channels.FakeLogger{},
+			})
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+			require.Same(t, next, n)
+		})
+	}
+}
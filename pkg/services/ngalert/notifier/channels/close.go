@@ -0,0 +1,21 @@
+package channels
+
+import (
+	"github.com/grafana/alerting/alerting/notifier/channels"
+)
+
+// Closer is implemented by notifiers that keep connections open between
+// notifications, such as to a message broker. Close is called when the notifier
+// is replaced, for example when the configuration is reloaded, or once a test
+// notification has been sent.
+type Closer interface {
+	Close()
+}
+
+// Close closes the notifier if it implements Closer. Wrapping notifiers forward
+// it to the notifier they wrap.
+func Close(n channels.NotificationChannel) {
+	if c, ok := n.(Closer); ok {
+		c.Close()
+	}
+}
@@ -0,0 +1,52 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/stretchr/testify/require"
+)
+
+type closeRecordingNotifier struct {
+	*channels.Base
+	closed bool
+}
+
+func (n *closeRecordingNotifier) Notify(context.Context, ...*types.Alert) (bool, error) {
+	return true, nil
+}
+
+func (n *closeRecordingNotifier) SendResolved() bool {
+	return true
+}
+
+func (n *closeRecordingNotifier) Close() {
+	n.closed = true
+}
+
+func TestClose(t *testing.T) {
+	cfg := &channels.NotificationChannelConfig{
+		Name:     "close_testing",
+		Type:     "webhook",
+		Settings: json.RawMessage(`{"queue_size": 1, "coalesce": {"window": "1m"}, "send_if": "true"}`),
+	}
+	inner := &closeRecordingNotifier{Base: channels.NewBase(cfg)}
+	factory := func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+		return inner, nil
+	}
+	factory = withNotifyErrors(withCoalescing(withSendOnlyDuring(withStaticLabels(withDefaultAlertname(withRequireLabels(withSendIf(withLabelFilter(factory))))))))
+	factory = WithDestinationPolicy(factory, nil)
+	factory = WithMetrics(factory, nil)
+	n, err := WithQueue(factory, nil)(channels.FactoryConfig{
+		Config: cfg,
+		Logger: &channels.FakeLogger{},
+	})
+	require.NoError(t, err)
+
+	// The wrapping notifiers forward Close to the notifier they wrap.
+	Close(n)
+	require.True(t, inner.closed)
+}
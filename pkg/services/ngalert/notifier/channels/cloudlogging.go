@@ -0,0 +1,248 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	logging "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+)
+
+// cloudLoggingLogNameRegexp matches the ID of a log.
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry
+var cloudLoggingLogNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\-./]{1,512}$`)
+
+const (
+	cloudLoggingDefaultSeverity  = "ERROR"
+	cloudLoggingResolvedSeverity = "NOTICE"
+)
+
+// cloudLoggingSeverities maps the severity label of alerts to the severity of log
+// entries, ordered from the highest to the lowest severity.
+var cloudLoggingSeverities = []struct {
+	label    string
+	severity string
+}{
+	{label: "critical", severity: "CRITICAL"},
+	{label: "error", severity: "ERROR"},
+	{label: "warning", severity: "WARNING"},
+	{label: "info", severity: "INFO"},
+}
+
+// cloudLoggingClient writes log entries to Cloud Logging.
+type cloudLoggingClient interface {
+	WriteLogEntries(ctx context.Context, req *logging.WriteLogEntriesRequest) error
+}
+
+// cloudLoggingService is the cloudLoggingClient for the Cloud Logging API.
+type cloudLoggingService struct {
+	service *logging.Service
+}
+
+func (s cloudLoggingService) WriteLogEntries(ctx context.Context, req *logging.WriteLogEntriesRequest) error {
+	_, err := s.service.Entries.Write(req).Context(ctx).Do()
+	return err
+}
+
+// CloudLoggingNotifier is responsible for writing
+// alert notifications to a Google Cloud Logging log.
+type CloudLoggingNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	tmpl        *template.Template
+	settings    *cloudLoggingSettings
+	maxValueLen int
+
+	// mtx protects client, which is created on the first notification
+	// and then reused for all further notifications.
+	mtx    sync.Mutex
+	client cloudLoggingClient
+}
+
+type cloudLoggingSettings struct {
+	ProjectID       string `json:"projectId,omitempty" yaml:"projectId,omitempty"`
+	LogName         string `json:"logName,omitempty" yaml:"logName,omitempty"`
+	Credentials     string `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+	Title           string `json:"title,omitempty" yaml:"title,omitempty"`
+	Message         string `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage string `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+}
+
+// cloudLoggingPayload is the jsonPayload of the log entry.
+type cloudLoggingPayload struct {
+	*channels.ExtendedData
+
+	GroupKey string `json:"groupKey"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+}
+
+func buildCloudLoggingSettings(fc channels.FactoryConfig) (*cloudLoggingSettings, error) {
+	var settings cloudLoggingSettings
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	if settings.ProjectID == "" {
+		return nil, errors.New("could not find project ID in settings")
+	}
+	if !pubSubProjectIDRegexp.MatchString(settings.ProjectID) {
+		return nil, fmt.Errorf("invalid project ID %q", settings.ProjectID)
+	}
+	if settings.LogName == "" {
+		return nil, errors.New("could not find log name in settings")
+	}
+	if !cloudLoggingLogNameRegexp.MatchString(settings.LogName) {
+		return nil, fmt.Errorf("invalid log name %q", settings.LogName)
+	}
+	settings.Credentials = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "credentials", settings.Credentials)
+	if settings.Credentials != "" && !json.Valid([]byte(settings.Credentials)) {
+		return nil, errors.New("credentials must be a service account key in JSON format")
+	}
+	if settings.Title == "" {
+		settings.Title = channels.DefaultMessageTitleEmbed
+	}
+	if settings.Message == "" {
+		settings.Message = channels.DefaultMessageEmbed
+	}
+	return &settings, nil
+}
+
+func CloudLoggingFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	ch, err := newCloudLoggingNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return ch, nil
+}
+
+// newCloudLoggingNotifier is the constructor function for the Cloud Logging notifier.
+func newCloudLoggingNotifier(fc channels.FactoryConfig) (*CloudLoggingNotifier, error) {
+	settings, err := buildCloudLoggingSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CloudLoggingNotifier{
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+	}, nil
+}
+
+// Notify writes a log entry for the alert notification, with the labels common to
+// all alerts as the labels of the entry.
+func (cn *CloudLoggingNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	groupKey, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var tmplErr error
+	tmpl, data := tmplText(ctx, cn.tmpl, as, cn.log, &tmplErr, cn.maxValueLen)
+
+	payload := cloudLoggingPayload{
+		ExtendedData: data,
+		GroupKey:     groupKey.String(),
+		Title:        tmpl(cn.settings.Title),
+		Message:      tmpl(messageForAlerts(cn.settings.Message, cn.settings.ResolvedMessage, as)),
+	}
+	if tmplErr != nil {
+		cn.log.Warn("failed to template Cloud Logging entry", "error", tmplErr.Error())
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal log entry: %w", err)
+	}
+
+	client, err := cn.getClient()
+	if err != nil {
+		return false, fmt.Errorf("failed to create Cloud Logging client: %w", err)
+	}
+
+	logName := fmt.Sprintf("projects/%s/logs/%s", cn.settings.ProjectID, url.PathEscape(cn.settings.LogName))
+	req := &logging.WriteLogEntriesRequest{
+		Entries: []*logging.LogEntry{{
+			LogName: logName,
+			Resource: &logging.MonitoredResource{
+				Type:   "global",
+				Labels: map[string]string{"project_id": cn.settings.ProjectID},
+			},
+			Severity:    cloudLoggingSeverity(as),
+			Labels:      data.CommonLabels,
+			JsonPayload: b,
+		}},
+	}
+	if err := client.WriteLogEntries(ctx, req); err != nil {
+		return false, fmt.Errorf("failed to write to Cloud Logging log %q: %w", logName, err)
+	}
+
+	return true, nil
+}
+
+// cloudLoggingSeverity returns the severity of the log entry for the alerts. It is the
+// highest severity of the firing alerts, or NOTICE if all alerts are resolved.
+func cloudLoggingSeverity(as []*types.Alert) string {
+	if types.Alerts(as...).Status() == model.AlertResolved {
+		return cloudLoggingResolvedSeverity
+	}
+	for _, s := range cloudLoggingSeverities {
+		for _, a := range as {
+			if a.Status() == model.AlertFiring && strings.EqualFold(string(a.Labels["severity"]), s.label) {
+				return s.severity
+			}
+		}
+	}
+	return cloudLoggingDefaultSeverity
+}
+
+// getClient returns the Cloud Logging client, creating it if it does not exist.
+func (cn *CloudLoggingNotifier) getClient() (cloudLoggingClient, error) {
+	cn.mtx.Lock()
+	defer cn.mtx.Unlock()
+	if cn.client != nil {
+		return cn.client, nil
+	}
+
+	var opts []option.ClientOption
+	if cn.settings.Credentials != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(cn.settings.Credentials)))
+	}
+	// Without credentials the client uses Application Default Credentials.
+
+	// The client is used for all further notifications and so must not
+	// be bound to the context of this notification.
+	service, err := logging.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	cn.client = cloudLoggingService{service: service}
+	return cn.client, nil
+}
+
+func (cn *CloudLoggingNotifier) SendResolved() bool {
+	return !cn.GetDisableResolveMessage()
+}
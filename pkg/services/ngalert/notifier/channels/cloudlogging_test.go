@@ -0,0 +1,223 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	logging "google.golang.org/api/logging/v2"
+)
+
+// fakeCloudLoggingClient records the requests to write log entries.
+type fakeCloudLoggingClient struct {
+	requests []*logging.WriteLogEntriesRequest
+}
+
+func (c *fakeCloudLoggingClient) WriteLogEntries(_ context.Context, req *logging.WriteLogEntriesRequest) error {
+	c.requests = append(c.requests, req)
+	return nil
+}
+
+func TestCloudLoggingNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expLogName   string
+		expSeverity  string
+		expLabels    map[string]string
+		expTitle     string
+		expMessage   string
+		expInitError string
+	}{{
+		name:     "Default config with one alert",
+		settings: `{"projectId": "my-project", "logName": "grafana-alerts"}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+					Annotations: model.LabelSet{"ann1": "annv1"},
+				},
+			},
+		},
+		expLogName:  "projects/my-project/logs/grafana-alerts",
+		expSeverity: "ERROR",
+		expLabels:   map[string]string{"alertname": "alert1", "lbl1": "val1"},
+		expTitle:    "[FIRING:1]  (val1)",
+		expMessage:  "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+	}, {
+		name: "Custom config with the highest severity of the alerts",
+		settings: `{
+			"projectId": "my-project",
+			"logName": "alerts/grafana",
+			"title": "{{ .CommonLabels.alertname }}",
+			"message": "{{ len .Alerts.Firing }} alerts are firing"
+		}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1", "severity": "warning"},
+				},
+			}, {
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1", "severity": "critical"},
+				},
+			},
+		},
+		expLogName:  "projects/my-project/logs/alerts%2Fgrafana",
+		expSeverity: "CRITICAL",
+		expLabels:   map[string]string{"alertname": "alert1"},
+		expTitle:    "alert1",
+		expMessage:  "2 alerts are firing",
+	}, {
+		name: "Resolved alerts",
+		settings: `{
+			"projectId": "my-project",
+			"logName": "grafana-alerts",
+			"title": "{{ .CommonLabels.alertname }}",
+			"message": "{{ len .Alerts.Resolved }} alerts are resolved"
+		}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:   model.LabelSet{"alertname": "alert1", "severity": "critical"},
+					StartsAt: time.Now().Add(-2 * time.Hour),
+					EndsAt:   time.Now().Add(-time.Hour),
+				},
+			},
+		},
+		expLogName:  "projects/my-project/logs/grafana-alerts",
+		expSeverity: "NOTICE",
+		expLabels:   map[string]string{"alertname": "alert1", "severity": "critical"},
+		expTitle:    "alert1",
+		expMessage:  "1 alerts are resolved",
+	}, {
+		name: "Resolved alerts with resolved message",
+		settings: `{
+			"projectId": "my-project",
+			"logName": "grafana-alerts",
+			"title": "{{ .CommonLabels.alertname }}",
+			"message": "{{ len .Alerts.Firing }} alerts are firing",
+			"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+		}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:   model.LabelSet{"alertname": "alert1", "severity": "critical"},
+					StartsAt: time.Now().Add(-2 * time.Hour),
+					EndsAt:   time.Now().Add(-time.Hour),
+				},
+			},
+		},
+		expLogName:  "projects/my-project/logs/grafana-alerts",
+		expSeverity: "NOTICE",
+		expLabels:   map[string]string{"alertname": "alert1", "severity": "critical"},
+		expTitle:    "alert1",
+		expMessage:  "1 alerts are resolved",
+	}, {
+		name: "Firing alerts with resolved message",
+		settings: `{
+			"projectId": "my-project",
+			"logName": "grafana-alerts",
+			"title": "{{ .CommonLabels.alertname }}",
+			"message": "{{ len .Alerts.Firing }} alerts are firing",
+			"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+		}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1", "severity": "critical"},
+				},
+			},
+		},
+		expLogName:  "projects/my-project/logs/grafana-alerts",
+		expSeverity: "CRITICAL",
+		expLabels:   map[string]string{"alertname": "alert1", "severity": "critical"},
+		expTitle:    "alert1",
+		expMessage:  "1 alerts are firing",
+	}, {
+		name:         "Error in initing, missing project ID",
+		settings:     `{"logName": "grafana-alerts"}`,
+		expInitError: `could not find project ID in settings`,
+	}, {
+		name:         "Error in initing, invalid project ID",
+		settings:     `{"projectId": "My_Project", "logName": "grafana-alerts"}`,
+		expInitError: `invalid project ID "My_Project"`,
+	}, {
+		name:         "Error in initing, missing log name",
+		settings:     `{"projectId": "my-project"}`,
+		expInitError: `could not find log name in settings`,
+	}, {
+		name:         "Error in initing, invalid log name",
+		settings:     `{"projectId": "my-project", "logName": "grafana alerts"}`,
+		expInitError: `invalid log name "grafana alerts"`,
+	}, {
+		name:         "Error in initing, invalid credentials",
+		settings:     `{"projectId": "my-project", "logName": "grafana-alerts", "credentials": "not json"}`,
+		expInitError: `credentials must be a service account key in JSON format`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "cloudlogging_testing",
+					Type:     "cloudlogging",
+					Settings: json.RawMessage(c.settings),
+				},
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			cn, err := newCloudLoggingNotifier(fc)
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			client := &fakeCloudLoggingClient{}
+			cn.client = client
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := cn.Notify(ctx, c.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.Len(t, client.requests, 1)
+			require.Len(t, client.requests[0].Entries, 1)
+			entry := client.requests[0].Entries[0]
+			require.Equal(t, c.expLogName, entry.LogName)
+			require.Equal(t, &logging.MonitoredResource{
+				Type:   "global",
+				Labels: map[string]string{"project_id": "my-project"},
+			}, entry.Resource)
+			require.Equal(t, c.expSeverity, entry.Severity)
+			require.Equal(t, c.expLabels, entry.Labels)
+
+			var payload map[string]interface{}
+			require.NoError(t, json.Unmarshal(entry.JsonPayload, &payload))
+			require.Equal(t, "alertname", payload["groupKey"])
+			require.Equal(t, c.expTitle, payload["title"])
+			require.Equal(t, c.expMessage, payload["message"])
+			require.Len(t, payload["alerts"], len(c.alerts))
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// coalesceMaxWindow is the longest that notifications can be held back to coalesce
+	// them, as the notifications of the first groups are delayed by the window.
+	coalesceMaxWindow = time.Minute
+	// coalesceDefaultMaxAlerts is the default number of alerts after which a batch is
+	// sent before the end of the window.
+	coalesceDefaultMaxAlerts = 100
+)
+
+// groupKeyReceivers are the receivers that identify incidents by the group key, such as
+// PagerDuty with the dedup_key and Opsgenie with the alias. As a coalesced notification
+// has a group key made of the keys of its groups, the incident it opens would not be
+// resolved by the resolved notification of one of the groups, so these receivers do
+// not support coalescing.
+var groupKeyReceivers = map[string]struct{}{
+	"kafka":     {},
+	"opsgenie":  {},
+	"pagerduty": {},
+	"squadcast": {},
+	"victorops": {},
+	"zenduty":   {},
+}
+
+// coalesceSettings are the settings to coalesce the notifications of groups.
+type coalesceSettings struct {
+	window    time.Duration
+	maxAlerts int
+}
+
+// buildCoalesce returns the coalesce_window and coalesce_max_alerts settings of the
+// notifier, or nil if notifications are not coalesced.
+func buildCoalesce(fc channels.FactoryConfig) (*coalesceSettings, error) {
+	var settings struct {
+		Window    string      `json:"coalesce_window,omitempty" yaml:"coalesce_window,omitempty"`
+		MaxAlerts json.Number `json:"coalesce_max_alerts,omitempty" yaml:"coalesce_max_alerts,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.Window == "" {
+		if settings.MaxAlerts != "" {
+			return nil, errors.New("coalesce_max_alerts requires coalesce_window")
+		}
+		return nil, nil
+	}
+	window, err := time.ParseDuration(settings.Window)
+	if err != nil || window <= 0 || window > coalesceMaxWindow {
+		return nil, fmt.Errorf("invalid value for coalesce_window: %q, must be a positive duration of at most %s", settings.Window, coalesceMaxWindow)
+	}
+	maxAlerts := coalesceDefaultMaxAlerts
+	if settings.MaxAlerts != "" {
+		maxAlerts, err = strconv.Atoi(settings.MaxAlerts.String())
+		if err != nil || maxAlerts <= 0 {
+			return nil, fmt.Errorf("invalid value for coalesce_max_alerts: %q, must be a positive integer", settings.MaxAlerts)
+		}
+	}
+	return &coalesceSettings{window: window, maxAlerts: maxAlerts}, nil
+}
+
+// coalesceNotifier merges the notifications of the groups of a notifier that are sent
+// within a window into one notification.
+type coalesceNotifier struct {
+	channels.NotificationChannel
+	log      channels.Logger
+	clock    clock.Clock
+	settings coalesceSettings
+
+	mtx   sync.Mutex
+	batch *coalesceBatch
+}
+
+// coalesceBatch is the notifications that are merged into one notification.
+type coalesceBatch struct {
+	calls     []*coalesceCall
+	numAlerts int
+	timer     *clock.Timer
+	// done is closed when the notification is sent, with its result in ok and err.
+	done chan struct{}
+	ok   bool
+	err  error
+}
+
+// coalesceCall is a notification of a group in a batch.
+type coalesceCall struct {
+	ctx    context.Context
+	alerts []*types.Alert
+}
+
+// withCoalescing wraps the factory so that notifiers with the coalesce_window setting
+// merge the notifications of groups that are sent within the window, such as when many
+// small groups fire at once. The notification is sent at the end of the window, or when
+// it has coalesce_max_alerts alerts.
+func withCoalescing(factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		settings, err := buildCoalesce(fc)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: err.Error(),
+				Cfg:    *fc.Config,
+			}
+		}
+		if _, ok := groupKeyReceivers[fc.Config.Type]; ok && settings != nil {
+			return nil, receiverInitError{
+				Reason: fmt.Sprintf("coalesce_window is not supported by %s receivers, as they identify incidents by the group key", fc.Config.Type),
+				Cfg:    *fc.Config,
+			}
+		}
+		n, err := factory(fc)
+		if err != nil || settings == nil {
+			return n, err
+		}
+		return &coalesceNotifier{
+			NotificationChannel: n,
+			log:                 fc.Logger,
+			clock:               clock.New(),
+			settings:            *settings,
+		}, nil
+	}
+}
+
+// Notify adds the alerts to the batch, and waits until the batch is sent so the result
+// of the notification is returned to each group. If ctx is done before the batch is
+// sent, the alerts are removed from the batch, so they are sent when the notification
+// is retried instead.
+func (cn *coalesceNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	call := &coalesceCall{ctx: ctx, alerts: as}
+
+	cn.mtx.Lock()
+	b := cn.batch
+	if b == nil {
+		b = &coalesceBatch{done: make(chan struct{})}
+		b.timer = cn.clock.AfterFunc(cn.settings.window, func() { cn.flush(b) })
+		cn.batch = b
+	}
+	b.calls = append(b.calls, call)
+	b.numAlerts += len(as)
+	full := b.numAlerts >= cn.settings.maxAlerts
+	cn.mtx.Unlock()
+
+	if full {
+		cn.flush(b)
+	}
+
+	select {
+	case <-b.done:
+		return b.ok, b.err
+	case <-ctx.Done():
+		cn.remove(b, call)
+		return false, ctx.Err()
+	}
+}
+
+// remove removes the call from the batch if the batch is not sent yet. The batch is
+// discarded if it has no calls left.
+func (cn *coalesceNotifier) remove(b *coalesceBatch, call *coalesceCall) {
+	cn.mtx.Lock()
+	defer cn.mtx.Unlock()
+	if cn.batch != b {
+		return
+	}
+	for i, c := range b.calls {
+		if c == call {
+			b.calls = append(b.calls[:i], b.calls[i+1:]...)
+			b.numAlerts -= len(call.alerts)
+			break
+		}
+	}
+	if len(b.calls) == 0 {
+		b.timer.Stop()
+		cn.batch = nil
+	}
+}
+
+// flush sends the batch, unless it is already sent by the timer or a full batch.
+func (cn *coalesceNotifier) flush(b *coalesceBatch) {
+	cn.mtx.Lock()
+	if cn.batch != b {
+		cn.mtx.Unlock()
+		return
+	}
+	cn.batch = nil
+	b.timer.Stop()
+	calls := b.calls
+	cn.mtx.Unlock()
+
+	ctx, cancel := coalescedContext(calls)
+	defer cancel()
+	alerts := coalesceAlerts(calls)
+	cn.log.Debug("sending coalesced notification", "groups", len(calls), "alerts", len(alerts))
+	b.ok, b.err = cn.NotificationChannel.Notify(ctx, alerts...)
+	close(b.done)
+}
+
+// coalescedContext returns the context of the notification of the calls. It has the
+// values of the context of the first call, as the notification outlives the calls
+// whose contexts are done, and the earliest deadline of the calls. Its group key
+// has the group keys of the calls, and its group labels are the labels common to the
+// group labels of the calls.
+func coalescedContext(calls []*coalesceCall) (context.Context, context.CancelFunc) {
+	var (
+		ctx         context.Context = detachedContext{calls[0].ctx}
+		deadline    time.Time
+		keys        []string
+		seen        = make(map[string]struct{}, len(calls))
+		groupLabels model.LabelSet
+	)
+	for i, c := range calls {
+		if d, ok := c.ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+			deadline = d
+		}
+		if key, err := notify.ExtractGroupKey(c.ctx); err == nil {
+			if _, ok := seen[key.String()]; !ok {
+				seen[key.String()] = struct{}{}
+				keys = append(keys, key.String())
+			}
+		}
+		labels, _ := notify.GroupLabels(c.ctx)
+		if i == 0 {
+			groupLabels = labels.Clone()
+			continue
+		}
+		for name, value := range groupLabels {
+			if labels[name] != value {
+				delete(groupLabels, name)
+			}
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		ctx = notify.WithGroupKey(ctx, strings.Join(keys, ","))
+	}
+	ctx = notify.WithGroupLabels(ctx, groupLabels)
+	if deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// coalesceAlerts returns the alerts of the calls. Alerts that are in more than one
+// group are only sent once.
+func coalesceAlerts(calls []*coalesceCall) []*types.Alert {
+	var alerts []*types.Alert
+	seen := make(map[model.Fingerprint]struct{})
+	for _, c := range calls {
+		for _, a := range c.alerts {
+			if _, ok := seen[a.Fingerprint()]; ok {
+				continue
+			}
+			seen[a.Fingerprint()] = struct{}{}
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts
+}
+
+// HealthCheck checks the wrapped notifier, as health checks are not coalesced.
+func (cn *coalesceNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, cn.NotificationChannel)
+}
+
+func (cn *coalesceNotifier) Close() {
+	Close(cn.NotificationChannel)
+}
@@ -0,0 +1,309 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesce(t *testing.T) {
+	newNotifier := func(t *testing.T, settings string) (*coalesceNotifier, *webhookRecordingSender, *clock.Mock) {
+		t.Helper()
+		sender := &webhookRecordingSender{}
+		factory, ok := Factory("webhook")
+		require.True(t, ok)
+		n, err := factory(channels.FactoryConfig{
+			Config: &channels.NotificationChannelConfig{
+				Name:     "webhook_testing",
+				Type:     "webhook",
+				Settings: json.RawMessage(settings),
+			},
+			ImageStore:          &channels.UnavailableImageStore{},
+			NotificationService: sender,
+			DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+				return fallback
+			},
+			Template: templateForTests(t),
+			Logger:   &channels.FakeLogger{},
+		})
+		require.NoError(t, err)
+		cn, ok := n.(*notifyErrorNotifier).NotificationChannel.(*coalesceNotifier)
+		require.True(t, ok)
+		mock := clock.NewMock()
+		cn.clock = mock
+		return cn, sender, mock
+	}
+
+	type result struct {
+		ok  bool
+		err error
+	}
+	// notifyGroup sends the notification of a group in a goroutine, and waits until its
+	// alerts are in the batch.
+	notifyGroup := func(ctx context.Context, cn *coalesceNotifier, group string, names ...model.LabelValue) <-chan result {
+		as := make([]*types.Alert, 0, len(names))
+		for _, name := range names {
+			as = append(as, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": name, "group": model.LabelValue(group)}}})
+		}
+		ctx = notify.WithGroupKey(ctx, group)
+		ctx = notify.WithGroupLabels(ctx, model.LabelSet{"group": model.LabelValue(group), "env": "prod"})
+
+		cn.mtx.Lock()
+		var numCalls int
+		if cn.batch != nil {
+			numCalls = len(cn.batch.calls)
+		}
+		cn.mtx.Unlock()
+
+		res := make(chan result, 1)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			ok, err := cn.Notify(ctx, as...)
+			res <- result{ok: ok, err: err}
+		}()
+		// The notification returns without waiting if its alerts fill the batch.
+		require.Eventually(t, func() bool {
+			select {
+			case <-done:
+				return true
+			default:
+			}
+			cn.mtx.Lock()
+			defer cn.mtx.Unlock()
+			return cn.batch != nil && len(cn.batch.calls) > numCalls
+		}, time.Second, time.Millisecond)
+		return res
+	}
+	// sentAlerts returns the alertnames of the alerts in the request, and its group labels.
+	sentAlerts := func(t *testing.T, r channels.SendWebhookSettings) ([]string, map[string]string) {
+		t.Helper()
+		var msg struct {
+			Alerts []struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"alerts"`
+			GroupLabels map[string]string `json:"groupLabels"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(r.Body), &msg))
+		names := make([]string, 0, len(msg.Alerts))
+		for _, a := range msg.Alerts {
+			names = append(names, a.Labels["alertname"])
+		}
+		return names, msg.GroupLabels
+	}
+
+	t.Run("Groups within the window are sent in one notification", func(t *testing.T) {
+		cn, sender, mock := newNotifier(t, `{"url": "http://localhost/test", "coalesce_window": "10s"}`)
+
+		res1 := notifyGroup(context.Background(), cn, "group1", "alert1")
+		mock.Add(5 * time.Second)
+		res2 := notifyGroup(context.Background(), cn, "group2", "alert2", "alert3")
+		require.Empty(t, sender.requests)
+
+		// The window starts with the first group.
+		mock.Add(5 * time.Second)
+		for _, res := range []<-chan result{res1, res2} {
+			r := <-res
+			require.NoError(t, r.err)
+			require.True(t, r.ok)
+		}
+		require.Len(t, sender.requests, 1)
+		names, groupLabels := sentAlerts(t, sender.requests[0])
+		require.Equal(t, []string{"alert1", "alert2", "alert3"}, names)
+		require.Equal(t, map[string]string{"env": "prod"}, groupLabels)
+
+		// The next group starts a new window.
+		res3 := notifyGroup(context.Background(), cn, "group3", "alert4")
+		mock.Add(10 * time.Second)
+		require.NoError(t, (<-res3).err)
+		require.Len(t, sender.requests, 2)
+		names, _ = sentAlerts(t, sender.requests[1])
+		require.Equal(t, []string{"alert4"}, names)
+	})
+
+	t.Run("Full batch is sent before the end of the window", func(t *testing.T) {
+		cn, sender, _ := newNotifier(t, `{"url": "http://localhost/test", "coalesce_window": "10s", "coalesce_max_alerts": 3}`)
+
+		res1 := notifyGroup(context.Background(), cn, "group1", "alert1")
+		res2 := notifyGroup(context.Background(), cn, "group2", "alert2", "alert3")
+		for _, res := range []<-chan result{res1, res2} {
+			r := <-res
+			require.NoError(t, r.err)
+			require.True(t, r.ok)
+		}
+		require.Len(t, sender.requests, 1)
+		names, _ := sentAlerts(t, sender.requests[0])
+		require.Equal(t, []string{"alert1", "alert2", "alert3"}, names)
+	})
+
+	t.Run("Groups whose context is done are removed from the batch", func(t *testing.T) {
+		cn, sender, mock := newNotifier(t, `{"url": "http://localhost/test", "coalesce_window": "10s"}`)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		res1 := notifyGroup(ctx, cn, "group1", "alert1")
+		res2 := notifyGroup(context.Background(), cn, "group2", "alert2")
+		cancel()
+		r := <-res1
+		require.ErrorIs(t, r.err, context.Canceled)
+		require.False(t, r.ok)
+
+		mock.Add(10 * time.Second)
+		require.NoError(t, (<-res2).err)
+		require.Len(t, sender.requests, 1)
+		names, _ := sentAlerts(t, sender.requests[0])
+		require.Equal(t, []string{"alert2"}, names)
+	})
+
+	t.Run("Resolved notification of a group in a coalesced batch has the group key of the group", func(t *testing.T) {
+		cn, sender, mock := newNotifier(t, `{"url": "http://localhost/test", "coalesce_window": "10s"}`)
+		groupKey := func(t *testing.T, r channels.SendWebhookSettings) (string, string) {
+			t.Helper()
+			var msg struct {
+				GroupKey string `json:"groupKey"`
+				Status   string `json:"status"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(r.Body), &msg))
+			return msg.GroupKey, msg.Status
+		}
+
+		res1 := notifyGroup(context.Background(), cn, "group1", "alert1")
+		res2 := notifyGroup(context.Background(), cn, "group2", "alert2")
+		mock.Add(10 * time.Second)
+		require.NoError(t, (<-res1).err)
+		require.NoError(t, (<-res2).err)
+
+		// Only group1 resolves, so its resolved notification is not coalesced with group2.
+		ctx := notify.WithGroupKey(context.Background(), "group1")
+		ctx = notify.WithGroupLabels(ctx, model.LabelSet{"group": "group1"})
+		res := make(chan result, 1)
+		go func() {
+			ok, err := cn.Notify(ctx, &types.Alert{Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": "alert1", "group": "group1"},
+				EndsAt: time.Now().Add(-time.Minute),
+			}})
+			res <- result{ok: ok, err: err}
+		}()
+		require.Eventually(t, func() bool {
+			cn.mtx.Lock()
+			defer cn.mtx.Unlock()
+			return cn.batch != nil
+		}, time.Second, time.Millisecond)
+		mock.Add(10 * time.Second)
+		require.NoError(t, (<-res).err)
+
+		// Receivers that identify incidents by the group key would not resolve the
+		// incident of the firing notification, which is why they cannot coalesce.
+		require.Len(t, sender.requests, 2)
+		key, status := groupKey(t, sender.requests[0])
+		require.Equal(t, "group1,group2", key)
+		require.Equal(t, "firing", status)
+		key, status = groupKey(t, sender.requests[1])
+		require.Equal(t, "group1", key)
+		require.Equal(t, "resolved", status)
+	})
+
+	t.Run("Errors are returned to all groups", func(t *testing.T) {
+		cn, sender, mock := newNotifier(t, `{"url": "http://localhost/test", "coalesce_window": "10s"}`)
+		sender.fail = "alert2"
+
+		res1 := notifyGroup(context.Background(), cn, "group1", "alert1")
+		res2 := notifyGroup(context.Background(), cn, "group2", "alert2")
+		mock.Add(10 * time.Second)
+		for _, res := range []<-chan result{res1, res2} {
+			require.EqualError(t, (<-res).err, "failed to send request")
+		}
+	})
+}
+
+func TestCoalesce_GroupKeyReceivers(t *testing.T) {
+	cases := []struct {
+		receiverType string
+		settings     string
+	}{{
+		receiverType: "pagerduty",
+		settings:     `{"integrationKey": "abcdefgh0123456789", "coalesce_window": "10s"}`,
+	}, {
+		receiverType: "opsgenie",
+		settings:     `{"apiKey": "abcdefgh0123456789", "coalesce_window": "10s"}`,
+	}, {
+		receiverType: "victorops",
+		settings:     `{"url": "http://localhost/test", "coalesce_window": "10s"}`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.receiverType, func(t *testing.T) {
+			factory, ok := Factory(c.receiverType)
+			require.True(t, ok)
+			_, err := factory(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "coalesce_testing",
+					Type:     c.receiverType,
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: mockNotificationService(),
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: templateForTests(t),
+				Logger:   &channels.FakeLogger{},
+			})
+			require.EqualError(t, err, fmt.Sprintf(`failed to validate receiver "coalesce_testing" of type "%s": coalesce_window is not supported by %s receivers, as they identify incidents by the group key`, c.receiverType, c.receiverType))
+		})
+	}
+}
+
+func TestBuildCoalesce(t *testing.T) {
+	cases := []struct {
+		name        string
+		settings    string
+		expSettings *coalesceSettings
+		expError    string
+	}{{
+		name:     "Not coalesced by default",
+		settings: `{}`,
+	}, {
+		name:        "Default max alerts",
+		settings:    `{"coalesce_window": "5s"}`,
+		expSettings: &coalesceSettings{window: 5 * time.Second, maxAlerts: coalesceDefaultMaxAlerts},
+	}, {
+		name:        "Max alerts",
+		settings:    `{"coalesce_window": "1m", "coalesce_max_alerts": 10}`,
+		expSettings: &coalesceSettings{window: time.Minute, maxAlerts: 10},
+	}, {
+		name:     "Window is too long",
+		settings: `{"coalesce_window": "2m"}`,
+		expError: `invalid value for coalesce_window: "2m", must be a positive duration of at most 1m0s`,
+	}, {
+		name:     "Invalid max alerts",
+		settings: `{"coalesce_window": "5s", "coalesce_max_alerts": 0}`,
+		expError: `invalid value for coalesce_max_alerts: "0", must be a positive integer`,
+	}, {
+		name:     "Max alerts without window",
+		settings: `{"coalesce_max_alerts": 10}`,
+		expError: "coalesce_max_alerts requires coalesce_window",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settings, err := buildCoalesce(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{Settings: json.RawMessage(c.settings)},
+			})
+			if c.expError != "" {
+				require.EqualError(t, err, c.expError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expSettings, settings)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+// defaultAlertname is the alertname of alerts without one, such as alerts from
+// external sources, so titles do not have a blank name.
+const defaultAlertname = "alert"
+
+// buildDefaultAlertname returns the default_alertname setting of the notifier, or
+// defaultAlertname if it is not set.
+func buildDefaultAlertname(fc channels.FactoryConfig) (model.LabelValue, error) {
+	var settings struct {
+		DefaultAlertname string `json:"default_alertname,omitempty" yaml:"default_alertname,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return "", fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.DefaultAlertname == "" {
+		return defaultAlertname, nil
+	}
+	return model.LabelValue(settings.DefaultAlertname), nil
+}
+
+// defaultAlertnameNotifier sets the alertname of the alerts of the notifications of a
+// notifier that do not have one.
+type defaultAlertnameNotifier struct {
+	channels.NotificationChannel
+	alertname model.LabelValue
+}
+
+// withDefaultAlertname wraps the factory so that alerts without an alertname label,
+// or with an empty one, have the default alertname in the template data and the
+// payloads.
+func withDefaultAlertname(factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		alertname, err := buildDefaultAlertname(fc)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: err.Error(),
+				Cfg:    *fc.Config,
+			}
+		}
+		n, err := factory(fc)
+		if err != nil {
+			return nil, err
+		}
+		return &defaultAlertnameNotifier{
+			NotificationChannel: n,
+			alertname:           alertname,
+		}, nil
+	}
+}
+
+// Notify sends the notification with the default alertname set on copies of the alerts
+// without one, as the alerts are shared with the other notifiers of the contact point.
+// An empty alertname in the group labels is set too, as alerts are grouped by it.
+func (dn *defaultAlertnameNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	alerts := make([]*types.Alert, 0, len(as))
+	for _, a := range as {
+		if a.Labels[model.AlertNameLabel] != "" {
+			alerts = append(alerts, a)
+			continue
+		}
+		alert := *a
+		alert.Labels = a.Labels.Clone()
+		if alert.Labels == nil {
+			alert.Labels = model.LabelSet{}
+		}
+		alert.Labels[model.AlertNameLabel] = dn.alertname
+		alerts = append(alerts, &alert)
+	}
+	if groupLabels, ok := notify.GroupLabels(ctx); ok {
+		if alertname, ok := groupLabels[model.AlertNameLabel]; ok && alertname == "" {
+			groupLabels = groupLabels.Clone()
+			groupLabels[model.AlertNameLabel] = dn.alertname
+			ctx = notify.WithGroupLabels(ctx, groupLabels)
+		}
+	}
+	return dn.NotificationChannel.Notify(ctx, alerts...)
+}
+
+// HealthCheck checks the wrapped notifier, as the alertname does not apply to health checks.
+func (dn *defaultAlertnameNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, dn.NotificationChannel)
+}
+
+func (dn *defaultAlertnameNotifier) Close() {
+	Close(dn.NotificationChannel)
+}
@@ -0,0 +1,98 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultAlertname(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	cases := []struct {
+		name         string
+		settings     string
+		labels       model.LabelSet
+		expTitle     string
+		expLabels    map[string]string
+		expInitError string
+	}{{
+		name:      "Alertname of alerts is kept",
+		settings:  `{"url": "http://localhost/test"}`,
+		labels:    model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+		expTitle:  "[FIRING:1] alert1 (val1)",
+		expLabels: map[string]string{"alertname": "alert1", "lbl1": "val1"},
+	}, {
+		name:      "Default alertname is used for alerts without alertname",
+		settings:  `{"url": "http://localhost/test"}`,
+		labels:    model.LabelSet{"lbl1": "val1"},
+		expTitle:  "[FIRING:1] alert (val1)",
+		expLabels: map[string]string{"alertname": "alert", "lbl1": "val1"},
+	}, {
+		name:      "Configured default alertname is used for alerts with an empty alertname",
+		settings:  `{"url": "http://localhost/test", "default_alertname": "External alert"}`,
+		labels:    model.LabelSet{"alertname": "", "lbl1": "val1"},
+		expTitle:  "[FIRING:1] External alert (val1)",
+		expLabels: map[string]string{"alertname": "External alert", "lbl1": "val1"},
+	}, {
+		name:         "Error in initialization, invalid settings",
+		settings:     `{"url": "http://localhost/test", "default_alertname": 1}`,
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": failed to unmarshal settings: json: cannot unmarshal number into Go struct field .default_alertname of type string`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			factory, ok := Factory("webhook")
+			require.True(t, ok)
+			n, err := factory(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			alert := &types.Alert{Alert: model.Alert{Labels: c.labels.Clone()}}
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": c.labels["alertname"]})
+			ok, err = n.Notify(ctx, alert)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			var msg struct {
+				Alerts []struct {
+					Labels map[string]string `json:"labels"`
+				} `json:"alerts"`
+				Title string `json:"title"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(webhookSender.Webhook.Body), &msg))
+			require.Equal(t, c.expTitle, msg.Title)
+			require.Len(t, msg.Alerts, 1)
+			require.Equal(t, c.expLabels, msg.Alerts[0].Labels)
+
+			// The alert is shared with other notifiers, so it must not be changed.
+			require.Equal(t, c.labels, alert.Labels)
+		})
+	}
+}
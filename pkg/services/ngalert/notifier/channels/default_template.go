@@ -1,6 +1,7 @@
 package channels
 
 import (
+	"net/url"
 	"os"
 	"testing"
 
@@ -25,6 +26,9 @@ func templateForTests(t *testing.T) *template.Template {
 
 	tmpl, err := template.FromGlobs(f.Name())
 	require.NoError(t, err)
+	// Tests can override the external URL, but the template data cannot be created without one.
+	tmpl.ExternalURL, err = url.Parse("http://localhost")
+	require.NoError(t, err)
 
 	return tmpl
 }
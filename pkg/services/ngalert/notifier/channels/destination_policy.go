@@ -0,0 +1,300 @@
+package channels
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+
+	"github.com/grafana/grafana/pkg/services/notifications"
+)
+
+// DestinationPolicy restricts the hosts that notifiers send requests to, so that the
+// contact points of tenants cannot send requests to internal services such as the
+// metadata endpoint 169.254.169.254. A host is denied if its name or any of its IP
+// addresses are denied. If hosts are allowed, a host must also be allowed by its name
+// or by all of its IP addresses.
+type DestinationPolicy struct {
+	allowedHosts []string
+	allowedNets  []*net.IPNet
+	deniedHosts  []string
+	deniedNets   []*net.IPNet
+
+	// client sends the webhooks of notifiers, which are sent with the shared client of
+	// the notification service otherwise. It is created once, so its connections are
+	// reused.
+	clientOnce sync.Once
+	client     *http.Client
+}
+
+// NewDestinationPolicy returns the policy with the allowed and denied destinations, or
+// nil if there are neither. Destinations are hostnames, IP addresses or CIDR ranges.
+// Hostnames starting with "*." match all subdomains.
+func NewDestinationPolicy(allowed, denied []string) (*DestinationPolicy, error) {
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil, nil
+	}
+	var (
+		p   DestinationPolicy
+		err error
+	)
+	if p.allowedHosts, p.allowedNets, err = parseDestinations(allowed); err != nil {
+		return nil, err
+	}
+	if p.deniedHosts, p.deniedNets, err = parseDestinations(denied); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// parseDestinations returns the hostnames and the networks of the IP addresses and CIDR
+// ranges in the destinations.
+func parseDestinations(destinations []string) ([]string, []*net.IPNet, error) {
+	var (
+		hosts []string
+		nets  []*net.IPNet
+	)
+	for _, d := range destinations {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(d); err == nil {
+			nets = append(nets, ipNet)
+			continue
+		}
+		if ip := net.ParseIP(strings.Trim(d, "[]")); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		if !hostnameRegexp.MatchString(strings.TrimPrefix(d, "*.")) {
+			return nil, nil, fmt.Errorf("invalid destination %q, must be a hostname, IP address or CIDR range", d)
+		}
+		hosts = append(hosts, d)
+	}
+	return hosts, nets, nil
+}
+
+// destinationBlockedError is returned when a request is sent to a host that is not
+// allowed by the destination policy.
+type destinationBlockedError struct {
+	Host string
+	IP   net.IP
+}
+
+func (e destinationBlockedError) Error() string {
+	if e.IP == nil || e.IP.String() == e.Host {
+		return fmt.Sprintf("destination %q is not allowed", e.Host)
+	}
+	return fmt.Sprintf("destination %q is not allowed, it resolves to %s", e.Host, e.IP)
+}
+
+// resolve returns the IP addresses of the host, or an error if the host or any of its
+// IP addresses are not allowed. The host is resolved with the resolver, or the system
+// resolver if it is nil.
+func (p *DestinationPolicy) resolve(ctx context.Context, resolver *net.Resolver, host string) ([]net.IP, error) {
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if matchesHosts(host, p.deniedHosts) {
+		return nil, destinationBlockedError{Host: host}
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	hostAllowed := len(p.allowedHosts) == 0 && len(p.allowedNets) == 0 || matchesHosts(host, p.allowedHosts)
+	for _, ip := range ips {
+		if containsIP(p.deniedNets, ip) || !hostAllowed && !containsIP(p.allowedNets, ip) {
+			return nil, destinationBlockedError{Host: host, IP: ip}
+		}
+	}
+	return ips, nil
+}
+
+// dialContext returns a function that dials the IP addresses of the host of the address
+// after they are checked, instead of the host, so that the IP address that is dialed is
+// the one that is checked even if the host resolves to another IP address in between,
+// as in DNS rebinding.
+func (p *DestinationPolicy) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := p.resolve(ctx, dialer.Resolver, host)
+		if err != nil {
+			return nil, err
+		}
+		var firstErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return nil, firstErr
+	}
+}
+
+// proxy returns a function that returns the proxy of a request from the environment. The
+// host of the request is checked if it is sent through a proxy, as the proxy is dialed
+// instead of the host. The proxy must be allowed too.
+func (p *DestinationPolicy) proxy(resolver *net.Resolver) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		u, err := http.ProxyFromEnvironment(req)
+		if err != nil || u == nil {
+			return u, err
+		}
+		if _, err := p.resolve(req.Context(), resolver, req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		return u, nil
+	}
+}
+
+// matchesHosts returns true if the host is one of the hosts. Hosts starting with "*."
+// match all subdomains.
+func matchesHosts(host string, hosts []string) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(host, h) {
+			return true
+		}
+		if domain := strings.TrimPrefix(h, "*"); domain != h && len(host) > len(domain) && strings.EqualFold(host[len(host)-len(domain):], domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpClient returns the client that sends requests to the destinations allowed by the
+// policy. It dials the IP addresses that are checked, for redirects too. Its transport
+// has the settings of the transport that is shared by webhooks, such as the connection
+// limits, as it sends webhooks instead of the shared client.
+func (p *DestinationPolicy) httpClient() *http.Client {
+	p.clientOnce.Do(func() {
+		transport := notifications.NewWebhookTransport()
+		transport.Proxy = p.proxy(nil)
+		transport.DialContext = p.dialContext(&net.Dialer{Timeout: defaultHTTPTimeout})
+		p.client = &http.Client{
+			Timeout:   defaultHTTPTimeout,
+			Transport: transport,
+		}
+	})
+	return p.client
+}
+
+// dialWithDestinationPolicy connects to the address with the dialer. The destination
+// policy of ctx, if it has one, is enforced. The connection is upgraded to TLS with the
+// configuration if it is not nil.
+func dialWithDestinationPolicy(ctx context.Context, dialer *net.Dialer, network, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	dial := dialer.DialContext
+	if p := destinationPolicyFromContext(ctx); p != nil {
+		dial = p.dialContext(dialer)
+	}
+	conn, err := dial(ctx, network, addr)
+	if err != nil || tlsConfig == nil {
+		return conn, err
+	}
+	// The IP address is dialed, so the server name is set as by tls.Dialer.
+	cfg := tlsConfig.Clone()
+	if cfg.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			cfg.ServerName = host
+		}
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+type destinationPolicyKey struct{}
+
+// withDestinationPolicy returns a copy of ctx with the destination policy, which is
+// enforced by sendHTTPRequest and the notifiers that connect to servers themselves.
+// Webhooks sent by the notification service are sent with the client of the policy.
+func withDestinationPolicy(ctx context.Context, p *DestinationPolicy) context.Context {
+	ctx = notifications.WithWebhookClient(ctx, p.httpClient())
+	return context.WithValue(ctx, destinationPolicyKey{}, p)
+}
+
+// destinationPolicyFromContext returns the destination policy of ctx, or nil if there
+// is none.
+func destinationPolicyFromContext(ctx context.Context) *DestinationPolicy {
+	p, _ := ctx.Value(destinationPolicyKey{}).(*DestinationPolicy)
+	return p
+}
+
+// WithDestinationPolicy wraps the factory so that the requests of its notifiers are only
+// sent to the destinations allowed by the policy. The policy is enforced when
+// connections are dialed, so the IP addresses that are checked are the ones that are
+// dialed, including for redirects.
+func WithDestinationPolicy(factory func(channels.FactoryConfig) (channels.NotificationChannel, error), p *DestinationPolicy) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	if p == nil {
+		return factory
+	}
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		n, err := factory(fc)
+		if err != nil {
+			return nil, err
+		}
+		return &destinationPolicyNotifier{
+			NotificationChannel: n,
+			policy:              p,
+		}, nil
+	}
+}
+
+// destinationPolicyNotifier adds the destination policy to the context of the
+// notifications of the notifier.
+type destinationPolicyNotifier struct {
+	channels.NotificationChannel
+	policy *DestinationPolicy
+}
+
+func (dn *destinationPolicyNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	return dn.NotificationChannel.Notify(withDestinationPolicy(ctx, dn.policy), as...)
+}
+
+func (dn *destinationPolicyNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(withDestinationPolicy(ctx, dn.policy), dn.NotificationChannel)
+}
+
+func (dn *destinationPolicyNotifier) Close() {
+	Close(dn.NotificationChannel)
+}
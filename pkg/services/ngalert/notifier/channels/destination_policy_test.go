@@ -0,0 +1,368 @@
+package channels
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestNewDestinationPolicy(t *testing.T) {
+	p, err := NewDestinationPolicy(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	p, err = NewDestinationPolicy([]string{"*.example.com", "10.0.0.1"}, []string{"169.254.0.0/16", "::1", "internal.example.com"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"*.example.com"}, p.allowedHosts)
+	require.Equal(t, []string{"10.0.0.1/32"}, networkStrings(p.allowedNets))
+	require.Equal(t, []string{"internal.example.com"}, p.deniedHosts)
+	require.Equal(t, []string{"169.254.0.0/16", "::1/128"}, networkStrings(p.deniedNets))
+
+	_, err = NewDestinationPolicy(nil, []string{"10.0.0.0/33"})
+	require.EqualError(t, err, `invalid destination "10.0.0.0/33", must be a hostname, IP address or CIDR range`)
+}
+
+func TestDestinationPolicy_HTTPClient(t *testing.T) {
+	p, err := NewDestinationPolicy(nil, []string{"169.254.0.0/16"})
+	require.NoError(t, err)
+
+	// The transport has the settings of the transport that is shared by webhooks.
+	transport, ok := p.httpClient().Transport.(*http.Transport)
+	require.True(t, ok)
+	webhookTransport := notifications.NewWebhookTransport()
+	require.Equal(t, webhookTransport.IdleConnTimeout, transport.IdleConnTimeout)
+	require.Equal(t, webhookTransport.MaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	require.Equal(t, webhookTransport.MaxConnsPerHost, transport.MaxConnsPerHost)
+
+	_, err = p.httpClient().Get("http://169.254.169.254/latest/meta-data")
+	require.ErrorContains(t, err, `destination "169.254.169.254" is not allowed`)
+}
+
+func networkStrings(nets []*net.IPNet) []string {
+	s := make([]string, 0, len(nets))
+	for _, n := range nets {
+		s = append(s, n.String())
+	}
+	return s
+}
+
+func TestSendHTTPRequest_DestinationPolicy(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		requests int
+	)
+	// The server listens on 127.0.0.2, so that it is not the DNS server, which listens on
+	// 127.0.0.1 and must be allowed too.
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	ln, err := net.Listen("tcp", "127.0.0.2:0")
+	require.NoError(t, err)
+	require.NoError(t, server.Listener.Close())
+	server.Listener = ln
+	server.Start()
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// The DNS server resolves rebind.grafana.test to the server the first time, and to the
+	// metadata endpoint after that. Other names are resolved to the server.
+	var numRebindQueries int
+	addr := newTestDNSServer(t, func(name string) [4]byte {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if name == "rebind.grafana.test." {
+			numRebindQueries++
+			if numRebindQueries > 1 {
+				return [4]byte{169, 254, 169, 254}
+			}
+		}
+		return [4]byte{127, 0, 0, 2}
+	})
+	resolver, err := buildHTTPResolver(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Settings: json.RawMessage(fmt.Sprintf(`{"dns_server": %q}`, addr)),
+		},
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		host     string
+		allowed  []string
+		denied   []string
+		expError string
+		// lookupError is true if the error is returned by the resolver, which does not
+		// wrap the errors of the DNS server.
+		lookupError bool
+	}{{
+		name:   "Allowed by denylist",
+		host:   "alertmanager.grafana.test",
+		denied: []string{"169.254.0.0/16", "10.0.0.0/8"},
+	}, {
+		name:     "Denied IP address",
+		host:     "169.254.169.254",
+		denied:   []string{"169.254.0.0/16"},
+		expError: `destination "169.254.169.254" is not allowed`,
+	}, {
+		name:     "Denied resolved IP address",
+		host:     "alertmanager.grafana.test",
+		denied:   []string{"127.0.0.2"},
+		expError: `destination "alertmanager.grafana.test" is not allowed, it resolves to 127.0.0.2`,
+	}, {
+		name:        "Denied DNS server",
+		host:        "alertmanager.grafana.test",
+		denied:      []string{"127.0.0.1"},
+		expError:    `destination "127.0.0.1" is not allowed`,
+		lookupError: true,
+	}, {
+		name:     "Denied hostname",
+		host:     "alertmanager.grafana.test",
+		denied:   []string{"*.grafana.test"},
+		expError: `destination "alertmanager.grafana.test" is not allowed`,
+	}, {
+		name:    "Allowed hostname",
+		host:    "alertmanager.grafana.test",
+		allowed: []string{"alertmanager.grafana.test", "127.0.0.1"},
+	}, {
+		name:    "Allowed resolved IP address",
+		host:    "alertmanager.grafana.test",
+		allowed: []string{"127.0.0.0/8"},
+	}, {
+		name:     "Not in allowlist",
+		host:     "alertmanager.grafana.test",
+		allowed:  []string{"10.0.0.0/8", "*.example.com", "127.0.0.1"},
+		expError: `destination "alertmanager.grafana.test" is not allowed, it resolves to 127.0.0.2`,
+	}, {
+		name:     "Denylist takes precedence",
+		host:     "alertmanager.grafana.test",
+		allowed:  []string{"alertmanager.grafana.test", "127.0.0.1"},
+		denied:   []string{"127.0.0.2"},
+		expError: `destination "alertmanager.grafana.test" is not allowed, it resolves to 127.0.0.2`,
+	}, {
+		// The IP address that is checked is dialed, so the host is not resolved again.
+		name:    "DNS rebinding",
+		host:    "rebind.grafana.test",
+		allowed: []string{"rebind.grafana.test", "127.0.0.1"},
+		denied:  []string{"169.254.0.0/16"},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mtx.Lock()
+			before := requests
+			mtx.Unlock()
+
+			p, err := NewDestinationPolicy(c.allowed, c.denied)
+			require.NoError(t, err)
+			u := *serverURL
+			u.Host = net.JoinHostPort(c.host, serverURL.Port())
+			_, err = sendHTTPRequest(withDestinationPolicy(context.Background(), p), &u, httpCfg{
+				resolver: resolver,
+			}, &channels.FakeLogger{})
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if c.expError != "" {
+				require.ErrorContains(t, err, c.expError)
+				if !c.lookupError {
+					require.ErrorAs(t, err, &destinationBlockedError{})
+				}
+				require.Equal(t, before, requests)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, before+1, requests)
+		})
+	}
+
+	t.Run("Redirects are checked", func(t *testing.T) {
+		redirectServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusTemporaryRedirect)
+		}))
+		t.Cleanup(redirectServer.Close)
+		u, err := url.Parse(redirectServer.URL)
+		require.NoError(t, err)
+
+		p, err := NewDestinationPolicy(nil, []string{"169.254.169.254"})
+		require.NoError(t, err)
+		_, err = sendHTTPRequest(withDestinationPolicy(context.Background(), p), u, httpCfg{
+			maxRedirects:  1,
+			redirectHosts: []string{"169.254.169.254"},
+		}, &channels.FakeLogger{})
+		require.ErrorAs(t, err, &destinationBlockedError{})
+	})
+}
+
+// notificationServiceSender sends webhooks with the notification service, as in Grafana.
+type notificationServiceSender struct {
+	ns *notifications.NotificationService
+}
+
+func newNotificationServiceSender(t *testing.T) notificationServiceSender {
+	t.Helper()
+	cfg := setting.NewCfg()
+	cfg.Smtp.FromAddress = "grafana@example.com"
+	ns, err := notifications.ProvideService(bus.ProvideBus(tracing.InitializeTracerForTest()), cfg, notifications.NewFakeMailer(), nil)
+	require.NoError(t, err)
+	return notificationServiceSender{ns: ns}
+}
+
+func (s notificationServiceSender) SendWebhook(ctx context.Context, cmd *channels.SendWebhookSettings) error {
+	return s.ns.SendWebhookSync(ctx, &models.SendWebhookSync{
+		Url:         cmd.URL,
+		User:        cmd.User,
+		Password:    cmd.Password,
+		Body:        cmd.Body,
+		HttpMethod:  cmd.HTTPMethod,
+		HttpHeader:  cmd.HTTPHeader,
+		ContentType: cmd.ContentType,
+		Validation:  cmd.Validation,
+	})
+}
+
+func (s notificationServiceSender) SendEmail(context.Context, *channels.SendEmailSettings) error {
+	return errors.New("emails are not supported")
+}
+
+func TestWithDestinationPolicy(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		requests int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	redirectServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	t.Cleanup(redirectServer.Close)
+
+	p, err := NewDestinationPolicy(nil, []string{"169.254.0.0/16"})
+	require.NoError(t, err)
+	sender := newNotificationServiceSender(t)
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	alert := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}}
+
+	notify := func(t *testing.T, typ, settings string) (bool, error) {
+		t.Helper()
+		factory, ok := Factory(typ)
+		require.True(t, ok)
+		n, err := WithDestinationPolicy(factory, p)(channels.FactoryConfig{
+			Config: &channels.NotificationChannelConfig{
+				Name:     typ + "_testing",
+				Type:     typ,
+				Settings: json.RawMessage(settings),
+			},
+			ImageStore:          &channels.UnavailableImageStore{},
+			NotificationService: sender,
+			DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+				return fallback
+			},
+			Template: templateForTests(t),
+			Logger:   &channels.FakeLogger{},
+		})
+		require.NoError(t, err)
+		return n.Notify(ctx, alert)
+	}
+
+	t.Run("Blocked webhook is not sent", func(t *testing.T) {
+		_, err := notify(t, "webhook", `{"url": "http://169.254.169.254/latest/meta-data"}`)
+		require.ErrorAs(t, err, &destinationBlockedError{})
+		require.ErrorContains(t, err, `destination "169.254.169.254" is not allowed`)
+	})
+
+	t.Run("Redirect of webhook to blocked host is not followed", func(t *testing.T) {
+		_, err := notify(t, "webhook", fmt.Sprintf(`{"url": %q}`, redirectServer.URL))
+		require.ErrorAs(t, err, &destinationBlockedError{})
+	})
+
+	t.Run("Allowed webhook is sent", func(t *testing.T) {
+		mtx.Lock()
+		before := requests
+		mtx.Unlock()
+		ok, err := notify(t, "webhook", fmt.Sprintf(`{"url": %q}`, server.URL))
+		require.NoError(t, err)
+		require.True(t, ok)
+		mtx.Lock()
+		defer mtx.Unlock()
+		require.Equal(t, before+1, requests)
+	})
+
+	t.Run("Blocked Slack message is not sent", func(t *testing.T) {
+		_, err := notify(t, "slack", `{"url": "http://169.254.169.254/latest/meta-data"}`)
+		require.ErrorAs(t, err, &destinationBlockedError{})
+	})
+
+	t.Run("Blocked syslog message is not sent", func(t *testing.T) {
+		_, err := notify(t, "syslog", `{"address": "169.254.169.254:514"}`)
+		require.ErrorAs(t, err, &destinationBlockedError{})
+	})
+
+	t.Run("Blocked MQTT message is not sent", func(t *testing.T) {
+		_, err := notify(t, "mqtt", `{"brokerUrl": "tcp://169.254.169.254:1883", "topic": "grafana/alerts"}`)
+		require.ErrorAs(t, err, &destinationBlockedError{})
+	})
+
+	t.Run("Blocked AMQP message is not sent", func(t *testing.T) {
+		_, err := notify(t, "amqp", `{"url": "amqp://169.254.169.254:5672", "exchange": "alerts"}`)
+		require.ErrorAs(t, err, &destinationBlockedError{})
+	})
+}
+
+func TestDialWithDestinationPolicy(t *testing.T) {
+	// The certificate of the test server is valid for 127.0.0.1.
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	addr := server.Listener.Addr().String()
+	tlsConfig := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	t.Run("Allowed", func(t *testing.T) {
+		p, err := NewDestinationPolicy([]string{"127.0.0.1"}, nil)
+		require.NoError(t, err)
+		conn, err := dialWithDestinationPolicy(withDestinationPolicy(context.Background(), p), &net.Dialer{}, "tcp", addr, tlsConfig)
+		require.NoError(t, err)
+		require.IsType(t, &tls.Conn{}, conn)
+		require.NoError(t, conn.Close())
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		p, err := NewDestinationPolicy(nil, []string{"127.0.0.0/8"})
+		require.NoError(t, err)
+		_, err = dialWithDestinationPolicy(withDestinationPolicy(context.Background(), p), &net.Dialer{}, "tcp", addr, tlsConfig)
+		require.ErrorAs(t, err, &destinationBlockedError{})
+	})
+
+	t.Run("Without a policy", func(t *testing.T) {
+		conn, err := dialWithDestinationPolicy(context.Background(), &net.Dialer{}, "tcp", addr, nil)
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+	})
+}
@@ -15,10 +15,11 @@ import (
 const defaultDingdingMsgType = "link"
 
 type dingDingSettings struct {
-	URL         string `json:"url,omitempty" yaml:"url,omitempty"`
-	MessageType string `json:"msgType,omitempty" yaml:"msgType,omitempty"`
-	Title       string `json:"title,omitempty" yaml:"title,omitempty"`
-	Message     string `json:"message,omitempty" yaml:"message,omitempty"`
+	URL             string `json:"url,omitempty" yaml:"url,omitempty"`
+	MessageType     string `json:"msgType,omitempty" yaml:"msgType,omitempty"`
+	Title           string `json:"title,omitempty" yaml:"title,omitempty"`
+	Message         string `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage string `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
 }
 
 func buildDingDingSettings(fc channels.FactoryConfig) (*dingDingSettings, error) {
@@ -59,22 +60,28 @@ func newDingDingNotifier(fc channels.FactoryConfig) (*DingDingNotifier, error) {
 	if err != nil {
 		return nil, err
 	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
 	return &DingDingNotifier{
-		Base:     channels.NewBase(fc.Config),
-		log:      fc.Logger,
-		ns:       fc.NotificationService,
-		tmpl:     fc.Template,
-		settings: *settings,
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		ns:          fc.NotificationService,
+		tmpl:        fc.Template,
+		settings:    *settings,
+		maxValueLen: maxValueLen,
 	}, nil
 }
 
 // DingDingNotifier is responsible for sending alert notifications to ding ding.
 type DingDingNotifier struct {
 	*channels.Base
-	log      channels.Logger
-	ns       channels.WebhookSender
-	tmpl     *template.Template
-	settings dingDingSettings
+	log         channels.Logger
+	ns          channels.WebhookSender
+	tmpl        *template.Template
+	settings    dingDingSettings
+	maxValueLen int
 }
 
 // Notify sends the alert notification to dingding.
@@ -84,9 +91,9 @@ func (dd *DingDingNotifier) Notify(ctx context.Context, as ...*types.Alert) (boo
 	msgUrl := buildDingDingURL(dd)
 
 	var tmplErr error
-	tmpl, _ := channels.TmplText(ctx, dd.tmpl, as, dd.log, &tmplErr)
+	tmpl, _ := tmplText(ctx, dd.tmpl, as, dd.log, &tmplErr, dd.maxValueLen)
 
-	message := tmpl(dd.settings.Message)
+	message := tmpl(messageForAlerts(dd.settings.Message, dd.settings.ResolvedMessage, as))
 	title := tmpl(dd.settings.Title)
 
 	msgType := tmpl(dd.settings.MessageType)
@@ -122,7 +129,7 @@ func (dd *DingDingNotifier) SendResolved() bool {
 func buildDingDingURL(dd *DingDingNotifier) string {
 	q := url.Values{
 		"pc_slide": {"false"},
-		"url":      {joinUrlPath(dd.tmpl.ExternalURL.String(), "/alerting/list", dd.log)},
+		"url":      {grafanaURL(dd.tmpl.ExternalURL, "/alerting/list")},
 	}
 
 	// Use special link to auto open the message url outside Dingding
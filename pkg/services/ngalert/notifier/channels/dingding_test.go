@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
 	"github.com/prometheus/alertmanager/notify"
@@ -156,6 +157,71 @@ func TestDingdingNotifier(t *testing.T) {
 				"msgtype": "link",
 			},
 			expMsgError: nil,
+		}, {
+			name: "Custom config with resolved message and firing alerts",
+			settings: `{
+				"url": "http://localhost",
+				"message": "{{ len .Alerts.Firing }} alerts are firing",
+				"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved",
+				"msgType": "actionCard"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+						Annotations: model.LabelSet{"ann1": "annv2"},
+						EndsAt:      time.Now().Add(-time.Minute),
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"actionCard": map[string]interface{}{
+					"singleTitle": "More",
+					"singleURL":   "dingtalk://dingtalkclient/page/link?pc_slide=false&url=http%3A%2F%2Flocalhost%2Falerting%2Flist",
+					"text":        "1 alerts are firing",
+					"title":       "[FIRING:1]  ",
+				},
+				"msgtype": "actionCard",
+			},
+			expMsgError: nil,
+		}, {
+			name: "Custom config with resolved message and resolved alerts",
+			settings: `{
+				"url": "http://localhost",
+				"message": "{{ len .Alerts.Firing }} alerts are firing",
+				"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved",
+				"msgType": "actionCard"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+						EndsAt:      time.Now().Add(-time.Minute),
+					},
+				}, {
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+						Annotations: model.LabelSet{"ann1": "annv2"},
+						EndsAt:      time.Now().Add(-time.Minute),
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"actionCard": map[string]interface{}{
+					"singleTitle": "More",
+					"singleURL":   "dingtalk://dingtalkclient/page/link?pc_slide=false&url=http%3A%2F%2Flocalhost%2Falerting%2Flist",
+					"text":        "2 alerts are resolved",
+					"title":       "[RESOLVED]  ",
+				},
+				"msgtype": "actionCard",
+			},
+			expMsgError: nil,
 		}, {
 			name:         "Error in initing",
 			settings:     `{}`,
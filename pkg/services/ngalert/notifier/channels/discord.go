@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
+	"net/url"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -26,15 +29,74 @@ type discordEmbedType string
 const (
 	discordRichEmbed discordEmbedType = "rich"
 
-	discordMaxEmbeds     = 10
-	discordMaxMessageLen = 2000
+	discordMaxEmbeds        = 10
+	discordMaxMessageLen    = 2000
+	discordMaxButtonsPerRow = 5
+	discordMaxFooterLen     = 2048
+	discordMaxFields        = 25
+	discordMaxFieldNameLen  = 256
+	discordMaxFieldValueLen = 1024
+	discordMaxAuthorNameLen = 256
+	discordMaxMentionIDs    = 100
 )
 
+// discordSnowflakeRegexp matches the IDs of Discord users and roles.
+var discordSnowflakeRegexp = regexp.MustCompile(`^[0-9]{17,20}$`)
+
+// Component types and button styles are set according to https://discord.com/developers/docs/interactions/message-components
+const (
+	discordComponentTypeActionRow = 1
+	discordComponentTypeButton    = 2
+
+	discordButtonStyleLink = 5
+)
+
+// The buttons that can be added to messages.
+const (
+	discordButtonDashboard  = "dashboard"
+	discordButtonPanel      = "panel"
+	discordButtonSilence    = "silence"
+	discordButtonAlertRules = "alert_rules"
+
+	discordRunbookButtonLabel = "Open Runbook"
+	discordSourceButtonLabel  = "View Source"
+)
+
+var discordButtonLabels = map[string]string{
+	discordButtonDashboard:  "Open Dashboard",
+	discordButtonPanel:      "Open Panel",
+	discordButtonSilence:    "Silence",
+	discordButtonAlertRules: "Alert Rules",
+}
+
 type discordMessage struct {
-	Username  string             `json:"username,omitempty"`
-	Content   string             `json:"content"`
-	AvatarURL string             `json:"avatar_url,omitempty"`
-	Embeds    []discordLinkEmbed `json:"embeds,omitempty"`
+	Username   string             `json:"username,omitempty"`
+	Content    string             `json:"content"`
+	AvatarURL  string             `json:"avatar_url,omitempty"`
+	Embeds     []discordLinkEmbed `json:"embeds,omitempty"`
+	Components []discordComponent `json:"components,omitempty"`
+	// AllowedMentions is always set, so mentions of @everyone, @here and roles in
+	// label values do not ping unless allowed.
+	AllowedMentions *discordAllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+// discordAllowedMentions implements https://discord.com/developers/docs/resources/channel#allowed-mentions-object
+type discordAllowedMentions struct {
+	// Parse are the types of mentions in the content that ping, of everyone, roles
+	// and users. It must be an empty list rather than omitted to suppress all mentions.
+	Parse []string `json:"parse"`
+	Roles []string `json:"roles,omitempty"`
+	Users []string `json:"users,omitempty"`
+}
+
+// discordComponent implements https://discord.com/developers/docs/interactions/message-components#component-object
+// for action rows and link buttons.
+type discordComponent struct {
+	Type       int                `json:"type"`
+	Components []discordComponent `json:"components,omitempty"`
+	Style      int                `json:"style,omitempty"`
+	Label      string             `json:"label,omitempty"`
+	URL        string             `json:"url,omitempty"`
 }
 
 // discordLinkEmbed implements https://discord.com/developers/docs/resources/channel#embed-object
@@ -47,6 +109,17 @@ type discordLinkEmbed struct {
 	Footer *discordFooter `json:"footer,omitempty"`
 
 	Image *discordImage `json:"image,omitempty"`
+
+	Author *discordAuthor `json:"author,omitempty"`
+
+	Fields []discordEmbedField `json:"fields,omitempty"`
+}
+
+// discordEmbedField implements https://discord.com/developers/docs/resources/channel#embed-object-embed-field-structure
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
 }
 
 // discordFooter implements https://discord.com/developers/docs/resources/channel#embed-object-embed-footer-structure
@@ -55,6 +128,13 @@ type discordFooter struct {
 	IconURL string `json:"icon_url,omitempty"`
 }
 
+// discordAuthor implements https://discord.com/developers/docs/resources/channel#embed-object-embed-author-structure
+type discordAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
 // discordImage implements https://discord.com/developers/docs/resources/channel#embed-object-embed-footer-structure
 type discordImage struct {
 	URL string `json:"url"`
@@ -62,20 +142,73 @@ type discordImage struct {
 
 type DiscordNotifier struct {
 	*channels.Base
-	log        channels.Logger
-	ns         channels.WebhookSender
-	images     channels.ImageStore
-	tmpl       *template.Template
-	settings   *discordSettings
-	appVersion string
+	log         channels.Logger
+	ns          channels.WebhookSender
+	images      channels.ImageStore
+	tmpl        *template.Template
+	settings    *discordSettings
+	maxValueLen int
+	appVersion  string
+	// includeFingerprints is true if the fingerprints of the alerts are included in the footer.
+	includeFingerprints bool
+	// runbookAnnotation is the annotation with the URL of the runbook button.
+	runbookAnnotation string
+	// username is the username to post as, unless UseDiscordUsername is set.
+	username string
+	// resolvedURL, if set, is the webhook URL of notifications with only resolved alerts.
+	resolvedURL string
+	// silenceDuration, if set, is the duration pre-filled in the form of silence links.
+	silenceDuration string
 }
 
 type discordSettings struct {
-	Title              string `json:"title,omitempty" yaml:"title,omitempty"`
-	Message            string `json:"message,omitempty" yaml:"message,omitempty"`
-	AvatarURL          string `json:"avatar_url,omitempty" yaml:"avatar_url,omitempty"`
+	Title           string `json:"title,omitempty" yaml:"title,omitempty"`
+	Message         string `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage string `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+	AvatarURL       string `json:"avatar_url,omitempty" yaml:"avatar_url,omitempty"`
+	// WebhookURL can reference environment variables, such as ${env:DISCORD_WEBHOOK_URL},
+	// which are resolved when the notifier is created.
 	WebhookURL         string `json:"url,omitempty" yaml:"url,omitempty"`
 	UseDiscordUsername bool   `json:"use_discord_username,omitempty" yaml:"use_discord_username,omitempty"`
+	// AvatarFromImage uses the URL of the first image of the alerts as the avatar,
+	// falling back to AvatarURL if none of the alerts have an image with a URL.
+	AvatarFromImage bool `json:"avatar_from_image,omitempty" yaml:"avatar_from_image,omitempty"`
+	// SeverityUsernames are the usernames to post as for alerts with a severity label.
+	// The username of the highest severity of the alerts is used, see highestSeverity.
+	SeverityUsernames []discordSeverityUsername `json:"severity_usernames,omitempty" yaml:"severity_usernames,omitempty"`
+	// Buttons are the link buttons added to the message, such as dashboard and silence.
+	Buttons channels.CommaSeparatedStrings `json:"buttons,omitempty" yaml:"buttons,omitempty"`
+	// Fields are the fields of the embed, with the values of a label or annotation.
+	Fields []discordField `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// AuthorName, AuthorURL and AuthorIconURL are the templated author of the embed,
+	// such as the team that owns the alerts. The author is omitted without a name.
+	AuthorName    string `json:"author_name,omitempty" yaml:"author_name,omitempty"`
+	AuthorURL     string `json:"author_url,omitempty" yaml:"author_url,omitempty"`
+	AuthorIconURL string `json:"author_icon_url,omitempty" yaml:"author_icon_url,omitempty"`
+	// AllowedMentionRoles and AllowedMentionUsers are the IDs of the roles and users
+	// that can be pinged by mentions. Mentions of @everyone and @here never ping, and
+	// mentions of roles only ping for the allowed roles. All users can be pinged
+	// unless users are allowed.
+	AllowedMentionRoles channels.CommaSeparatedStrings `json:"allowed_mention_roles,omitempty" yaml:"allowed_mention_roles,omitempty"`
+	AllowedMentionUsers channels.CommaSeparatedStrings `json:"allowed_mention_users,omitempty" yaml:"allowed_mention_users,omitempty"`
+}
+
+// discordField is a field of the embed with the value of either a label or an
+// annotation of the alerts.
+type discordField struct {
+	// Name is the name of the field. It defaults to the label or annotation.
+	Name       string `json:"name,omitempty" yaml:"name,omitempty"`
+	Label      string `json:"label,omitempty" yaml:"label,omitempty"`
+	Annotation string `json:"annotation,omitempty" yaml:"annotation,omitempty"`
+	Inline     bool   `json:"inline,omitempty" yaml:"inline,omitempty"`
+}
+
+// discordSeverityUsername is the username, and optionally the avatar, to post
+// as for alerts with the severity.
+type discordSeverityUsername struct {
+	Severity  string `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Username  string `json:"username,omitempty" yaml:"username,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty" yaml:"avatar_url,omitempty"`
 }
 
 func buildDiscordSettings(fc channels.FactoryConfig) (*discordSettings, error) {
@@ -87,15 +220,66 @@ func buildDiscordSettings(fc channels.FactoryConfig) (*discordSettings, error) {
 	if settings.WebhookURL == "" {
 		return nil, errors.New("could not find webhook url property in settings")
 	}
+	settings.WebhookURL, err = resolveEnvReferences("url", settings.WebhookURL)
+	if err != nil {
+		return nil, err
+	}
+	if settings.WebhookURL == "" {
+		return nil, errors.New("webhook url is empty after resolving environment variables")
+	}
 	if settings.Title == "" {
 		settings.Title = channels.DefaultMessageTitleEmbed
 	}
 	if settings.Message == "" {
 		settings.Message = channels.DefaultMessageEmbed
 	}
+	for _, su := range settings.SeverityUsernames {
+		if su.Severity == "" || su.Username == "" {
+			return nil, errors.New("severity and username are required in severity_usernames")
+		}
+	}
+	if len(settings.Buttons) > discordMaxButtonsPerRow {
+		return nil, fmt.Errorf("at most %d buttons are allowed", discordMaxButtonsPerRow)
+	}
+	for _, b := range settings.Buttons {
+		if _, ok := discordButtonLabels[b]; !ok {
+			return nil, fmt.Errorf("invalid value for buttons: %q, must be one of dashboard, panel, silence, alert_rules", b)
+		}
+	}
+	if len(settings.Fields) > discordMaxFields {
+		return nil, fmt.Errorf("at most %d fields are allowed", discordMaxFields)
+	}
+	for i, f := range settings.Fields {
+		if (f.Label == "") == (f.Annotation == "") {
+			return nil, errors.New("exactly one of label and annotation is required in fields")
+		}
+		if f.Name == "" {
+			settings.Fields[i].Name = f.Label + f.Annotation
+		}
+	}
+	if err := validateDiscordMentionIDs("allowed_mention_roles", settings.AllowedMentionRoles); err != nil {
+		return nil, err
+	}
+	if err := validateDiscordMentionIDs("allowed_mention_users", settings.AllowedMentionUsers); err != nil {
+		return nil, err
+	}
 	return &settings, nil
 }
 
+// validateDiscordMentionIDs returns an error if the IDs in the setting are not valid
+// IDs of roles or users, or there are too many of them.
+func validateDiscordMentionIDs(setting string, ids []string) error {
+	if len(ids) > discordMaxMentionIDs {
+		return fmt.Errorf("at most %d IDs are allowed in %s", discordMaxMentionIDs, setting)
+	}
+	for _, id := range ids {
+		if !discordSnowflakeRegexp.MatchString(id) {
+			return fmt.Errorf("invalid ID in %s: %q", setting, id)
+		}
+	}
+	return nil
+}
+
 type discordAttachment struct {
 	url       string
 	reader    io.ReadCloser
@@ -120,30 +304,95 @@ func newDiscordNotifier(fc channels.FactoryConfig) (*DiscordNotifier, error) {
 	if err != nil {
 		return nil, err
 	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
+	includeFingerprints, err := buildIncludeFingerprints(fc)
+	if err != nil {
+		return nil, err
+	}
+	runbookAnnotation, err := buildRunbookAnnotation(fc)
+	if err != nil {
+		return nil, err
+	}
+	username, err := buildSenderName(fc)
+	if err != nil {
+		return nil, err
+	}
+	if username == "" {
+		username = defaultSenderName
+	}
+	resolvedURL, err := buildResolvedURL(fc)
+	if err != nil {
+		return nil, err
+	}
+	silenceDuration, err := buildSilenceDuration(fc)
+	if err != nil {
+		return nil, err
+	}
 	return &DiscordNotifier{
-		Base:       channels.NewBase(fc.Config),
-		log:        fc.Logger,
-		ns:         fc.NotificationService,
-		images:     fc.ImageStore,
-		tmpl:       fc.Template,
-		settings:   settings,
-		appVersion: fc.GrafanaBuildVersion,
+		Base:                channels.NewBase(fc.Config),
+		log:                 fc.Logger,
+		ns:                  fc.NotificationService,
+		images:              images,
+		tmpl:                fc.Template,
+		settings:            settings,
+		maxValueLen:         maxValueLen,
+		appVersion:          fc.GrafanaBuildVersion,
+		includeFingerprints: includeFingerprints,
+		runbookAnnotation:   runbookAnnotation,
+		username:            username,
+		resolvedURL:         resolvedURL,
+		silenceDuration:     silenceDuration,
 	}, nil
 }
 
 func (d DiscordNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	alerts := types.Alerts(as...)
 
-	var msg discordMessage
+	msg := discordMessage{
+		AllowedMentions: d.allowedMentions(),
+	}
 
 	if !d.settings.UseDiscordUsername {
-		msg.Username = "Grafana"
+		msg.Username = d.username
 	}
 
 	var tmplErr error
-	tmpl, _ := channels.TmplText(ctx, d.tmpl, as, d.log, &tmplErr)
+	tmpl, data := tmplText(ctx, d.tmpl, as, d.log, &tmplErr, d.maxValueLen)
+	setSilenceDuration(data, d.silenceDuration, d.log)
+
+	// The images are retrieved once for the template data, the avatar and the attachments.
+	// The alerts are augmented with the URLs of their images, so templates can link to
+	// them such as with ![panel]({{ .ImageURL }}). Images without a public URL are only
+	// uploaded as attachments. No more images are retrieved once there is one for each
+	// embed, as the message cannot have more.
+	var (
+		images    []alertImage
+		numImages int
+	)
+	_ = withStoredImages(ctx, d.log, d.images,
+		func(index int, image channels.Image) error {
+			images = append(images, alertImage{index: index, image: image})
+			if len(image.URL) != 0 {
+				data.Alerts[index].ImageURL = image.URL
+			}
+			if len(image.URL) != 0 || len(image.Path) != 0 {
+				numImages++
+			}
+			if numImages >= discordMaxEmbeds-1 {
+				return channels.ErrImagesDone
+			}
+			return nil
+		},
+		as...)
 
-	msg.Content = tmpl(d.settings.Message)
+	msg.Content = tmpl(messageForAlerts(d.settings.Message, d.settings.ResolvedMessage, as))
 	if tmplErr != nil {
 		d.log.Warn("failed to template Discord notification content", "error", tmplErr.Error())
 		// Reset tmplErr for templating other fields.
@@ -159,19 +408,40 @@ func (d DiscordNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool,
 		msg.Content = truncatedMsg
 	}
 
-	if d.settings.AvatarURL != "" {
-		msg.AvatarURL = tmpl(d.settings.AvatarURL)
+	avatarURL := d.settings.AvatarURL
+	if su := d.severityUsername(as); su != nil {
+		msg.Username = su.Username
+		if su.AvatarURL != "" {
+			avatarURL = su.AvatarURL
+		}
+	}
+
+	if avatarURL != "" {
+		msg.AvatarURL = tmpl(avatarURL)
 		if tmplErr != nil {
-			d.log.Warn("failed to template Discord Avatar URL", "error", tmplErr.Error(), "fallback", d.settings.AvatarURL)
-			msg.AvatarURL = d.settings.AvatarURL
+			d.log.Warn("failed to template Discord Avatar URL", "error", tmplErr.Error(), "fallback", avatarURL)
+			msg.AvatarURL = avatarURL
 			tmplErr = nil
 		}
 	}
 
+	if d.settings.AvatarFromImage {
+		for _, img := range images {
+			if img.image.URL != "" {
+				msg.AvatarURL = img.image.URL
+				break
+			}
+		}
+	}
+
 	footer := &discordFooter{
 		Text:    "Grafana v" + d.appVersion,
 		IconURL: "https://grafana.com/static/assets/img/fav32.png",
 	}
+	if d.includeFingerprints {
+		// Discord messages do not have metadata, so the fingerprints are shown in the footer.
+		footer.Text, _ = channels.TruncateInRunes(footer.Text+" | Fingerprints: "+strings.Join(alertFingerprints(as), ", "), discordMaxFooterLen)
+	}
 
 	var linkEmbed discordLinkEmbed
 
@@ -183,16 +453,18 @@ func (d DiscordNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool,
 	}
 	linkEmbed.Footer = footer
 	linkEmbed.Type = discordRichEmbed
+	linkEmbed.Fields = d.buildFields(data)
+	linkEmbed.Author = d.buildAuthor(tmpl, &tmplErr)
 
 	color, _ := strconv.ParseInt(strings.TrimLeft(getAlertStatusColor(alerts.Status()), "#"), 16, 0)
 	linkEmbed.Color = color
 
-	ruleURL := joinUrlPath(d.tmpl.ExternalURL.String(), "/alerting/list", d.log)
+	ruleURL := grafanaURL(d.tmpl.ExternalURL, "/alerting/list")
 	linkEmbed.URL = ruleURL
 
 	embeds := []discordLinkEmbed{linkEmbed}
 
-	attachments := d.constructAttachments(ctx, as, discordMaxEmbeds-1)
+	attachments := d.constructAttachments(as, images, discordMaxEmbeds-1)
 	for _, a := range attachments {
 		color, _ := strconv.ParseInt(strings.TrimLeft(getAlertStatusColor(alerts.Status()), "#"), 16, 0)
 		embed := discordLinkEmbed{
@@ -206,16 +478,21 @@ func (d DiscordNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool,
 	}
 
 	msg.Embeds = embeds
+	msg.Components = d.buildComponents(data, ruleURL, runbookURL(as, d.runbookAnnotation))
 
 	if tmplErr != nil {
 		d.log.Warn("failed to template Discord message", "error", tmplErr.Error())
 		tmplErr = nil
 	}
 
-	u := tmpl(d.settings.WebhookURL)
+	webhookURL := d.settings.WebhookURL
+	if d.resolvedURL != "" && alerts.Status() == model.AlertResolved {
+		webhookURL = d.resolvedURL
+	}
+	u := tmpl(webhookURL)
 	if tmplErr != nil {
-		d.log.Warn("failed to template Discord URL", "error", tmplErr.Error(), "fallback", d.settings.WebhookURL)
-		u = d.settings.WebhookURL
+		d.log.Warn("failed to template Discord URL", "error", tmplErr.Error(), "fallback", webhookURL)
+		u = webhookURL
 	}
 
 	body, err := json.Marshal(msg)
@@ -235,56 +512,229 @@ func (d DiscordNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool,
 	return true, nil
 }
 
+// HealthCheck gets the webhook, which checks that it exists without executing it.
+// Templated webhook URLs cannot be checked without alerts.
+func (d DiscordNotifier) HealthCheck(ctx context.Context) error {
+	if strings.Contains(d.settings.WebhookURL, "{{") {
+		return ErrHealthCheckNotSupported
+	}
+	u, err := url.Parse(d.settings.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse webhook URL: %w", err)
+	}
+	if _, err := sendHTTPRequest(ctx, u, httpCfg{method: http.MethodGet}, d.log); err != nil {
+		return fmt.Errorf("failed to get Discord webhook: %w", err)
+	}
+	return nil
+}
+
+// allowedMentions returns the mentions that ping. Discord rejects messages that both
+// parse user mentions and list users, so users are only parsed if none are listed.
+func (d DiscordNotifier) allowedMentions() *discordAllowedMentions {
+	am := &discordAllowedMentions{
+		Parse: []string{},
+		Roles: d.settings.AllowedMentionRoles,
+		Users: d.settings.AllowedMentionUsers,
+	}
+	if len(am.Users) == 0 {
+		am.Parse = append(am.Parse, "users")
+	}
+	return am
+}
+
+// buildAuthor returns the author of the embed, or nil if the author name is not set or
+// fails to template. The URL and icon URL are omitted if they fail to template.
+func (d DiscordNotifier) buildAuthor(tmpl func(string) string, tmplErr *error) *discordAuthor {
+	if d.settings.AuthorName == "" {
+		return nil
+	}
+	name := tmpl(d.settings.AuthorName)
+	if *tmplErr != nil {
+		d.log.Warn("failed to template Discord embed author name, omitting author", "error", (*tmplErr).Error())
+		*tmplErr = nil
+		return nil
+	}
+	if name == "" {
+		return nil
+	}
+	author := &discordAuthor{}
+	author.Name, _ = channels.TruncateInRunes(name, discordMaxAuthorNameLen)
+	if d.settings.AuthorURL != "" {
+		author.URL = tmpl(d.settings.AuthorURL)
+		if *tmplErr != nil {
+			d.log.Warn("failed to template Discord embed author URL, omitting URL", "error", (*tmplErr).Error())
+			author.URL = ""
+			*tmplErr = nil
+		}
+	}
+	if d.settings.AuthorIconURL != "" {
+		author.IconURL = tmpl(d.settings.AuthorIconURL)
+		if *tmplErr != nil {
+			d.log.Warn("failed to template Discord embed author icon URL, omitting icon", "error", (*tmplErr).Error())
+			author.IconURL = ""
+			*tmplErr = nil
+		}
+	}
+	return author
+}
+
+// severityUsername returns the username for the highest severity of the alerts,
+// or nil if none of the alerts have a severity with a username.
+func (d DiscordNotifier) severityUsername(as []*types.Alert) *discordSeverityUsername {
+	mapped := make([]string, 0, len(d.settings.SeverityUsernames))
+	for _, su := range d.settings.SeverityUsernames {
+		mapped = append(mapped, su.Severity)
+	}
+	if i := highestSeverity(as, mapped); i >= 0 {
+		return &d.settings.SeverityUsernames[i]
+	}
+	return nil
+}
+
+// buildComponents returns an action row with the configured link buttons. The
+// dashboard, panel and silence buttons link to the first alert with the URL, and
+// are omitted if none of the alerts have one. Buttons linking to the runbook and the
+// source of the alerts are added if the alerts have them and the row is not full.
+func (d DiscordNotifier) buildComponents(data *channels.ExtendedData, ruleURL, runbookURL string) []discordComponent {
+	buttons := make([]discordComponent, 0, len(d.settings.Buttons)+1)
+	for _, b := range d.settings.Buttons {
+		var u string
+		for _, a := range data.Alerts {
+			switch b {
+			case discordButtonDashboard:
+				u = a.DashboardURL
+			case discordButtonPanel:
+				u = a.PanelURL
+			case discordButtonSilence:
+				u = a.SilenceURL
+			}
+			if u != "" {
+				break
+			}
+		}
+		if b == discordButtonAlertRules {
+			u = ruleURL
+		}
+		if u == "" {
+			continue
+		}
+		buttons = append(buttons, discordComponent{
+			Type:  discordComponentTypeButton,
+			Style: discordButtonStyleLink,
+			Label: discordButtonLabels[b],
+			URL:   u,
+		})
+	}
+	if runbookURL != "" && len(buttons) < discordMaxButtonsPerRow {
+		buttons = append(buttons, discordComponent{
+			Type:  discordComponentTypeButton,
+			Style: discordButtonStyleLink,
+			Label: discordRunbookButtonLabel,
+			URL:   runbookURL,
+		})
+	}
+	if u := generatorURL(data); u != "" && len(buttons) < discordMaxButtonsPerRow {
+		buttons = append(buttons, discordComponent{
+			Type:  discordComponentTypeButton,
+			Style: discordButtonStyleLink,
+			Label: discordSourceButtonLabel,
+			URL:   u,
+		})
+	}
+	if len(buttons) == 0 {
+		return nil
+	}
+	return []discordComponent{{
+		Type:       discordComponentTypeActionRow,
+		Components: buttons,
+	}}
+}
+
 func (d DiscordNotifier) SendResolved() bool {
 	return !d.GetDisableResolveMessage()
 }
 
-func (d DiscordNotifier) constructAttachments(ctx context.Context, as []*types.Alert, embedQuota int) []discordAttachment {
+// alertImage is the stored image of the alert at the index.
+type alertImage struct {
+	index int
+	image channels.Image
+}
+
+func (d DiscordNotifier) constructAttachments(as []*types.Alert, images []alertImage, embedQuota int) []discordAttachment {
 	attachments := make([]discordAttachment, 0)
 
-	_ = withStoredImages(ctx, d.log, d.images,
-		func(index int, image channels.Image) error {
-			if embedQuota < 1 {
-				return channels.ErrImagesDone
-			}
+	for _, img := range images {
+		if embedQuota < 1 {
+			break
+		}
+		index, image := img.index, img.image
+
+		if len(image.URL) > 0 {
+			attachments = append(attachments, discordAttachment{
+				url:       image.URL,
+				state:     as[index].Status(),
+				alertName: as[index].Name(),
+			})
+			embedQuota--
+			continue
+		}
 
-			if len(image.URL) > 0 {
-				attachments = append(attachments, discordAttachment{
-					url:       image.URL,
-					state:     as[index].Status(),
-					alertName: as[index].Name(),
-				})
-				embedQuota--
-				return nil
+		// If we have a local file, but no public URL, upload the image as an attachment.
+		if len(image.Path) > 0 {
+			base := filepath.Base(image.Path)
+			url := fmt.Sprintf("attachment://%s", base)
+			reader, err := openImage(image.Path)
+			if err != nil && !errors.Is(err, channels.ErrImageNotFound) {
+				d.log.Warn("failed to retrieve image data from store", "error", err)
+				continue
 			}
 
-			// If we have a local file, but no public URL, upload the image as an attachment.
-			if len(image.Path) > 0 {
-				base := filepath.Base(image.Path)
-				url := fmt.Sprintf("attachment://%s", base)
-				reader, err := openImage(image.Path)
-				if err != nil && !errors.Is(err, channels.ErrImageNotFound) {
-					d.log.Warn("failed to retrieve image data from store", "error", err)
-					return nil
-				}
-
-				attachments = append(attachments, discordAttachment{
-					url:       url,
-					name:      base,
-					reader:    reader,
-					state:     as[index].Status(),
-					alertName: as[index].Name(),
-				})
-				embedQuota--
-			}
-			return nil
-		},
-		as...,
-	)
+			attachments = append(attachments, discordAttachment{
+				url:       url,
+				name:      base,
+				reader:    reader,
+				state:     as[index].Status(),
+				alertName: as[index].Name(),
+			})
+			embedQuota--
+		}
+	}
 
 	return attachments
 }
 
+// buildFields returns the fields of the embed. The value of a field is the distinct
+// values of its label or annotation in the alerts, and fields without values are omitted.
+func (d DiscordNotifier) buildFields(data *channels.ExtendedData) []discordEmbedField {
+	var fields []discordEmbedField
+	for _, f := range d.settings.Fields {
+		var values []string
+		seen := make(map[string]struct{})
+		for _, alert := range data.Alerts {
+			value := alert.Labels[f.Label]
+			if f.Annotation != "" {
+				value = alert.Annotations[f.Annotation]
+			}
+			if _, ok := seen[value]; ok || value == "" {
+				continue
+			}
+			seen[value] = struct{}{}
+			values = append(values, value)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		name, _ := channels.TruncateInRunes(f.Name, discordMaxFieldNameLen)
+		value, _ := channels.TruncateInRunes(strings.Join(values, ", "), discordMaxFieldValueLen)
+		fields = append(fields, discordEmbedField{
+			Name:   name,
+			Value:  value,
+			Inline: f.Inline,
+		})
+	}
+	return fields
+}
+
 func (d DiscordNotifier) buildRequest(url string, body []byte, attachments []discordAttachment) (*channels.SendWebhookSettings, error) {
 	cmd := &channels.SendWebhookSettings{
 		URL:        url,
@@ -318,12 +768,7 @@ func (d DiscordNotifier) buildRequest(url string, body []byte, attachments []dis
 		if a.reader != nil { // We have an image to upload.
 			err = func() error {
 				defer func() { _ = a.reader.Close() }()
-				part, err := w.CreateFormFile("", a.name)
-				if err != nil {
-					return err
-				}
-				_, err = io.Copy(part, a.reader)
-				return err
+				return writeMultipartFile(w, "", a.name, a.reader)
 			}()
 			if err != nil {
 				return nil, err
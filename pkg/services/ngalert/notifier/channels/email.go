@@ -5,9 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/url"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 
@@ -21,18 +19,20 @@ import (
 // alert notifications over email.
 type EmailNotifier struct {
 	*channels.Base
-	log      channels.Logger
-	ns       channels.EmailSender
-	images   channels.ImageStore
-	tmpl     *template.Template
-	settings *emailSettings
+	log         channels.Logger
+	ns          channels.EmailSender
+	images      channels.ImageStore
+	tmpl        *template.Template
+	settings    *emailSettings
+	maxValueLen int
 }
 
 type emailSettings struct {
-	SingleEmail bool
-	Addresses   []string
-	Message     string
-	Subject     string
+	SingleEmail     bool
+	Addresses       []string
+	Message         string
+	ResolvedMessage string
+	Subject         string
 }
 
 func EmailFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
@@ -48,10 +48,11 @@ func EmailFactory(fc channels.FactoryConfig) (channels.NotificationChannel, erro
 
 func buildEmailSettings(fc channels.FactoryConfig) (*emailSettings, error) {
 	type emailSettingsRaw struct {
-		SingleEmail bool   `json:"singleEmail,omitempty"`
-		Addresses   string `json:"addresses,omitempty"`
-		Message     string `json:"message,omitempty"`
-		Subject     string `json:"subject,omitempty"`
+		SingleEmail     bool   `json:"singleEmail,omitempty"`
+		Addresses       string `json:"addresses,omitempty"`
+		Message         string `json:"message,omitempty"`
+		ResolvedMessage string `json:"resolved_message,omitempty"`
+		Subject         string `json:"subject,omitempty"`
 	}
 
 	var settings emailSettingsRaw
@@ -70,10 +71,11 @@ func buildEmailSettings(fc channels.FactoryConfig) (*emailSettings, error) {
 	}
 
 	return &emailSettings{
-		SingleEmail: settings.SingleEmail,
-		Message:     settings.Message,
-		Subject:     settings.Subject,
-		Addresses:   addresses,
+		SingleEmail:     settings.SingleEmail,
+		Message:         settings.Message,
+		ResolvedMessage: settings.ResolvedMessage,
+		Subject:         settings.Subject,
+		Addresses:       addresses,
 	}, nil
 }
 
@@ -82,34 +84,33 @@ func buildEmailNotifier(fc channels.FactoryConfig) (*EmailNotifier, error) {
 	if err != nil {
 		return nil, err
 	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
 	return &EmailNotifier{
-		Base:     channels.NewBase(fc.Config),
-		log:      fc.Logger,
-		ns:       fc.NotificationService,
-		images:   fc.ImageStore,
-		tmpl:     fc.Template,
-		settings: settings,
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		ns:          fc.NotificationService,
+		images:      images,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
 	}, nil
 }
 
 // Notify sends the alert notification.
 func (en *EmailNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
 	var tmplErr error
-	tmpl, data := channels.TmplText(ctx, en.tmpl, alerts, en.log, &tmplErr)
+	tmpl, data := tmplText(ctx, en.tmpl, alerts, en.log, &tmplErr, en.maxValueLen)
 
 	subject := tmpl(en.settings.Subject)
-	alertPageURL := en.tmpl.ExternalURL.String()
-	ruleURL := en.tmpl.ExternalURL.String()
-	u, err := url.Parse(en.tmpl.ExternalURL.String())
-	if err == nil {
-		basePath := u.Path
-		u.Path = path.Join(basePath, "/alerting/list")
-		ruleURL = u.String()
-		u.RawQuery = "alertState=firing&view=state"
-		alertPageURL = u.String()
-	} else {
-		en.log.Debug("failed to parse external URL", "url", en.tmpl.ExternalURL.String(), "error", err.Error())
-	}
+	ruleURL := grafanaURL(en.tmpl.ExternalURL, "/alerting/list")
+	alertPageURL := ruleURL + "?alertState=firing&view=state"
 
 	// Extend alerts data with images, if available.
 	var embeddedFiles []string
@@ -133,7 +134,7 @@ func (en *EmailNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bo
 		Subject: subject,
 		Data: map[string]interface{}{
 			"Title":             subject,
-			"Message":           tmpl(en.settings.Message),
+			"Message":           tmpl(messageForAlerts(en.settings.Message, en.settings.ResolvedMessage, alerts)),
 			"Status":            data.Status,
 			"Alerts":            data.Alerts,
 			"GroupLabels":       data.GroupLabels,
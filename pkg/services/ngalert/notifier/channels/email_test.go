@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
 	"github.com/prometheus/alertmanager/template"
@@ -222,4 +223,44 @@ func TestEmailNotifier_Notify(t *testing.T) {
 			},
 		}, expected)
 	})
+
+	t.Run("with a resolved message it should send it for resolved alerts", func(t *testing.T) {
+		jsonData := `{
+			"addresses": "someops@example.com",
+			"message": "{{ len .Alerts.Firing }} alerts are firing",
+			"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+		}`
+
+		emailSender := mockNotificationService()
+
+		fc := channels.FactoryConfig{
+			Config: &channels.NotificationChannelConfig{
+				Name:     "ops",
+				Type:     "email",
+				Settings: json.RawMessage(jsonData),
+			},
+			NotificationService: emailSender,
+			DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+				return fallback
+			},
+			ImageStore: &channels.UnavailableImageStore{},
+			Template:   tmpl,
+			Logger:     &channels.FakeLogger{},
+		}
+
+		emailNotifier, err := EmailFactory(fc)
+		require.NoError(t, err)
+
+		firing := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}}
+		ok, err := emailNotifier.Notify(context.Background(), firing)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "1 alerts are firing", emailSender.EmailSync.Data["Message"])
+
+		resolved := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}, EndsAt: time.Now().Add(-time.Minute)}}
+		ok, err = emailNotifier.Notify(context.Background(), resolved)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "1 alerts are resolved", emailSender.EmailSync.Data["Message"])
+	})
 }
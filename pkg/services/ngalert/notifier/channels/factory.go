@@ -1,6 +1,8 @@
 package channels
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
@@ -8,28 +10,86 @@ import (
 
 var receiverFactories = map[string]func(channels.FactoryConfig) (channels.NotificationChannel, error){
 	"prometheus-alertmanager": AlertmanagerFactory,
+	"amqp":                    AMQPFactory,
 	"dingding":                DingDingFactory,
+	"cloudlogging":            CloudLoggingFactory,
 	"discord":                 DiscordFactory,
 	"email":                   EmailFactory,
+	"file":                    FileFactory,
 	"googlechat":              GoogleChatFactory,
+	"heartbeat":               HeartbeatFactory,
 	"kafka":                   KafkaFactory,
 	"line":                    LineFactory,
-	"opsgenie":                channels.OpsgenieFactory,
-	"pagerduty":               channels.PagerdutyFactory,
+	"mqtt":                    MQTTFactory,
+	"opsgenie":                OpsgenieFactory,
+	"pagerduty":               PagerdutyFactory,
+	"pubsub":                  PubSubFactory,
 	"pushover":                channels.PushoverFactory,
+	"rocketchat":              RocketChatFactory,
 	"sensugo":                 channels.SensuGoFactory,
 	"slack":                   SlackFactory,
-	"teams":                   channels.TeamsFactory,
-	"telegram":                channels.TelegramFactory,
+	"squadcast":               SquadcastFactory,
+	"syslog":                  SyslogFactory,
+	"teams":                   TeamsFactory,
+	"telegram":                TelegramFactory,
 	"threema":                 channels.ThreemaFactory,
 	"victorops":               channels.VictorOpsFactory,
-	"webhook":                 channels.WebHookFactory,
+	"webhook":                 WebHookFactory,
 	"wecom":                   channels.WeComFactory,
 	"webex":                   channels.WebexFactory,
+	"zenduty":                 ZendutyFactory,
+}
+
+// resolvedMessageReceivers are the receivers that send resolved_message instead of their
+// message when all alerts are resolved.
+var resolvedMessageReceivers = map[string]struct{}{
+	"amqp":         {},
+	"cloudlogging": {},
+	"dingding":     {},
+	"discord":      {},
+	"email":        {},
+	"file":         {},
+	"googlechat":   {},
+	"mqtt":         {},
+	"pubsub":       {},
+	"rocketchat":   {},
+	"slack":        {},
+	"squadcast":    {},
+	"syslog":       {},
+	"teams":        {},
+	"telegram":     {},
+	"webhook":      {},
+	"zenduty":      {},
 }
 
 func Factory(receiverType string) (func(channels.FactoryConfig) (channels.NotificationChannel, error), bool) {
 	receiverType = strings.ToLower(receiverType)
 	factory, exists := receiverFactories[receiverType]
-	return factory, exists
+	if !exists {
+		return nil, false
+	}
+	return withNotifyErrors(withCoalescing(withCircuitBreaker(withSendOnlyDuring(withStaticLabels(withDefaultAlertname(withRequireLabels(withSendIf(withLabelFilter(withResolvedMessage(receiverType, factory)))))))))), true
+}
+
+// withResolvedMessage wraps the factory so that it fails if resolved_message is set for
+// a receiver that does not support it, rather than ignoring it.
+func withResolvedMessage(receiverType string, factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	if _, ok := resolvedMessageReceivers[receiverType]; ok {
+		return factory
+	}
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		var settings struct {
+			ResolvedMessage string `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+		}
+		if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+		}
+		if settings.ResolvedMessage != "" {
+			return nil, receiverInitError{
+				Reason: fmt.Sprintf("resolved_message is not supported by %s receivers", receiverType),
+				Cfg:    *fc.Config,
+			}
+		}
+		return factory(fc)
+	}
 }
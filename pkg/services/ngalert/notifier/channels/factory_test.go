@@ -0,0 +1,81 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFactory_ResolvedMessage(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	cases := []struct {
+		name         string
+		receiverType string
+		settings     string
+		expInitError string
+	}{{
+		name:         "resolved_message is supported by webhook receivers",
+		receiverType: "webhook",
+		settings:     `{"url": "http://localhost/test", "resolved_message": "resolved"}`,
+	}, {
+		name:         "resolved_message is supported by slack receivers",
+		receiverType: "slack",
+		settings:     `{"url": "http://localhost/test", "resolved_message": "resolved"}`,
+	}, {
+		name:         "Receivers without resolved_message support are created if it is not set",
+		receiverType: "pagerduty",
+		settings:     `{"integrationKey": "abcdefgh0123456789"}`,
+	}, {
+		name:         "Error in initialization, resolved_message is not supported by pagerduty",
+		receiverType: "pagerduty",
+		settings:     `{"integrationKey": "abcdefgh0123456789", "resolved_message": "resolved"}`,
+		expInitError: `failed to validate receiver "resolved_message_testing" of type "pagerduty": resolved_message is not supported by pagerduty receivers`,
+	}, {
+		name:         "Error in initialization, resolved_message is not supported by opsgenie",
+		receiverType: "opsgenie",
+		settings:     `{"apiKey": "abcdefgh0123456789", "resolved_message": "resolved"}`,
+		expInitError: `failed to validate receiver "resolved_message_testing" of type "opsgenie": resolved_message is not supported by opsgenie receivers`,
+	}, {
+		name:         "Error in initialization, resolved_message is not supported by prometheus-alertmanager",
+		receiverType: "prometheus-alertmanager",
+		settings:     `{"url": "http://localhost:9093", "resolved_message": "resolved"}`,
+		expInitError: `failed to validate receiver "resolved_message_testing" of type "prometheus-alertmanager": resolved_message is not supported by prometheus-alertmanager receivers`,
+	}, {
+		name:         "Error in initialization, resolved_message is not supported by pushover",
+		receiverType: "pushover",
+		settings:     `{"userKey": "user", "apiToken": "token", "resolved_message": "resolved"}`,
+		expInitError: `failed to validate receiver "resolved_message_testing" of type "pushover": resolved_message is not supported by pushover receivers`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "resolved_message_testing",
+					Type:     c.receiverType,
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: mockNotificationService(),
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			factory, ok := Factory(c.receiverType)
+			require.True(t, ok)
+			_, err := factory(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
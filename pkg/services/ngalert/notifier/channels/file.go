@@ -0,0 +1,301 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+const (
+	// fileDefaultMaxSize is the default size in bytes after which the file is rotated.
+	fileDefaultMaxSize = 100 * 1024 * 1024
+	// fileDefaultMaxBackups is the default number of rotated files that are kept.
+	fileDefaultMaxBackups = 1
+)
+
+// fileLocks has a mutex for each path that notifications are written to. Notifiers of
+// different receivers, or of the same receiver before and after a configuration reload,
+// can write to the same file, so lines must not be written or rotated concurrently.
+var fileLocks sync.Map
+
+// FileNotifier is responsible for appending alert notifications as JSON lines
+// to a local file, such as for an external agent to ship.
+type FileNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	tmpl        *template.Template
+	settings    *fileSettings
+	maxValueLen int
+}
+
+type fileSettings struct {
+	// Dir is the directory of file notifiers that the file is in.
+	Dir  string
+	Path string
+	// MaxSize is the size in bytes after which the file is rotated, and MaxBackups
+	// is the number of rotated files, such as path.1, that are kept.
+	MaxSize    int64
+	MaxBackups int
+	Title      string
+	Message    string
+	// ResolvedMessage, if set, is the message of notifications with only resolved alerts.
+	ResolvedMessage string
+}
+
+// fileMessage is the JSON line appended to the file.
+type fileMessage struct {
+	*channels.ExtendedData
+
+	GroupKey string `json:"groupKey"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+}
+
+func buildFileSettings(fc channels.FactoryConfig) (*fileSettings, error) {
+	var raw struct {
+		Path            string      `json:"path,omitempty" yaml:"path,omitempty"`
+		MaxSize         json.Number `json:"max_size,omitempty" yaml:"max_size,omitempty"`
+		MaxBackups      json.Number `json:"max_backups,omitempty" yaml:"max_backups,omitempty"`
+		Title           string      `json:"title,omitempty" yaml:"title,omitempty"`
+		Message         string      `json:"message,omitempty" yaml:"message,omitempty"`
+		ResolvedMessage string      `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	dir := getOptions().FileDirectory
+	if dir == "" {
+		return nil, errors.New("the file notifier is disabled, as no directory is configured for it")
+	}
+	if raw.Path == "" {
+		return nil, errors.New("could not find path property in settings")
+	}
+	path, err := fileNotifierPath(dir, raw.Path)
+	if err != nil {
+		return nil, err
+	}
+	settings := fileSettings{
+		Dir:             dir,
+		Path:            path,
+		MaxSize:         fileDefaultMaxSize,
+		MaxBackups:      fileDefaultMaxBackups,
+		Title:           raw.Title,
+		Message:         raw.Message,
+		ResolvedMessage: raw.ResolvedMessage,
+	}
+	if raw.MaxSize != "" {
+		n, err := strconv.ParseInt(raw.MaxSize.String(), 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid value for max_size: %q, must be a positive number of bytes", raw.MaxSize)
+		}
+		settings.MaxSize = n
+	}
+	if raw.MaxBackups != "" {
+		n, err := strconv.Atoi(raw.MaxBackups.String())
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid value for max_backups: %q, must be a non-negative integer", raw.MaxBackups)
+		}
+		settings.MaxBackups = n
+	}
+	if settings.Title == "" {
+		settings.Title = channels.DefaultMessageTitleEmbed
+	}
+	if settings.Message == "" {
+		settings.Message = channels.DefaultMessageEmbed
+	}
+	return &settings, nil
+}
+
+func FileFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	fn, err := newFileNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return fn, nil
+}
+
+// newFileNotifier is the constructor for the file notifier. It checks that the file
+// can be written, so a wrong path is reported when the receiver is saved.
+func newFileNotifier(fc channels.FactoryConfig) (*FileNotifier, error) {
+	settings, err := buildFileSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	f, err := openFileForAppend(settings.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for path: %q is not writable: %w", settings.Path, err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close %q: %w", settings.Path, err)
+	}
+	return &FileNotifier{
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+	}, nil
+}
+
+// Notify appends the notification as a JSON line to the file.
+func (fn *FileNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	groupKey, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var tmplErr error
+	tmpl, data := tmplText(ctx, fn.tmpl, as, fn.log, &tmplErr, fn.maxValueLen)
+	msg := fileMessage{
+		ExtendedData: data,
+		GroupKey:     groupKey.String(),
+		Title:        tmpl(fn.settings.Title),
+		Message:      tmpl(messageForAlerts(fn.settings.Message, fn.settings.ResolvedMessage, as)),
+	}
+	if tmplErr != nil {
+		fn.log.Warn("failed to template file message", "error", tmplErr.Error())
+	}
+
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if err := fn.appendLine(append(line, '\n')); err != nil {
+		fn.log.Error("failed to write notification to file", "path", fn.settings.Path, "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// appendLine writes the line to the end of the file, and first rotates the file if
+// the line would make it larger than the max size.
+func (fn *FileNotifier) appendLine(line []byte) error {
+	mtx, _ := fileLocks.LoadOrStore(fn.settings.Path, &sync.Mutex{})
+	mtx.(*sync.Mutex).Lock()
+	defer mtx.(*sync.Mutex).Unlock()
+
+	// The path is checked again, as symbolic links can be created after the notifier.
+	if _, err := fileNotifierPath(fn.settings.Dir, fn.settings.Path); err != nil {
+		return err
+	}
+	info, err := os.Stat(fn.settings.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	// A line larger than the max size is written to an empty file rather than dropped.
+	if err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > fn.settings.MaxSize {
+		if err := fn.rotate(); err != nil {
+			return err
+		}
+	}
+
+	f, err := openFileForAppend(fn.settings.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fn.log.Warn("failed to close file", "path", fn.settings.Path, "error", err)
+		}
+	}()
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	return nil
+}
+
+// rotate renames the file to path.1, and each backup to the next number, such as
+// path.1 to path.2. The oldest backup is removed once there are max backups, and the
+// file is removed if no backups are kept.
+func (fn *FileNotifier) rotate() error {
+	p := fn.settings.Path
+	if fn.settings.MaxBackups == 0 {
+		if err := os.Remove(p); err != nil {
+			return fmt.Errorf("failed to rotate file: %w", err)
+		}
+		return nil
+	}
+	for i := fn.settings.MaxBackups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", p, i), fmt.Sprintf("%s.%d", p, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate file: %w", err)
+		}
+	}
+	if err := os.Rename(p, p+".1"); err != nil {
+		return fmt.Errorf("failed to rotate file: %w", err)
+	}
+	fn.log.Debug("rotated file", "path", p, "max_backups", fn.settings.MaxBackups)
+	return nil
+}
+
+// fileNotifierPath returns the path of the file in the directory of file notifiers. The
+// path is relative to the directory, or an absolute path in it. Paths that leave the
+// directory, such as with .. or symbolic links, are rejected, so that contact points
+// cannot write to or rotate other files of Grafana.
+func fileNotifierPath(dir, path string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the directory of file notifiers: %w", err)
+	}
+	p := path
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+	p = filepath.Clean(p)
+	if !isInDirectory(dir, p) {
+		return "", fmt.Errorf("invalid value for path: %q, must be in the directory of file notifiers", path)
+	}
+
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the directory of file notifiers: %w", err)
+	}
+	realParent, err := filepath.EvalSymlinks(filepath.Dir(p))
+	if err != nil {
+		return "", fmt.Errorf("invalid value for path: %q is not writable: %w", path, err)
+	}
+	if !isInDirectory(realDir, filepath.Join(realParent, filepath.Base(p))) {
+		return "", fmt.Errorf("invalid value for path: %q, must be in the directory of file notifiers", path)
+	}
+	if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("invalid value for path: %q, must not be a symbolic link", path)
+	}
+	return p, nil
+}
+
+// isInDirectory returns true if the path is in the directory or its subdirectories.
+func isInDirectory(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// openFileForAppend opens the file for appending, and creates it if it does not exist.
+// The path comes from the settings of the receiver, which are validated to be in the
+// directory of file notifiers.
+//
+//nolint:gosec
+func openFileForAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+}
+
+func (fn *FileNotifier) SendResolved() bool {
+	return !fn.GetDisableResolveMessage()
+}
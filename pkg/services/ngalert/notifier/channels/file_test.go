@@ -0,0 +1,254 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+// setFileDirectoryForTests sets the directory of file notifiers for the test.
+func setFileDirectoryForTests(t *testing.T, dir string) {
+	t.Helper()
+	prev := getOptions()
+	o := prev
+	o.FileDirectory = dir
+	SetOptions(o)
+	t.Cleanup(func() { SetOptions(prev) })
+}
+
+func newFileNotifierForTests(t *testing.T, settings string) (*FileNotifier, error) {
+	t.Helper()
+	return newFileNotifier(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Name:     "file_testing",
+			Type:     "file",
+			Settings: json.RawMessage(settings),
+		},
+		Template: templateForTests(t),
+		Logger:   &channels.FakeLogger{},
+	})
+}
+
+// readFileLines returns the JSON lines in the file.
+func readFileLines(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+
+	var lines []map[string]interface{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(s.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.NoError(t, s.Err())
+	return lines
+}
+
+func TestFileNotifier(t *testing.T) {
+	dir := t.TempDir()
+	setFileDirectoryForTests(t, dir)
+	path := filepath.Join(dir, "alerts.jsonl")
+	fn, err := newFileNotifierForTests(t, fmt.Sprintf(`{"path": %q, "title": "{{ .CommonLabels.alertname }}"}`, path))
+	require.NoError(t, err)
+
+	// The file is created when the notifier is created, to check that it is writable.
+	require.FileExists(t, path)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	for _, name := range []string{"alert1", "alert2"} {
+		ok, err := fn.Notify(ctx, &types.Alert{
+			Alert: model.Alert{Labels: model.LabelSet{"alertname": model.LabelValue(name)}},
+		})
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	lines := readFileLines(t, path)
+	require.Len(t, lines, 2)
+	require.Equal(t, "alert1", lines[0]["title"])
+	require.Equal(t, "alert2", lines[1]["title"])
+	require.Equal(t, "alertname", lines[0]["groupKey"])
+	require.Equal(t, "firing", lines[0]["status"])
+	require.Len(t, lines[0]["alerts"], 1)
+}
+
+func TestFileNotifier_ResolvedMessage(t *testing.T) {
+	dir := t.TempDir()
+	setFileDirectoryForTests(t, dir)
+	path := filepath.Join(dir, "alerts.jsonl")
+	fn, err := newFileNotifierForTests(t, fmt.Sprintf(`{
+		"path": %q,
+		"message": "{{ len .Alerts.Firing }} alerts are firing",
+		"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+	}`, path))
+	require.NoError(t, err)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	for _, endsAt := range []time.Time{{}, time.Now().Add(-time.Minute)} {
+		ok, err := fn.Notify(ctx, &types.Alert{
+			Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}, EndsAt: endsAt},
+		})
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	lines := readFileLines(t, path)
+	require.Len(t, lines, 2)
+	require.Equal(t, "1 alerts are firing", lines[0]["message"])
+	require.Equal(t, "1 alerts are resolved", lines[1]["message"])
+}
+
+func TestFileNotifier_SymlinkAfterInit(t *testing.T) {
+	dir := t.TempDir()
+	setFileDirectoryForTests(t, dir)
+	path := filepath.Join(dir, "alerts.jsonl")
+	fn, err := newFileNotifierForTests(t, fmt.Sprintf(`{"path": %q}`, path))
+	require.NoError(t, err)
+
+	// The file is replaced with a link to a file outside of the directory.
+	outside := filepath.Join(t.TempDir(), "grafana.db")
+	require.NoError(t, os.WriteFile(outside, []byte("data"), 0600))
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.Symlink(outside, path))
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	_, err = fn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+	require.EqualError(t, err, fmt.Sprintf(`invalid value for path: %q, must not be a symbolic link`, path))
+	b, err := os.ReadFile(outside)
+	require.NoError(t, err)
+	require.Equal(t, "data", string(b))
+}
+
+func TestFileNotifier_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	setFileDirectoryForTests(t, dir)
+	path := filepath.Join(dir, "alerts.jsonl")
+	// Each line is larger than the max size, so the file is rotated before each line
+	// after the first.
+	fn, err := newFileNotifierForTests(t, fmt.Sprintf(`{"path": %q, "title": "{{ .CommonLabels.alertname }}", "max_size": 10, "max_backups": 2}`, path))
+	require.NoError(t, err)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	for _, name := range []string{"alert1", "alert2", "alert3", "alert4"} {
+		ok, err := fn.Notify(ctx, &types.Alert{
+			Alert: model.Alert{Labels: model.LabelSet{"alertname": model.LabelValue(name)}},
+		})
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	for p, expTitle := range map[string]string{path: "alert4", path + ".1": "alert3", path + ".2": "alert2"} {
+		lines := readFileLines(t, p)
+		require.Len(t, lines, 1)
+		require.Equal(t, expTitle, lines[0]["title"])
+	}
+	require.NoFileExists(t, path+".3")
+}
+
+func TestFileNotifier_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	setFileDirectoryForTests(t, dir)
+	path := filepath.Join(dir, "alerts.jsonl")
+	settings := fmt.Sprintf(`{"path": %q}`, path)
+	// Notifiers of different receivers can write to the same file.
+	notifiers := make([]*FileNotifier, 2)
+	for i := range notifiers {
+		fn, err := newFileNotifierForTests(t, settings)
+		require.NoError(t, err)
+		notifiers[i] = fn
+	}
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := notifiers[i%2].Notify(ctx, &types.Alert{
+				Alert: model.Alert{Labels: model.LabelSet{"alertname": model.LabelValue(fmt.Sprintf("alert%d", i))}},
+			})
+			require.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	require.Len(t, readFileLines(t, path), 20)
+}
+
+func TestFileNotifier_InitErrors(t *testing.T) {
+	dir := t.TempDir()
+	setFileDirectoryForTests(t, dir)
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "alerts.jsonl"), filepath.Join(dir, "link.jsonl")))
+
+	cases := []struct {
+		name         string
+		settings     string
+		expInitError string
+	}{{
+		name:         "Path is required",
+		settings:     `{}`,
+		expInitError: "could not find path property in settings",
+	}, {
+		name:         "Path must be in the directory",
+		settings:     fmt.Sprintf(`{"path": %q}`, filepath.Join(outside, "alerts.jsonl")),
+		expInitError: fmt.Sprintf(`invalid value for path: %q, must be in the directory of file notifiers`, filepath.Join(outside, "alerts.jsonl")),
+	}, {
+		name:         "Relative path must not leave the directory",
+		settings:     `{"path": "../grafana.db"}`,
+		expInitError: `invalid value for path: "../grafana.db", must be in the directory of file notifiers`,
+	}, {
+		name:         "Path must not be the directory",
+		settings:     fmt.Sprintf(`{"path": %q}`, dir+"/sub/.."),
+		expInitError: fmt.Sprintf(`invalid value for path: %q, must be in the directory of file notifiers`, dir+"/sub/.."),
+	}, {
+		name:         "Path must not leave the directory with a symbolic link to a directory",
+		settings:     `{"path": "link/alerts.jsonl"}`,
+		expInitError: `invalid value for path: "link/alerts.jsonl", must be in the directory of file notifiers`,
+	}, {
+		name:         "Path must not be a symbolic link",
+		settings:     `{"path": "link.jsonl"}`,
+		expInitError: `invalid value for path: "link.jsonl", must not be a symbolic link`,
+	}, {
+		name:         "Path must be writable",
+		settings:     fmt.Sprintf(`{"path": %q}`, filepath.Join(dir, "missing", "alerts.jsonl")),
+		expInitError: fmt.Sprintf(`invalid value for path: %q is not writable: lstat %s: no such file or directory`, filepath.Join(dir, "missing", "alerts.jsonl"), filepath.Join(dir, "missing")),
+	}, {
+		name:         "Invalid max_size",
+		settings:     fmt.Sprintf(`{"path": %q, "max_size": 0}`, filepath.Join(dir, "alerts.jsonl")),
+		expInitError: `invalid value for max_size: "0", must be a positive number of bytes`,
+	}, {
+		name:         "Invalid max_backups",
+		settings:     fmt.Sprintf(`{"path": %q, "max_backups": -1}`, filepath.Join(dir, "alerts.jsonl")),
+		expInitError: `invalid value for max_backups: "-1", must be a non-negative integer`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := newFileNotifierForTests(t, c.settings)
+			require.EqualError(t, err, c.expInitError)
+		})
+	}
+
+	t.Run("Disabled without a directory", func(t *testing.T) {
+		setFileDirectoryForTests(t, "")
+		_, err := newFileNotifierForTests(t, fmt.Sprintf(`{"path": %q}`, filepath.Join(dir, "alerts.jsonl")))
+		require.EqualError(t, err, "the file notifier is disabled, as no directory is configured for it")
+	})
+}
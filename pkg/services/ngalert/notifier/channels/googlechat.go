@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/grafana/alerting/alerting/notifier/channels"
 	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/alertmanager/types"
@@ -17,18 +18,21 @@ import (
 // alert notifications to Google chat.
 type GoogleChatNotifier struct {
 	*channels.Base
-	log        channels.Logger
-	ns         channels.WebhookSender
-	images     channels.ImageStore
-	tmpl       *template.Template
-	settings   *googleChatSettings
-	appVersion string
+	log         channels.Logger
+	ns          channels.WebhookSender
+	images      channels.ImageStore
+	tmpl        *template.Template
+	settings    *googleChatSettings
+	maxValueLen int
+	appVersion  string
+	clock       clock.Clock
 }
 
 type googleChatSettings struct {
-	URL     string `json:"url,omitempty" yaml:"url,omitempty"`
-	Title   string `json:"title,omitempty" yaml:"title,omitempty"`
-	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	URL             string `json:"url,omitempty" yaml:"url,omitempty"`
+	Title           string `json:"title,omitempty" yaml:"title,omitempty"`
+	Message         string `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage string `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
 }
 
 func buildGoogleChatSettings(fc channels.FactoryConfig) (*googleChatSettings, error) {
@@ -66,14 +70,24 @@ func newGoogleChatNotifier(fc channels.FactoryConfig) (*GoogleChatNotifier, erro
 	if err != nil {
 		return nil, err
 	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
 	return &GoogleChatNotifier{
-		Base:       channels.NewBase(fc.Config),
-		log:        fc.Logger,
-		ns:         fc.NotificationService,
-		images:     fc.ImageStore,
-		tmpl:       fc.Template,
-		settings:   settings,
-		appVersion: fc.GrafanaBuildVersion,
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		ns:          fc.NotificationService,
+		images:      images,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+		appVersion:  fc.GrafanaBuildVersion,
+		clock:       clock.New(),
 	}, nil
 }
 
@@ -82,11 +96,11 @@ func (gcn *GoogleChatNotifier) Notify(ctx context.Context, as ...*types.Alert) (
 	gcn.log.Debug("executing Google Chat notification")
 
 	var tmplErr error
-	tmpl, _ := channels.TmplText(ctx, gcn.tmpl, as, gcn.log, &tmplErr)
+	tmpl, _ := tmplText(ctx, gcn.tmpl, as, gcn.log, &tmplErr, gcn.maxValueLen)
 
 	var widgets []widget
 
-	if msg := tmpl(gcn.settings.Message); msg != "" {
+	if msg := tmpl(messageForAlerts(gcn.settings.Message, gcn.settings.ResolvedMessage, as)); msg != "" {
 		// Add a text paragraph widget for the message if there is a message.
 		// Google Chat API doesn't accept an empty text property.
 		widgets = append(widgets, textParagraphWidget{Text: text{Text: msg}})
@@ -97,7 +111,7 @@ func (gcn *GoogleChatNotifier) Notify(ctx context.Context, as ...*types.Alert) (
 		tmplErr = nil
 	}
 
-	ruleURL := joinUrlPath(gcn.tmpl.ExternalURL.String(), "/alerting/list", gcn.log)
+	ruleURL := grafanaURL(gcn.tmpl.ExternalURL, "/alerting/list")
 	if gcn.isUrlAbsolute(ruleURL) {
 		// Add a button widget (link to Grafana).
 		widgets = append(widgets, buttonWidget{
@@ -121,7 +135,7 @@ func (gcn *GoogleChatNotifier) Notify(ctx context.Context, as ...*types.Alert) (
 	// Add text paragraph widget for the build version and timestamp.
 	widgets = append(widgets, textParagraphWidget{
 		Text: text{
-			Text: "Grafana v" + gcn.appVersion + " | " + (timeNow()).Format(time.RFC822),
+			Text: "Grafana v" + gcn.appVersion + " | " + gcn.clock.Now().Format(time.RFC822),
 		},
 	})
 
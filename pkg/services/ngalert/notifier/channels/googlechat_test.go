@@ -19,7 +19,6 @@ import (
 
 func TestGoogleChatNotifier(t *testing.T) {
 	constNow := time.Now()
-	defer mockTimeNow(constNow)()
 	appVersion := fmt.Sprintf("%d.0.0", rand.Uint32())
 
 	cases := []struct {
@@ -205,6 +204,61 @@ func TestGoogleChatNotifier(t *testing.T) {
 				},
 			},
 			expMsgError: nil,
+		}, {
+			name:        "Customized resolved message",
+			settings:    `{"url": "http://localhost", "title": "{{ .CommonLabels.alertname }}", "message": "{{ len .Alerts.Firing }} firing", "resolved_message": "{{ len .Alerts.Resolved }} resolved"}`,
+			externalURL: "http://localhost",
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1"},
+						EndsAt: constNow.Add(-time.Minute),
+					},
+				},
+			},
+			expMsg: &outerStruct{
+				PreviewText:  "alert1",
+				FallbackText: "alert1",
+				Cards: []card{
+					{
+						Header: header{
+							Title: "alert1",
+						},
+						Sections: []section{
+							{
+								Widgets: []widget{
+									textParagraphWidget{
+										Text: text{
+											Text: "1 resolved",
+										},
+									},
+									buttonWidget{
+										Buttons: []button{
+											{
+												TextButton: textButton{
+													Text: "OPEN IN GRAFANA",
+													OnClick: onClick{
+														OpenLink: openLink{
+															URL: "http://localhost/alerting/list",
+														},
+													},
+												},
+											},
+										},
+									},
+									textParagraphWidget{
+										Text: text{
+											// RFC822 only has the minute, hence it works in most cases.
+											Text: "Grafana v" + appVersion + " | " + constNow.Format(time.RFC822),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expMsgError: nil,
 		}, {
 			name:        "Customized title",
 			settings:    `{"url": "http://localhost", "title": "Alerts firing: {{ len .Alerts.Firing }}"}`,
@@ -487,6 +541,7 @@ func TestGoogleChatNotifier(t *testing.T) {
 				return
 			}
 			require.NoError(t, err)
+			pn.clock = mockClock(constNow)
 
 			ctx := notify.WithGroupKey(context.Background(), "alertname")
 			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
@@ -0,0 +1,28 @@
+package channels
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+)
+
+// ErrHealthCheckNotSupported is returned by notifiers that cannot check their
+// endpoint without sending a notification.
+var ErrHealthCheckNotSupported = errors.New("health check is not supported")
+
+// HealthChecker is implemented by notifiers that can check that their endpoint is
+// reachable and their credentials are valid without sending a notification.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthCheck checks the endpoint of the notifier. It returns ErrHealthCheckNotSupported
+// if the notifier does not implement HealthChecker.
+func HealthCheck(ctx context.Context, n channels.NotificationChannel) error {
+	hc, ok := n.(HealthChecker)
+	if !ok {
+		return ErrHealthCheckNotSupported
+	}
+	return hc.HealthCheck(ctx)
+}
@@ -0,0 +1,102 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// HeartbeatNotifier is responsible for pinging a URL on each notification,
+// regardless of the alerts. It is meant for "always firing" watchdog alerts
+// that keep the dead man's switch of an external service alive.
+type HeartbeatNotifier struct {
+	*channels.Base
+	log      channels.Logger
+	settings *heartbeatSettings
+}
+
+type heartbeatSettings struct {
+	URL        string `json:"url,omitempty" yaml:"url,omitempty"`
+	HTTPMethod string `json:"httpMethod,omitempty" yaml:"httpMethod,omitempty"`
+	Body       string `json:"body,omitempty" yaml:"body,omitempty"`
+
+	url *url.URL
+}
+
+func buildHeartbeatSettings(fc channels.FactoryConfig) (*heartbeatSettings, error) {
+	var settings heartbeatSettings
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	settings.URL = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "url", settings.URL)
+	if settings.URL == "" {
+		return nil, errors.New("could not find url property in settings")
+	}
+	if !isHTTPURL(settings.URL) {
+		return nil, errors.New("url must be a valid http or https URL")
+	}
+	settings.url, err = url.Parse(settings.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url property in settings: %w", err)
+	}
+	switch strings.ToUpper(settings.HTTPMethod) {
+	case "":
+		settings.HTTPMethod = http.MethodPost
+	case http.MethodGet, http.MethodPost, http.MethodPut:
+		settings.HTTPMethod = strings.ToUpper(settings.HTTPMethod)
+	default:
+		return nil, fmt.Errorf("invalid value for httpMethod: %q, must be one of GET, POST, PUT", settings.HTTPMethod)
+	}
+	return &settings, nil
+}
+
+func HeartbeatFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	hn, err := newHeartbeatNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return hn, nil
+}
+
+// newHeartbeatNotifier is the constructor for the heartbeat notifier.
+func newHeartbeatNotifier(fc channels.FactoryConfig) (*HeartbeatNotifier, error) {
+	settings, err := buildHeartbeatSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	return &HeartbeatNotifier{
+		Base:     channels.NewBase(fc.Config),
+		log:      fc.Logger,
+		settings: settings,
+	}, nil
+}
+
+// Notify pings the URL with the configured method and body. The alerts are not sent.
+// It is sent with sendHTTPRequest, as the notification service only sends webhooks
+// with POST and PUT.
+func (hn *HeartbeatNotifier) Notify(ctx context.Context, _ ...*types.Alert) (bool, error) {
+	cfg := httpCfg{
+		method: hn.settings.HTTPMethod,
+		body:   []byte(hn.settings.Body),
+	}
+	if _, err := sendHTTPRequest(ctx, hn.settings.url, cfg, hn.log); err != nil {
+		hn.log.Error("failed to send heartbeat", "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (hn *HeartbeatNotifier) SendResolved() bool {
+	return !hn.GetDisableResolveMessage()
+}
@@ -0,0 +1,126 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeatNotifier(t *testing.T) {
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expMethod    string
+		expBody      string
+		expInitError string
+	}{
+		{
+			name:     "Default config",
+			settings: `{"url": "https://heartbeat.example.com/ping/abcd"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "Watchdog"},
+					},
+				},
+			},
+			expMethod: "POST",
+			expBody:   "",
+		}, {
+			name:     "Custom method and body",
+			settings: `{"url": "https://heartbeat.example.com/ping/abcd", "httpMethod": "put", "body": "{\"status\": \"alive\"}"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "Watchdog", "severity": "none"},
+						Annotations: model.LabelSet{"summary": "always firing"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "Watchdog", "cluster": "prod"},
+					},
+				},
+			},
+			expMethod: "PUT",
+			expBody:   `{"status": "alive"}`,
+		}, {
+			name:     "GET without body",
+			settings: `{"url": "https://heartbeat.example.com/ping/abcd", "httpMethod": "get"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "Watchdog"},
+					},
+				},
+			},
+			expMethod: "GET",
+			expBody:   "",
+		}, {
+			name:         "Error in initing, missing URL",
+			settings:     `{}`,
+			expInitError: `could not find url property in settings`,
+		}, {
+			name:         "Error in initing, invalid method",
+			settings:     `{"url": "https://heartbeat.example.com/ping/abcd", "httpMethod": "DELETE"}`,
+			expInitError: `invalid value for httpMethod: "DELETE", must be one of GET, POST, PUT`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				reqURL    string
+				reqMethod string
+				reqBody   string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				reqURL, reqMethod, reqBody = r.URL.Path, r.Method, string(b)
+				w.WriteHeader(http.StatusOK)
+			}))
+			t.Cleanup(server.Close)
+			settings := strings.ReplaceAll(c.settings, "https://heartbeat.example.com", server.URL)
+
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "heartbeat_testing",
+					Type:     "heartbeat",
+					Settings: json.RawMessage(settings),
+				},
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Logger: &channels.FakeLogger{},
+			}
+
+			hn, err := newHeartbeatNotifier(fc)
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := hn.Notify(ctx, c.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.Equal(t, "/ping/abcd", reqURL)
+			require.Equal(t, c.expMethod, reqMethod)
+			require.Equal(t, c.expBody, reqBody)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package channels
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
@@ -17,11 +19,17 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 )
 
+const (
+	// kafkaDefaultMaxMessageBytes is the default max.message.bytes of Kafka brokers.
+	kafkaDefaultMaxMessageBytes = 1048588
+)
+
 type kafkaBody struct {
 	Records []kafkaRecordEnvelope `json:"records"`
 }
 
 type kafkaRecordEnvelope struct {
+	Key   string      `json:"key,omitempty"`
 	Value kafkaRecord `json:"value"`
 }
 
@@ -44,11 +52,12 @@ type kafkaContext struct {
 // alert notifications to Kafka.
 type KafkaNotifier struct {
 	*channels.Base
-	log      channels.Logger
-	images   channels.ImageStore
-	ns       channels.WebhookSender
-	tmpl     *template.Template
-	settings *kafkaSettings
+	log         channels.Logger
+	images      channels.ImageStore
+	ns          channels.WebhookSender
+	tmpl        *template.Template
+	settings    *kafkaSettings
+	maxValueLen int
 }
 
 type kafkaSettings struct {
@@ -56,6 +65,14 @@ type kafkaSettings struct {
 	Topic       string `json:"kafkaTopic,omitempty" yaml:"kafkaTopic,omitempty"`
 	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 	Details     string `json:"details,omitempty" yaml:"details,omitempty"`
+	// Compression is the content coding of the body of requests, which can only be
+	// gzip. It is sent in the Content-Encoding header, and only compresses requests
+	// to the proxy. Records are compressed by the producer of the proxy, as configured
+	// by its compression.type.
+	Compression string `json:"compression,omitempty" yaml:"compression,omitempty"`
+	// MaxMessageBytes is the maximum size of a record. Alerts that do not fit in one
+	// record are split into multiple records.
+	MaxMessageBytes int `json:"max_message_bytes,omitempty" yaml:"max_message_bytes,omitempty"`
 }
 
 func buildKafkaSettings(fc channels.FactoryConfig) (*kafkaSettings, error) {
@@ -77,6 +94,15 @@ func buildKafkaSettings(fc channels.FactoryConfig) (*kafkaSettings, error) {
 	if settings.Details == "" {
 		settings.Details = channels.DefaultMessageEmbed
 	}
+	if settings.Compression != "" && settings.Compression != "gzip" {
+		return nil, fmt.Errorf("invalid value for compression: %q, must be gzip", settings.Compression)
+	}
+	if settings.MaxMessageBytes < 0 {
+		return nil, fmt.Errorf("invalid value for max_message_bytes: %d", settings.MaxMessageBytes)
+	}
+	if settings.MaxMessageBytes == 0 {
+		settings.MaxMessageBytes = kafkaDefaultMaxMessageBytes
+	}
 	return &settings, nil
 }
 
@@ -97,33 +123,41 @@ func newKafkaNotifier(fc channels.FactoryConfig) (*KafkaNotifier, error) {
 	if err != nil {
 		return nil, err
 	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
 
 	return &KafkaNotifier{
-		Base:     channels.NewBase(fc.Config),
-		log:      fc.Logger,
-		images:   fc.ImageStore,
-		ns:       fc.NotificationService,
-		tmpl:     fc.Template,
-		settings: settings,
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		images:      images,
+		ns:          fc.NotificationService,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
 	}, nil
 }
 
 // Notify sends the alert notification.
 func (kn *KafkaNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	var tmplErr error
-	tmpl, _ := channels.TmplText(ctx, kn.tmpl, as, kn.log, &tmplErr)
+	tmpl, _ := tmplText(ctx, kn.tmpl, as, kn.log, &tmplErr, kn.maxValueLen)
 
 	topicURL := strings.TrimRight(kn.settings.Endpoint, "/") + "/topics/" + tmpl(kn.settings.Topic)
+	if tmplErr != nil {
+		kn.log.Warn("failed to template Kafka topic", "error", tmplErr.Error())
+	}
 
-	body, err := kn.buildBody(ctx, tmpl, as...)
+	body, err := kn.buildBody(ctx, as...)
 	if err != nil {
 		return false, err
 	}
 
-	if tmplErr != nil {
-		kn.log.Warn("failed to template Kafka message", "error", tmplErr.Error())
-	}
-
 	cmd := &channels.SendWebhookSettings{
 		URL:        topicURL,
 		Body:       body,
@@ -134,6 +168,15 @@ func (kn *KafkaNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool,
 		},
 	}
 
+	if kn.settings.Compression != "" {
+		b, err := gzipKafkaBody([]byte(body))
+		if err != nil {
+			return false, fmt.Errorf("failed to compress Kafka message: %w", err)
+		}
+		cmd.Body = string(b)
+		cmd.HTTPHeader["Content-Encoding"] = kn.settings.Compression
+	}
+
 	if err = kn.ns.SendWebhook(ctx, cmd); err != nil {
 		kn.log.Error("Failed to send notification to Kafka", "error", err, "body", body)
 		return false, err
@@ -146,41 +189,124 @@ func (kn *KafkaNotifier) SendResolved() bool {
 	return !kn.GetDisableResolveMessage()
 }
 
-func (kn *KafkaNotifier) buildBody(ctx context.Context, tmpl func(string) string, as ...*types.Alert) (string, error) {
-	var record kafkaRecord
-	record.Client = "Grafana"
-	record.Description = tmpl(kn.settings.Description)
-	record.Details = tmpl(kn.settings.Details)
-
-	state := buildState(as...)
-	kn.log.Debug("notifying Kafka", "alert_state", state)
-	record.AlertState = state
-
-	ruleURL := joinUrlPath(kn.tmpl.ExternalURL.String(), "/alerting/list", kn.log)
-	record.ClientURL = ruleURL
+func (kn *KafkaNotifier) buildBody(ctx context.Context, as ...*types.Alert) (string, error) {
+	records, err := kn.buildRecords(ctx, as)
+	if err != nil {
+		return "", err
+	}
 
-	contexts := buildContextImages(ctx, kn.log, kn.images, as...)
-	if len(contexts) > 0 {
-		record.Contexts = contexts
+	body, err := json.Marshal(kafkaBody{Records: records})
+	if err != nil {
+		return "", err
 	}
+	return string(body), nil
+}
 
+// buildRecords returns the records for the alerts. If the record is larger than
+// max_message_bytes, the alerts are split in half with a record for each half until
+// the records fit. Records are keyed by the group key, so the records of a group are
+// written to the same partition in order, whether or not they are split. The template
+// data and images of the alerts are only built once, and each record is templated with
+// the data of its alerts.
+func (kn *KafkaNotifier) buildRecords(ctx context.Context, as []*types.Alert) ([]kafkaRecordEnvelope, error) {
 	groupKey, err := notify.ExtractGroupKey(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	record.IncidentKey = groupKey.Hash()
 
-	records := kafkaBody{
-		Records: []kafkaRecordEnvelope{
-			{Value: record},
-		},
+	var tmplErr error
+	_, data := tmplText(ctx, kn.tmpl, as, kn.log, &tmplErr, kn.maxValueLen)
+	images := buildContextImages(ctx, kn.log, kn.images, as...)
+	kn.log.Debug("notifying Kafka", "alert_state", buildState(as...))
+
+	records, err := kn.splitRecords(groupKey.Hash(), data, images, as, 0, &tmplErr)
+	if tmplErr != nil {
+		kn.log.Warn("failed to template Kafka message", "error", tmplErr.Error())
 	}
+	return records, err
+}
 
-	body, err := json.Marshal(records)
+// splitRecords returns the records for the alerts, which start at offset in the alerts
+// of the template data and images.
+func (kn *KafkaNotifier) splitRecords(key string, data *channels.ExtendedData, images map[int]kafkaContext, as []*types.Alert, offset int, tmplErr *error) ([]kafkaRecordEnvelope, error) {
+	record := kn.buildRecord(key, alertsData(data, offset, offset+len(as)), images, as, offset, tmplErr)
+	b, err := json.Marshal(record)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(body), nil
+	if len(b) <= kn.settings.MaxMessageBytes {
+		return []kafkaRecordEnvelope{{Key: key, Value: record}}, nil
+	}
+	if len(as) <= 1 {
+		kn.log.Warn("Kafka record exceeds max_message_bytes", "bytes", len(b), "max_message_bytes", kn.settings.MaxMessageBytes)
+		return []kafkaRecordEnvelope{{Key: key, Value: record}}, nil
+	}
+
+	half := len(as) / 2
+	records, err := kn.splitRecords(key, data, images, as[:half], offset, tmplErr)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := kn.splitRecords(key, data, images, as[half:], offset+half, tmplErr)
+	if err != nil {
+		return nil, err
+	}
+	return append(records, rest...), nil
+}
+
+// buildRecord returns the record for the alerts, templated with their template data.
+func (kn *KafkaNotifier) buildRecord(key string, data *channels.ExtendedData, images map[int]kafkaContext, as []*types.Alert, offset int, tmplErr *error) kafkaRecord {
+	tmpl := func(s string) string {
+		if *tmplErr != nil {
+			return ""
+		}
+		var text string
+		text, *tmplErr = kn.tmpl.ExecuteTextString(s, data)
+		return text
+	}
+
+	record := kafkaRecord{
+		Client:      "Grafana",
+		Description: tmpl(kn.settings.Description),
+		Details:     tmpl(kn.settings.Details),
+		AlertState:  buildState(as...),
+		ClientURL:   grafanaURL(kn.tmpl.ExternalURL, "/alerting/list"),
+		IncidentKey: key,
+	}
+	for i := range as {
+		if c, ok := images[offset+i]; ok {
+			record.Contexts = append(record.Contexts, c)
+		}
+	}
+	return record
+}
+
+// alertsData returns a copy of the template data with only the alerts from i to j. The
+// common labels and annotations of all the alerts are also common to these alerts.
+func alertsData(data *channels.ExtendedData, i, j int) *channels.ExtendedData {
+	if i == 0 && j == len(data.Alerts) {
+		return data
+	}
+	d := *data
+	d.Alerts = data.Alerts[i:j]
+	d.Status = string(model.AlertResolved)
+	if len(d.Alerts.Firing()) > 0 {
+		d.Status = string(model.AlertFiring)
+	}
+	return &d
+}
+
+// gzipKafkaBody compresses the body with gzip.
+func gzipKafkaBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 func buildState(as ...*types.Alert) models.AlertStateType {
@@ -192,15 +318,16 @@ func buildState(as ...*types.Alert) models.AlertStateType {
 	return models.AlertStateAlerting
 }
 
-func buildContextImages(ctx context.Context, l channels.Logger, imageStore channels.ImageStore, as ...*types.Alert) []kafkaContext {
-	var contexts []kafkaContext
+// buildContextImages returns the image contexts of the alerts by the index of the alert.
+func buildContextImages(ctx context.Context, l channels.Logger, imageStore channels.ImageStore, as ...*types.Alert) map[int]kafkaContext {
+	contexts := make(map[int]kafkaContext)
 	_ = withStoredImages(ctx, l, imageStore,
-		func(_ int, image channels.Image) error {
+		func(index int, image channels.Image) error {
 			if image.URL != "" {
-				contexts = append(contexts, kafkaContext{
+				contexts[index] = kafkaContext{
 					Type:   "image",
 					Source: image.URL,
-				})
+				}
 			}
 			return nil
 		}, as...)
@@ -1,9 +1,13 @@
 package channels
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
@@ -50,6 +54,7 @@ func TestKafkaNotifier(t *testing.T) {
 			expMsg: `{
 				  "records": [
 					{
+					  "key": "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
 					  "value": {
 						"alert_state": "alerting",
 						"client": "Grafana",
@@ -86,6 +91,7 @@ func TestKafkaNotifier(t *testing.T) {
 			expMsg: `{
 				  "records": [
 					{
+					  "key": "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
 					  "value": {
 						"alert_state": "alerting",
 						"client": "Grafana",
@@ -103,6 +109,10 @@ func TestKafkaNotifier(t *testing.T) {
 			name:         "Endpoint missing",
 			settings:     `{"kafkaTopic": "sometopic"}`,
 			expInitError: `could not find kafka rest proxy endpoint property in settings`,
+		}, {
+			name:         "Invalid compression",
+			settings:     `{"kafkaRestProxy": "http://localhost", "kafkaTopic": "sometopic", "compression": "zstd"}`,
+			expInitError: `invalid value for compression: "zstd", must be gzip`,
 		}, {
 			name:         "Topic missing",
 			settings:     `{"kafkaRestProxy": "http://localhost"}`,
@@ -154,3 +164,79 @@ func TestKafkaNotifier(t *testing.T) {
 		})
 	}
 }
+
+func TestKafkaNotifier_CompressionAndChunking(t *testing.T) {
+	tmpl := templateForTests(t)
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	webhookSender := mockNotificationService()
+	imageStore := &countingImageStore{ImageStore: newFakeImageStore(2)}
+	fc := channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Name: "kafka_testing",
+			Type: "kafka",
+			Settings: json.RawMessage(`{
+				"kafkaRestProxy": "http://localhost",
+				"kafkaTopic": "sometopic",
+				"compression": "gzip",
+				"max_message_bytes": 1000
+			}`),
+		},
+		ImageStore:          imageStore,
+		NotificationService: webhookSender,
+		Template:            tmpl,
+		Logger:              &channels.FakeLogger{},
+	}
+	kn, err := newKafkaNotifier(fc)
+	require.NoError(t, err)
+
+	var alerts []*types.Alert
+	for i := 0; i < 8; i++ {
+		alerts = append(alerts, &types.Alert{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1", "instance": model.LabelValue(fmt.Sprintf("instance-%d", i))},
+				Annotations: model.LabelSet{"summary": model.LabelValue(strings.Repeat("x", 100))},
+			},
+		})
+	}
+	alerts[0].Annotations["__alertImageToken__"] = "test-image-1"
+	alerts[7].Annotations["__alertImageToken__"] = "test-image-2"
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+	ok, err := kn.Notify(ctx, alerts...)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Equal(t, "gzip", webhookSender.Webhook.HTTPHeader["Content-Encoding"])
+	r, err := gzip.NewReader(strings.NewReader(webhookSender.Webhook.Body))
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	var body kafkaBody
+	require.NoError(t, json.Unmarshal(b, &body))
+	require.Greater(t, len(body.Records), 1)
+
+	// The images are only fetched once, and each is in the record of its alert.
+	require.Equal(t, 2, imageStore.calls)
+	require.Equal(t, []kafkaContext{{Type: "image", Source: "https://www.example.com/test-image-1.jpg"}}, body.Records[0].Value.Contexts)
+	require.Equal(t, []kafkaContext{{Type: "image", Source: "https://www.example.com/test-image-2.jpg"}}, body.Records[len(body.Records)-1].Value.Contexts)
+
+	firing := 0
+	for _, record := range body.Records {
+		require.Equal(t, "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733", record.Key)
+		require.Equal(t, record.Key, record.Value.IncidentKey)
+		value, err := json.Marshal(record.Value)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(value), 1000)
+
+		var n int
+		_, err = fmt.Sscanf(record.Value.Description, "[FIRING:%d]", &n)
+		require.NoError(t, err)
+		firing += n
+	}
+	require.Equal(t, len(alerts), firing)
+}
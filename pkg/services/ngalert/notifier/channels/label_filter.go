@@ -0,0 +1,143 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+type labelFilterSettings struct {
+	// IncludeLabels, if set, are the only labels in the notifications. They are
+	// regular expressions matching the whole name of labels, such as team or internal_.*.
+	IncludeLabels []string `json:"include_labels,omitempty" yaml:"include_labels,omitempty"`
+	// ExcludeLabels are removed from the notifications, even if they are included.
+	ExcludeLabels []string `json:"exclude_labels,omitempty" yaml:"exclude_labels,omitempty"`
+}
+
+// labelFilter filters labels by name.
+type labelFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+func buildLabelFilter(fc channels.FactoryConfig) (*labelFilter, error) {
+	var settings labelFilterSettings
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if len(settings.IncludeLabels) == 0 && len(settings.ExcludeLabels) == 0 {
+		return nil, nil
+	}
+	include, err := compileLabelPatterns("include_labels", settings.IncludeLabels)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileLabelPatterns("exclude_labels", settings.ExcludeLabels)
+	if err != nil {
+		return nil, err
+	}
+	return &labelFilter{include: include, exclude: exclude}, nil
+}
+
+// compileLabelPatterns compiles the patterns of the setting, anchored so they match
+// the whole name of labels.
+func compileLabelPatterns(setting string, patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in %s: %q: %w", setting, p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// keep returns true if the label is included and not excluded.
+func (f *labelFilter) keep(name model.LabelName) bool {
+	for _, re := range f.exclude {
+		if re.MatchString(string(name)) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(string(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns a copy of the labels without the labels that are not kept.
+func (f *labelFilter) filter(labels model.LabelSet) model.LabelSet {
+	res := make(model.LabelSet, len(labels))
+	for name, value := range labels {
+		if f.keep(name) {
+			res[name] = value
+		}
+	}
+	return res
+}
+
+// labelFilterNotifier removes labels from the alerts of the notifications of a notifier.
+type labelFilterNotifier struct {
+	channels.NotificationChannel
+	filter *labelFilter
+}
+
+// withLabelFilter wraps the factory so that notifiers with the include_labels or
+// exclude_labels settings only have the kept labels in the template data and the
+// payloads. Exclusion wins if a label is both included and excluded.
+func withLabelFilter(factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		filter, err := buildLabelFilter(fc)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: err.Error(),
+				Cfg:    *fc.Config,
+			}
+		}
+		n, err := factory(fc)
+		if err != nil || filter == nil {
+			return n, err
+		}
+		return &labelFilterNotifier{
+			NotificationChannel: n,
+			filter:              filter,
+		}, nil
+	}
+}
+
+// Notify sends the notification with the labels filtered on copies of the alerts,
+// as the alerts are shared with the other notifiers of the contact point. The group
+// labels are filtered too.
+func (ln *labelFilterNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	alerts := make([]*types.Alert, 0, len(as))
+	for _, a := range as {
+		alert := *a
+		alert.Labels = ln.filter.filter(a.Labels)
+		alerts = append(alerts, &alert)
+	}
+	if groupLabels, ok := notify.GroupLabels(ctx); ok {
+		ctx = notify.WithGroupLabels(ctx, ln.filter.filter(groupLabels))
+	}
+	return ln.NotificationChannel.Notify(ctx, alerts...)
+}
+
+// HealthCheck checks the wrapped notifier, as labels do not apply to health checks.
+func (ln *labelFilterNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, ln.NotificationChannel)
+}
+
+func (ln *labelFilterNotifier) Close() {
+	Close(ln.NotificationChannel)
+}
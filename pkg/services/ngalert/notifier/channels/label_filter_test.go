@@ -0,0 +1,114 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelFilter(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	labels := model.LabelSet{
+		"alertname":    "alert1",
+		"env":          "prod",
+		"team":         "platform",
+		"internal_id":  "1234",
+		"internal_key": "secret",
+	}
+
+	cases := []struct {
+		name           string
+		settings       string
+		expLabels      map[string]string
+		expGroupLabels map[string]string
+		expInitError   string
+	}{{
+		name:           "All labels are sent without filters",
+		settings:       `{"url": "http://localhost/test"}`,
+		expLabels:      map[string]string{"alertname": "alert1", "env": "prod", "team": "platform", "internal_id": "1234", "internal_key": "secret"},
+		expGroupLabels: map[string]string{"alertname": "alert1", "team": "platform"},
+	}, {
+		name:           "Only included labels are sent",
+		settings:       `{"url": "http://localhost/test", "include_labels": ["alertname", "env"]}`,
+		expLabels:      map[string]string{"alertname": "alert1", "env": "prod"},
+		expGroupLabels: map[string]string{"alertname": "alert1"},
+	}, {
+		name:           "Excluded labels are not sent",
+		settings:       `{"url": "http://localhost/test", "exclude_labels": ["internal_.*"]}`,
+		expLabels:      map[string]string{"alertname": "alert1", "env": "prod", "team": "platform"},
+		expGroupLabels: map[string]string{"alertname": "alert1", "team": "platform"},
+	}, {
+		name:           "Exclusion wins over inclusion",
+		settings:       `{"url": "http://localhost/test", "include_labels": ["alertname", "team", "internal_.*"], "exclude_labels": ["team", "internal_key"]}`,
+		expLabels:      map[string]string{"alertname": "alert1", "internal_id": "1234"},
+		expGroupLabels: map[string]string{"alertname": "alert1"},
+	}, {
+		name:           "Patterns match the whole label name",
+		settings:       `{"url": "http://localhost/test", "exclude_labels": ["internal", "e"]}`,
+		expLabels:      map[string]string{"alertname": "alert1", "env": "prod", "team": "platform", "internal_id": "1234", "internal_key": "secret"},
+		expGroupLabels: map[string]string{"alertname": "alert1", "team": "platform"},
+	}, {
+		name:         "Error in initialization, invalid pattern",
+		settings:     `{"url": "http://localhost/test", "exclude_labels": ["internal_(.*"]}`,
+		expInitError: "failed to validate receiver \"webhook_testing\" of type \"webhook\": invalid pattern in exclude_labels: \"internal_(.*\": error parsing regexp: missing closing ): `^(?:internal_(.*)$`",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			factory, ok := Factory("webhook")
+			require.True(t, ok)
+			n, err := factory(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			alert := &types.Alert{Alert: model.Alert{Labels: labels.Clone()}}
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": "alert1", "team": "platform"})
+			ok, err = n.Notify(ctx, alert)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			var msg struct {
+				Alerts []struct {
+					Labels map[string]string `json:"labels"`
+				} `json:"alerts"`
+				CommonLabels map[string]string `json:"commonLabels"`
+				GroupLabels  map[string]string `json:"groupLabels"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(webhookSender.Webhook.Body), &msg))
+			require.Len(t, msg.Alerts, 1)
+			require.Equal(t, c.expLabels, msg.Alerts[0].Labels)
+			require.Equal(t, c.expLabels, msg.CommonLabels)
+			require.Equal(t, c.expGroupLabels, msg.GroupLabels)
+
+			// The alert is shared with other notifiers, so it must not be changed.
+			require.Equal(t, labels, alert.Labels)
+		})
+	}
+}
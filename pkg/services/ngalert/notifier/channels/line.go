@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"path"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
 	"github.com/prometheus/alertmanager/template"
@@ -21,10 +20,11 @@ var (
 // alert notifications to LINE.
 type LineNotifier struct {
 	*channels.Base
-	log      channels.Logger
-	ns       channels.WebhookSender
-	tmpl     *template.Template
-	settings *lineSettings
+	log         channels.Logger
+	ns          channels.WebhookSender
+	tmpl        *template.Template
+	settings    *lineSettings
+	maxValueLen int
 }
 
 type lineSettings struct {
@@ -69,13 +69,18 @@ func newLineNotifier(fc channels.FactoryConfig) (*LineNotifier, error) {
 	if err != nil {
 		return nil, err
 	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
 
 	return &LineNotifier{
-		Base:     channels.NewBase(fc.Config),
-		log:      fc.Logger,
-		ns:       fc.NotificationService,
-		tmpl:     fc.Template,
-		settings: settings,
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		ns:          fc.NotificationService,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
 	}, nil
 }
 
@@ -111,10 +116,10 @@ func (ln *LineNotifier) SendResolved() bool {
 }
 
 func (ln *LineNotifier) buildMessage(ctx context.Context, as ...*types.Alert) string {
-	ruleURL := path.Join(ln.tmpl.ExternalURL.String(), "/alerting/list")
+	ruleURL := grafanaURL(ln.tmpl.ExternalURL, "/alerting/list")
 
 	var tmplErr error
-	tmpl, _ := channels.TmplText(ctx, ln.tmpl, as, ln.log, &tmplErr)
+	tmpl, _ := tmplText(ctx, ln.tmpl, as, ln.log, &tmplErr, ln.maxValueLen)
 
 	body := fmt.Sprintf(
 		"%s\n%s\n\n%s",
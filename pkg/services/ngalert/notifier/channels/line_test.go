@@ -44,7 +44,7 @@ func TestLineNotifier(t *testing.T) {
 				"Authorization": "Bearer sometoken",
 				"Content-Type":  "application/x-www-form-urlencoded;charset=UTF-8",
 			},
-			expMsg:      "message=%5BFIRING%3A1%5D++%28val1%29%0Ahttp%3A%2Flocalhost%2Falerting%2Flist%0A%0A%2A%2AFiring%2A%2A%0A%0AValue%3A+%5Bno+value%5D%0ALabels%3A%0A+-+alertname+%3D+alert1%0A+-+lbl1+%3D+val1%0AAnnotations%3A%0A+-+ann1+%3D+annv1%0ASilence%3A+http%3A%2F%2Flocalhost%2Falerting%2Fsilence%2Fnew%3Falertmanager%3Dgrafana%26matcher%3Dalertname%253Dalert1%26matcher%3Dlbl1%253Dval1%0ADashboard%3A+http%3A%2F%2Flocalhost%2Fd%2Fabcd%0APanel%3A+http%3A%2F%2Flocalhost%2Fd%2Fabcd%3FviewPanel%3Defgh%0A",
+			expMsg:      "message=%5BFIRING%3A1%5D++%28val1%29%0Ahttp%3A%2F%2Flocalhost%2Falerting%2Flist%0A%0A%2A%2AFiring%2A%2A%0A%0AValue%3A+%5Bno+value%5D%0ALabels%3A%0A+-+alertname+%3D+alert1%0A+-+lbl1+%3D+val1%0AAnnotations%3A%0A+-+ann1+%3D+annv1%0ASilence%3A+http%3A%2F%2Flocalhost%2Falerting%2Fsilence%2Fnew%3Falertmanager%3Dgrafana%26matcher%3Dalertname%253Dalert1%26matcher%3Dlbl1%253Dval1%0ADashboard%3A+http%3A%2F%2Flocalhost%2Fd%2Fabcd%0APanel%3A+http%3A%2F%2Flocalhost%2Fd%2Fabcd%3FviewPanel%3Defgh%0A",
 			expMsgError: nil,
 		}, {
 			name:     "Multiple alerts",
@@ -66,7 +66,7 @@ func TestLineNotifier(t *testing.T) {
 				"Authorization": "Bearer sometoken",
 				"Content-Type":  "application/x-www-form-urlencoded;charset=UTF-8",
 			},
-			expMsg:      "message=%5BFIRING%3A2%5D++%0Ahttp%3A%2Flocalhost%2Falerting%2Flist%0A%0A%2A%2AFiring%2A%2A%0A%0AValue%3A+%5Bno+value%5D%0ALabels%3A%0A+-+alertname+%3D+alert1%0A+-+lbl1+%3D+val1%0AAnnotations%3A%0A+-+ann1+%3D+annv1%0ASilence%3A+http%3A%2F%2Flocalhost%2Falerting%2Fsilence%2Fnew%3Falertmanager%3Dgrafana%26matcher%3Dalertname%253Dalert1%26matcher%3Dlbl1%253Dval1%0A%0AValue%3A+%5Bno+value%5D%0ALabels%3A%0A+-+alertname+%3D+alert1%0A+-+lbl1+%3D+val2%0AAnnotations%3A%0A+-+ann1+%3D+annv2%0ASilence%3A+http%3A%2F%2Flocalhost%2Falerting%2Fsilence%2Fnew%3Falertmanager%3Dgrafana%26matcher%3Dalertname%253Dalert1%26matcher%3Dlbl1%253Dval2%0A",
+			expMsg:      "message=%5BFIRING%3A2%5D++%0Ahttp%3A%2F%2Flocalhost%2Falerting%2Flist%0A%0A%2A%2AFiring%2A%2A%0A%0AValue%3A+%5Bno+value%5D%0ALabels%3A%0A+-+alertname+%3D+alert1%0A+-+lbl1+%3D+val1%0AAnnotations%3A%0A+-+ann1+%3D+annv1%0ASilence%3A+http%3A%2F%2Flocalhost%2Falerting%2Fsilence%2Fnew%3Falertmanager%3Dgrafana%26matcher%3Dalertname%253Dalert1%26matcher%3Dlbl1%253Dval1%0A%0AValue%3A+%5Bno+value%5D%0ALabels%3A%0A+-+alertname+%3D+alert1%0A+-+lbl1+%3D+val2%0AAnnotations%3A%0A+-+ann1+%3D+annv2%0ASilence%3A+http%3A%2F%2Flocalhost%2Falerting%2Fsilence%2Fnew%3Falertmanager%3Dgrafana%26matcher%3Dalertname%253Dalert1%26matcher%3Dlbl1%253Dval2%0A",
 			expMsgError: nil,
 		}, {
 			name:     "One alert custom title and description",
@@ -83,7 +83,7 @@ func TestLineNotifier(t *testing.T) {
 				"Authorization": "Bearer sometoken",
 				"Content-Type":  "application/x-www-form-urlencoded;charset=UTF-8",
 			},
-			expMsg:      "message=customTitle+1%0Ahttp%3A%2Flocalhost%2Falerting%2Flist%0A%0AcustomDescription",
+			expMsg:      "message=customTitle+1%0Ahttp%3A%2F%2Flocalhost%2Falerting%2Flist%0A%0AcustomDescription",
 			expMsgError: nil,
 		}, {
 			name:         "Token missing",
@@ -138,3 +138,43 @@ func TestLineNotifier(t *testing.T) {
 		})
 	}
 }
+
+func TestLineNotifier_Subpath(t *testing.T) {
+	tmpl := templateForTests(t)
+	externalURL, err := url.Parse("https://example.com/grafana/")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	webhookSender := mockNotificationService()
+	pn, err := newLineNotifier(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Name:     "line_testing",
+			Type:     "line",
+			Settings: json.RawMessage(`{"token": "sometoken"}`),
+		},
+		NotificationService: webhookSender,
+		DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+			return fallback
+		},
+		Template: tmpl,
+		Logger:   &channels.FakeLogger{},
+	})
+	require.NoError(t, err)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	ok, err := pn.Notify(ctx, &types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1"},
+			Annotations: model.LabelSet{"__dashboardUid__": "abcd", "__panelId__": "efgh"},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	values, err := url.ParseQuery(webhookSender.Webhook.Body)
+	require.NoError(t, err)
+	msg := values.Get("message")
+	require.Contains(t, msg, "\nhttps://example.com/grafana/alerting/list\n")
+	require.Contains(t, msg, "Dashboard: https://example.com/grafana/d/abcd\n")
+	require.Contains(t, msg, "Panel: https://example.com/grafana/d/abcd?viewPanel=efgh\n")
+}
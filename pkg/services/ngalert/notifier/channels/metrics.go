@@ -0,0 +1,154 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics are the metrics of notifiers. They are labeled by the type of the notifier.
+type Metrics struct {
+	// FailedAttempts is the number of attempts to send notifications that failed. They
+	// are also labeled by whether the notifier asked to retry the notification.
+	FailedAttempts *prometheus.CounterVec
+	// RateLimited is the number of attempts to send notifications that were rate limited
+	// by the service with HTTP 429. They are also counted in FailedAttempts.
+	RateLimited *prometheus.CounterVec
+	// CircuitOpen is the number of notifications that were not sent because the circuit
+	// breaker of the notifier was open. They are not counted in FailedAttempts.
+	CircuitOpen *prometheus.CounterVec
+	// ImageFetchFailures is the number of images that could not be fetched from the
+	// image store. Images that do not exist are not counted.
+	ImageFetchFailures *prometheus.CounterVec
+	// QueueDepth is the number of notifications waiting in the queues of notifiers.
+	QueueDepth *prometheus.GaugeVec
+	// QueueDropped is the number of notifications dropped because the queue was full.
+	QueueDropped *prometheus.CounterVec
+	// QueueSendFailures is the number of queued notifications that failed to be sent.
+	// They are not retried, as Notify succeeded once they were queued.
+	QueueSendFailures *prometheus.CounterVec
+}
+
+func NewMetrics(r prometheus.Registerer) *Metrics {
+	return &Metrics{
+		FailedAttempts: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grafana",
+			Subsystem: "alerting",
+			Name:      "notification_failed_attempts_total",
+			Help:      "The total number of attempts to send notifications that failed, by whether they will be retried.",
+		}, []string{"integration", "retry"}),
+		RateLimited: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grafana",
+			Subsystem: "alerting",
+			Name:      "notification_rate_limited_total",
+			Help:      "The total number of attempts to send notifications that were rate limited by the service.",
+		}, []string{"integration"}),
+		CircuitOpen: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grafana",
+			Subsystem: "alerting",
+			Name:      "notification_circuit_open_total",
+			Help:      "The total number of notifications that were not sent because the circuit breaker was open.",
+		}, []string{"integration"}),
+		ImageFetchFailures: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grafana",
+			Subsystem: "alerting",
+			Name:      "notification_image_fetch_failures_total",
+			Help:      "The total number of images that could not be fetched for notifications.",
+		}, []string{"integration"}),
+		QueueDepth: promauto.With(r).NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "grafana",
+			Subsystem: "alerting",
+			Name:      "notification_queue_depth",
+			Help:      "The number of notifications waiting to be sent in the queues of notifiers.",
+		}, []string{"integration"}),
+		QueueDropped: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grafana",
+			Subsystem: "alerting",
+			Name:      "notification_queue_dropped_total",
+			Help:      "The total number of notifications dropped because the queue was full.",
+		}, []string{"integration"}),
+		QueueSendFailures: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grafana",
+			Subsystem: "alerting",
+			Name:      "notification_queue_send_failures_total",
+			Help:      "The total number of queued notifications that failed to be sent.",
+		}, []string{"integration"}),
+	}
+}
+
+// WithMetrics wraps the factory so that the notifiers it creates update the metrics.
+func WithMetrics(factory func(channels.FactoryConfig) (channels.NotificationChannel, error), m *Metrics) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	if m == nil {
+		return factory
+	}
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		if fc.ImageStore != nil {
+			fc.ImageStore = &metricsImageStore{
+				ImageStore: fc.ImageStore,
+				failures:   m.ImageFetchFailures.WithLabelValues(fc.Config.Type),
+			}
+		}
+		n, err := factory(fc)
+		if err != nil {
+			return nil, err
+		}
+		return &metricsNotifier{
+			NotificationChannel: n,
+			failedAttempts:      m.FailedAttempts.MustCurryWith(prometheus.Labels{"integration": fc.Config.Type}),
+			rateLimited:         m.RateLimited.WithLabelValues(fc.Config.Type),
+			circuitOpen:         m.CircuitOpen.WithLabelValues(fc.Config.Type),
+		}, nil
+	}
+}
+
+// metricsNotifier counts the attempts to send notifications that failed, were rate
+// limited, or were rejected by the circuit breaker.
+type metricsNotifier struct {
+	channels.NotificationChannel
+	failedAttempts *prometheus.CounterVec
+	rateLimited    prometheus.Counter
+	circuitOpen    prometheus.Counter
+}
+
+func (mn *metricsNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	retry, err := mn.NotificationChannel.Notify(ctx, as...)
+	if err == nil {
+		return retry, err
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		mn.circuitOpen.Inc()
+		return retry, err
+	}
+	mn.failedAttempts.WithLabelValues(strconv.FormatBool(retry)).Inc()
+	if classifyNotifyError(err) == NotifyErrorRateLimited {
+		mn.rateLimited.Inc()
+	}
+	return retry, err
+}
+
+func (mn *metricsNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, mn.NotificationChannel)
+}
+
+func (mn *metricsNotifier) Close() {
+	Close(mn.NotificationChannel)
+}
+
+// metricsImageStore counts the images that could not be fetched.
+type metricsImageStore struct {
+	channels.ImageStore
+	failures prometheus.Counter
+}
+
+func (s *metricsImageStore) GetImage(ctx context.Context, token string) (*channels.Image, error) {
+	img, err := s.ImageStore.GetImage(ctx, token)
+	if err != nil && !errors.Is(err, channels.ErrImageNotFound) && !errors.Is(err, channels.ErrImagesUnavailable) {
+		s.failures.Inc()
+	}
+	return img, err
+}
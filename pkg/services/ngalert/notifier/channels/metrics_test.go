@@ -0,0 +1,211 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+type fakeNotifier struct {
+	images channels.ImageStore
+	retry  bool
+	err    error
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	for _, a := range as {
+		_, _ = getImage(ctx, &channels.FakeLogger{}, n.images, *a)
+	}
+	return n.retry, n.err
+}
+
+func (n *fakeNotifier) SendResolved() bool { return true }
+
+type failingImageStore struct{}
+
+func (failingImageStore) GetImage(_ context.Context, _ string) (*channels.Image, error) {
+	return nil, errors.New("database is locked")
+}
+
+func TestWithMetrics(t *testing.T) {
+	cases := []struct {
+		name                  string
+		notifier              *fakeNotifier
+		imageStore            channels.ImageStore
+		expFailedAttempts     float64
+		expRateLimited        float64
+		expCircuitOpen        float64
+		expImageFetchFailures float64
+	}{{
+		name:              "Retried notification increments the failed attempts",
+		notifier:          &fakeNotifier{retry: true, err: errors.New("service unavailable")},
+		imageStore:        newFakeImageStore(1),
+		expFailedAttempts: 1,
+	}, {
+		name:              "Failed notification that is not retried increments the failed attempts",
+		notifier:          &fakeNotifier{retry: false, err: errors.New("bad request")},
+		imageStore:        newFakeImageStore(1),
+		expFailedAttempts: 1,
+	}, {
+		name:              "Rate limited notification increments the rate limited attempts",
+		notifier:          &fakeNotifier{retry: true, err: NotifyError{Category: NotifyErrorRateLimited, Err: httpStatusError{StatusCode: http.StatusTooManyRequests}}},
+		imageStore:        newFakeImageStore(1),
+		expFailedAttempts: 1,
+		expRateLimited:    1,
+	}, {
+		name:           "Notification rejected by the circuit breaker is not a failed attempt",
+		notifier:       &fakeNotifier{retry: true, err: ErrCircuitOpen},
+		imageStore:     newFakeImageStore(1),
+		expCircuitOpen: 1,
+	}, {
+		name:       "Successful notification",
+		notifier:   &fakeNotifier{retry: true},
+		imageStore: newFakeImageStore(1),
+	}, {
+		name:       "Missing image is not a failure",
+		notifier:   &fakeNotifier{retry: true},
+		imageStore: &fakeImageStore{},
+	}, {
+		name:                  "Image fetch failure increments the image fetch failures",
+		notifier:              &fakeNotifier{retry: true},
+		imageStore:            failingImageStore{},
+		expImageFetchFailures: 1,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := NewMetrics(prometheus.NewRegistry())
+			factory := WithMetrics(func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+				c.notifier.images = fc.ImageStore
+				return c.notifier, nil
+			}, m)
+
+			n, err := factory(channels.FactoryConfig{
+				Config:     &channels.NotificationChannelConfig{Type: "webhook"},
+				ImageStore: c.imageStore,
+			})
+			require.NoError(t, err)
+
+			retry, err := n.Notify(context.Background(), &types.Alert{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1"},
+					Annotations: model.LabelSet{models.ImageTokenAnnotation: "test-image-1"},
+				},
+			})
+			require.Equal(t, c.notifier.retry, retry)
+			require.Equal(t, c.notifier.err, err)
+
+			require.Equal(t, c.expFailedAttempts, testutil.ToFloat64(m.FailedAttempts.WithLabelValues("webhook", strconv.FormatBool(c.notifier.retry))))
+			require.Equal(t, c.expRateLimited, testutil.ToFloat64(m.RateLimited.WithLabelValues("webhook")))
+			require.Equal(t, c.expCircuitOpen, testutil.ToFloat64(m.CircuitOpen.WithLabelValues("webhook")))
+			require.Equal(t, c.expImageFetchFailures, testutil.ToFloat64(m.ImageFetchFailures.WithLabelValues("webhook")))
+		})
+	}
+}
+
+func TestWithMetrics_FailedAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	cases := []struct {
+		name     string
+		typ      string
+		factory  func(channels.FactoryConfig) (channels.NotificationChannel, error)
+		settings string
+	}{{
+		name:     "Alertmanager",
+		typ:      "prometheus-alertmanager",
+		factory:  AlertmanagerFactory,
+		settings: fmt.Sprintf(`{"url": %q}`, server.URL),
+	}, {
+		name:     "webhook",
+		typ:      "webhook",
+		factory:  WebHookFactory,
+		settings: fmt.Sprintf(`{"url": %q}`, server.URL),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := NewMetrics(prometheus.NewRegistry())
+			n, err := WithMetrics(c.factory, m)(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     c.name,
+					Type:     c.typ,
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: newNotificationServiceSender(t),
+				DecryptFunc: func(_ context.Context, _ map[string][]byte, _ string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   templateForTests(t),
+				Logger:     &channels.FakeLogger{},
+			})
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			for i := 0; i < 2; i++ {
+				retry, err := n.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+				require.Error(t, err)
+				require.False(t, retry)
+			}
+
+			require.Equal(t, float64(2), testutil.ToFloat64(m.FailedAttempts.WithLabelValues(c.typ, "false")))
+			require.Equal(t, float64(0), testutil.ToFloat64(m.FailedAttempts.WithLabelValues(c.typ, "true")))
+		})
+	}
+}
+
+func TestWithMetrics_RateLimitedAndCircuitOpen(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+
+	factory, ok := Factory("webhook")
+	require.True(t, ok)
+	m := NewMetrics(prometheus.NewRegistry())
+	n, err := WithMetrics(factory, m)(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Name:     "webhook_testing",
+			Type:     "webhook",
+			Settings: json.RawMessage(fmt.Sprintf(`{"url": %q, "circuit_breaker_failures": 2}`, server.URL)),
+		},
+		NotificationService: newNotificationServiceSender(t),
+		DecryptFunc: func(_ context.Context, _ map[string][]byte, _ string, fallback string) string {
+			return fallback
+		},
+		ImageStore: &channels.UnavailableImageStore{},
+		Template:   templateForTests(t),
+		Logger:     &channels.FakeLogger{},
+	})
+	require.NoError(t, err)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	for i := 0; i < 3; i++ {
+		retry, err := n.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+		require.Error(t, err)
+		require.Equal(t, i == 2, retry)
+	}
+
+	require.Equal(t, float64(2), testutil.ToFloat64(m.FailedAttempts.WithLabelValues("webhook", "false")))
+	require.Equal(t, float64(2), testutil.ToFloat64(m.RateLimited.WithLabelValues("webhook")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.CircuitOpen.WithLabelValues("webhook")))
+}
@@ -0,0 +1,434 @@
+package channels
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+)
+
+const (
+	// mqttKeepAlive is the keep alive interval of connections, so brokers do not close
+	// connections that are idle between notifications.
+	mqttKeepAlive = 60 * time.Second
+	// mqttDisconnectQuiesce is the time in milliseconds to wait for the broker to
+	// receive the DISCONNECT packet when closing connections.
+	mqttDisconnectQuiesce = 250
+)
+
+// mqttSchemes are the schemes of broker URLs, with the default port and whether they use TLS.
+var mqttSchemes = map[string]struct {
+	port string
+	tls  bool
+}{
+	"tcp":   {port: "1883"},
+	"mqtt":  {port: "1883"},
+	"ssl":   {port: "8883", tls: true},
+	"mqtts": {port: "8883", tls: true},
+}
+
+// mqttPublisher publishes messages to an MQTT broker.
+type mqttPublisher interface {
+	Publish(ctx context.Context, topic string, qos byte, retain bool, payload []byte) error
+	// IsClosed returns true if the publisher cannot publish any more messages.
+	IsClosed() bool
+	Close() error
+}
+
+// MQTTNotifier is responsible for publishing
+// alert notifications to a topic of an MQTT broker.
+type MQTTNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	tmpl        *template.Template
+	settings    *mqttSettings
+	maxValueLen int
+	// tlsConfig is the TLS configuration of brokers with the ssl and mqtts schemes.
+	tlsConfig *tls.Config
+	dial      func(ctx context.Context) (mqttPublisher, error)
+
+	// mtx protects publisher, which is created on the first notification and then
+	// reused for all further notifications until it fails or the notifier is closed.
+	// It also serializes notifications, so there is at most one message waiting to
+	// be acknowledged.
+	mtx       sync.Mutex
+	publisher mqttPublisher
+	// closed is true once the notifier is closed. Notifications that are still sent
+	// after it, such as queued notifications, connect for each notification.
+	closed bool
+}
+
+type mqttSettings struct {
+	// BrokerURL is the URL of the broker, such as ssl://mqtt.example.com:8883.
+	BrokerURL string `json:"brokerUrl,omitempty" yaml:"brokerUrl,omitempty"`
+	// ClientID is the client identifier of the connection. The broker assigns one if it
+	// is empty.
+	ClientID string `json:"clientId,omitempty" yaml:"clientId,omitempty"`
+	Topic    string `json:"topic,omitempty" yaml:"topic,omitempty"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	// QoS is the quality of service of messages: 0, 1 or 2.
+	QoS                json.Number `json:"qos,omitempty" yaml:"qos,omitempty"`
+	Retain             bool        `json:"retain,omitempty" yaml:"retain,omitempty"`
+	InsecureSkipVerify bool        `json:"insecureSkipVerify,omitempty" yaml:"insecureSkipVerify,omitempty"`
+	Title              string      `json:"title,omitempty" yaml:"title,omitempty"`
+	Message            string      `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage    string      `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+	// SeverityMapping overrides QoS and Retain for alerts with the severity. The mapping
+	// of the highest severity of the alerts is used, see highestSeverity.
+	SeverityMapping []mqttSeverity `json:"severity_mapping,omitempty" yaml:"severity_mapping,omitempty"`
+	// ClearRetainedOnResolve publishes an empty retained message after resolved
+	// notifications, which clears the retained message of the topic.
+	ClearRetainedOnResolve bool `json:"clear_retained_on_resolve,omitempty" yaml:"clear_retained_on_resolve,omitempty"`
+
+	qos     byte
+	address string
+	useTLS  bool
+}
+
+// mqttSeverity is the QoS and retain flag of notifications with alerts with the severity.
+// QoS and Retain default to the settings of the notifier.
+type mqttSeverity struct {
+	Severity string      `json:"severity,omitempty" yaml:"severity,omitempty"`
+	QoS      json.Number `json:"qos,omitempty" yaml:"qos,omitempty"`
+	Retain   *bool       `json:"retain,omitempty" yaml:"retain,omitempty"`
+
+	qos byte
+}
+
+// mqttMessage is the JSON payload of the published message.
+type mqttMessage struct {
+	*channels.ExtendedData
+
+	GroupKey string `json:"groupKey"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+}
+
+func buildMQTTSettings(fc channels.FactoryConfig) (*mqttSettings, error) {
+	var settings mqttSettings
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	if settings.BrokerURL == "" {
+		return nil, errors.New("could not find brokerUrl property in settings")
+	}
+	errInvalidURL := fmt.Errorf("invalid brokerUrl property in settings: %q, must be a tcp, mqtt, ssl or mqtts URL without credentials", settings.BrokerURL)
+	u, err := url.Parse(settings.BrokerURL)
+	if err != nil {
+		return nil, errInvalidURL
+	}
+	scheme, ok := mqttSchemes[strings.ToLower(u.Scheme)]
+	if !ok || u.Hostname() == "" || u.User != nil || (u.Path != "" && u.Path != "/") {
+		return nil, errInvalidURL
+	}
+	settings.useTLS = scheme.tls
+	settings.address = u.Host
+	if u.Port() == "" {
+		settings.address = net.JoinHostPort(u.Hostname(), scheme.port)
+	}
+
+	if settings.Topic == "" {
+		return nil, errors.New("could not find topic property in settings")
+	}
+	if strings.ContainsAny(settings.Topic, "+#\x00") || len(settings.Topic) > 65535 {
+		return nil, fmt.Errorf("invalid topic property in settings: %q, must not contain wildcards", settings.Topic)
+	}
+	if len(settings.ClientID) > 65535 {
+		return nil, errors.New("invalid clientId property in settings, must be at most 65535 bytes")
+	}
+	if settings.qos, err = parseMQTTQoS(settings.QoS, 0); err != nil {
+		return nil, fmt.Errorf("invalid value for qos: %w", err)
+	}
+	for i, s := range settings.SeverityMapping {
+		if s.Severity == "" {
+			return nil, errors.New("severity is required in severity_mapping")
+		}
+		if settings.SeverityMapping[i].qos, err = parseMQTTQoS(s.QoS, settings.qos); err != nil {
+			return nil, fmt.Errorf("invalid qos in severity_mapping: %w", err)
+		}
+	}
+
+	settings.Password = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "password", settings.Password)
+	if settings.Username == "" && settings.Password != "" {
+		return nil, errors.New("username is required when password is set")
+	}
+	if settings.Title == "" {
+		settings.Title = channels.DefaultMessageTitleEmbed
+	}
+	if settings.Message == "" {
+		settings.Message = channels.DefaultMessageEmbed
+	}
+	return &settings, nil
+}
+
+// parseMQTTQoS parses the QoS, or returns def if it is empty.
+func parseMQTTQoS(s json.Number, def byte) (byte, error) {
+	if s == "" {
+		return def, nil
+	}
+	qos, err := strconv.Atoi(s.String())
+	if err != nil || qos < 0 || qos > 2 {
+		return 0, fmt.Errorf("%q, must be 0, 1 or 2", s)
+	}
+	return byte(qos), nil
+}
+
+func MQTTFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	ch, err := newMQTTNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return ch, nil
+}
+
+// newMQTTNotifier is the constructor function for the MQTT notifier.
+func newMQTTNotifier(fc channels.FactoryConfig) (*MQTTNotifier, error) {
+	settings, err := buildMQTTSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := buildTLSConfig(fc)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil && !settings.useTLS {
+		return nil, errors.New("tls_ca_cert, tls_client_cert and tls_client_key require a brokerUrl with the ssl or mqtts scheme")
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	tlsConfig.InsecureSkipVerify = settings.InsecureSkipVerify
+
+	mn := &MQTTNotifier{
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+		tlsConfig:   tlsConfig,
+	}
+	mn.dial = mn.dialBroker
+	return mn, nil
+}
+
+// Notify publishes the alert notification as JSON to the topic. If clear_retained_on_resolve
+// is set, resolved notifications are not retained and are followed by an empty retained
+// message, so new subscribers do not receive the message of the resolved alerts.
+func (mn *MQTTNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	groupKey, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var tmplErr error
+	tmpl, data := tmplText(ctx, mn.tmpl, as, mn.log, &tmplErr, mn.maxValueLen)
+
+	msg := mqttMessage{
+		ExtendedData: data,
+		GroupKey:     groupKey.String(),
+		Title:        tmpl(mn.settings.Title),
+		Message:      tmpl(messageForAlerts(mn.settings.Message, mn.settings.ResolvedMessage, as)),
+	}
+	if tmplErr != nil {
+		mn.log.Warn("failed to template MQTT message", "error", tmplErr.Error())
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	qos, retain := mn.publishOptions(as)
+	clearRetained := mn.settings.ClearRetainedOnResolve && types.Alerts(as...).Status() == model.AlertResolved
+	if err := mn.publish(ctx, qos, retain && !clearRetained, body); err != nil {
+		mn.log.Error("failed to publish MQTT message", "error", err, "topic", mn.settings.Topic)
+		return false, err
+	}
+	if clearRetained {
+		if err := mn.publish(ctx, qos, true, nil); err != nil {
+			mn.log.Error("failed to clear retained MQTT message", "error", err, "topic", mn.settings.Topic)
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// publishOptions returns the QoS and retain flag for the highest severity of the alerts
+// in the severity mapping, or the qos and retain settings if none of the alerts have a
+// severity in the mapping.
+func (mn *MQTTNotifier) publishOptions(as []*types.Alert) (byte, bool) {
+	mapped := make([]string, 0, len(mn.settings.SeverityMapping))
+	for _, s := range mn.settings.SeverityMapping {
+		mapped = append(mapped, s.Severity)
+	}
+	i := highestSeverity(as, mapped)
+	if i < 0 {
+		return mn.settings.qos, mn.settings.Retain
+	}
+	s := mn.settings.SeverityMapping[i]
+	if s.Retain != nil {
+		return s.qos, *s.Retain
+	}
+	return s.qos, mn.settings.Retain
+}
+
+// publish publishes the message with the reused publisher. If the publisher was
+// created for an earlier notification and fails, for example because the broker
+// closed the connection, the message is published once more with a new publisher.
+func (mn *MQTTNotifier) publish(ctx context.Context, qos byte, retain bool, payload []byte) error {
+	mn.mtx.Lock()
+	defer mn.mtx.Unlock()
+	if mn.closed {
+		defer mn.closePublisher()
+	}
+
+	reused := mn.publisher != nil && !mn.publisher.IsClosed()
+	for {
+		if mn.publisher == nil || mn.publisher.IsClosed() {
+			mn.closePublisher()
+			p, err := mn.dial(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+			}
+			mn.publisher = p
+		}
+		err := mn.publisher.Publish(ctx, mn.settings.Topic, qos, retain, payload)
+		if err == nil {
+			return nil
+		}
+		mn.closePublisher()
+		if !reused || ctx.Err() != nil {
+			return fmt.Errorf("failed to publish to topic %q: %w", mn.settings.Topic, err)
+		}
+		mn.log.Debug("reconnecting to MQTT broker after publishing failed", "error", err)
+		reused = false
+	}
+}
+
+// Close disconnects from the broker.
+func (mn *MQTTNotifier) Close() {
+	mn.mtx.Lock()
+	defer mn.mtx.Unlock()
+	mn.closed = true
+	mn.closePublisher()
+}
+
+// closePublisher closes the publisher, if any, so the next notification reconnects.
+func (mn *MQTTNotifier) closePublisher() {
+	if mn.publisher == nil {
+		return
+	}
+	if err := mn.publisher.Close(); err != nil {
+		mn.log.Debug("failed to close MQTT connection", "error", err)
+	}
+	mn.publisher = nil
+}
+
+// dialBroker connects to the broker. The connection is not bound to the context, which
+// is only used to connect, as it is reused for all further notifications.
+func (mn *MQTTNotifier) dialBroker(ctx context.Context) (mqttPublisher, error) {
+	var tlsConfig *tls.Config
+	if mn.settings.useTLS {
+		tlsConfig = mn.tlsConfig
+	}
+	dialErr := make(chan error, 1)
+	opts := mqtt.NewClientOptions().
+		AddBroker("tcp://" + mn.settings.address).
+		SetClientID(mn.settings.ClientID).
+		SetUsername(mn.settings.Username).
+		SetPassword(mn.settings.Password).
+		// Clean session, as messages are not queued for Grafana.
+		SetCleanSession(true).
+		SetProtocolVersion(4).
+		SetKeepAlive(mqttKeepAlive).
+		SetConnectTimeout(defaultHTTPTimeout).
+		SetWriteTimeout(defaultHTTPTimeout).
+		// Notifications reconnect when the connection is closed, see publish.
+		SetAutoReconnect(false).
+		SetCustomOpenConnectionFn(func(*url.URL, mqtt.ClientOptions) (net.Conn, error) {
+			conn, err := dialWithDestinationPolicy(ctx, &net.Dialer{Timeout: defaultHTTPTimeout}, "tcp", mn.settings.address, tlsConfig)
+			if err != nil {
+				dialErr <- err
+			}
+			return conn, err
+		})
+
+	client := mqtt.NewClient(opts)
+	if err := waitForMQTTToken(ctx, client.Connect()); err != nil {
+		client.Disconnect(0)
+		// The client only keeps the message of dial errors, such as those of the
+		// destination policy.
+		select {
+		case err := <-dialErr:
+			return nil, err
+		default:
+			return nil, err
+		}
+	}
+	return &mqttClient{client: client}, nil
+}
+
+func (mn *MQTTNotifier) SendResolved() bool {
+	return !mn.GetDisableResolveMessage()
+}
+
+// mqttClient is the mqttPublisher of a client connected to the broker.
+type mqttClient struct {
+	client mqtt.Client
+}
+
+// Publish publishes the message and waits for the acknowledgements of its quality of
+// service.
+func (c *mqttClient) Publish(ctx context.Context, topic string, qos byte, retain bool, payload []byte) error {
+	return waitForMQTTToken(ctx, c.client.Publish(topic, qos, retain, payload))
+}
+
+func (c *mqttClient) IsClosed() bool {
+	return !c.client.IsConnectionOpen()
+}
+
+// Close disconnects from the broker and closes the connection.
+func (c *mqttClient) Close() error {
+	c.client.Disconnect(mqttDisconnectQuiesce)
+	return nil
+}
+
+// waitForMQTTToken waits until the operation of the token completes and returns its
+// error, or until the context is done.
+func waitForMQTTToken(ctx context.Context, t mqtt.Token) error {
+	timer := time.NewTimer(defaultHTTPTimeout)
+	defer timer.Stop()
+	select {
+	case <-t.Done():
+		return t.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return errors.New("timed out waiting for the broker")
+	}
+}
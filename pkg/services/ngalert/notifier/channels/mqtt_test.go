@@ -0,0 +1,541 @@
+package channels
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	mqttserver "github.com/mochi-co/mqtt/server"
+	"github.com/mochi-co/mqtt/server/events"
+	"github.com/mochi-co/mqtt/server/listeners"
+	"github.com/mochi-co/mqtt/server/listeners/auth"
+	"github.com/mochi-co/mqtt/server/system"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+type testMQTTConnect struct {
+	clientID string
+	username string
+	password string
+}
+
+type testMQTTPublish struct {
+	topic   string
+	qos     byte
+	retain  bool
+	payload []byte
+}
+
+// testMQTTListener is the listener of the embedded broker for a listener of the test,
+// so brokers can listen on any port and with TLS.
+type testMQTTListener struct {
+	ln net.Listener
+	ac auth.Controller
+}
+
+func (l *testMQTTListener) SetConfig(*listeners.Config) {}
+
+func (l *testMQTTListener) Listen(*system.Info) error { return nil }
+
+func (l *testMQTTListener) Serve(establish listeners.EstablishFunc) {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			_ = establish(l.ID(), conn, l.ac)
+		}()
+	}
+}
+
+func (l *testMQTTListener) ID() string { return "test" }
+
+func (l *testMQTTListener) Close(closeClients listeners.CloseFunc) {
+	closeClients(l.ID())
+	_ = l.ln.Close()
+}
+
+// testMQTTBroker is an embedded MQTT broker that records the connections and published messages.
+type testMQTTBroker struct {
+	ln     net.Listener
+	server *mqttserver.Server
+	// refuseConnections refuses all connections as with a bad user name or password.
+	refuseConnections bool
+
+	mtx         sync.Mutex
+	connects    []testMQTTConnect
+	disconnects int
+	published   []testMQTTPublish
+}
+
+// start serves the broker on the listener until the test ends.
+func (b *testMQTTBroker) start(t *testing.T, ln net.Listener) {
+	b.ln = ln
+	b.server = mqttserver.NewServer(nil)
+	b.server.Events.OnConnect = func(_ events.Client, pk events.Packet) {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		b.connects = append(b.connects, testMQTTConnect{
+			clientID: pk.ClientIdentifier,
+			username: string(pk.Username),
+			password: string(pk.Password),
+		})
+	}
+	b.server.Events.OnDisconnect = func(events.Client, error) {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		b.disconnects++
+	}
+	b.server.Events.OnMessage = func(_ events.Client, pk events.Packet) (events.Packet, error) {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		b.published = append(b.published, testMQTTPublish{
+			topic:   pk.TopicName,
+			qos:     pk.FixedHeader.Qos,
+			retain:  pk.FixedHeader.Retain,
+			payload: append([]byte(nil), pk.Payload...),
+		})
+		return pk, nil
+	}
+
+	var ac auth.Controller = &auth.Allow{}
+	if b.refuseConnections {
+		ac = &auth.Disallow{}
+	}
+	require.NoError(t, b.server.AddListener(&testMQTTListener{ln: ln, ac: ac}, nil))
+	require.NoError(t, b.server.Serve())
+	t.Cleanup(func() {
+		_ = b.server.Close()
+	})
+}
+
+func (b *testMQTTBroker) addr() string {
+	return b.ln.Addr().String()
+}
+
+func (b *testMQTTBroker) getConnects() []testMQTTConnect {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return append([]testMQTTConnect(nil), b.connects...)
+}
+
+func (b *testMQTTBroker) getDisconnects() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.disconnects
+}
+
+func (b *testMQTTBroker) getPublished() []testMQTTPublish {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return append([]testMQTTPublish(nil), b.published...)
+}
+
+// closeConnections closes the connections of all clients.
+func (b *testMQTTBroker) closeConnections() {
+	for _, cl := range b.server.Clients.GetAll() {
+		cl.Stop(errors.New("closed by test"))
+	}
+}
+
+func newMQTTNotifierForTests(t *testing.T, settings string) (*MQTTNotifier, error) {
+	tmpl := templateForTests(t)
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	return newMQTTNotifier(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Name:     "mqtt_testing",
+			Type:     "mqtt",
+			Settings: json.RawMessage(settings),
+		},
+		DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+			return fallback
+		},
+		Template: tmpl,
+		Logger:   &channels.FakeLogger{},
+	})
+}
+
+func TestMQTTNotifier(t *testing.T) {
+	alerts := []*types.Alert{{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+			Annotations: model.LabelSet{"ann1": "annv1"},
+		},
+	}}
+	caCert, _ := newTestCertificate(t)
+
+	cases := []struct {
+		name         string
+		settings     string
+		expConnect   testMQTTConnect
+		expTopic     string
+		expQoS       byte
+		expRetain    bool
+		expTitle     string
+		expMessage   string
+		expInitError string
+	}{{
+		name:       "Default config",
+		settings:   `{"brokerUrl": "tcp://BROKER", "topic": "grafana/alerts"}`,
+		expTopic:   "grafana/alerts",
+		expTitle:   "[FIRING:1]  (val1)",
+		expMessage: "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+	}, {
+		name: "Custom config with QoS 1 and retain",
+		settings: `{
+			"brokerUrl": "mqtt://BROKER",
+			"topic": "site/alerts",
+			"clientId": "grafana-1",
+			"username": "grafana",
+			"password": "secret",
+			"qos": 1,
+			"retain": true,
+			"title": "{{ .CommonLabels.alertname }}",
+			"message": "{{ len .Alerts.Firing }} alerts are firing"
+		}`,
+		expConnect: testMQTTConnect{clientID: "grafana-1", username: "grafana", password: "secret"},
+		expTopic:   "site/alerts",
+		expQoS:     1,
+		expRetain:  true,
+		expTitle:   "alert1",
+		expMessage: "1 alerts are firing",
+	}, {
+		name:       "QoS 2",
+		settings:   `{"brokerUrl": "tcp://BROKER", "topic": "grafana/alerts", "qos": "2", "title": "Title", "message": "Message"}`,
+		expTopic:   "grafana/alerts",
+		expQoS:     2,
+		expTitle:   "Title",
+		expMessage: "Message",
+	}, {
+		name:         "Error in initing, missing broker URL",
+		settings:     `{"topic": "grafana/alerts"}`,
+		expInitError: `could not find brokerUrl property in settings`,
+	}, {
+		name:         "Error in initing, invalid broker URL",
+		settings:     `{"brokerUrl": "http://BROKER", "topic": "grafana/alerts"}`,
+		expInitError: `invalid brokerUrl property in settings: "http://BROKER", must be a tcp, mqtt, ssl or mqtts URL without credentials`,
+	}, {
+		name:         "Error in initing, missing topic",
+		settings:     `{"brokerUrl": "tcp://BROKER"}`,
+		expInitError: `could not find topic property in settings`,
+	}, {
+		name:         "Error in initing, topic with wildcards",
+		settings:     `{"brokerUrl": "tcp://BROKER", "topic": "grafana/#"}`,
+		expInitError: `invalid topic property in settings: "grafana/#", must not contain wildcards`,
+	}, {
+		name:         "Error in initing, invalid QoS",
+		settings:     `{"brokerUrl": "tcp://BROKER", "topic": "grafana/alerts", "qos": 3}`,
+		expInitError: `invalid value for qos: "3", must be 0, 1 or 2`,
+	}, {
+		name:         "Error in initing, severity mapping without severity",
+		settings:     `{"brokerUrl": "tcp://BROKER", "topic": "grafana/alerts", "severity_mapping": [{"qos": 1}]}`,
+		expInitError: `severity is required in severity_mapping`,
+	}, {
+		name:         "Error in initing, severity mapping with invalid QoS",
+		settings:     `{"brokerUrl": "tcp://BROKER", "topic": "grafana/alerts", "severity_mapping": [{"severity": "critical", "qos": -1}]}`,
+		expInitError: `invalid qos in severity_mapping: "-1", must be 0, 1 or 2`,
+	}, {
+		name:         "Error in initing, password without username",
+		settings:     `{"brokerUrl": "tcp://BROKER", "topic": "grafana/alerts", "password": "secret"}`,
+		expInitError: `username is required when password is set`,
+	}, {
+		name:         "Error in initing, TLS certificates without TLS",
+		settings:     fmt.Sprintf(`{"brokerUrl": "tcp://BROKER", "topic": "grafana/alerts", "tls_format": "base64", "tls_ca_cert": %q}`, base64.StdEncoding.EncodeToString(caCert)),
+		expInitError: `tls_ca_cert, tls_client_cert and tls_client_key require a brokerUrl with the ssl or mqtts scheme`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			broker := &testMQTTBroker{}
+			broker.start(t, ln)
+
+			// BROKER is the address of the broker, which is only known once it is started.
+			mn, err := newMQTTNotifierForTests(t, strings.ReplaceAll(c.settings, "BROKER", broker.addr()))
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.Equal(t, strings.ReplaceAll(c.expInitError, "BROKER", broker.addr()), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			t.Cleanup(mn.Close)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := mn.Notify(ctx, alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			// Messages with QoS 0 are not acknowledged, so they can be published after Notify returns.
+			require.Eventually(t, func() bool {
+				return len(broker.getPublished()) == 1
+			}, time.Second, 10*time.Millisecond)
+			require.Equal(t, []testMQTTConnect{c.expConnect}, broker.getConnects())
+			published := broker.getPublished()[0]
+			require.Equal(t, c.expTopic, published.topic)
+			require.Equal(t, c.expQoS, published.qos)
+			require.Equal(t, c.expRetain, published.retain)
+
+			var msg map[string]interface{}
+			require.NoError(t, json.Unmarshal(published.payload, &msg))
+			require.Equal(t, "alertname", msg["groupKey"])
+			require.Equal(t, c.expTitle, msg["title"])
+			require.Equal(t, c.expMessage, msg["message"])
+			require.Equal(t, "firing", msg["status"])
+		})
+	}
+}
+
+func TestMQTTNotifier_SeverityMapping(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	broker := &testMQTTBroker{}
+	broker.start(t, ln)
+
+	mn, err := newMQTTNotifierForTests(t, fmt.Sprintf(`{
+		"brokerUrl": "tcp://%s",
+		"topic": "grafana/alerts",
+		"qos": 1,
+		"severity_mapping": [
+			{"severity": "critical", "qos": 2, "retain": true},
+			{"severity": "warning"}
+		],
+		"clear_retained_on_resolve": true
+	}`, broker.addr()))
+	require.NoError(t, err)
+	t.Cleanup(mn.Close)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	critical := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "severity": "critical"}}}
+	warning := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "severity": "warning"}}}
+
+	// Critical alerts are retained, so late subscribers receive them.
+	_, err = mn.Notify(ctx, warning, critical)
+	require.NoError(t, err)
+	_, err = mn.Notify(ctx, warning)
+	require.NoError(t, err)
+
+	// Resolved alerts clear the retained message with an empty retained message.
+	resolved := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "alert1", "severity": "critical"},
+		StartsAt: time.Now().Add(-time.Hour),
+		EndsAt:   time.Now().Add(-time.Minute),
+	}}
+	_, err = mn.Notify(ctx, resolved)
+	require.NoError(t, err)
+
+	published := broker.getPublished()
+	require.Len(t, published, 4)
+	require.Equal(t, byte(2), published[0].qos)
+	require.True(t, published[0].retain)
+	require.Equal(t, byte(1), published[1].qos)
+	require.False(t, published[1].retain)
+	require.Equal(t, byte(2), published[2].qos)
+	require.False(t, published[2].retain)
+	require.Contains(t, string(published[2].payload), `"status":"resolved"`)
+	require.Equal(t, byte(2), published[3].qos)
+	require.True(t, published[3].retain)
+	require.Empty(t, published[3].payload)
+}
+
+func TestMQTTNotifier_SeverityMappingOrder(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	broker := &testMQTTBroker{}
+	broker.start(t, ln)
+
+	// The mapping is not ordered by severity, the critical mapping must still be used
+	// for a group with critical and info alerts.
+	mn, err := newMQTTNotifierForTests(t, fmt.Sprintf(`{
+		"brokerUrl": "tcp://%s",
+		"topic": "grafana/alerts",
+		"qos": 0,
+		"severity_mapping": [
+			{"severity": "info", "qos": 1},
+			{"severity": "critical", "qos": 2, "retain": true}
+		]
+	}`, broker.addr()))
+	require.NoError(t, err)
+	t.Cleanup(mn.Close)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	info := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "severity": "info"}}}
+	critical := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2", "severity": "critical"}}}
+
+	_, err = mn.Notify(ctx, info, critical)
+	require.NoError(t, err)
+	_, err = mn.Notify(ctx, info)
+	require.NoError(t, err)
+
+	published := broker.getPublished()
+	require.Len(t, published, 2)
+	require.Equal(t, byte(2), published[0].qos)
+	require.True(t, published[0].retain)
+	require.Equal(t, byte(1), published[1].qos)
+	require.False(t, published[1].retain)
+}
+
+func TestMQTTNotifier_ResolvedMessage(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	broker := &testMQTTBroker{}
+	broker.start(t, ln)
+
+	mn, err := newMQTTNotifierForTests(t, fmt.Sprintf(`{
+		"brokerUrl": "tcp://%s",
+		"topic": "grafana/alerts",
+		"qos": 1,
+		"message": "{{ len .Alerts.Firing }} alerts are firing",
+		"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+	}`, broker.addr()))
+	require.NoError(t, err)
+	t.Cleanup(mn.Close)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	for _, endsAt := range []time.Time{{}, time.Now().Add(-time.Minute)} {
+		_, err = mn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}, EndsAt: endsAt}})
+		require.NoError(t, err)
+	}
+
+	published := broker.getPublished()
+	require.Len(t, published, 2)
+	var firing, resolved map[string]interface{}
+	require.NoError(t, json.Unmarshal(published[0].payload, &firing))
+	require.Equal(t, "1 alerts are firing", firing["message"])
+	require.NoError(t, json.Unmarshal(published[1].payload, &resolved))
+	require.Equal(t, "1 alerts are resolved", resolved["message"])
+}
+
+func TestMQTTNotifier_ReusesConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	broker := &testMQTTBroker{}
+	broker.start(t, ln)
+
+	mn, err := newMQTTNotifierForTests(t, fmt.Sprintf(`{"brokerUrl": "tcp://%s", "topic": "grafana/alerts", "qos": 1}`, broker.addr()))
+	require.NoError(t, err)
+	t.Cleanup(mn.Close)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	for i := 0; i < 3; i++ {
+		_, err := mn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+		require.NoError(t, err)
+	}
+	require.Len(t, broker.getConnects(), 1)
+	require.Len(t, broker.getPublished(), 3)
+}
+
+func TestMQTTNotifier_Close(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	broker := &testMQTTBroker{}
+	broker.start(t, ln)
+
+	mn, err := newMQTTNotifierForTests(t, fmt.Sprintf(`{"brokerUrl": "tcp://%s", "topic": "grafana/alerts", "qos": 1}`, broker.addr()))
+	require.NoError(t, err)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	_, err = mn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+	require.NoError(t, err)
+	require.Len(t, broker.getConnects(), 1)
+	require.Zero(t, broker.getDisconnects())
+
+	// Closing the notifier disconnects from the broker.
+	Close(mn)
+	require.Eventually(t, func() bool {
+		return broker.getDisconnects() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Notifications sent after it connect and disconnect for each notification.
+	_, err = mn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+	require.NoError(t, err)
+	require.Len(t, broker.getConnects(), 2)
+	require.Len(t, broker.getPublished(), 2)
+	require.Eventually(t, func() bool {
+		return broker.getDisconnects() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestMQTTNotifier_Reconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	broker := &testMQTTBroker{}
+	broker.start(t, ln)
+
+	mn, err := newMQTTNotifierForTests(t, fmt.Sprintf(`{"brokerUrl": "tcp://%s", "topic": "grafana/alerts", "qos": 1}`, broker.addr()))
+	require.NoError(t, err)
+	t.Cleanup(mn.Close)
+
+	// The broker closes the connection after each message, so each notification
+	// reconnects, either before publishing or after publishing fails.
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	for i := 0; i < 2; i++ {
+		_, err := mn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+		require.NoError(t, err)
+		broker.closeConnections()
+	}
+	require.Len(t, broker.getConnects(), 2)
+	require.Len(t, broker.getPublished(), 2)
+}
+
+func TestMQTTNotifier_ConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	broker := &testMQTTBroker{refuseConnections: true}
+	broker.start(t, ln)
+
+	mn, err := newMQTTNotifierForTests(t, fmt.Sprintf(`{"brokerUrl": "tcp://%s", "topic": "grafana/alerts", "username": "grafana", "password": "wrong"}`, broker.addr()))
+	require.NoError(t, err)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	ok, err := mn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+	require.EqualError(t, err, "failed to connect to MQTT broker: bad user name or password")
+	require.False(t, ok)
+	require.Empty(t, broker.getPublished())
+}
+
+func TestMQTTNotifier_TLS(t *testing.T) {
+	// The certificate of the test server is valid for 127.0.0.1.
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: server.TLS.Certificates,
+		MinVersion:   tls.VersionTLS12,
+	})
+	require.NoError(t, err)
+	broker := &testMQTTBroker{}
+	broker.start(t, ln)
+
+	caCert := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	mn, err := newMQTTNotifierForTests(t, fmt.Sprintf(`{"brokerUrl": "ssl://%s", "topic": "grafana/alerts", "qos": 1, "tls_ca_cert": %q}`, broker.addr(), caCert))
+	require.NoError(t, err)
+	t.Cleanup(mn.Close)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	ok, err := mn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, broker.getPublished(), 1)
+	require.Equal(t, "grafana/alerts", broker.getPublished()[0].topic)
+}
@@ -0,0 +1,116 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+
+	"github.com/grafana/grafana/pkg/services/notifications"
+)
+
+// NotifyErrorCategory is the category of the cause of a failed notification.
+type NotifyErrorCategory string
+
+const (
+	// NotifyErrorAuth is for failures to authenticate or authorize with the service.
+	NotifyErrorAuth NotifyErrorCategory = "auth"
+	// NotifyErrorRateLimited is for notifications that were rate limited by the service.
+	NotifyErrorRateLimited NotifyErrorCategory = "rate_limited"
+	// NotifyErrorNetwork is for failures to connect to the service, or timeouts.
+	NotifyErrorNetwork NotifyErrorCategory = "network"
+	// NotifyErrorBadRequest is for notifications that were rejected by the service.
+	NotifyErrorBadRequest NotifyErrorCategory = "bad_request"
+	// NotifyErrorServer is for errors of the service.
+	NotifyErrorServer NotifyErrorCategory = "server_error"
+)
+
+// NotifyError is the error of a failed notification with the category of its cause, so
+// the cause can be shown with a hint to fix it.
+type NotifyError struct {
+	Category NotifyErrorCategory
+	Err      error
+}
+
+func (e NotifyError) Error() string { return e.Err.Error() }
+
+func (e NotifyError) Unwrap() error { return e.Err }
+
+// classifyNotifyError returns the category of the error from the status code of the
+// response or the network error, or an empty category if it cannot be classified.
+func classifyNotifyError(err error) NotifyErrorCategory {
+	var (
+		statusErr  httpStatusError
+		webhookErr notifications.WebhookResponseError
+		netErr     net.Error
+		statusCode int
+	)
+	switch {
+	case errors.As(err, &statusErr):
+		statusCode = statusErr.StatusCode
+	case errors.As(err, &webhookErr):
+		statusCode = webhookErr.StatusCode
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return NotifyErrorNetwork
+	default:
+		return ""
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return NotifyErrorAuth
+	case statusCode == http.StatusTooManyRequests:
+		return NotifyErrorRateLimited
+	case statusCode >= 500:
+		return NotifyErrorServer
+	case statusCode >= 400:
+		return NotifyErrorBadRequest
+	default:
+		return ""
+	}
+}
+
+// notifyErrorNotifier classifies the errors of the notifications of a notifier.
+type notifyErrorNotifier struct {
+	channels.NotificationChannel
+}
+
+// withNotifyErrors wraps the factory so that the errors of notifications are returned
+// as a NotifyError if they can be classified.
+func withNotifyErrors(factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		n, err := factory(fc)
+		if err != nil {
+			return nil, err
+		}
+		return &notifyErrorNotifier{NotificationChannel: n}, nil
+	}
+}
+
+// Notify sends the notification and classifies its error.
+func (nn *notifyErrorNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	ok, err := nn.NotificationChannel.Notify(ctx, as...)
+	if err == nil {
+		return ok, nil
+	}
+	var notifyErr NotifyError
+	if errors.As(err, &notifyErr) {
+		return ok, err
+	}
+	if category := classifyNotifyError(err); category != "" {
+		return ok, NotifyError{Category: category, Err: err}
+	}
+	return ok, err
+}
+
+// HealthCheck checks the wrapped notifier, as its errors are not notification errors.
+func (nn *notifyErrorNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, nn.NotificationChannel)
+}
+
+func (nn *notifyErrorNotifier) Close() {
+	Close(nn.NotificationChannel)
+}
@@ -0,0 +1,138 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/notifications"
+)
+
+func TestClassifyNotifyError(t *testing.T) {
+	cases := []struct {
+		name        string
+		err         error
+		expCategory NotifyErrorCategory
+	}{{
+		name:        "Unauthorized webhook",
+		err:         fmt.Errorf("failed to send: %w", notifications.WebhookResponseError{StatusCode: 401, Status: "401 Unauthorized"}),
+		expCategory: NotifyErrorAuth,
+	}, {
+		name:        "Forbidden HTTP request",
+		err:         httpStatusError{StatusCode: http.StatusForbidden},
+		expCategory: NotifyErrorAuth,
+	}, {
+		name:        "Too many requests",
+		err:         notifications.WebhookResponseError{StatusCode: 429, Status: "429 Too Many Requests"},
+		expCategory: NotifyErrorRateLimited,
+	}, {
+		name:        "Bad request",
+		err:         httpStatusError{StatusCode: http.StatusBadRequest},
+		expCategory: NotifyErrorBadRequest,
+	}, {
+		name:        "Not found",
+		err:         notifications.WebhookResponseError{StatusCode: 404, Status: "404 Not Found"},
+		expCategory: NotifyErrorBadRequest,
+	}, {
+		name:        "Service unavailable",
+		err:         httpStatusError{StatusCode: http.StatusServiceUnavailable},
+		expCategory: NotifyErrorServer,
+	}, {
+		name:        "Connection refused",
+		err:         &url.Error{Op: "Post", URL: "http://localhost", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}},
+		expCategory: NotifyErrorNetwork,
+	}, {
+		name:        "Deadline exceeded",
+		err:         fmt.Errorf("failed to send: %w", context.DeadlineExceeded),
+		expCategory: NotifyErrorNetwork,
+	}, {
+		name: "Other errors are not classified",
+		err:  errors.New("failed to template message"),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expCategory, classifyNotifyError(c.err))
+		})
+	}
+}
+
+func TestSendHTTPRequest_StatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	_, err = sendHTTPRequest(context.Background(), u, httpCfg{}, &channels.FakeLogger{})
+	require.EqualError(t, err, "failed to send HTTP request - status code 401")
+	require.Equal(t, NotifyErrorAuth, classifyNotifyError(err))
+}
+
+func TestNotifyErrors(t *testing.T) {
+	cases := []struct {
+		name        string
+		err         error
+		expCategory NotifyErrorCategory
+	}{{
+		name:        "Errors with a category are a NotifyError",
+		err:         notifications.WebhookResponseError{StatusCode: 401, Status: "401 Unauthorized"},
+		expCategory: NotifyErrorAuth,
+	}, {
+		name: "Other errors are unchanged",
+		err:  errors.New("webhook failed validation"),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			webhookSender.ShouldError = c.err
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(`{"url": "http://localhost/test"}`),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: templateForTests(t),
+				Logger:   &channels.FakeLogger{},
+			}
+
+			factory, ok := Factory("webhook")
+			require.True(t, ok)
+			n, err := factory(fc)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err = n.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+			require.False(t, ok)
+			require.ErrorIs(t, err, c.err)
+			require.EqualError(t, err, c.err.Error())
+
+			var notifyErr NotifyError
+			if c.expCategory == "" {
+				require.False(t, errors.As(err, &notifyErr))
+				return
+			}
+			require.True(t, errors.As(err, &notifyErr))
+			require.Equal(t, c.expCategory, notifyErr.Category)
+		})
+	}
+}
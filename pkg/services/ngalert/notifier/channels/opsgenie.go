@@ -0,0 +1,477 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	OpsgenieSendTags    = "tags"
+	OpsgenieSendDetails = "details"
+	OpsgenieSendBoth    = "both"
+	// https://docs.opsgenie.com/docs/alert-api - 130 characters meaning runes.
+	opsGenieMaxMessageLenRunes = 130
+	// opsgenieDefaultCloseNote is the note added to alerts when they are closed.
+	opsgenieDefaultCloseNote = "Resolved by Grafana"
+	// opsgenieDefaultCloseSource is the user and source of closing alerts in the
+	// alert timeline.
+	opsgenieDefaultCloseSource = "Grafana"
+)
+
+var (
+	OpsgenieAlertURL = "https://api.opsgenie.com/v2/alerts"
+	ValidPriorities  = map[string]bool{"P1": true, "P2": true, "P3": true, "P4": true, "P5": true}
+
+	// opsgenieRegionAlertURLs are the alert API endpoints of the Opsgenie regions.
+	// https://docs.opsgenie.com/docs/european-service-region
+	opsgenieRegionAlertURLs = map[string]string{
+		"us": OpsgenieAlertURL,
+		"eu": "https://api.eu.opsgenie.com/v2/alerts",
+	}
+)
+
+// OpsgenieNotifier is responsible for sending alert notifications to Opsgenie.
+type OpsgenieNotifier struct {
+	*channels.Base
+	tmpl        *template.Template
+	log         channels.Logger
+	ns          channels.WebhookSender
+	images      channels.ImageStore
+	settings    *opsgenieSettings
+	maxValueLen int
+}
+
+type opsgenieSettings struct {
+	APIKey           string
+	APIUrl           string
+	Message          string
+	Description      string
+	AutoClose        bool
+	OverridePriority bool
+	SendTagsAs       string
+	// CloseNote is the templated note added to the alert when it is closed.
+	CloseNote string
+	// CloseUser and CloseSource are the templated user and source that closed the
+	// alert in the alert timeline.
+	CloseUser   string
+	CloseSource string
+}
+
+func buildOpsgenieSettings(fc channels.FactoryConfig) (*opsgenieSettings, error) {
+	type rawSettings struct {
+		APIKey           string `json:"apiKey,omitempty" yaml:"apiKey,omitempty"`
+		APIUrl           string `json:"apiUrl,omitempty" yaml:"apiUrl,omitempty"`
+		Region           string `json:"region,omitempty" yaml:"region,omitempty"`
+		Message          string `json:"message,omitempty" yaml:"message,omitempty"`
+		Description      string `json:"description,omitempty" yaml:"description,omitempty"`
+		AutoClose        *bool  `json:"autoClose,omitempty" yaml:"autoClose,omitempty"`
+		OverridePriority *bool  `json:"overridePriority,omitempty" yaml:"overridePriority,omitempty"`
+		SendTagsAs       string `json:"sendTagsAs,omitempty" yaml:"sendTagsAs,omitempty"`
+		CloseNote        string `json:"close_note,omitempty" yaml:"close_note,omitempty"`
+		CloseUser        string `json:"close_user,omitempty" yaml:"close_user,omitempty"`
+		CloseSource      string `json:"close_source,omitempty" yaml:"close_source,omitempty"`
+	}
+
+	raw := rawSettings{}
+	err := json.Unmarshal(fc.Config.Settings, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	raw.APIKey = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "apiKey", raw.APIKey)
+	if raw.APIKey == "" {
+		return nil, errors.New("could not find api key property in settings")
+	}
+	// An explicit API URL takes precedence over the region.
+	regionURL, ok := opsgenieRegionAlertURLs[strings.ToLower(raw.Region)]
+	if raw.Region != "" && !ok {
+		return nil, fmt.Errorf("invalid value for region: %q, must be one of us, eu", raw.Region)
+	}
+	if raw.APIUrl == "" {
+		raw.APIUrl = OpsgenieAlertURL
+		if regionURL != "" {
+			raw.APIUrl = regionURL
+		}
+	}
+
+	if strings.TrimSpace(raw.Message) == "" {
+		raw.Message = channels.DefaultMessageTitleEmbed
+	}
+
+	switch raw.SendTagsAs {
+	case OpsgenieSendTags, OpsgenieSendDetails, OpsgenieSendBoth:
+	case "":
+		raw.SendTagsAs = OpsgenieSendTags
+	default:
+		return nil, fmt.Errorf("invalid value for sendTagsAs: %q", raw.SendTagsAs)
+	}
+
+	if strings.TrimSpace(raw.CloseNote) == "" {
+		raw.CloseNote = opsgenieDefaultCloseNote
+	}
+	if strings.TrimSpace(raw.CloseUser) == "" {
+		raw.CloseUser = opsgenieDefaultCloseSource
+	}
+	if strings.TrimSpace(raw.CloseSource) == "" {
+		raw.CloseSource = opsgenieDefaultCloseSource
+	}
+
+	if raw.AutoClose == nil {
+		autoClose := true
+		raw.AutoClose = &autoClose
+	}
+	if raw.OverridePriority == nil {
+		overridePriority := true
+		raw.OverridePriority = &overridePriority
+	}
+
+	return &opsgenieSettings{
+		APIKey:           raw.APIKey,
+		APIUrl:           raw.APIUrl,
+		Message:          raw.Message,
+		Description:      raw.Description,
+		AutoClose:        *raw.AutoClose,
+		OverridePriority: *raw.OverridePriority,
+		SendTagsAs:       raw.SendTagsAs,
+		CloseNote:        raw.CloseNote,
+		CloseUser:        raw.CloseUser,
+		CloseSource:      raw.CloseSource,
+	}, nil
+}
+
+func OpsgenieFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	notifier, err := NewOpsgenieNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return notifier, nil
+}
+
+// NewOpsgenieNotifier is the constructor for the Opsgenie notifier
+func NewOpsgenieNotifier(fc channels.FactoryConfig) (*OpsgenieNotifier, error) {
+	settings, err := buildOpsgenieSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
+	return &OpsgenieNotifier{
+		Base:        channels.NewBase(fc.Config),
+		tmpl:        fc.Template,
+		log:         fc.Logger,
+		ns:          fc.NotificationService,
+		images:      images,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+	}, nil
+}
+
+// Notify sends an alert notification to Opsgenie
+func (on *OpsgenieNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	on.log.Debug("executing Opsgenie notification", "notification", on.Name)
+
+	alerts := types.Alerts(as...)
+	if alerts.Status() == model.AlertResolved && !on.SendResolved() {
+		on.log.Debug("not sending a trigger to Opsgenie", "status", alerts.Status(), "auto resolve", on.SendResolved())
+		return true, nil
+	}
+
+	body, url, err := on.buildOpsgenieMessage(ctx, alerts, as)
+	if err != nil {
+		return false, fmt.Errorf("build Opsgenie message: %w", err)
+	}
+
+	if url == "" {
+		// Resolved alert with no auto close.
+		// Hence skip sending anything.
+		return true, nil
+	}
+
+	cmd := &channels.SendWebhookSettings{
+		URL:        url,
+		Body:       string(body),
+		HTTPMethod: http.MethodPost,
+		HTTPHeader: map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": fmt.Sprintf("GenieKey %s", on.settings.APIKey),
+		},
+	}
+
+	// The status code of the response is only known to the validation function.
+	var statusCode int
+	closing := alerts.Status() == model.AlertResolved
+	if closing {
+		cmd.Validation = func(_ []byte, code int) error {
+			statusCode = code
+			return nil
+		}
+	}
+
+	if err := on.ns.SendWebhook(ctx, cmd); err != nil {
+		// The alert to close might not have been created yet, in which case
+		// there is nothing to close.
+		if closing && statusCode == http.StatusNotFound {
+			on.log.Debug("Opsgenie alert to close was not found", "url", url)
+			return true, nil
+		}
+		return false, fmt.Errorf("send notification to Opsgenie: %w", err)
+	}
+
+	// The alert is created once the message is sent, so an image that fails to
+	// upload does not fail the notification.
+	if !closing {
+		if err := on.uploadAttachment(ctx, url, as); err != nil {
+			on.log.Warn("failed to upload image to Opsgenie", "error", err)
+		}
+	}
+
+	return true, nil
+}
+
+// uploadAttachment uploads the first stored image of the alerts as an attachment of
+// the alert created at apiURL. Nothing is uploaded if none of the alerts have an image
+// file, such as when images are unavailable.
+func (on *OpsgenieNotifier) uploadAttachment(ctx context.Context, apiURL string, as []*types.Alert) error {
+	key, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	var cmd *channels.SendWebhookSettings
+	err = withStoredImages(ctx, on.log, on.images, func(_ int, image channels.Image) error {
+		if image.Path == "" {
+			return nil
+		}
+		f, err := openImage(image.Path)
+		if err != nil {
+			if errors.Is(err, channels.ErrImageNotFound) {
+				return nil
+			}
+			return err
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				on.log.Warn("failed to close image", "error", err)
+			}
+		}()
+
+		var b bytes.Buffer
+		w := multipart.NewWriter(&b)
+		if boundary := GetBoundary(); boundary != "" {
+			if err := w.SetBoundary(boundary); err != nil {
+				return err
+			}
+		}
+		if err := writeMultipartFile(w, "file", filepath.Base(image.Path), f); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("failed to close multipart writer: %w", err)
+		}
+
+		cmd = &channels.SendWebhookSettings{
+			URL:        joinUrlPath(apiURL, key.Hash()+"/attachments", on.log) + "?identifierType=alias",
+			Body:       b.String(),
+			HTTPMethod: http.MethodPost,
+			HTTPHeader: map[string]string{
+				"Content-Type":  w.FormDataContentType(),
+				"Authorization": fmt.Sprintf("GenieKey %s", on.settings.APIKey),
+			},
+		}
+		return channels.ErrImagesDone
+	}, as...)
+	if err != nil {
+		return err
+	}
+	if cmd == nil {
+		return nil
+	}
+
+	on.log.Debug("uploading image to Opsgenie", "alias", key.Hash())
+	return on.ns.SendWebhook(ctx, cmd)
+}
+
+func (on *OpsgenieNotifier) buildOpsgenieMessage(ctx context.Context, alerts model.Alerts, as []*types.Alert) (payload []byte, apiURL string, err error) {
+	key, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if alerts.Status() == model.AlertResolved {
+		// For resolved notification, we only need the user, source and note.
+		// Don't need to run other templates.
+		if !on.settings.AutoClose { // TODO This should be handled by DisableResolveMessage?
+			return nil, "", nil
+		}
+		var tmplErr error
+		tmpl, _ := tmplText(ctx, on.tmpl, as, on.log, &tmplErr, on.maxValueLen)
+		msg := opsGenieCloseMessage{
+			User:   tmpl(on.settings.CloseUser),
+			Source: tmpl(on.settings.CloseSource),
+			Note:   tmpl(on.settings.CloseNote),
+		}
+		if tmplErr != nil {
+			on.log.Warn("failed to template Opsgenie close message", "error", tmplErr.Error())
+		}
+		// Opsgenie attributes the close to the API key if the user is empty.
+		if msg.User == "" {
+			msg.User = opsgenieDefaultCloseSource
+		}
+		if msg.Source == "" {
+			msg.Source = opsgenieDefaultCloseSource
+		}
+		data, err := json.Marshal(msg)
+		apiURL = joinUrlPath(on.settings.APIUrl, key.Hash()+"/close", on.log) + "?identifierType=alias"
+		return data, apiURL, err
+	}
+
+	ruleURL := grafanaURL(on.tmpl.ExternalURL, "/alerting/list")
+
+	var tmplErr error
+	tmpl, data := tmplText(ctx, on.tmpl, as, on.log, &tmplErr, on.maxValueLen)
+
+	message, truncated := channels.TruncateInRunes(tmpl(on.settings.Message), opsGenieMaxMessageLenRunes)
+	if truncated {
+		on.log.Warn("Truncated message", "alert", key, "max_runes", opsGenieMaxMessageLenRunes)
+	}
+
+	description := tmpl(on.settings.Description)
+	if strings.TrimSpace(description) == "" {
+		description = fmt.Sprintf(
+			"%s\n%s\n\n%s",
+			tmpl(channels.DefaultMessageTitleEmbed),
+			ruleURL,
+			tmpl(channels.DefaultMessageEmbed),
+		)
+	}
+
+	var priority string
+
+	// In the new alerting system we've moved away from the grafana-tags. Instead, annotations on the rule itself should be used.
+	lbls := make(map[string]string, len(data.CommonLabels))
+	for k, v := range data.CommonLabels {
+		lbls[k] = tmpl(v)
+		if k == "og_priority" && on.settings.OverridePriority {
+			if ValidPriorities[v] {
+				priority = v
+			}
+		}
+	}
+
+	// Check for templating errors
+	if tmplErr != nil {
+		on.log.Warn("failed to template Opsgenie message", "error", tmplErr.Error())
+		tmplErr = nil
+	}
+
+	details := make(map[string]interface{})
+	details["url"] = ruleURL
+	if on.sendDetails() {
+		for k, v := range lbls {
+			details[k] = v
+		}
+		var images []string
+		_ = withStoredImages(ctx, on.log, on.images,
+			func(_ int, image channels.Image) error {
+				if len(image.URL) == 0 {
+					return nil
+				}
+				images = append(images, image.URL)
+				return nil
+			},
+			as...)
+
+		if len(images) != 0 {
+			details["image_urls"] = images
+		}
+	}
+
+	tags := make([]string, 0, len(lbls))
+	if on.sendTags() {
+		for k, v := range lbls {
+			tags = append(tags, fmt.Sprintf("%s:%s", k, v))
+		}
+	}
+	sort.Strings(tags)
+
+	result := opsGenieCreateMessage{
+		Alias:       key.Hash(),
+		Description: description,
+		Tags:        tags,
+		Source:      "Grafana",
+		Message:     message,
+		Details:     details,
+		Priority:    priority,
+	}
+
+	apiURL = tmpl(on.settings.APIUrl)
+	if tmplErr != nil {
+		on.log.Warn("failed to template Opsgenie URL", "error", tmplErr.Error(), "fallback", on.settings.APIUrl)
+		apiURL = on.settings.APIUrl
+	}
+
+	b, err := json.Marshal(result)
+	return b, apiURL, err
+}
+
+func (on *OpsgenieNotifier) SendResolved() bool {
+	return !on.GetDisableResolveMessage()
+}
+
+func (on *OpsgenieNotifier) sendDetails() bool {
+	return on.settings.SendTagsAs == OpsgenieSendDetails || on.settings.SendTagsAs == OpsgenieSendBoth
+}
+
+func (on *OpsgenieNotifier) sendTags() bool {
+	return on.settings.SendTagsAs == OpsgenieSendTags || on.settings.SendTagsAs == OpsgenieSendBoth
+}
+
+type opsGenieCreateMessage struct {
+	Alias       string                           `json:"alias"`
+	Message     string                           `json:"message"`
+	Description string                           `json:"description,omitempty"`
+	Details     map[string]interface{}           `json:"details"`
+	Source      string                           `json:"source"`
+	Responders  []opsGenieCreateMessageResponder `json:"responders,omitempty"`
+	Tags        []string                         `json:"tags"`
+	Note        string                           `json:"note,omitempty"`
+	Priority    string                           `json:"priority,omitempty"`
+	Entity      string                           `json:"entity,omitempty"`
+	Actions     []string                         `json:"actions,omitempty"`
+}
+
+type opsGenieCreateMessageResponder struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+	Type     string `json:"type"` // team, user, escalation, schedule etc.
+}
+
+type opsGenieCloseMessage struct {
+	User   string `json:"user,omitempty"`
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
@@ -0,0 +1,624 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpsgenieNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expMsg       string
+		expInitError string
+		expMsgError  error
+	}{
+		{
+			name:     "Default config with one alert",
+			settings: `{"apiKey": "abcdefgh0123456789"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: `{
+				"alias": "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				"description": "[FIRING:1]  (val1)\nhttp://localhost/alerting/list\n\n**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+				"details": {
+					"url": "http://localhost/alerting/list"
+				},
+				"message": "[FIRING:1]  (val1)",
+				"source": "Grafana",
+				"tags": ["alertname:alert1", "lbl1:val1"]
+			}`,
+		},
+		{
+			name:     "Default config with one alert, custom message and description",
+			settings: `{"apiKey": "abcdefgh0123456789", "message": "test message", "description": "test description"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: `{
+				"alias": "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				"description": "test description",
+				"details": {
+					"url": "http://localhost/alerting/list"
+				},
+				"message": "test message",
+				"source": "Grafana",
+				"tags": ["alertname:alert1", "lbl1:val1"]
+			}`,
+		},
+		{
+			name: "Default config with one alert, message length > 130",
+			settings: `{
+				"apiKey": "abcdefgh0123456789",
+				"message": "IyJnsW78xQoiBJ7L7NqASv31JCFf0At3r9KUykqBVxSiC6qkDhvDLDW9VImiFcq0Iw2XwFy5fX4FcbTmlkaZzUzjVwx9VUuokhzqQlJVhWDYFqhj3a5wX0LjyvNQjsqT9WaWJAWOJanwOAWon"
+				}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: `{
+				"alias": "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				"description": "[FIRING:1]  (val1)\nhttp://localhost/alerting/list\n\n**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+				"details": {
+					"url": "http://localhost/alerting/list"
+				},
+				"message": "IyJnsW78xQoiBJ7L7NqASv31JCFf0At3r9KUykqBVxSiC6qkDhvDLDW9VImiFcq0Iw2XwFy5fX4FcbTmlkaZzUzjVwx9VUuokhzqQlJVhWDYFqhj3a5wX0LjyvNQjsqT9…",
+				"source": "Grafana",
+				"tags": ["alertname:alert1", "lbl1:val1"]
+			}`,
+		},
+		{
+			name:     "Default config with one alert, templated message and description",
+			settings: `{"apiKey": "abcdefgh0123456789", "message": "Firing: {{ len .Alerts.Firing }}", "description": "{{ len .Alerts.Firing }} firing, {{ len .Alerts.Resolved }} resolved."}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: `{
+				"alias": "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				"description": "1 firing, 0 resolved.",
+				"details": {
+					"url": "http://localhost/alerting/list"
+				},
+				"message": "Firing: 1",
+				"source": "Grafana",
+				"tags": ["alertname:alert1", "lbl1:val1"]
+			}`,
+		},
+		{
+			name: "Default config with one alert and send tags as tags, empty description and message",
+			settings: `{
+				"apiKey": "abcdefgh0123456789",
+				"sendTagsAs": "tags",
+				"message": " ",
+				"description": " "
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				},
+			},
+			expMsg: `{
+				"alias": "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				"description": "[FIRING:1]  (val1)\nhttp://localhost/alerting/list\n\n**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+				"details": {
+					"url": "http://localhost/alerting/list"
+				},
+				"message": "[FIRING:1]  (val1)",
+				"source": "Grafana",
+				"tags": ["alertname:alert1", "lbl1:val1"]
+			}`,
+		},
+		{
+			name: "Default config with one alert and send tags as details",
+			settings: `{
+				"apiKey": "abcdefgh0123456789",
+				"sendTagsAs": "details"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				},
+			},
+			expMsg: `{
+				"alias": "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				"description": "[FIRING:1]  (val1)\nhttp://localhost/alerting/list\n\n**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+				"details": {
+					"alertname": "alert1",
+					"lbl1": "val1",
+					"url": "http://localhost/alerting/list"
+				},
+				"message": "[FIRING:1]  (val1)",
+				"source": "Grafana",
+				"tags": []
+			}`,
+		},
+		{
+			name: "Custom config with multiple alerts and send tags as both details and tag",
+			settings: `{
+				"apiKey": "abcdefgh0123456789",
+				"sendTagsAs": "both"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				},
+			},
+			expMsg: `{
+				"alias": "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				"description": "[FIRING:2]  \nhttp://localhost/alerting/list\n\n**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val2\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval2\n",
+				"details": {
+					"alertname": "alert1",
+					"url": "http://localhost/alerting/list"
+				},
+				"message": "[FIRING:2]  ",
+				"source": "Grafana",
+				"tags": ["alertname:alert1"]
+			}`,
+			expMsgError: nil,
+		},
+		{
+			name:     "Resolved is not sent when auto close is false",
+			settings: `{"apiKey": "abcdefgh0123456789", "autoClose": false}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+						EndsAt:      time.Now().Add(-1 * time.Minute),
+					},
+				},
+			},
+		},
+		{
+			name:         "Error when incorrect settings",
+			settings:     `{}`,
+			expInitError: `could not find api key property in settings`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settingsJSON := json.RawMessage(c.settings)
+			secureSettings := make(map[string][]byte)
+
+			webhookSender := mockNotificationService()
+			webhookSender.Webhook.Body = "<not-sent>"
+
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:           "opsgenie_testing",
+					Type:           "opsgenie",
+					Settings:       settingsJSON,
+					SecureSettings: secureSettings,
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   tmpl,
+				Logger:     &channels.FakeLogger{},
+			}
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			pn, err := NewOpsgenieNotifier(fc)
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			ok, err := pn.Notify(ctx, c.alerts...)
+			if c.expMsgError != nil {
+				require.False(t, ok)
+				require.Error(t, err)
+				require.Equal(t, c.expMsgError.Error(), err.Error())
+				return
+			}
+			require.True(t, ok)
+			require.NoError(t, err)
+
+			if c.expMsg == "" {
+				// No notification was expected.
+				require.Equal(t, "<not-sent>", webhookSender.Webhook.Body)
+			} else {
+				require.JSONEq(t, c.expMsg, webhookSender.Webhook.Body)
+			}
+		})
+	}
+}
+
+func TestOpsgenieNotifier_Region(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	firing := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "alert1"},
+		},
+	}
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "alert1"},
+			StartsAt: time.Now().Add(-2 * time.Hour),
+			EndsAt:   time.Now().Add(-time.Hour),
+		},
+	}
+
+	cases := []struct {
+		name         string
+		settings     string
+		alert        *types.Alert
+		expURL       string
+		expInitError string
+	}{{
+		name:     "US endpoint is used by default",
+		settings: `{"apiKey": "abcdefgh0123456789"}`,
+		alert:    firing,
+		expURL:   "https://api.opsgenie.com/v2/alerts",
+	}, {
+		name:     "EU endpoint is used for the EU region",
+		settings: `{"apiKey": "abcdefgh0123456789", "region": "eu"}`,
+		alert:    firing,
+		expURL:   "https://api.eu.opsgenie.com/v2/alerts",
+	}, {
+		name:     "EU endpoint is used to close alerts for the EU region",
+		settings: `{"apiKey": "abcdefgh0123456789", "region": "EU"}`,
+		alert:    resolved,
+		expURL:   "https://api.eu.opsgenie.com/v2/alerts/6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733/close?identifierType=alias",
+	}, {
+		name:     "Explicit API URL takes precedence over the region",
+		settings: `{"apiKey": "abcdefgh0123456789", "region": "eu", "apiUrl": "https://opsgenie.example.com/v2/alerts/"}`,
+		alert:    resolved,
+		expURL:   "https://opsgenie.example.com/v2/alerts/6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733/close?identifierType=alias",
+	}, {
+		name:         "Error in initing, invalid region",
+		settings:     `{"apiKey": "abcdefgh0123456789", "region": "ap"}`,
+		expInitError: `invalid value for region: "ap", must be one of us, eu`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "opsgenie_testing",
+					Type:     "opsgenie",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   tmpl,
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := NewOpsgenieNotifier(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, c.alert)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, c.expURL, webhookSender.Webhook.URL)
+		})
+	}
+}
+
+// opsgenieStatusCodeSender responds to webhooks with the status code.
+type opsgenieStatusCodeSender struct {
+	*notificationServiceMock
+	statusCode int
+}
+
+func (ns *opsgenieStatusCodeSender) SendWebhook(ctx context.Context, cmd *channels.SendWebhookSettings) error {
+	_ = ns.notificationServiceMock.SendWebhook(ctx, cmd)
+	if cmd.Validation != nil {
+		if err := cmd.Validation(nil, ns.statusCode); err != nil {
+			return err
+		}
+	}
+	if ns.statusCode/100 != 2 {
+		return fmt.Errorf("webhook response status %d", ns.statusCode)
+	}
+	return nil
+}
+
+func TestOpsgenieNotifier_CloseNotFound(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	firing := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "alert1"},
+		},
+	}
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "alert1"},
+			StartsAt: time.Now().Add(-2 * time.Hour),
+			EndsAt:   time.Now().Add(-time.Hour),
+		},
+	}
+
+	cases := []struct {
+		name       string
+		alert      *types.Alert
+		statusCode int
+		expErr     string
+	}{{
+		name:       "404 on close is successful",
+		alert:      resolved,
+		statusCode: http.StatusNotFound,
+	}, {
+		name:       "other errors on close fail",
+		alert:      resolved,
+		statusCode: http.StatusInternalServerError,
+		expErr:     "send notification to Opsgenie: webhook response status 500",
+	}, {
+		name:       "404 on create fails",
+		alert:      firing,
+		statusCode: http.StatusNotFound,
+		expErr:     "send notification to Opsgenie: webhook response status 404",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := &opsgenieStatusCodeSender{
+				notificationServiceMock: mockNotificationService(),
+				statusCode:              c.statusCode,
+			}
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "opsgenie_testing",
+					Type:     "opsgenie",
+					Settings: json.RawMessage(`{"apiKey": "abcdefgh0123456789"}`),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   tmpl,
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := NewOpsgenieNotifier(fc)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, c.alert)
+			if c.expErr != "" {
+				require.False(t, ok)
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ok)
+		})
+	}
+}
+
+func TestOpsgenieNotifier_CloseNote(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "alert1"},
+			StartsAt: time.Now().Add(-2 * time.Hour),
+			EndsAt:   time.Now().Add(-time.Hour),
+		},
+	}
+
+	cases := []struct {
+		name     string
+		settings string
+		expMsg   string
+	}{{
+		name:     "Default close note",
+		settings: `{"apiKey": "abcdefgh0123456789"}`,
+		expMsg:   `{"user": "Grafana", "source": "Grafana", "note": "Resolved by Grafana"}`,
+	}, {
+		name:     "Templated close note",
+		settings: `{"apiKey": "abcdefgh0123456789", "close_note": "{{ .CommonLabels.alertname }} is {{ .Status }}"}`,
+		expMsg:   `{"user": "Grafana", "source": "Grafana", "note": "alert1 is resolved"}`,
+	}, {
+		name: "Templated close user and source",
+		settings: `{
+			"apiKey": "abcdefgh0123456789",
+			"close_user": "{{ .CommonLabels.alertname }}-bot",
+			"close_source": "Grafana {{ .Receiver }}"
+		}`,
+		expMsg: `{"user": "alert1-bot", "source": "Grafana opsgenie_receiver", "note": "Resolved by Grafana"}`,
+	}, {
+		name:     "Close user and source default to Grafana if templated as empty",
+		settings: `{"apiKey": "abcdefgh0123456789", "close_user": "{{ .CommonLabels.team }}", "close_source": "{{ .CommonLabels.team }}"}`,
+		expMsg:   `{"user": "Grafana", "source": "Grafana", "note": "Resolved by Grafana"}`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "opsgenie_testing",
+					Type:     "opsgenie",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   tmpl,
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := NewOpsgenieNotifier(fc)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithReceiverName(ctx, "opsgenie_receiver")
+			ok, err := pn.Notify(ctx, resolved)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, "https://api.opsgenie.com/v2/alerts/6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733/close?identifierType=alias", webhookSender.Webhook.URL)
+			require.JSONEq(t, c.expMsg, webhookSender.Webhook.Body)
+		})
+	}
+}
+
+func TestOpsgenieNotifier_Attachment(t *testing.T) {
+	origGetBoundary := GetBoundary
+	GetBoundary = func() string { return "abcd" }
+	t.Cleanup(func() { GetBoundary = origGetBoundary })
+
+	imagePath := filepath.Join(t.TempDir(), "test-image-1.png")
+	require.NoError(t, os.WriteFile(imagePath, []byte("image"), 0600))
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1"},
+			Annotations: model.LabelSet{"__alertImageToken__": "test-image-1"},
+		},
+	}
+
+	cases := []struct {
+		name       string
+		images     channels.ImageStore
+		expURLs    []string
+		expBody    string
+		expHeaders map[string]string
+	}{{
+		name:   "The image is uploaded after the alert is created",
+		images: &fakeImageStore{Images: []*channels.Image{{Token: "test-image-1", Path: imagePath}}},
+		expURLs: []string{
+			"https://api.opsgenie.com/v2/alerts",
+			"https://api.opsgenie.com/v2/alerts/6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733/attachments?identifierType=alias",
+		},
+		expBody: "--abcd\r\nContent-Disposition: form-data; name=\"file\"; filename=\"test-image-1.png\"\r\nContent-Type: application/octet-stream\r\n\r\nimage\r\n--abcd--\r\n",
+		expHeaders: map[string]string{
+			"Content-Type":  "multipart/form-data; boundary=abcd",
+			"Authorization": "GenieKey abcdefgh0123456789",
+		},
+	}, {
+		name:    "Images without a file are not uploaded",
+		images:  newFakeImageStore(1),
+		expURLs: []string{"https://api.opsgenie.com/v2/alerts"},
+	}, {
+		name:    "Nothing is uploaded if images are unavailable",
+		images:  &channels.UnavailableImageStore{},
+		expURLs: []string{"https://api.opsgenie.com/v2/alerts"},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := &webhookRecordingSender{}
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "opsgenie_testing",
+					Type:     "opsgenie",
+					Settings: json.RawMessage(`{"apiKey": "abcdefgh0123456789"}`),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: c.images,
+				Template:   templateForTests(t),
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := NewOpsgenieNotifier(fc)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, alert)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			urls := make([]string, 0, len(webhookSender.requests))
+			for _, r := range webhookSender.requests {
+				urls = append(urls, r.URL)
+			}
+			require.Equal(t, c.expURLs, urls)
+			if c.expBody != "" {
+				upload := webhookSender.requests[1]
+				require.Equal(t, http.MethodPost, upload.HTTPMethod)
+				require.Equal(t, c.expBody, upload.Body)
+				require.Equal(t, c.expHeaders, upload.HTTPHeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package channels
+
+import (
+	"sync"
+)
+
+// Options are the options of notifiers that are set by the operator of Grafana, as
+// opposed to the settings of contact points, which are set by the users of Grafana.
+type Options struct {
+	// FileDirectory is the directory that file notifiers write to. File notifiers
+	// cannot be created if it is empty.
+	FileDirectory string
+	// WebhookTemplatesDirectory is the directory of the template files of webhook
+	// notifiers. Template files cannot be used if it is empty.
+	WebhookTemplatesDirectory string
+	// EnvironmentVariables are the environment variables that can be referenced in the
+	// settings of contact points. Names ending with "*" allow all environment variables
+	// with the prefix.
+	EnvironmentVariables []string
+}
+
+var (
+	optionsMtx sync.RWMutex
+	options    Options
+)
+
+// SetOptions sets the options of notifiers. It must be called before notifiers are
+// created, as notifiers that are already created keep the previous options.
+func SetOptions(o Options) {
+	optionsMtx.Lock()
+	defer optionsMtx.Unlock()
+	options = o
+}
+
+// getOptions returns the options of notifiers.
+func getOptions() Options {
+	optionsMtx.RLock()
+	defer optionsMtx.RUnlock()
+	return options
+}
@@ -0,0 +1,536 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// https://developer.pagerduty.com/docs/ZG9jOjExMDI5NTgx-send-an-alert-event - 1024 characters or runes.
+	pagerDutyMaxV2SummaryLenRunes = 1024
+)
+
+const (
+	pagerDutyEventTrigger     = "trigger"
+	pagerDutyEventAcknowledge = "acknowledge"
+	pagerDutyEventResolve     = "resolve"
+
+	defaultSeverity = "critical"
+	defaultClient   = "Grafana"
+
+	// Alert events trigger and resolve incidents, change events record informational
+	// changes such as deploys, see https://developer.pagerduty.com/docs/ZG9jOjExMDI5NTgy-send-a-change-event.
+	pagerDutyEventTypeAlert  = "alert"
+	pagerDutyEventTypeChange = "change"
+
+	// defaultRoutingKeyLabel is the label whose value selects the routing key of alerts
+	// in the routing_keys setting.
+	defaultRoutingKeyLabel = "severity"
+)
+
+var (
+	knownSeverity        = map[string]struct{}{defaultSeverity: {}, "error": {}, "warning": {}, "info": {}}
+	knownEventActions    = map[string]struct{}{pagerDutyEventTrigger: {}, pagerDutyEventAcknowledge: {}, pagerDutyEventResolve: {}}
+	PagerdutyEventAPIURL = "https://events.pagerduty.com/v2/enqueue"
+	// PagerdutyChangeEventAPIURL is the endpoint of change events.
+	PagerdutyChangeEventAPIURL = "https://events.pagerduty.com/v2/change/enqueue"
+	// pagerDutyRoutingKeyRegexp matches the 32 character routing keys of Events API v2
+	// integrations.
+	pagerDutyRoutingKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9]{32}$`)
+)
+
+// PagerdutyNotifier is responsible for sending
+// alert notifications to pagerduty
+type PagerdutyNotifier struct {
+	*channels.Base
+	tmpl        *template.Template
+	log         channels.Logger
+	ns          channels.WebhookSender
+	images      channels.ImageStore
+	settings    *pagerdutySettings
+	maxValueLen int
+}
+
+type pagerdutySettings struct {
+	Key           string `json:"integrationKey,omitempty" yaml:"integrationKey,omitempty"`
+	Severity      string `json:"severity,omitempty" yaml:"severity,omitempty"`
+	customDetails map[string]string
+	Class         string `json:"class,omitempty" yaml:"class,omitempty"`
+	Component     string `json:"component,omitempty" yaml:"component,omitempty"`
+	Group         string `json:"group,omitempty" yaml:"group,omitempty"`
+	Summary       string `json:"summary,omitempty" yaml:"summary,omitempty"`
+	// Source is templated for each alert, such as {{ .CommonLabels.instance }}. It
+	// defaults to the host of the external URL.
+	Source    string `json:"source,omitempty" yaml:"source,omitempty"`
+	Client    string `json:"client,omitempty" yaml:"client,omitempty"`
+	ClientURL string `json:"client_url,omitempty" yaml:"client_url,omitempty"`
+	// EventActionLabel is the name of a label whose value is the event action of
+	// firing alerts, one of trigger, acknowledge or resolve.
+	EventActionLabel string `json:"event_action_label,omitempty" yaml:"event_action_label,omitempty"`
+	// EventType is alert or change. It defaults to alert.
+	EventType string `json:"event_type,omitempty" yaml:"event_type,omitempty"`
+	// EventTypeLabel is the name of a label whose value is the event type of the
+	// alerts, overriding EventType.
+	EventTypeLabel string `json:"event_type_label,omitempty" yaml:"event_type_label,omitempty"`
+	// RoutingKeys are the routing keys of alerts by the value of their RoutingKeyLabel
+	// label, such as to send critical alerts to a different service. Alerts without
+	// a routing key are sent with the integration key.
+	RoutingKeys map[string]string `json:"routing_keys,omitempty" yaml:"routing_keys,omitempty"`
+	// RoutingKeyLabel is the name of the label whose value selects the routing key of
+	// alerts. It defaults to severity.
+	RoutingKeyLabel string `json:"routing_key_label,omitempty" yaml:"routing_key_label,omitempty"`
+}
+
+func buildPagerdutySettings(fc channels.FactoryConfig) (*pagerdutySettings, error) {
+	settings := pagerdutySettings{}
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	settings.Key = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "integrationKey", settings.Key)
+	if settings.Key == "" {
+		return nil, errors.New("could not find integration key property in settings")
+	}
+
+	settings.customDetails = map[string]string{
+		"firing":       `{{ template "__text_alert_list" .Alerts.Firing }}`,
+		"resolved":     `{{ template "__text_alert_list" .Alerts.Resolved }}`,
+		"num_firing":   `{{ .Alerts.Firing | len }}`,
+		"num_resolved": `{{ .Alerts.Resolved | len }}`,
+	}
+
+	if settings.EventActionLabel != "" && !model.LabelName(settings.EventActionLabel).IsValid() {
+		return nil, fmt.Errorf("invalid value for event_action_label: %q", settings.EventActionLabel)
+	}
+
+	switch settings.EventType {
+	case "":
+		settings.EventType = pagerDutyEventTypeAlert
+	case pagerDutyEventTypeAlert, pagerDutyEventTypeChange:
+	default:
+		return nil, fmt.Errorf("invalid value for event_type: %q, must be one of alert, change", settings.EventType)
+	}
+	if settings.EventTypeLabel != "" && !model.LabelName(settings.EventTypeLabel).IsValid() {
+		return nil, fmt.Errorf("invalid value for event_type_label: %q", settings.EventTypeLabel)
+	}
+
+	if settings.RoutingKeyLabel == "" {
+		settings.RoutingKeyLabel = defaultRoutingKeyLabel
+	}
+	if !model.LabelName(settings.RoutingKeyLabel).IsValid() {
+		return nil, fmt.Errorf("invalid value for routing_key_label: %q", settings.RoutingKeyLabel)
+	}
+	// Label values are matched case-insensitively, as severities are often capitalized.
+	routingKeys := make(map[string]string, len(settings.RoutingKeys))
+	for value, key := range settings.RoutingKeys {
+		if value == "" {
+			return nil, errors.New("invalid value for routing_keys: label values must not be empty")
+		}
+		if !pagerDutyRoutingKeyRegexp.MatchString(key) {
+			return nil, fmt.Errorf("invalid value for routing_keys: routing key of %q must be 32 letters or digits", value)
+		}
+		routingKeys[strings.ToLower(value)] = key
+	}
+	settings.RoutingKeys = routingKeys
+
+	if settings.Severity == "" {
+		settings.Severity = defaultSeverity
+	}
+	if settings.Component == "" {
+		settings.Component = "Grafana"
+	}
+	if settings.Summary == "" {
+		settings.Summary = channels.DefaultMessageTitleEmbed
+	}
+	if settings.Client == "" {
+		settings.Client = defaultClient
+	}
+	if settings.ClientURL == "" {
+		settings.ClientURL = "{{ .ExternalURL }}"
+	}
+	return &settings, nil
+}
+
+func PagerdutyFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	pdn, err := newPagerdutyNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return pdn, nil
+}
+
+// NewPagerdutyNotifier is the constructor for the PagerDuty notifier
+func newPagerdutyNotifier(fc channels.FactoryConfig) (*PagerdutyNotifier, error) {
+	settings, err := buildPagerdutySettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PagerdutyNotifier{
+		Base:        channels.NewBase(fc.Config),
+		tmpl:        fc.Template,
+		log:         fc.Logger,
+		ns:          fc.NotificationService,
+		images:      images,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+	}, nil
+}
+
+// Notify sends an alert notification to PagerDuty
+func (pn *PagerdutyNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	if pn.eventType(as) == pagerDutyEventTypeChange {
+		return pn.notifyChange(ctx, as)
+	}
+
+	// Alerts with different routing keys are sent to different services, so they are
+	// sent in an event for each routing key.
+	for _, group := range pn.groupByRoutingKey(as) {
+		if err := pn.notifyAlerts(ctx, group); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// notifyAlerts sends an alert event for the alerts, which have the same routing key.
+func (pn *PagerdutyNotifier) notifyAlerts(ctx context.Context, as []*types.Alert) error {
+	alerts := types.Alerts(as...)
+	if alerts.Status() == model.AlertResolved && !pn.SendResolved() {
+		pn.log.Debug("not sending a trigger to Pagerduty", "status", alerts.Status(), "auto resolve", pn.SendResolved())
+		return nil
+	}
+
+	msg, eventType, err := pn.buildPagerdutyMessage(ctx, alerts, as)
+	if err != nil {
+		return fmt.Errorf("build pagerduty message: %w", err)
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal json: %w", err)
+	}
+
+	pn.log.Info("notifying Pagerduty", "event_type", eventType)
+	cmd := &channels.SendWebhookSettings{
+		URL:        PagerdutyEventAPIURL,
+		Body:       string(body),
+		HTTPMethod: "POST",
+		HTTPHeader: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+	if err := pn.ns.SendWebhook(ctx, cmd); err != nil {
+		return fmt.Errorf("send notification to Pagerduty: %w", err)
+	}
+	return nil
+}
+
+// routingKey returns the routing key of the alert in the routing_keys setting, or
+// the integration key if its label has no routing key.
+func (pn *PagerdutyNotifier) routingKey(a *types.Alert) string {
+	value := strings.ToLower(string(a.Labels[model.LabelName(pn.settings.RoutingKeyLabel)]))
+	if key, ok := pn.settings.RoutingKeys[value]; ok {
+		return key
+	}
+	return pn.settings.Key
+}
+
+// groupByRoutingKey returns the alerts grouped by their routing key, in the order the
+// routing keys are first seen.
+func (pn *PagerdutyNotifier) groupByRoutingKey(as []*types.Alert) [][]*types.Alert {
+	if len(pn.settings.RoutingKeys) == 0 {
+		return [][]*types.Alert{as}
+	}
+	var groups [][]*types.Alert
+	index := make(map[string]int)
+	for _, a := range as {
+		key := pn.routingKey(a)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], a)
+	}
+	return groups
+}
+
+func (pn *PagerdutyNotifier) buildPagerdutyMessage(ctx context.Context, alerts model.Alerts, as []*types.Alert) (*pagerDutyMessage, string, error) {
+	key, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	eventType := pagerDutyEventTrigger
+	if alerts.Status() == model.AlertResolved {
+		eventType = pagerDutyEventResolve
+	}
+
+	var tmplErr error
+	tmpl, data := tmplText(ctx, pn.tmpl, as, pn.log, &tmplErr, pn.maxValueLen)
+
+	if eventType == pagerDutyEventTrigger && pn.settings.EventActionLabel != "" {
+		eventType = pn.eventAction(data)
+	}
+
+	details := make(map[string]string, len(pn.settings.customDetails))
+	for k, v := range pn.settings.customDetails {
+		detail, err := pn.tmpl.ExecuteTextString(v, data)
+		if err != nil {
+			return nil, "", fmt.Errorf("%q: failed to template %q: %w", k, v, err)
+		}
+		details[k] = detail
+	}
+
+	severity := strings.ToLower(tmpl(pn.settings.Severity))
+	if _, ok := knownSeverity[severity]; !ok {
+		pn.log.Warn("Severity is not in the list of known values - using default severity", "actualSeverity", severity, "defaultSeverity", defaultSeverity)
+		severity = defaultSeverity
+	}
+
+	msg := &pagerDutyMessage{
+		Client:      tmpl(pn.settings.Client),
+		ClientURL:   tmpl(pn.settings.ClientURL),
+		RoutingKey:  pn.routingKey(as[0]),
+		EventAction: eventType,
+		DedupKey:    key.Hash(),
+		Links: []pagerDutyLink{{
+			HRef: pn.tmpl.ExternalURL.String(),
+			Text: "External URL",
+		}},
+		Payload: pagerDutyPayload{
+			Source:        pn.source(ctx, as),
+			Component:     tmpl(pn.settings.Component),
+			Summary:       tmpl(pn.settings.Summary),
+			Severity:      severity,
+			CustomDetails: details,
+			// Class and Group are omitted if they are not set or are templated to an empty string.
+			Class: tmpl(pn.settings.Class),
+			Group: tmpl(pn.settings.Group),
+		},
+	}
+
+	_ = withStoredImages(ctx, pn.log, pn.images,
+		func(_ int, image channels.Image) error {
+			if len(image.URL) != 0 {
+				msg.Images = append(msg.Images, pagerDutyImage{Src: image.URL})
+			}
+
+			return nil
+		},
+		as...)
+
+	summary, truncated := channels.TruncateInRunes(msg.Payload.Summary, pagerDutyMaxV2SummaryLenRunes)
+	if truncated {
+		pn.log.Warn("Truncated summary", "key", key, "runes", pagerDutyMaxV2SummaryLenRunes)
+	}
+	msg.Payload.Summary = summary
+
+	if tmplErr != nil {
+		pn.log.Warn("failed to template PagerDuty message", "error", tmplErr.Error())
+	}
+
+	return msg, eventType, nil
+}
+
+// source returns the source of the alerts. The source setting is templated for each
+// alert, and the distinct sources are joined. It returns the host of the external URL
+// if the source is not set or is templated to an empty string for all alerts.
+func (pn *PagerdutyNotifier) source(ctx context.Context, as []*types.Alert) string {
+	var sources []string
+	if pn.settings.Source != "" {
+		seen := make(map[string]struct{}, len(as))
+		for _, a := range as {
+			var tmplErr error
+			tmpl, _ := tmplText(ctx, pn.tmpl, []*types.Alert{a}, pn.log, &tmplErr, pn.maxValueLen)
+			source := strings.TrimSpace(tmpl(pn.settings.Source))
+			if tmplErr != nil {
+				pn.log.Warn("failed to template PagerDuty source", "alert", a.Name(), "error", tmplErr.Error())
+				continue
+			}
+			if _, ok := seen[source]; ok || source == "" {
+				continue
+			}
+			seen[source] = struct{}{}
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) > 0 {
+		return strings.Join(sources, ", ")
+	}
+	if host := pn.tmpl.ExternalURL.Hostname(); host != "" {
+		return host
+	}
+	return defaultClient
+}
+
+// eventAction returns the event action in the event_action_label label common to all
+// alerts. It returns trigger if the alerts do not have the same event action, or it
+// is not a known event action.
+func (pn *PagerdutyNotifier) eventAction(data *channels.ExtendedData) string {
+	action, ok := data.CommonLabels[pn.settings.EventActionLabel]
+	if !ok {
+		return pagerDutyEventTrigger
+	}
+	action = strings.ToLower(action)
+	if _, ok := knownEventActions[action]; !ok {
+		pn.log.Warn("Event action is not in the list of known values - using trigger", "actualEventAction", action)
+		return pagerDutyEventTrigger
+	}
+	return action
+}
+
+// eventType returns the event type in the event_type_label label common to all alerts.
+// It returns the event_type setting if the alerts do not have the same event type, or
+// it is not a known event type.
+func (pn *PagerdutyNotifier) eventType(as []*types.Alert) string {
+	if pn.settings.EventTypeLabel == "" {
+		return pn.settings.EventType
+	}
+	var eventType string
+	for i, a := range as {
+		v := strings.ToLower(string(a.Labels[model.LabelName(pn.settings.EventTypeLabel)]))
+		if i > 0 && v != eventType {
+			return pn.settings.EventType
+		}
+		eventType = v
+	}
+	if eventType != pagerDutyEventTypeAlert && eventType != pagerDutyEventTypeChange {
+		return pn.settings.EventType
+	}
+	return eventType
+}
+
+// notifyChange sends a change event for each firing alert. Resolved alerts are not
+// sent, as changes are not resolved.
+func (pn *PagerdutyNotifier) notifyChange(ctx context.Context, as []*types.Alert) (bool, error) {
+	for _, a := range as {
+		if a.Resolved() {
+			continue
+		}
+		body, err := json.Marshal(pn.buildChangeEvent(ctx, a))
+		if err != nil {
+			return false, fmt.Errorf("marshal json: %w", err)
+		}
+
+		pn.log.Info("notifying Pagerduty", "event_type", pagerDutyEventTypeChange, "alert", a.Name())
+		cmd := &channels.SendWebhookSettings{
+			URL:        PagerdutyChangeEventAPIURL,
+			Body:       string(body),
+			HTTPMethod: "POST",
+			HTTPHeader: map[string]string{
+				"Content-Type": "application/json",
+			},
+		}
+		if err := pn.ns.SendWebhook(ctx, cmd); err != nil {
+			return false, fmt.Errorf("send change event to Pagerduty: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// buildChangeEvent returns the change event of the alert. Its custom details are the
+// labels of the alert.
+func (pn *PagerdutyNotifier) buildChangeEvent(ctx context.Context, a *types.Alert) *pagerDutyChangeEvent {
+	var tmplErr error
+	tmpl, data := tmplText(ctx, pn.tmpl, []*types.Alert{a}, pn.log, &tmplErr, pn.maxValueLen)
+
+	summary, truncated := channels.TruncateInRunes(tmpl(pn.settings.Summary), pagerDutyMaxV2SummaryLenRunes)
+	if truncated {
+		pn.log.Warn("Truncated summary", "alert", a.Name(), "runes", pagerDutyMaxV2SummaryLenRunes)
+	}
+	if tmplErr != nil {
+		pn.log.Warn("failed to template PagerDuty change event", "error", tmplErr.Error())
+	}
+
+	details := make(map[string]string, len(a.Labels))
+	for k, v := range data.Alerts[0].Labels {
+		details[k] = v
+	}
+	return &pagerDutyChangeEvent{
+		RoutingKey: pn.routingKey(a),
+		Payload: pagerDutyChangePayload{
+			Summary:       summary,
+			Timestamp:     a.StartsAt.UTC().Format(time.RFC3339),
+			Source:        pn.source(ctx, []*types.Alert{a}),
+			CustomDetails: details,
+		},
+		Links: []pagerDutyLink{{
+			HRef: pn.tmpl.ExternalURL.String(),
+			Text: "External URL",
+		}},
+	}
+}
+
+func (pn *PagerdutyNotifier) SendResolved() bool {
+	return !pn.GetDisableResolveMessage()
+}
+
+type pagerDutyMessage struct {
+	RoutingKey  string           `json:"routing_key,omitempty"`
+	ServiceKey  string           `json:"service_key,omitempty"`
+	DedupKey    string           `json:"dedup_key,omitempty"`
+	EventAction string           `json:"event_action"`
+	Payload     pagerDutyPayload `json:"payload"`
+	Client      string           `json:"client,omitempty"`
+	ClientURL   string           `json:"client_url,omitempty"`
+	Links       []pagerDutyLink  `json:"links,omitempty"`
+	Images      []pagerDutyImage `json:"images,omitempty"`
+}
+
+type pagerDutyChangeEvent struct {
+	RoutingKey string                 `json:"routing_key"`
+	Payload    pagerDutyChangePayload `json:"payload"`
+	Links      []pagerDutyLink        `json:"links,omitempty"`
+}
+
+type pagerDutyChangePayload struct {
+	Summary       string            `json:"summary"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Source        string            `json:"source,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	HRef string `json:"href"`
+	Text string `json:"text"`
+}
+
+type pagerDutyImage struct {
+	Src string `json:"src"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Class         string            `json:"class,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
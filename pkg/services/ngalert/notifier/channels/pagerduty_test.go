@@ -0,0 +1,712 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagerdutyNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expMsg       *pagerDutyMessage
+		expInitError string
+		expMsgError  error
+	}{
+		{
+			name:     "Default config with one alert",
+			settings: `{"integrationKey": "abcdefgh0123456789"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: &pagerDutyMessage{
+				RoutingKey:  "abcdefgh0123456789",
+				DedupKey:    "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				EventAction: "trigger",
+				Payload: pagerDutyPayload{
+					Summary:   "[FIRING:1]  (val1)",
+					Source:    "localhost",
+					Severity:  defaultSeverity,
+					Component: "Grafana",
+					CustomDetails: map[string]string{
+						"firing":       "\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+						"num_firing":   "1",
+						"num_resolved": "0",
+						"resolved":     "",
+					},
+				},
+				Client:    "Grafana",
+				ClientURL: "http://localhost",
+				Links:     []pagerDutyLink{{HRef: "http://localhost", Text: "External URL"}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name:     "should map unknown severity",
+			settings: `{"integrationKey": "abcdefgh0123456789", "severity": "{{ .CommonLabels.severity }}"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1", "severity": "invalid-severity"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: &pagerDutyMessage{
+				RoutingKey:  "abcdefgh0123456789",
+				DedupKey:    "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				EventAction: "trigger",
+				Payload: pagerDutyPayload{
+					Summary:   "[FIRING:1]  (val1 invalid-severity)",
+					Source:    "localhost",
+					Severity:  defaultSeverity,
+					Component: "Grafana",
+					CustomDetails: map[string]string{
+						"firing":       "\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\n - severity = invalid-severity\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1&matcher=severity%3Dinvalid-severity\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+						"num_firing":   "1",
+						"num_resolved": "0",
+						"resolved":     "",
+					},
+				},
+				Client:    "Grafana",
+				ClientURL: "http://localhost",
+				Links:     []pagerDutyLink{{HRef: "http://localhost", Text: "External URL"}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Should expand templates in fields",
+			settings: `{
+				"integrationKey": "abcdefgh0123456789", 
+				"severity" : "{{ .CommonLabels.severity }}", 
+				"class": "{{ .CommonLabels.class }}",  
+				"component": "{{ .CommonLabels.component }}", 
+				"group" : "{{ .CommonLabels.group }}", 
+				"source": "{{ .CommonLabels.source }}",
+				"client": "client-{{ .CommonLabels.source }}",
+				"client_url": "http://localhost:20200/{{ .CommonLabels.group }}"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1", "severity": "critical", "class": "test-class", "group": "test-group", "component": "test-component", "source": "test-source"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: &pagerDutyMessage{
+				RoutingKey:  "abcdefgh0123456789",
+				DedupKey:    "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				EventAction: "trigger",
+				Payload: pagerDutyPayload{
+					Summary:   "[FIRING:1]  (test-class test-component test-group val1 critical test-source)",
+					Source:    "test-source",
+					Severity:  "critical",
+					Class:     "test-class",
+					Component: "test-component",
+					Group:     "test-group",
+					CustomDetails: map[string]string{
+						"firing":       "\nValue: [no value]\nLabels:\n - alertname = alert1\n - class = test-class\n - component = test-component\n - group = test-group\n - lbl1 = val1\n - severity = critical\n - source = test-source\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=class%3Dtest-class&matcher=component%3Dtest-component&matcher=group%3Dtest-group&matcher=lbl1%3Dval1&matcher=severity%3Dcritical&matcher=source%3Dtest-source\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+						"num_firing":   "1",
+						"num_resolved": "0",
+						"resolved":     "",
+					},
+				},
+				Client:    "client-test-source",
+				ClientURL: "http://localhost:20200/test-group",
+				Links:     []pagerDutyLink{{HRef: "http://localhost", Text: "External URL"}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name:     "Default config with one alert and custom summary",
+			settings: `{"integrationKey": "abcdefgh0123456789", "summary": "Alerts firing: {{ len .Alerts.Firing }}"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: &pagerDutyMessage{
+				RoutingKey:  "abcdefgh0123456789",
+				DedupKey:    "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				EventAction: "trigger",
+				Payload: pagerDutyPayload{
+					Summary:   "Alerts firing: 1",
+					Source:    "localhost",
+					Severity:  defaultSeverity,
+					Component: "Grafana",
+					CustomDetails: map[string]string{
+						"firing":       "\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+						"num_firing":   "1",
+						"num_resolved": "0",
+						"resolved":     "",
+					},
+				},
+				Client:    "Grafana",
+				ClientURL: "http://localhost",
+				Links:     []pagerDutyLink{{HRef: "http://localhost", Text: "External URL"}},
+			},
+			expMsgError: nil,
+		}, {
+			name: "Custom config with multiple alerts",
+			settings: `{
+				"integrationKey": "abcdefgh0123456789",
+				"severity": "warning",
+				"class": "{{ .Status }}",
+				"component": "My Grafana",
+				"group": "my_group"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+						Annotations: model.LabelSet{"ann1": "annv2"},
+					},
+				},
+			},
+			expMsg: &pagerDutyMessage{
+				RoutingKey:  "abcdefgh0123456789",
+				DedupKey:    "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				EventAction: "trigger",
+				Payload: pagerDutyPayload{
+					Summary:   "[FIRING:2]  ",
+					Source:    "localhost",
+					Severity:  "warning",
+					Class:     "firing",
+					Component: "My Grafana",
+					Group:     "my_group",
+					CustomDetails: map[string]string{
+						"firing":       "\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val2\nAnnotations:\n - ann1 = annv2\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval2\n",
+						"num_firing":   "2",
+						"num_resolved": "0",
+						"resolved":     "",
+					},
+				},
+				Client:    "Grafana",
+				ClientURL: "http://localhost",
+				Links:     []pagerDutyLink{{HRef: "http://localhost", Text: "External URL"}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name:     "should truncate long summary",
+			settings: fmt.Sprintf(`{"integrationKey": "abcdefgh0123456789", "summary": "%s"}`, strings.Repeat("1", rand.Intn(100)+1025)),
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: &pagerDutyMessage{
+				RoutingKey:  "abcdefgh0123456789",
+				DedupKey:    "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				EventAction: "trigger",
+				Payload: pagerDutyPayload{
+					Summary:   fmt.Sprintf("%s…", strings.Repeat("1", 1023)),
+					Source:    "localhost",
+					Severity:  defaultSeverity,
+					Component: "Grafana",
+					CustomDetails: map[string]string{
+						"firing":       "\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+						"num_firing":   "1",
+						"num_resolved": "0",
+						"resolved":     "",
+					},
+				},
+				Client:    "Grafana",
+				ClientURL: "http://localhost",
+				Links:     []pagerDutyLink{{HRef: "http://localhost", Text: "External URL"}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Should omit class and group if templated to an empty string",
+			settings: `{
+				"integrationKey": "abcdefgh0123456789",
+				"class": "{{ .CommonLabels.class }}",
+				"group": "{{ .CommonLabels.group }}"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+					},
+				},
+			},
+			expMsg: &pagerDutyMessage{
+				RoutingKey:  "abcdefgh0123456789",
+				DedupKey:    "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				EventAction: "trigger",
+				Payload: pagerDutyPayload{
+					Summary:   "[FIRING:1]  (val1)",
+					Source:    "localhost",
+					Severity:  defaultSeverity,
+					Component: "Grafana",
+					CustomDetails: map[string]string{
+						"firing":       "\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+						"num_firing":   "1",
+						"num_resolved": "0",
+						"resolved":     "",
+					},
+				},
+				Client:    "Grafana",
+				ClientURL: "http://localhost",
+				Links:     []pagerDutyLink{{HRef: "http://localhost", Text: "External URL"}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Should acknowledge alerts with the event action label",
+			settings: `{
+				"integrationKey": "abcdefgh0123456789",
+				"event_action_label": "pagerduty_action"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1", "pagerduty_action": "acknowledge"},
+					},
+				},
+			},
+			expMsg: &pagerDutyMessage{
+				RoutingKey:  "abcdefgh0123456789",
+				DedupKey:    "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				EventAction: "acknowledge",
+				Payload: pagerDutyPayload{
+					Summary:   "[FIRING:1]  (acknowledge)",
+					Source:    "localhost",
+					Severity:  defaultSeverity,
+					Component: "Grafana",
+					CustomDetails: map[string]string{
+						"firing":       "\nValue: [no value]\nLabels:\n - alertname = alert1\n - pagerduty_action = acknowledge\nAnnotations:\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=pagerduty_action%3Dacknowledge\n",
+						"num_firing":   "1",
+						"num_resolved": "0",
+						"resolved":     "",
+					},
+				},
+				Client:    "Grafana",
+				ClientURL: "http://localhost",
+				Links:     []pagerDutyLink{{HRef: "http://localhost", Text: "External URL"}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Should trigger alerts with an unknown event action",
+			settings: `{
+				"integrationKey": "abcdefgh0123456789",
+				"event_action_label": "pagerduty_action"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1", "pagerduty_action": "snooze"},
+					},
+				},
+			},
+			expMsg: &pagerDutyMessage{
+				RoutingKey:  "abcdefgh0123456789",
+				DedupKey:    "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733",
+				EventAction: "trigger",
+				Payload: pagerDutyPayload{
+					Summary:   "[FIRING:1]  (snooze)",
+					Source:    "localhost",
+					Severity:  defaultSeverity,
+					Component: "Grafana",
+					CustomDetails: map[string]string{
+						"firing":       "\nValue: [no value]\nLabels:\n - alertname = alert1\n - pagerduty_action = snooze\nAnnotations:\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=pagerduty_action%3Dsnooze\n",
+						"num_firing":   "1",
+						"num_resolved": "0",
+						"resolved":     "",
+					},
+				},
+				Client:    "Grafana",
+				ClientURL: "http://localhost",
+				Links:     []pagerDutyLink{{HRef: "http://localhost", Text: "External URL"}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name:         "Error in initing, invalid event action label",
+			settings:     `{"integrationKey": "abcdefgh0123456789", "event_action_label": "pagerduty-action"}`,
+			expInitError: `invalid value for event_action_label: "pagerduty-action"`,
+		},
+		{
+			name:         "Error in initing, invalid event type",
+			settings:     `{"integrationKey": "abcdefgh0123456789", "event_type": "deploy"}`,
+			expInitError: `invalid value for event_type: "deploy", must be one of alert, change`,
+		},
+		{
+			name:         "Error in initing, invalid event type label",
+			settings:     `{"integrationKey": "abcdefgh0123456789", "event_type_label": "pagerduty-event"}`,
+			expInitError: `invalid value for event_type_label: "pagerduty-event"`,
+		},
+		{
+			name:         "Error in initing",
+			settings:     `{}`,
+			expInitError: `could not find integration key property in settings`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settingsJSON := json.RawMessage(c.settings)
+			secureSettings := make(map[string][]byte)
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:           "pageduty_testing",
+					Type:           "pagerduty",
+					Settings:       settingsJSON,
+					SecureSettings: secureSettings,
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+			pn, err := newPagerdutyNotifier(fc)
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := pn.Notify(ctx, c.alerts...)
+			if c.expMsgError != nil {
+				require.False(t, ok)
+				require.Error(t, err)
+				require.Equal(t, c.expMsgError.Error(), err.Error())
+				return
+			}
+			require.True(t, ok)
+			require.NoError(t, err)
+
+			expBody, err := json.Marshal(c.expMsg)
+			require.NoError(t, err)
+
+			require.JSONEq(t, string(expBody), webhookSender.Webhook.Body)
+		})
+	}
+}
+
+func TestPagerdutyNotifier_Source(t *testing.T) {
+	tmpl := templateForTests(t)
+	externalURL, err := url.Parse("https://grafana.example.com:3000/grafana")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	alert := func(labels model.LabelSet) *types.Alert {
+		return &types.Alert{Alert: model.Alert{Labels: labels}}
+	}
+
+	cases := []struct {
+		name      string
+		settings  string
+		alerts    []*types.Alert
+		expSource string
+	}{{
+		name:      "Host of the external URL by default",
+		settings:  `{"integrationKey": "abcdefgh0123456789"}`,
+		alerts:    []*types.Alert{alert(model.LabelSet{"alertname": "alert1", "instance": "host1:9100"})},
+		expSource: "grafana.example.com",
+	}, {
+		name:      "Static source",
+		settings:  `{"integrationKey": "abcdefgh0123456789", "source": "db-cluster"}`,
+		alerts:    []*types.Alert{alert(model.LabelSet{"alertname": "alert1", "instance": "host1:9100"})},
+		expSource: "db-cluster",
+	}, {
+		name:     "Source templated for each alert",
+		settings: `{"integrationKey": "abcdefgh0123456789", "source": "{{ .CommonLabels.instance }}"}`,
+		alerts: []*types.Alert{
+			alert(model.LabelSet{"alertname": "alert1", "instance": "host1:9100"}),
+			alert(model.LabelSet{"alertname": "alert1", "instance": "host2:9100"}),
+			alert(model.LabelSet{"alertname": "alert1", "instance": "host1:9100"}),
+			alert(model.LabelSet{"alertname": "alert1"}),
+		},
+		expSource: "host1:9100, host2:9100",
+	}, {
+		name:      "Host of the external URL if the source is empty for all alerts",
+		settings:  `{"integrationKey": "abcdefgh0123456789", "source": "{{ .CommonLabels.instance }}"}`,
+		alerts:    []*types.Alert{alert(model.LabelSet{"alertname": "alert1"})},
+		expSource: "grafana.example.com",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pn, err := newPagerdutyNotifier(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:           "pageduty_testing",
+					Type:           "pagerduty",
+					Settings:       json.RawMessage(c.settings),
+					SecureSettings: map[string][]byte{},
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: mockNotificationService(),
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			})
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			msg, _, err := pn.buildPagerdutyMessage(ctx, types.Alerts(c.alerts...), c.alerts)
+			require.NoError(t, err)
+			require.Equal(t, c.expSource, msg.Payload.Source)
+		})
+	}
+}
+
+func TestPagerdutyNotifier_ChangeEvent(t *testing.T) {
+	tmpl := templateForTests(t)
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	startsAt := time.Date(2022, 12, 1, 10, 30, 0, 0, time.UTC)
+	alert := func(labels model.LabelSet) *types.Alert {
+		return &types.Alert{Alert: model.Alert{Labels: labels, StartsAt: startsAt}}
+	}
+
+	cases := []struct {
+		name     string
+		settings string
+		alerts   []*types.Alert
+		expURLs  []string
+		expMsgs  []string
+	}{{
+		name:     "Change event for each firing alert",
+		settings: `{"integrationKey": "abcdefgh0123456789", "event_type": "change", "summary": "Deployed {{ .CommonLabels.service }}"}`,
+		alerts: []*types.Alert{
+			alert(model.LabelSet{"alertname": "deploy", "service": "api", "version": "1.2.3"}),
+			{Alert: model.Alert{Labels: model.LabelSet{"alertname": "deploy", "service": "web"}, StartsAt: startsAt, EndsAt: startsAt.Add(time.Minute)}},
+		},
+		expURLs: []string{PagerdutyChangeEventAPIURL},
+		expMsgs: []string{`{
+			"routing_key": "abcdefgh0123456789",
+			"payload": {
+				"summary": "Deployed api",
+				"timestamp": "2022-12-01T10:30:00Z",
+				"source": "localhost",
+				"custom_details": {"alertname": "deploy", "service": "api", "version": "1.2.3"}
+			},
+			"links": [{"href": "http://localhost", "text": "External URL"}]
+		}`},
+	}, {
+		name:     "Event type from label",
+		settings: `{"integrationKey": "abcdefgh0123456789", "event_type_label": "pagerduty_event", "summary": "Deployed {{ .CommonLabels.service }}", "source": "{{ .CommonLabels.service }}"}`,
+		alerts: []*types.Alert{
+			alert(model.LabelSet{"alertname": "deploy", "service": "api", "pagerduty_event": "Change"}),
+		},
+		expURLs: []string{PagerdutyChangeEventAPIURL},
+		expMsgs: []string{`{
+			"routing_key": "abcdefgh0123456789",
+			"payload": {
+				"summary": "Deployed api",
+				"timestamp": "2022-12-01T10:30:00Z",
+				"source": "api",
+				"custom_details": {"alertname": "deploy", "service": "api", "pagerduty_event": "Change"}
+			},
+			"links": [{"href": "http://localhost", "text": "External URL"}]
+		}`},
+	}, {
+		name:     "Alert event without the label",
+		settings: `{"integrationKey": "abcdefgh0123456789", "event_type_label": "pagerduty_event"}`,
+		alerts: []*types.Alert{
+			alert(model.LabelSet{"alertname": "deploy", "service": "api", "pagerduty_event": "change"}),
+			alert(model.LabelSet{"alertname": "deploy", "service": "web"}),
+		},
+		expURLs: []string{PagerdutyEventAPIURL},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sender := &webhookRecordingSender{}
+			pn, err := newPagerdutyNotifier(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:           "pageduty_testing",
+					Type:           "pagerduty",
+					Settings:       json.RawMessage(c.settings),
+					SecureSettings: map[string][]byte{},
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: sender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			})
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := pn.Notify(ctx, c.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.Len(t, sender.requests, len(c.expURLs))
+			for i, r := range sender.requests {
+				require.Equal(t, c.expURLs[i], r.URL)
+				require.Equal(t, "POST", r.HTTPMethod)
+				if i < len(c.expMsgs) {
+					require.JSONEq(t, c.expMsgs[i], r.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestPagerdutyNotifier_RoutingKeys(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	const (
+		criticalKey = "c0000000000000000000000000000001"
+		warningKey  = "w0000000000000000000000000000002"
+	)
+	alert := func(name, severity string) *types.Alert {
+		labels := model.LabelSet{"alertname": model.LabelValue(name)}
+		if severity != "" {
+			labels["severity"] = model.LabelValue(severity)
+		}
+		return &types.Alert{Alert: model.Alert{Labels: labels}}
+	}
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expKeys      []string
+		expFiring    []string
+		expInitError string
+	}{{
+		name:      "Alerts are sent with the integration key without routing_keys",
+		settings:  `{"integrationKey": "abcdefgh0123456789"}`,
+		alerts:    []*types.Alert{alert("alert1", "critical"), alert("alert2", "warning")},
+		expKeys:   []string{"abcdefgh0123456789"},
+		expFiring: []string{"2"},
+	}, {
+		name: "Alerts are sent to the service of their severity",
+		settings: fmt.Sprintf(`{
+			"integrationKey": "abcdefgh0123456789",
+			"routing_keys": {"critical": %q, "Warning": %q}
+		}`, criticalKey, warningKey),
+		alerts:    []*types.Alert{alert("alert1", "warning"), alert("alert2", "critical"), alert("alert3", "info"), alert("alert4", "Critical"), alert("alert5", "")},
+		expKeys:   []string{warningKey, criticalKey, "abcdefgh0123456789"},
+		expFiring: []string{"1", "2", "2"},
+	}, {
+		name: "Alerts with the same routing key are sent together",
+		settings: fmt.Sprintf(`{
+			"integrationKey": "abcdefgh0123456789",
+			"routing_keys": {"critical": %q}
+		}`, criticalKey),
+		alerts:    []*types.Alert{alert("alert1", "critical"), alert("alert2", "critical")},
+		expKeys:   []string{criticalKey},
+		expFiring: []string{"2"},
+	}, {
+		name: "Routing key from a custom label",
+		settings: fmt.Sprintf(`{
+			"integrationKey": "abcdefgh0123456789",
+			"routing_key_label": "alertname",
+			"routing_keys": {"alert2": %q}
+		}`, criticalKey),
+		alerts:    []*types.Alert{alert("alert1", "critical"), alert("alert2", "warning")},
+		expKeys:   []string{"abcdefgh0123456789", criticalKey},
+		expFiring: []string{"1", "1"},
+	}, {
+		name:         "Error in initialization, invalid routing key",
+		settings:     `{"integrationKey": "abcdefgh0123456789", "routing_keys": {"critical": "abc"}}`,
+		expInitError: `invalid value for routing_keys: routing key of "critical" must be 32 letters or digits`,
+	}, {
+		name:         "Error in initialization, empty label value",
+		settings:     fmt.Sprintf(`{"integrationKey": "abcdefgh0123456789", "routing_keys": {"": %q}}`, criticalKey),
+		expInitError: "invalid value for routing_keys: label values must not be empty",
+	}, {
+		name:         "Error in initialization, invalid routing_key_label",
+		settings:     `{"integrationKey": "abcdefgh0123456789", "routing_key_label": "sev-level"}`,
+		expInitError: `invalid value for routing_key_label: "sev-level"`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sender := &webhookRecordingSender{}
+			pn, err := newPagerdutyNotifier(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:           "pageduty_testing",
+					Type:           "pagerduty",
+					Settings:       json.RawMessage(c.settings),
+					SecureSettings: map[string][]byte{},
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: sender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			})
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := pn.Notify(ctx, c.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.Len(t, sender.requests, len(c.expKeys))
+			for i, r := range sender.requests {
+				require.Equal(t, PagerdutyEventAPIURL, r.URL)
+				var msg pagerDutyMessage
+				require.NoError(t, json.Unmarshal([]byte(r.Body), &msg))
+				require.Equal(t, c.expKeys[i], msg.RoutingKey)
+				require.Equal(t, c.expFiring[i], msg.Payload.CustomDetails["num_firing"])
+			}
+		})
+	}
+}
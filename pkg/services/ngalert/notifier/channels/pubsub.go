@@ -0,0 +1,212 @@
+package channels
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+)
+
+var (
+	// pubSubProjectIDRegexp matches the ID of a Google Cloud project.
+	// https://cloud.google.com/resource-manager/docs/creating-managing-projects
+	pubSubProjectIDRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+	// pubSubTopicRegexp matches the ID of a Pub/Sub topic.
+	// https://cloud.google.com/pubsub/docs/admin#resource_names
+	pubSubTopicRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9\-_.~+%]{2,254}$`)
+)
+
+// PubSubNotifier is responsible for sending
+// alert notifications to a Google Cloud Pub/Sub topic.
+type PubSubNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	tmpl        *template.Template
+	settings    *pubSubSettings
+	maxValueLen int
+
+	// mtx protects service, which is created on the first notification
+	// and then reused for all further notifications.
+	mtx     sync.Mutex
+	service *pubsub.Service
+}
+
+type pubSubSettings struct {
+	ProjectID       string            `json:"projectId,omitempty" yaml:"projectId,omitempty"`
+	Topic           string            `json:"topic,omitempty" yaml:"topic,omitempty"`
+	Credentials     string            `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+	Title           string            `json:"title,omitempty" yaml:"title,omitempty"`
+	Message         string            `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage string            `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+	Attributes      map[string]string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// pubSubMessage is the JSON published to the topic.
+type pubSubMessage struct {
+	*channels.ExtendedData
+
+	GroupKey string `json:"groupKey"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+}
+
+func buildPubSubSettings(fc channels.FactoryConfig) (*pubSubSettings, error) {
+	var settings pubSubSettings
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	if settings.ProjectID == "" {
+		return nil, errors.New("could not find project ID in settings")
+	}
+	if !pubSubProjectIDRegexp.MatchString(settings.ProjectID) {
+		return nil, fmt.Errorf("invalid project ID %q", settings.ProjectID)
+	}
+	if settings.Topic == "" {
+		return nil, errors.New("could not find topic in settings")
+	}
+	if !pubSubTopicRegexp.MatchString(settings.Topic) || strings.HasPrefix(settings.Topic, "goog") {
+		return nil, fmt.Errorf("invalid topic %q", settings.Topic)
+	}
+	settings.Credentials = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "credentials", settings.Credentials)
+	if settings.Credentials != "" && !json.Valid([]byte(settings.Credentials)) {
+		return nil, errors.New("credentials must be a service account key in JSON format")
+	}
+	if settings.Title == "" {
+		settings.Title = channels.DefaultMessageTitleEmbed
+	}
+	if settings.Message == "" {
+		settings.Message = channels.DefaultMessageEmbed
+	}
+	return &settings, nil
+}
+
+func PubSubFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	ch, err := newPubSubNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return ch, nil
+}
+
+// newPubSubNotifier is the constructor function for the Pub/Sub notifier.
+func newPubSubNotifier(fc channels.FactoryConfig) (*PubSubNotifier, error) {
+	settings, err := buildPubSubSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PubSubNotifier{
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+	}, nil
+}
+
+// Notify publishes the alert notification to the topic. The message is published
+// before Notify returns.
+func (pn *PubSubNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	groupKey, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var tmplErr error
+	tmpl, data := tmplText(ctx, pn.tmpl, as, pn.log, &tmplErr, pn.maxValueLen)
+
+	msg := pubSubMessage{
+		ExtendedData: data,
+		GroupKey:     groupKey.String(),
+		Title:        tmpl(pn.settings.Title),
+		Message:      tmpl(messageForAlerts(pn.settings.Message, pn.settings.ResolvedMessage, as)),
+	}
+
+	attributes := make(map[string]string, len(pn.settings.Attributes))
+	for k, v := range pn.settings.Attributes {
+		// Pub/Sub does not accept attributes with empty values.
+		if value := tmpl(v); value != "" {
+			attributes[k] = value
+		}
+	}
+
+	if tmplErr != nil {
+		pn.log.Warn("failed to template Pub/Sub message", "error", tmplErr.Error())
+	}
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	service, err := pn.getService()
+	if err != nil {
+		return false, fmt.Errorf("failed to create Pub/Sub client: %w", err)
+	}
+
+	topic := fmt.Sprintf("projects/%s/topics/%s", pn.settings.ProjectID, pn.settings.Topic)
+	req := &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data:       base64.StdEncoding.EncodeToString(b),
+			Attributes: attributes,
+		}},
+	}
+	if _, err := service.Projects.Topics.Publish(topic, req).Context(ctx).Do(); err != nil {
+		return false, fmt.Errorf("failed to publish to Pub/Sub topic %q: %w", topic, err)
+	}
+
+	return true, nil
+}
+
+// getService returns the Pub/Sub client, creating it if it does not exist.
+func (pn *PubSubNotifier) getService() (*pubsub.Service, error) {
+	pn.mtx.Lock()
+	defer pn.mtx.Unlock()
+	if pn.service != nil {
+		return pn.service, nil
+	}
+
+	var opts []option.ClientOption
+	if host := os.Getenv("PUBSUB_EMULATOR_HOST"); host != "" {
+		// The emulator does not support authentication.
+		opts = append(opts, option.WithEndpoint("http://"+host+"/"), option.WithoutAuthentication())
+	} else if pn.settings.Credentials != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(pn.settings.Credentials)))
+	}
+	// Without credentials the client uses Application Default Credentials.
+
+	// The client is used for all further notifications and so must not
+	// be bound to the context of this notification.
+	service, err := pubsub.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	pn.service = service
+	return service, nil
+}
+
+func (pn *PubSubNotifier) SendResolved() bool {
+	return !pn.GetDisableResolveMessage()
+}
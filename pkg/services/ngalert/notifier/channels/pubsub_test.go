@@ -0,0 +1,195 @@
+package channels
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPubSubNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	// The emulator receives the requests that would be sent to Pub/Sub.
+	var (
+		lastPath string
+		lastBody []byte
+	)
+	emulator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastPath = r.URL.Path
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		lastBody = b
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"messageIds": ["1"]}`))
+	}))
+	t.Cleanup(emulator.Close)
+	t.Setenv("PUBSUB_EMULATOR_HOST", strings.TrimPrefix(emulator.URL, "http://"))
+
+	cases := []struct {
+		name          string
+		settings      string
+		alerts        []*types.Alert
+		expPath       string
+		expTitle      string
+		expMessage    string
+		expAttributes map[string]string
+		expInitError  string
+	}{{
+		name:     "Default config with one alert",
+		settings: `{"projectId": "my-project", "topic": "alerts"}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+					Annotations: model.LabelSet{"ann1": "annv1"},
+				},
+			},
+		},
+		expPath:    "/v1/projects/my-project/topics/alerts:publish",
+		expTitle:   "[FIRING:1]  (val1)",
+		expMessage: "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+	}, {
+		name: "Custom config with attributes",
+		settings: `{
+			"projectId": "my-project",
+			"topic": "alerts",
+			"title": "{{ .CommonLabels.alertname }}",
+			"message": "{{ len .Alerts.Firing }} alerts are firing",
+			"attributes": {
+				"severity": "{{ .CommonLabels.severity }}",
+				"missing": "{{ .CommonLabels.missing }}"
+			}
+		}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1", "severity": "critical"},
+				},
+			}, {
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1", "severity": "critical", "lbl1": "val1"},
+				},
+			},
+		},
+		expPath:       "/v1/projects/my-project/topics/alerts:publish",
+		expTitle:      "alert1",
+		expMessage:    "2 alerts are firing",
+		expAttributes: map[string]string{"severity": "critical"},
+	}, {
+		name: "Custom config with resolved message and resolved alerts",
+		settings: `{
+			"projectId": "my-project",
+			"topic": "alerts",
+			"title": "{{ .CommonLabels.alertname }}",
+			"message": "{{ len .Alerts.Firing }} alerts are firing",
+			"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+		}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1"},
+					EndsAt: time.Now().Add(-time.Minute),
+				},
+			},
+		},
+		expPath:    "/v1/projects/my-project/topics/alerts:publish",
+		expTitle:   "alert1",
+		expMessage: "1 alerts are resolved",
+	}, {
+		name:         "Error in initing, missing project ID",
+		settings:     `{"topic": "alerts"}`,
+		expInitError: `could not find project ID in settings`,
+	}, {
+		name:         "Error in initing, invalid project ID",
+		settings:     `{"projectId": "My_Project", "topic": "alerts"}`,
+		expInitError: `invalid project ID "My_Project"`,
+	}, {
+		name:         "Error in initing, missing topic",
+		settings:     `{"projectId": "my-project"}`,
+		expInitError: `could not find topic in settings`,
+	}, {
+		name:         "Error in initing, invalid topic",
+		settings:     `{"projectId": "my-project", "topic": "goog-alerts"}`,
+		expInitError: `invalid topic "goog-alerts"`,
+	}, {
+		name:         "Error in initing, invalid credentials",
+		settings:     `{"projectId": "my-project", "topic": "alerts", "credentials": "not json"}`,
+		expInitError: `credentials must be a service account key in JSON format`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "pubsub_testing",
+					Type:     "pubsub",
+					Settings: json.RawMessage(c.settings),
+				},
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			pn, err := newPubSubNotifier(fc)
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := pn.Notify(ctx, c.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, c.expPath, lastPath)
+
+			var req struct {
+				Messages []struct {
+					Data       string            `json:"data"`
+					Attributes map[string]string `json:"attributes"`
+				} `json:"messages"`
+			}
+			require.NoError(t, json.Unmarshal(lastBody, &req))
+			require.Len(t, req.Messages, 1)
+			require.Equal(t, c.expAttributes, req.Messages[0].Attributes)
+
+			b, err := base64.StdEncoding.DecodeString(req.Messages[0].Data)
+			require.NoError(t, err)
+			var msg map[string]interface{}
+			require.NoError(t, json.Unmarshal(b, &msg))
+			require.Equal(t, "alertname", msg["groupKey"])
+			require.Equal(t, string(types.Alerts(c.alerts...).Status()), msg["status"])
+			require.Equal(t, c.expTitle, msg["title"])
+			require.Equal(t, c.expMessage, msg["message"])
+			require.Len(t, msg["alerts"], len(c.alerts))
+
+			// The client should be reused for further notifications.
+			service := pn.service
+			ok, err = pn.Notify(ctx, c.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Same(t, service, pn.service)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	queueOverflowDropNewest = "drop_newest"
+	queueOverflowDropOldest = "drop_oldest"
+
+	// queueSendTimeout is the timeout of sending a queued notification, as the
+	// deadline of the notification pipeline does not apply to queued notifications.
+	queueSendTimeout = time.Minute
+)
+
+// ErrQueueFull is returned when a notification is dropped because the queue of
+// the notifier is full.
+var ErrQueueFull = errors.New("notification queue is full")
+
+type queueSettings struct {
+	// QueueSize is the maximum number of notifications waiting to be sent. Notifications
+	// are sent synchronously if it is 0.
+	QueueSize int `json:"queue_size,omitempty" yaml:"queue_size,omitempty"`
+	// QueueOverflow is what to do when the queue is full, either drop_newest to drop
+	// the new notification or drop_oldest to drop the oldest notification in the queue.
+	QueueOverflow string `json:"queue_overflow,omitempty" yaml:"queue_overflow,omitempty"`
+}
+
+func buildQueueSettings(fc channels.FactoryConfig) (*queueSettings, error) {
+	var settings queueSettings
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.QueueSize < 0 {
+		return nil, fmt.Errorf("invalid value for queue_size: %d", settings.QueueSize)
+	}
+	if settings.QueueOverflow == "" {
+		settings.QueueOverflow = queueOverflowDropNewest
+	}
+	if settings.QueueOverflow != queueOverflowDropNewest && settings.QueueOverflow != queueOverflowDropOldest {
+		return nil, fmt.Errorf("invalid value for queue_overflow: %q, must be one of drop_newest, drop_oldest", settings.QueueOverflow)
+	}
+	return &settings, nil
+}
+
+// WithQueue wraps the factory so that notifiers with the queue_size setting send
+// notifications asynchronously from a bounded queue. Notify returns once the
+// notification is queued, so slow receivers do not block other notifications.
+//
+// This trades delivery guarantees for throughput: as the notification pipeline only
+// sees that the notification was queued, notifications that fail to be sent from the
+// queue are not retried and are only logged and counted in QueueSendFailures.
+// Notifications that are still queued are lost when Grafana stops.
+func WithQueue(factory func(channels.FactoryConfig) (channels.NotificationChannel, error), m *Metrics) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	if m == nil {
+		m = NewMetrics(nil)
+	}
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		settings, err := buildQueueSettings(fc)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: err.Error(),
+				Cfg:    *fc.Config,
+			}
+		}
+		n, err := factory(fc)
+		if err != nil || settings.QueueSize == 0 {
+			return n, err
+		}
+		return &queueNotifier{
+			NotificationChannel: n,
+			log:                 fc.Logger,
+			size:                settings.QueueSize,
+			overflow:            settings.QueueOverflow,
+			depth:               m.QueueDepth.WithLabelValues(fc.Config.Type),
+			dropped:             m.QueueDropped.WithLabelValues(fc.Config.Type),
+			sendFailures:        m.QueueSendFailures.WithLabelValues(fc.Config.Type),
+		}, nil
+	}
+}
+
+type queuedNotification struct {
+	ctx    context.Context
+	alerts []*types.Alert
+}
+
+// queueNotifier queues notifications and sends them from a worker. The worker
+// is started when a notification is queued, and stops when the queue is empty.
+type queueNotifier struct {
+	channels.NotificationChannel
+	log      channels.Logger
+	size     int
+	overflow string
+	depth    prometheus.Gauge
+	dropped  prometheus.Counter
+	// sendFailures counts the queued notifications that failed to be sent.
+	sendFailures prometheus.Counter
+
+	mtx     sync.Mutex
+	queue   []queuedNotification
+	running bool
+}
+
+// Notify queues the notification. If the queue is full, it either drops the notification
+// and returns ErrQueueFull, or drops the oldest notification in the queue.
+func (qn *queueNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	qn.mtx.Lock()
+	defer qn.mtx.Unlock()
+
+	if len(qn.queue) >= qn.size {
+		qn.dropped.Inc()
+		if qn.overflow == queueOverflowDropNewest {
+			qn.log.Warn("dropping notification as the queue is full", "queue_size", qn.size, "alerts", len(as))
+			return false, ErrQueueFull
+		}
+		qn.log.Warn("dropping oldest notification as the queue is full", "queue_size", qn.size, "alerts", len(qn.queue[0].alerts))
+		qn.queue = qn.queue[1:]
+		qn.depth.Dec()
+	}
+
+	qn.queue = append(qn.queue, queuedNotification{ctx: detachedContext{ctx}, alerts: as})
+	qn.depth.Inc()
+	if !qn.running {
+		qn.running = true
+		go qn.drain()
+	}
+	return true, nil
+}
+
+// drain sends the queued notifications until the queue is empty.
+func (qn *queueNotifier) drain() {
+	for {
+		qn.mtx.Lock()
+		if len(qn.queue) == 0 {
+			qn.running = false
+			qn.mtx.Unlock()
+			return
+		}
+		n := qn.queue[0]
+		qn.queue = qn.queue[1:]
+		qn.depth.Dec()
+		qn.mtx.Unlock()
+
+		ctx, cancel := context.WithTimeout(n.ctx, queueSendTimeout)
+		if _, err := qn.NotificationChannel.Notify(ctx, n.alerts...); err != nil {
+			qn.sendFailures.Inc()
+			qn.log.Error("failed to send queued notification, it is not retried", "error", err)
+		}
+		cancel()
+	}
+}
+
+func (qn *queueNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, qn.NotificationChannel)
+}
+
+func (qn *queueNotifier) Close() {
+	Close(qn.NotificationChannel)
+}
+
+// detachedContext has the values of the context, such as the group key, but not its
+// deadline or cancellation, so queued notifications outlive the notification pipeline.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
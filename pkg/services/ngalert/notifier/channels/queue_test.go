@@ -0,0 +1,157 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingNotifier records the alert names of notifications once they are released.
+type blockingNotifier struct {
+	started chan struct{}
+	release chan struct{}
+
+	mtx  sync.Mutex
+	sent []string
+}
+
+func (n *blockingNotifier) Notify(_ context.Context, as ...*types.Alert) (bool, error) {
+	n.started <- struct{}{}
+	<-n.release
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.sent = append(n.sent, string(as[0].Labels[model.AlertNameLabel]))
+	return true, nil
+}
+
+func (n *blockingNotifier) SendResolved() bool { return true }
+
+func (n *blockingNotifier) sentAlerts() []string {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return append([]string(nil), n.sent...)
+}
+
+func TestWithQueue(t *testing.T) {
+	cases := []struct {
+		name       string
+		overflow   string
+		expErr     error
+		expSent    []string
+		expDropped float64
+	}{{
+		name:       "Newest notification is dropped",
+		overflow:   "drop_newest",
+		expErr:     ErrQueueFull,
+		expSent:    []string{"alert1", "alert2", "alert3"},
+		expDropped: 1,
+	}, {
+		name:       "Oldest notification is dropped",
+		overflow:   "drop_oldest",
+		expSent:    []string{"alert1", "alert3", "alert4"},
+		expDropped: 1,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := NewMetrics(prometheus.NewRegistry())
+			notifier := &blockingNotifier{started: make(chan struct{}, 4), release: make(chan struct{})}
+			factory := WithQueue(func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+				return notifier, nil
+			}, m)
+
+			settings, err := json.Marshal(map[string]interface{}{"queue_size": 2, "queue_overflow": c.overflow})
+			require.NoError(t, err)
+			n, err := factory(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{Type: "webhook", Settings: settings},
+				Logger: &channels.FakeLogger{},
+			})
+			require.NoError(t, err)
+
+			notify := func(name string) error {
+				_, err := n.Notify(context.Background(), &types.Alert{
+					Alert: model.Alert{Labels: model.LabelSet{model.AlertNameLabel: model.LabelValue(name)}},
+				})
+				return err
+			}
+
+			// The first notification is taken from the queue by the worker, which then
+			// blocks, so the next two notifications fill the queue.
+			require.NoError(t, notify("alert1"))
+			<-notifier.started
+			require.NoError(t, notify("alert2"))
+			require.NoError(t, notify("alert3"))
+			require.Equal(t, 2.0, testutil.ToFloat64(m.QueueDepth.WithLabelValues("webhook")))
+
+			err = notify("alert4")
+			if c.expErr != nil {
+				require.ErrorIs(t, err, c.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, 2.0, testutil.ToFloat64(m.QueueDepth.WithLabelValues("webhook")))
+			require.Equal(t, c.expDropped, testutil.ToFloat64(m.QueueDropped.WithLabelValues("webhook")))
+
+			close(notifier.release)
+			require.Eventually(t, func() bool {
+				return len(notifier.sentAlerts()) == len(c.expSent)
+			}, time.Second, 10*time.Millisecond)
+			require.Equal(t, c.expSent, notifier.sentAlerts())
+			require.Equal(t, 0.0, testutil.ToFloat64(m.QueueDepth.WithLabelValues("webhook")))
+		})
+	}
+}
+
+func TestWithQueue_SendFailures(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry())
+	factory := WithQueue(func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		return &fakeNotifier{retry: true, err: errors.New("service unavailable")}, nil
+	}, m)
+
+	n, err := factory(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{Type: "webhook", Settings: json.RawMessage(`{"queue_size": 2}`)},
+		Logger: &channels.FakeLogger{},
+	})
+	require.NoError(t, err)
+
+	// Notify succeeds once the notifications are queued, and the failures to send
+	// them are counted.
+	for i := 0; i < 2; i++ {
+		retry, err := n.Notify(context.Background(), &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+		require.NoError(t, err)
+		require.True(t, retry)
+	}
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(m.QueueSendFailures.WithLabelValues("webhook")) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWithQueue_Disabled(t *testing.T) {
+	notifier := &fakeNotifier{retry: true}
+	factory := WithQueue(func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		return notifier, nil
+	}, nil)
+
+	n, err := factory(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{Type: "webhook", Settings: json.RawMessage(`{}`)},
+		Logger: &channels.FakeLogger{},
+	})
+	require.NoError(t, err)
+	require.Same(t, notifier, n)
+
+	_, err = factory(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{Type: "webhook", Settings: json.RawMessage(`{"queue_size": 1, "queue_overflow": "block"}`)},
+		Logger: &channels.FakeLogger{},
+	})
+	require.EqualError(t, err, `failed to validate receiver of type "webhook": invalid value for queue_overflow: "block", must be one of drop_newest, drop_oldest`)
+}
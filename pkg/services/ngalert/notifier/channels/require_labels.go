@@ -0,0 +1,104 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+// buildRequireLabels returns the require_labels setting of the notifier, or nil if it
+// is not set. Alerts without any of the labels, or with an empty value, are not sent.
+func buildRequireLabels(fc channels.FactoryConfig) ([]model.LabelName, error) {
+	var settings struct {
+		RequireLabels []string `json:"require_labels,omitempty" yaml:"require_labels,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if len(settings.RequireLabels) == 0 {
+		return nil, nil
+	}
+	labels := make([]model.LabelName, 0, len(settings.RequireLabels))
+	for _, l := range settings.RequireLabels {
+		if !model.LabelName(l).IsValid() {
+			return nil, fmt.Errorf("invalid value for require_labels: %q is not a valid label name", l)
+		}
+		labels = append(labels, model.LabelName(l))
+	}
+	return labels, nil
+}
+
+// requireLabelsNotifier drops the alerts of the notifications of a notifier that do not
+// have the required labels.
+type requireLabelsNotifier struct {
+	channels.NotificationChannel
+	log    channels.Logger
+	labels []model.LabelName
+}
+
+// withRequireLabels wraps the factory so that notifiers with the require_labels setting
+// only send alerts that have all of the labels, such as to drop malformed alerts that
+// are of no use to the service.
+func withRequireLabels(factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		labels, err := buildRequireLabels(fc)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: err.Error(),
+				Cfg:    *fc.Config,
+			}
+		}
+		n, err := factory(fc)
+		if err != nil || len(labels) == 0 {
+			return n, err
+		}
+		return &requireLabelsNotifier{
+			NotificationChannel: n,
+			log:                 fc.Logger,
+			labels:              labels,
+		}, nil
+	}
+}
+
+// Notify sends the notification with the alerts that have the required labels. If no
+// alert has them, it succeeds without sending the notification.
+func (rn *requireLabelsNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	alerts := make([]*types.Alert, 0, len(as))
+	for _, a := range as {
+		if missing := rn.missingLabel(a.Labels); missing != "" {
+			rn.log.Debug("dropping alert without required label", "alert", a.Name(), "fingerprint", a.Fingerprint().String(), "label", missing)
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+	if len(alerts) == 0 {
+		rn.log.Debug("not sending notification as no alert has the required labels", "alerts", len(as))
+		return true, nil
+	}
+	return rn.NotificationChannel.Notify(ctx, alerts...)
+}
+
+// missingLabel returns the first required label that the labels do not have, or an
+// empty string if they have all of them.
+func (rn *requireLabelsNotifier) missingLabel(labels model.LabelSet) model.LabelName {
+	for _, l := range rn.labels {
+		if labels[l] == "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// HealthCheck checks the wrapped notifier, as required labels do not apply to health
+// checks.
+func (rn *requireLabelsNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, rn.NotificationChannel)
+}
+
+func (rn *requireLabelsNotifier) Close() {
+	Close(rn.NotificationChannel)
+}
@@ -0,0 +1,115 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireLabels(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	alerts := []model.LabelSet{
+		{"alertname": "alert1", "service": "api", "env": "prod"},
+		{"alertname": "alert2", "env": "prod"},
+		{"alertname": "alert3", "service": "", "env": "prod"},
+		{"alertname": "alert4", "service": "db"},
+	}
+
+	cases := []struct {
+		name          string
+		settings      string
+		alerts        []model.LabelSet
+		expAlertnames []string
+		expInitError  string
+	}{{
+		name:          "Alerts are sent without require_labels",
+		settings:      `{"url": "http://localhost/test"}`,
+		alerts:        alerts,
+		expAlertnames: []string{"alert1", "alert2", "alert3", "alert4"},
+	}, {
+		name:          "Alerts without a required label are dropped",
+		settings:      `{"url": "http://localhost/test", "require_labels": ["service"]}`,
+		alerts:        alerts,
+		expAlertnames: []string{"alert1", "alert4"},
+	}, {
+		name:          "Alerts must have all required labels",
+		settings:      `{"url": "http://localhost/test", "require_labels": ["service", "env"]}`,
+		alerts:        alerts,
+		expAlertnames: []string{"alert1"},
+	}, {
+		name:          "Static labels are required labels",
+		settings:      `{"url": "http://localhost/test", "require_labels": ["service", "env"], "static_labels": {"env": "staging"}}`,
+		alerts:        alerts,
+		expAlertnames: []string{"alert1", "alert4"},
+	}, {
+		name:     "Notification is not sent if no alert has the required labels",
+		settings: `{"url": "http://localhost/test", "require_labels": ["service"]}`,
+		alerts:   alerts[1:3],
+	}, {
+		name:         "Error in initialization, invalid label name",
+		settings:     `{"url": "http://localhost/test", "require_labels": ["service-name"]}`,
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": invalid value for require_labels: "service-name" is not a valid label name`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			factory, ok := Factory("webhook")
+			require.True(t, ok)
+			n, err := factory(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			as := make([]*types.Alert, 0, len(c.alerts))
+			for _, labels := range c.alerts {
+				as = append(as, &types.Alert{Alert: model.Alert{Labels: labels.Clone()}})
+			}
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{})
+			ok, err = n.Notify(ctx, as...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			if len(c.expAlertnames) == 0 {
+				require.Empty(t, webhookSender.Webhook.URL)
+				return
+			}
+			var msg struct {
+				Alerts []struct {
+					Labels map[string]string `json:"labels"`
+				} `json:"alerts"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(webhookSender.Webhook.Body), &msg))
+			alertnames := make([]string, 0, len(msg.Alerts))
+			for _, a := range msg.Alerts {
+				alertnames = append(alertnames, a.Labels["alertname"])
+			}
+			require.Equal(t, c.expAlertnames, alertnames)
+		})
+	}
+}
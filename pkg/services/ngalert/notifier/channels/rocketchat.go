@@ -0,0 +1,180 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// RocketChatNotifier is responsible for sending
+// alert notifications to a Rocket.Chat incoming webhook.
+type RocketChatNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	ns          channels.WebhookSender
+	images      channels.ImageStore
+	tmpl        *template.Template
+	settings    *rocketChatSettings
+	maxValueLen int
+}
+
+type rocketChatSettings struct {
+	URL          string `json:"url,omitempty" yaml:"url,omitempty"`
+	Channel      string `json:"channel,omitempty" yaml:"channel,omitempty"`
+	Alias        string `json:"alias,omitempty" yaml:"alias,omitempty"`
+	Emoji        string `json:"emoji,omitempty" yaml:"emoji,omitempty"`
+	AvatarURL    string `json:"avatar_url,omitempty" yaml:"avatar_url,omitempty"`
+	Title        string `json:"title,omitempty" yaml:"title,omitempty"`
+	Text         string `json:"text,omitempty" yaml:"text,omitempty"`
+	ResolvedText string `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+}
+
+// rocketChatMessage is the message sent to the Rocket.Chat incoming webhook,
+// see https://docs.rocket.chat/use-rocket.chat/workspace-administration/integrations.
+type rocketChatMessage struct {
+	Channel     string                 `json:"channel,omitempty"`
+	Alias       string                 `json:"alias,omitempty"`
+	Emoji       string                 `json:"emoji,omitempty"`
+	Avatar      string                 `json:"avatar,omitempty"`
+	Attachments []rocketChatAttachment `json:"attachments"`
+}
+
+type rocketChatAttachment struct {
+	Title     string `json:"title"`
+	TitleLink string `json:"title_link,omitempty"`
+	Text      string `json:"text"`
+	Color     string `json:"color"`
+	ImageURL  string `json:"image_url,omitempty"`
+}
+
+func buildRocketChatSettings(fc channels.FactoryConfig) (*rocketChatSettings, error) {
+	var settings rocketChatSettings
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	settings.URL = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "url", settings.URL)
+	if settings.URL == "" {
+		return nil, errors.New("could not find url property in settings")
+	}
+	if !isHTTPURL(settings.URL) {
+		return nil, errors.New("url must be a valid http or https URL of a Rocket.Chat incoming webhook")
+	}
+	if settings.Alias == "" {
+		senderName, err := buildSenderName(fc)
+		if err != nil {
+			return nil, err
+		}
+		settings.Alias = senderName
+	}
+	if settings.Emoji != "" && settings.AvatarURL != "" {
+		return nil, errors.New("only one of emoji and avatar_url can be set")
+	}
+	if settings.Title == "" {
+		settings.Title = channels.DefaultMessageTitleEmbed
+	}
+	if settings.Text == "" {
+		settings.Text = channels.DefaultMessageEmbed
+	}
+	return &settings, nil
+}
+
+func RocketChatFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	rn, err := newRocketChatNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return rn, nil
+}
+
+// newRocketChatNotifier is the constructor for the Rocket.Chat notifier.
+func newRocketChatNotifier(fc channels.FactoryConfig) (*RocketChatNotifier, error) {
+	settings, err := buildRocketChatSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
+	return &RocketChatNotifier{
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		ns:          fc.NotificationService,
+		images:      images,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+	}, nil
+}
+
+// Notify sends a message to the Rocket.Chat incoming webhook.
+func (rn *RocketChatNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	var tmplErr error
+	tmpl, _ := tmplText(ctx, rn.tmpl, as, rn.log, &tmplErr, rn.maxValueLen)
+
+	attachment := rocketChatAttachment{
+		Title:     tmpl(rn.settings.Title),
+		TitleLink: grafanaURL(rn.tmpl.ExternalURL, "/alerting/list"),
+		Text:      tmpl(messageForAlerts(rn.settings.Text, rn.settings.ResolvedText, as)),
+		Color:     getAlertStatusColor(types.Alerts(as...).Status()),
+	}
+
+	// Incoming webhooks cannot upload files, so images are shared via their URL.
+	_ = withStoredImages(ctx, rn.log, rn.images, func(_ int, image channels.Image) error {
+		if image.URL != "" {
+			attachment.ImageURL = image.URL
+			return channels.ErrImagesDone
+		}
+		return nil
+	}, as...)
+
+	msg := rocketChatMessage{
+		Channel:     tmpl(rn.settings.Channel),
+		Alias:       tmpl(rn.settings.Alias),
+		Emoji:       rn.settings.Emoji,
+		Avatar:      rn.settings.AvatarURL,
+		Attachments: []rocketChatAttachment{attachment},
+	}
+
+	if tmplErr != nil {
+		rn.log.Warn("failed to template Rocket.Chat message", "error", tmplErr.Error())
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal Rocket.Chat message: %w", err)
+	}
+
+	cmd := &channels.SendWebhookSettings{
+		URL:        rn.settings.URL,
+		HTTPMethod: "POST",
+		HTTPHeader: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(body),
+	}
+	if err := rn.ns.SendWebhook(ctx, cmd); err != nil {
+		rn.log.Error("failed to send notification to Rocket.Chat", "error", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (rn *RocketChatNotifier) SendResolved() bool {
+	return !rn.GetDisableResolveMessage()
+}
@@ -0,0 +1,233 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestRocketChatNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expMsg       map[string]interface{}
+		expInitError string
+		expMsgError  error
+	}{
+		{
+			name:     "Default config with one alert",
+			settings: `{"url": "https://chat.example.com/hooks/abcd"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"attachments": []map[string]interface{}{{
+					"title":      "[FIRING:1]  (val1)",
+					"title_link": "http://localhost/alerting/list",
+					"text":       "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+					"color":      "#D63232",
+				}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Custom config with channel, alias and emoji",
+			settings: `{
+				"url": "https://chat.example.com/hooks/abcd",
+				"channel": "#alerts-{{ .CommonLabels.team }}",
+				"alias": "Grafana {{ .CommonLabels.team }}",
+				"emoji": ":rotating_light:",
+				"title": "{{ .CommonLabels.alertname }}",
+				"text": "{{ len .Alerts.Firing }} alerts are firing"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1", "team": "ops", "instance": "a"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1", "team": "ops", "instance": "b"},
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"channel": "#alerts-ops",
+				"alias":   "Grafana ops",
+				"emoji":   ":rotating_light:",
+				"attachments": []map[string]interface{}{{
+					"title":      "alert1",
+					"title_link": "http://localhost/alerting/list",
+					"text":       "2 alerts are firing",
+					"color":      "#D63232",
+				}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Resolved alert with avatar",
+			settings: `{
+				"url": "https://chat.example.com/hooks/abcd",
+				"avatar_url": "https://grafana.com/logo.png",
+				"title": "{{ .CommonLabels.alertname }}",
+				"text": "{{ len .Alerts.Resolved }} alerts are resolved"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:   model.LabelSet{"alertname": "alert1"},
+						StartsAt: time.Now().Add(-2 * time.Hour),
+						EndsAt:   time.Now().Add(-time.Hour),
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"avatar": "https://grafana.com/logo.png",
+				"attachments": []map[string]interface{}{{
+					"title":      "alert1",
+					"title_link": "http://localhost/alerting/list",
+					"text":       "1 alerts are resolved",
+					"color":      "#36a64f",
+				}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Resolved alert with resolved message",
+			settings: `{
+				"url": "https://chat.example.com/hooks/abcd",
+				"title": "{{ .CommonLabels.alertname }}",
+				"text": "{{ len .Alerts.Firing }} alerts are firing",
+				"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:   model.LabelSet{"alertname": "alert1"},
+						StartsAt: time.Now().Add(-2 * time.Hour),
+						EndsAt:   time.Now().Add(-time.Hour),
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"attachments": []map[string]interface{}{{
+					"title":      "alert1",
+					"title_link": "http://localhost/alerting/list",
+					"text":       "1 alerts are resolved",
+					"color":      "#36a64f",
+				}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Alert with image",
+			settings: `{
+				"url": "https://chat.example.com/hooks/abcd",
+				"title": "{{ .CommonLabels.alertname }}",
+				"text": "{{ len .Alerts.Firing }} alerts are firing"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1"},
+						Annotations: model.LabelSet{models.ImageTokenAnnotation: "test-image-1"},
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"attachments": []map[string]interface{}{{
+					"title":      "alert1",
+					"title_link": "http://localhost/alerting/list",
+					"text":       "1 alerts are firing",
+					"color":      "#D63232",
+					"image_url":  "https://www.example.com/test-image-1.jpg",
+				}},
+			},
+			expMsgError: nil,
+		},
+		{
+			name:         "Error in initialization",
+			settings:     `{}`,
+			expInitError: `could not find url property in settings`,
+		},
+		{
+			name:         "Error in initialization, invalid URL",
+			settings:     `{"url": "chat.example.com/hooks/abcd"}`,
+			expInitError: `url must be a valid http or https URL of a Rocket.Chat incoming webhook`,
+		},
+		{
+			name:         "Error in initialization, emoji and avatar",
+			settings:     `{"url": "https://chat.example.com/hooks/abcd", "emoji": ":bell:", "avatar_url": "https://grafana.com/logo.png"}`,
+			expInitError: `only one of emoji and avatar_url can be set`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "rocketchat_testing",
+					Type:     "rocketchat",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          newFakeImageStore(1),
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			rn, err := newRocketChatNotifier(fc)
+			if c.expInitError != "" {
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := rn.Notify(ctx, c.alerts...)
+			if c.expMsgError != nil {
+				require.False(t, ok)
+				require.Error(t, err)
+				require.Equal(t, c.expMsgError.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.Equal(t, "https://chat.example.com/hooks/abcd", webhookSender.Webhook.URL)
+
+			expBody, err := json.Marshal(c.expMsg)
+			require.NoError(t, err)
+
+			require.JSONEq(t, string(expBody), webhookSender.Webhook.Body)
+		})
+	}
+}
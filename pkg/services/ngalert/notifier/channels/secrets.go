@@ -0,0 +1,155 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+)
+
+// secretReferenceRegexp matches references to secrets in settings, such as ${secret:name}.
+var secretReferenceRegexp = regexp.MustCompile(`\$\{secret:([^}]+)\}`)
+
+// SecretsResolver returns the value of the secret with the name, such as a token stored in Vault.
+type SecretsResolver func(name string) (string, error)
+
+// secretNameRegexp matches the names of secrets in the directory of secrets, which are
+// file names, so they cannot leave the directory.
+var secretNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// FileSecretsResolver returns the resolver of the secrets in the directory, such as
+// the secrets that are mounted by Docker or Kubernetes. The value of a secret is the
+// contents of the file with its name, without the trailing newline. Secrets cannot be
+// resolved if the directory is empty.
+func FileSecretsResolver(dir string) SecretsResolver {
+	return func(name string) (string, error) {
+		if dir == "" {
+			return "", errors.New("no directory is configured for secrets")
+		}
+		if !secretNameRegexp.MatchString(name) {
+			return "", errors.New("invalid secret name")
+		}
+		// The name is checked to be a file name, so the file is in the directory.
+		//nolint:gosec
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", errors.New("secret not found")
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r"), nil
+	}
+}
+
+// WithSecretsResolver returns a factory that resolves references to secrets in the settings
+// and secure settings of the notifier before creating it with factory. This means secrets do
+// not have to be stored in the contact point. References use the syntax ${secret:name}.
+func WithSecretsResolver(factory func(channels.FactoryConfig) (channels.NotificationChannel, error), resolve SecretsResolver) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		fc, err := resolveSecrets(fc, resolve)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: "failed to resolve secrets",
+				Err:    err,
+				Cfg:    *fc.Config,
+			}
+		}
+		return factory(fc)
+	}
+}
+
+// resolveSecrets returns a copy of the factory config with the references to secrets resolved.
+func resolveSecrets(fc channels.FactoryConfig, resolve SecretsResolver) (channels.FactoryConfig, error) {
+	settings, err := resolveSettingsSecrets(fc.Config.Settings, resolve)
+	if err != nil {
+		return fc, err
+	}
+
+	// Secure settings are encrypted, so they are resolved once decrypted.
+	secureSettings := make(map[string]string, len(fc.Config.SecureSettings))
+	for key := range fc.Config.SecureSettings {
+		value := fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, key, "")
+		if secureSettings[key], err = resolveSecretReferences(value, resolve); err != nil {
+			return fc, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	cfg := *fc.Config
+	cfg.Settings = settings
+	decrypt := fc.DecryptFunc
+	fc.Config = &cfg
+	fc.DecryptFunc = func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+		if value, ok := secureSettings[key]; ok {
+			return value
+		}
+		return decrypt(ctx, sjd, key, fallback)
+	}
+	return fc, nil
+}
+
+// resolveSettingsSecrets resolves the references to secrets in all strings in the settings.
+func resolveSettingsSecrets(settings json.RawMessage, resolve SecretsResolver) (json.RawMessage, error) {
+	if !secretReferenceRegexp.Match(settings) {
+		return settings, nil
+	}
+
+	// Numbers are decoded as json.Number so they are not changed.
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(settings))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	var walk func(v interface{}) (interface{}, error)
+	walk = func(v interface{}) (interface{}, error) {
+		var err error
+		switch v := v.(type) {
+		case string:
+			return resolveSecretReferences(v, resolve)
+		case map[string]interface{}:
+			for k := range v {
+				if v[k], err = walk(v[k]); err != nil {
+					return nil, fmt.Errorf("%s: %w", k, err)
+				}
+			}
+		case []interface{}:
+			for i := range v {
+				if v[i], err = walk(v[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return v, nil
+	}
+	v, err := walk(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// resolveSecretReferences replaces the references to secrets in s with their values.
+func resolveSecretReferences(s string, resolve SecretsResolver) (string, error) {
+	var err error
+	res := secretReferenceRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		name := secretReferenceRegexp.FindStringSubmatch(ref)[1]
+		var value string
+		if value, err = resolve(name); err != nil {
+			err = fmt.Errorf("failed to resolve secret %q: %w", name, err)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return res, nil
+}
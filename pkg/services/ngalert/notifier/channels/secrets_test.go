@@ -0,0 +1,147 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSecretsResolver(t *testing.T) {
+	secrets := map[string]string{
+		"token":    "abcd",
+		"password": "secret",
+	}
+	resolve := func(name string) (string, error) {
+		if v, ok := secrets[name]; ok {
+			return v, nil
+		}
+		return "", errors.New("secret not found")
+	}
+
+	cases := []struct {
+		name           string
+		settings       string
+		secureSettings map[string][]byte
+		expURL         string
+		expMaxAlerts   int
+		expUser        string
+		expPassword    string
+		expInitError   string
+	}{{
+		name:         "settings without references are unchanged",
+		settings:     `{"url": "http://localhost/test", "maxAlerts": 5}`,
+		expURL:       "http://localhost/test",
+		expMaxAlerts: 5,
+	}, {
+		name:         "references in settings are resolved",
+		settings:     `{"url": "http://localhost/test?token=${secret:token}", "maxAlerts": 5, "username": "user"}`,
+		expURL:       "http://localhost/test?token=abcd",
+		expMaxAlerts: 5,
+		expUser:      "user",
+	}, {
+		name:     "references in secure settings are resolved",
+		settings: `{"url": "http://localhost/test", "username": "user"}`,
+		secureSettings: map[string][]byte{
+			"password": []byte("${secret:password}"),
+		},
+		expURL:      "http://localhost/test",
+		expUser:     "user",
+		expPassword: "secret",
+	}, {
+		name:         "unknown secret in settings",
+		settings:     `{"url": "http://localhost/test?token=${secret:missing}"}`,
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": failed to resolve secrets: url: failed to resolve secret "missing": secret not found`,
+	}, {
+		name:     "unknown secret in secure settings",
+		settings: `{"url": "http://localhost/test"}`,
+		secureSettings: map[string][]byte{
+			"password": []byte("${secret:missing}"),
+		},
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": failed to resolve secrets: password: failed to resolve secret "missing": secret not found`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:           "webhook_testing",
+					Type:           "webhook",
+					Settings:       json.RawMessage(c.settings),
+					SecureSettings: c.secureSettings,
+				},
+				NotificationService: mockNotificationService(),
+				// The secure settings are not encrypted in the test.
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					if v, ok := sjd[key]; ok {
+						return string(v)
+					}
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   templateForTests(t),
+				Logger:     &channels.FakeLogger{},
+			}
+
+			n, err := WithSecretsResolver(WebHookFactory, resolve)(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				var initErr receiverInitError
+				require.ErrorAs(t, err, &initErr)
+				return
+			}
+			require.NoError(t, err)
+
+			wn, ok := n.(*WebhookNotifier)
+			require.True(t, ok)
+			require.Equal(t, c.expURL, wn.settings.URL)
+			require.Equal(t, c.expMaxAlerts, wn.settings.MaxAlerts)
+			require.Equal(t, c.expUser, wn.settings.User)
+			require.Equal(t, c.expPassword, wn.settings.Password)
+		})
+	}
+}
+
+func TestFileSecretsResolver(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("abcd\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(t.TempDir(), "outside"), []byte("secret"), 0600))
+	resolve := FileSecretsResolver(dir)
+
+	v, err := resolve("token")
+	require.NoError(t, err)
+	require.Equal(t, "abcd", v)
+
+	_, err = resolve("missing")
+	require.EqualError(t, err, "secret not found")
+
+	_, err = resolve("../outside")
+	require.EqualError(t, err, "invalid secret name")
+
+	_, err = FileSecretsResolver("")("token")
+	require.EqualError(t, err, "no directory is configured for secrets")
+
+	t.Run("references are resolved from the directory", func(t *testing.T) {
+		n, err := WithSecretsResolver(WebHookFactory, resolve)(channels.FactoryConfig{
+			Config: &channels.NotificationChannelConfig{
+				Name:     "webhook_testing",
+				Type:     "webhook",
+				Settings: json.RawMessage(`{"url": "http://localhost/test?token=${secret:token}"}`),
+			},
+			NotificationService: mockNotificationService(),
+			DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+				return fallback
+			},
+			ImageStore: &channels.UnavailableImageStore{},
+			Template:   templateForTests(t),
+			Logger:     &channels.FakeLogger{},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "http://localhost/test?token=abcd", n.(*WebhookNotifier).settings.URL)
+	})
+}
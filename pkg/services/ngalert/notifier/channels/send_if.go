@@ -0,0 +1,126 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// sendIfEnv is the environment of send_if expressions, which are evaluated for each alert.
+// Labels and annotations that the alert does not have are empty strings.
+type sendIfEnv struct {
+	Labels      map[string]string `expr:"labels"`
+	Annotations map[string]string `expr:"annotations"`
+	// Status is either firing or resolved.
+	Status string `expr:"status"`
+}
+
+// newSendIfEnv returns the environment of send_if expressions for the alert.
+func newSendIfEnv(a *types.Alert) sendIfEnv {
+	env := sendIfEnv{
+		Labels:      make(map[string]string, len(a.Labels)),
+		Annotations: make(map[string]string, len(a.Annotations)),
+		Status:      string(a.Status()),
+	}
+	for k, v := range a.Labels {
+		env.Labels[string(k)] = string(v)
+	}
+	for k, v := range a.Annotations {
+		env.Annotations[string(k)] = string(v)
+	}
+	return env
+}
+
+// buildSendIf returns the send_if setting of the notifier compiled, or nil if it is not
+// set. It is an expr expression that is evaluated for each alert, and only the alerts
+// for which it returns true are sent, such as
+//
+//	labels.env == "prod" and labels.severity in ["critical", "warning"]
+func buildSendIf(fc channels.FactoryConfig) (*vm.Program, error) {
+	var settings struct {
+		SendIf string `json:"send_if,omitempty" yaml:"send_if,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	sendIf := strings.TrimSpace(settings.SendIf)
+	if sendIf == "" {
+		return nil, nil
+	}
+	program, err := expr.Compile(sendIf, expr.Env(sendIfEnv{}), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("invalid send_if expression: %w", err)
+	}
+	return program, nil
+}
+
+// sendIfNotifier sends only the alerts of a notifier for which the send_if expression
+// returns true.
+type sendIfNotifier struct {
+	channels.NotificationChannel
+	log    channels.Logger
+	sendIf *vm.Program
+}
+
+// withSendIf wraps the factory so that notifiers with the send_if setting only send
+// the alerts for which the expression returns true.
+func withSendIf(factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		sendIf, err := buildSendIf(fc)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: err.Error(),
+				Cfg:    *fc.Config,
+			}
+		}
+		n, err := factory(fc)
+		if err != nil || sendIf == nil {
+			return n, err
+		}
+		return &sendIfNotifier{
+			NotificationChannel: n,
+			log:                 fc.Logger,
+			sendIf:              sendIf,
+		}, nil
+	}
+}
+
+// Notify sends the alerts for which the send_if expression returns true. If it returns
+// false for all alerts, it succeeds without sending the notification. Alerts for which
+// the expression fails are sent, so that they are not lost.
+func (sn *sendIfNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	alerts := make([]*types.Alert, 0, len(as))
+	for _, a := range as {
+		send, err := expr.Run(sn.sendIf, newSendIfEnv(a))
+		if err != nil {
+			sn.log.Warn("failed to evaluate send_if, sending alert", "alert", a.Name(), "fingerprint", a.Fingerprint().String(), "error", err)
+			alerts = append(alerts, a)
+			continue
+		}
+		if ok, _ := send.(bool); !ok {
+			sn.log.Debug("dropping alert as send_if returned false", "alert", a.Name(), "fingerprint", a.Fingerprint().String())
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+	if len(alerts) == 0 {
+		sn.log.Debug("not sending notification as send_if returned false for all alerts", "alerts", len(as))
+		return true, nil
+	}
+	return sn.NotificationChannel.Notify(ctx, alerts...)
+}
+
+// HealthCheck checks the wrapped notifier, as send_if does not apply to health checks.
+func (sn *sendIfNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, sn.NotificationChannel)
+}
+
+func (sn *sendIfNotifier) Close() {
+	Close(sn.NotificationChannel)
+}
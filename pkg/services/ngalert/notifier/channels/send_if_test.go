@@ -0,0 +1,171 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendIf(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	cases := []struct {
+		name         string
+		settings     string
+		labels       model.LabelSet
+		annotations  model.LabelSet
+		expSent      bool
+		expInitError string
+	}{{
+		name:     "Notification is sent without send_if",
+		settings: `{"url": "http://localhost/test"}`,
+		labels:   model.LabelSet{"alertname": "alert1", "env": "staging"},
+		expSent:  true,
+	}, {
+		name: "Notification is sent if send_if returns true",
+		settings: `{
+			"url": "http://localhost/test",
+			"send_if": "labels.env == \"prod\" and labels.severity in [\"critical\", \"warning\"]"
+		}`,
+		labels:  model.LabelSet{"alertname": "alert1", "env": "prod", "severity": "warning"},
+		expSent: true,
+	}, {
+		name: "Notification is not sent if send_if returns false",
+		settings: `{
+			"url": "http://localhost/test",
+			"send_if": "labels.env == \"prod\" and labels.severity in [\"critical\", \"warning\"]"
+		}`,
+		labels:  model.LabelSet{"alertname": "alert1", "env": "staging", "severity": "critical"},
+		expSent: false,
+	}, {
+		name: "Notification is not sent if send_if returns false for a label without a value",
+		settings: `{
+			"url": "http://localhost/test",
+			"send_if": "labels.env == \"prod\""
+		}`,
+		labels:  model.LabelSet{"alertname": "alert1"},
+		expSent: false,
+	}, {
+		name: "Static labels are used in send_if",
+		settings: `{
+			"url": "http://localhost/test",
+			"send_if": "labels.env == \"prod\"",
+			"static_labels": {"env": "prod"}
+		}`,
+		labels:  model.LabelSet{"alertname": "alert1"},
+		expSent: true,
+	}, {
+		name: "Annotations and status are used in send_if",
+		settings: `{
+			"url": "http://localhost/test",
+			"send_if": "status == \"firing\" and annotations.runbook_url != \"\""
+		}`,
+		labels:      model.LabelSet{"alertname": "alert1"},
+		annotations: model.LabelSet{"runbook_url": "http://localhost/runbook"},
+		expSent:     true,
+	}, {
+		name: "Notification is sent if send_if fails",
+		settings: `{
+			"url": "http://localhost/test",
+			"send_if": "int(labels.priority) > 1"
+		}`,
+		labels:  model.LabelSet{"alertname": "alert1", "priority": "high"},
+		expSent: true,
+	}, {
+		name:         "Error in initialization, invalid expression",
+		settings:     `{"url": "http://localhost/test", "send_if": "labels.env == "}`,
+		expInitError: "failed to validate receiver \"webhook_testing\" of type \"webhook\": invalid send_if expression: unexpected token EOF (1:13)\n | labels.env ==\n | ............^",
+	}, {
+		name:         "Error in initialization, expression does not return a boolean",
+		settings:     `{"url": "http://localhost/test", "send_if": "labels.env"}`,
+		expInitError: "failed to validate receiver \"webhook_testing\" of type \"webhook\": invalid send_if expression: expected bool, but got string",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			factory, ok := Factory("webhook")
+			require.True(t, ok)
+			n, err := factory(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err = n.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: c.labels, Annotations: c.annotations}})
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			if c.expSent {
+				require.Equal(t, "http://localhost/test", webhookSender.Webhook.URL)
+			} else {
+				require.Empty(t, webhookSender.Webhook.URL)
+			}
+		})
+	}
+}
+
+func TestSendIf_Alerts(t *testing.T) {
+	webhookSender := &webhookRecordingSender{}
+	factory, ok := Factory("webhook")
+	require.True(t, ok)
+	n, err := factory(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Name:     "webhook_testing",
+			Type:     "webhook",
+			Settings: json.RawMessage(`{"url": "http://localhost/test", "send_if": "labels.env == \"prod\""}`),
+		},
+		ImageStore:          &channels.UnavailableImageStore{},
+		NotificationService: webhookSender,
+		DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+			return fallback
+		},
+		Template: templateForTests(t),
+		Logger:   &channels.FakeLogger{},
+	})
+	require.NoError(t, err)
+
+	// The expression is evaluated for each alert, and only the alerts for which it
+	// returns true are sent.
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	ok, err = n.Notify(ctx,
+		&types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "env": "prod"}}},
+		&types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2", "env": "staging"}}},
+		&types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert3", "env": "prod"}}},
+	)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Len(t, webhookSender.requests, 1)
+	var msg WebhookMessage
+	require.NoError(t, json.Unmarshal([]byte(webhookSender.requests[0].Body), &msg))
+	names := make([]string, 0, len(msg.Alerts))
+	for _, a := range msg.Alerts {
+		names = append(names, a.Labels["alertname"])
+	}
+	require.Equal(t, []string{"alert1", "alert3"}, names)
+}
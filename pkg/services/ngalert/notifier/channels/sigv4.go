@@ -0,0 +1,76 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/grafana/alerting/alerting/notifier/channels"
+)
+
+// sigV4DefaultService is the service of requests to API Gateway endpoints, which are
+// the most common endpoints protected with IAM.
+const sigV4DefaultService = "execute-api"
+
+// sigV4Config is the configuration to sign requests with AWS Signature Version 4.
+type sigV4Config struct {
+	region      string
+	service     string
+	credentials *credentials.Credentials
+}
+
+// buildSigV4Config returns the SigV4 configuration in the sigv4_region, sigv4_service,
+// sigv4_access_key and sigv4_secret_key settings of the notifier, or nil if none of
+// them is set. The secret key is a secure setting.
+func buildSigV4Config(fc channels.FactoryConfig) (*sigV4Config, error) {
+	var settings struct {
+		Region    string `json:"sigv4_region,omitempty" yaml:"sigv4_region,omitempty"`
+		Service   string `json:"sigv4_service,omitempty" yaml:"sigv4_service,omitempty"`
+		AccessKey string `json:"sigv4_access_key,omitempty" yaml:"sigv4_access_key,omitempty"`
+		SecretKey string `json:"sigv4_secret_key,omitempty" yaml:"sigv4_secret_key,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	settings.SecretKey = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "sigv4_secret_key", settings.SecretKey)
+
+	if settings.Region == "" && settings.Service == "" && settings.AccessKey == "" && settings.SecretKey == "" {
+		return nil, nil
+	}
+	if settings.Region == "" {
+		return nil, errors.New("could not find sigv4_region property in settings")
+	}
+	if !hostnameRegexp.MatchString(settings.Region) {
+		return nil, fmt.Errorf("invalid value for sigv4_region: %q, must be an AWS region such as us-east-1", settings.Region)
+	}
+	if settings.AccessKey == "" || settings.SecretKey == "" {
+		return nil, errors.New("sigv4_access_key and sigv4_secret_key must be set together")
+	}
+	if settings.Service == "" {
+		settings.Service = sigV4DefaultService
+	}
+	return &sigV4Config{
+		region:      settings.Region,
+		service:     settings.Service,
+		credentials: credentials.NewStaticCredentials(settings.AccessKey, settings.SecretKey, ""),
+	}, nil
+}
+
+// sign adds the SigV4 signature of the request and its body to the headers of the
+// request. It must be called after all other headers are set, as they are signed.
+func (c *sigV4Config) sign(request *http.Request, body []byte, signTime time.Time) error {
+	signer := v4.NewSigner(c.credentials, func(s *v4.Signer) {
+		// The body of the request is kept, so it can be sent again on redirects.
+		s.DisableRequestBodyOverwrite = true
+	})
+	if _, err := signer.Sign(request, bytes.NewReader(body), c.service, c.region, signTime); err != nil {
+		return fmt.Errorf("failed to sign HTTP request: %w", err)
+	}
+	return nil
+}
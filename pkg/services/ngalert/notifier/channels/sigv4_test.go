@@ -0,0 +1,123 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/stretchr/testify/require"
+)
+
+func newSigV4FactoryConfig(settings string, secureSettings map[string]string) channels.FactoryConfig {
+	return channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Settings:       json.RawMessage(settings),
+			SecureSettings: map[string][]byte{},
+		},
+		DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+			if v, ok := secureSettings[key]; ok {
+				return v
+			}
+			return fallback
+		},
+	}
+}
+
+func TestBuildSigV4Config(t *testing.T) {
+	cases := []struct {
+		name           string
+		settings       string
+		secureSettings map[string]string
+		expNil         bool
+		expService     string
+		expErr         string
+	}{{
+		name:     "no SigV4 settings",
+		settings: `{}`,
+		expNil:   true,
+	}, {
+		name:           "default service",
+		settings:       `{"sigv4_region": "us-east-1", "sigv4_access_key": "AKID"}`,
+		secureSettings: map[string]string{"sigv4_secret_key": "SECRET"},
+		expService:     "execute-api",
+	}, {
+		name:           "custom service",
+		settings:       `{"sigv4_region": "eu-west-1", "sigv4_service": "lambda", "sigv4_access_key": "AKID"}`,
+		secureSettings: map[string]string{"sigv4_secret_key": "SECRET"},
+		expService:     "lambda",
+	}, {
+		name:     "region is required",
+		settings: `{"sigv4_access_key": "AKID", "sigv4_secret_key": "SECRET"}`,
+		expErr:   "could not find sigv4_region property in settings",
+	}, {
+		name:     "invalid region",
+		settings: `{"sigv4_region": "us east 1", "sigv4_access_key": "AKID", "sigv4_secret_key": "SECRET"}`,
+		expErr:   `invalid value for sigv4_region: "us east 1", must be an AWS region such as us-east-1`,
+	}, {
+		name:     "secret key is required",
+		settings: `{"sigv4_region": "us-east-1", "sigv4_access_key": "AKID"}`,
+		expErr:   "sigv4_access_key and sigv4_secret_key must be set together",
+	}, {
+		name:           "access key is required",
+		settings:       `{"sigv4_region": "us-east-1"}`,
+		secureSettings: map[string]string{"sigv4_secret_key": "SECRET"},
+		expErr:         "sigv4_access_key and sigv4_secret_key must be set together",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg, err := buildSigV4Config(newSigV4FactoryConfig(c.settings, c.secureSettings))
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			if c.expNil {
+				require.Nil(t, cfg)
+				return
+			}
+			require.NotNil(t, cfg)
+			require.Equal(t, c.expService, cfg.service)
+		})
+	}
+}
+
+func TestSigV4Config_Sign(t *testing.T) {
+	cfg, err := buildSigV4Config(newSigV4FactoryConfig(
+		`{"sigv4_region": "us-east-1", "sigv4_access_key": "AKIDEXAMPLE", "sigv4_secret_key": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}`, nil))
+	require.NoError(t, err)
+
+	body := []byte(`{"status":"firing"}`)
+	request, err := http.NewRequest(http.MethodPost, "https://abc123.execute-api.us-east-1.amazonaws.com/prod/alerts", strings.NewReader(string(body)))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+
+	require.NoError(t, cfg.sign(request, body, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)))
+	require.Equal(t, "20230102T030405Z", request.Header.Get("X-Amz-Date"))
+	require.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20230102/us-east-1/execute-api/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=77d6ef4cc8450ba5f369ffc23245a82784f28318672c90680aa4132fef3fd1fd", request.Header.Get("Authorization"))
+}
+
+func TestSendHTTPRequest_SigV4(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cfg, err := buildSigV4Config(newSigV4FactoryConfig(
+		`{"sigv4_region": "us-east-1", "sigv4_access_key": "AKID", "sigv4_secret_key": "SECRET"}`, nil))
+	require.NoError(t, err)
+	_, err = sendHTTPRequest(context.Background(), u, httpCfg{body: []byte(`{}`), sigV4: cfg}, &channels.FakeLogger{})
+	require.NoError(t, err)
+	require.Regexp(t, `^AWS4-HMAC-SHA256 Credential=AKID/\d{8}/us-east-1/execute-api/aws4_request, SignedHeaders=[a-z0-9;-]+, Signature=[0-9a-f]{64}$`, authorization)
+}
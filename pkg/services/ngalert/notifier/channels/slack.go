@@ -14,13 +14,18 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
 )
@@ -33,6 +38,25 @@ const (
 	maxImagesPerThreadTsMessage = "There are more images than can be shown here. To see the panels for all firing and resolved alerts please check Grafana"
 )
 
+// slackWorkflowVariableNameRegexp matches the names of the variables of workflow webhooks.
+var slackWorkflowVariableNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]{1,50}$`)
+
+// The types of the variables of workflow webhooks, and the values they accept.
+var slackWorkflowVariableTypes = map[string]*regexp.Regexp{
+	"text":    nil,
+	"user":    regexp.MustCompile(`^[UW][A-Z0-9]{8,}$`),
+	"channel": regexp.MustCompile(`^[CG][A-Z0-9]{8,}$`),
+}
+
+// slackWorkflowMaxTextLenRunes is the maximum length of text variables. Workflows
+// usually post them in messages, so it is the maximum length of the text of a section
+// block, see https://api.slack.com/reference/block-kit/blocks#section.
+const slackWorkflowMaxTextLenRunes = 3000
+
+// slackRecipientRegexp matches channel names, such as #alerts, user names, such as @user,
+// and channel, group and user IDs, such as C0123456789.
+var slackRecipientRegexp = regexp.MustCompile(`^(#[a-z0-9_-]{1,80}|@[a-z0-9._-]{1,80}|[CGDU][A-Z0-9]{8,})$`)
+
 var (
 	slackClient = &http.Client{
 		Timeout: time.Second * 30,
@@ -45,6 +69,7 @@ var (
 				Timeout: 30 * time.Second,
 			}).DialContext,
 			TLSHandshakeTimeout: 5 * time.Second,
+			IdleConnTimeout:     defaultIdleConnTimeout,
 		},
 	}
 )
@@ -56,6 +81,19 @@ type sendFunc func(ctx context.Context, req *http.Request, logger channels.Logge
 // https://api.slack.com/reference/messaging/attachments#legacy_fields - 1024, no units given, assuming runes or characters.
 const slackMaxTitleLenRunes = 1024
 
+const (
+	// slackMaxBlocks is the maximum number of blocks in a message, see
+	// https://api.slack.com/reference/block-kit/blocks.
+	slackMaxBlocks = 50
+	// slackMaxImageTitleLenRunes is the maximum length of the title of an image block,
+	// see https://api.slack.com/reference/block-kit/blocks#image.
+	slackMaxImageTitleLenRunes = 2000
+	// slackMaxFields and slackMaxFieldLenRunes are the maximum number of fields and the
+	// maximum length of their text, see https://api.slack.com/reference/block-kit/blocks#section.
+	slackMaxFields        = 10
+	slackMaxFieldLenRunes = 2000
+)
+
 // SlackNotifier is responsible for sending
 // alert notification to Slack.
 type SlackNotifier struct {
@@ -66,7 +104,17 @@ type SlackNotifier struct {
 	webhookSender channels.WebhookSender
 	sendFn        sendFunc
 	settings      slackSettings
+	maxValueLen   int
 	appVersion    string
+	// includeFingerprints is true if the fingerprints of the alerts are included in the message metadata.
+	includeFingerprints bool
+	// runbookAnnotation is the annotation with the URL of the runbook button.
+	runbookAnnotation string
+	// imageBlocks is the maximum number of images shown as image blocks, or 0 if
+	// images are attached or uploaded instead.
+	imageBlocks int
+	// silenceDuration, if set, is the duration pre-filled in the form of silence links.
+	silenceDuration string
 }
 
 type slackSettings struct {
@@ -75,6 +123,7 @@ type slackSettings struct {
 	Token          string                         `json:"token,omitempty" yaml:"token,omitempty"`
 	Recipient      string                         `json:"recipient,omitempty" yaml:"recipient,omitempty"`
 	Text           string                         `json:"text,omitempty" yaml:"text,omitempty"`
+	ResolvedText   string                         `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
 	Title          string                         `json:"title,omitempty" yaml:"title,omitempty"`
 	Username       string                         `json:"username,omitempty" yaml:"username,omitempty"`
 	IconEmoji      string                         `json:"icon_emoji,omitempty" yaml:"icon_emoji,omitempty"`
@@ -82,6 +131,38 @@ type slackSettings struct {
 	MentionChannel string                         `json:"mentionChannel,omitempty" yaml:"mentionChannel,omitempty"`
 	MentionUsers   channels.CommaSeparatedStrings `json:"mentionUsers,omitempty" yaml:"mentionUsers,omitempty"`
 	MentionGroups  channels.CommaSeparatedStrings `json:"mentionGroups,omitempty" yaml:"mentionGroups,omitempty"`
+	// RecipientAnnotation is the name of the annotation, or label, of alerts that overrides
+	// the recipient, such as slack_channel.
+	RecipientAnnotation string `json:"recipient_annotation,omitempty" yaml:"recipient_annotation,omitempty"`
+	// ResolvedChannel, if set, is the recipient of notifications with only resolved alerts,
+	// instead of the recipient. It can be templated.
+	ResolvedChannel string `json:"resolved_channel,omitempty" yaml:"resolved_channel,omitempty"`
+	// TitleLink is the templated link of the title. It defaults to the alert rules page.
+	TitleLink string `json:"title_link,omitempty" yaml:"title_link,omitempty"`
+	// UnfurlLinks and UnfurlMedia control the previews of links and media in the message.
+	// They are omitted if not set, and use the defaults of Slack.
+	UnfurlLinks *bool `json:"unfurl_links,omitempty" yaml:"unfurl_links,omitempty"`
+	UnfurlMedia *bool `json:"unfurl_media,omitempty" yaml:"unfurl_media,omitempty"`
+	// ImageBlocks is the maximum number of images of the alerts shown as image blocks
+	// in the message, instead of attaching the first image or uploading the images.
+	// Only images with a URL can be shown as image blocks.
+	ImageBlocks json.Number `json:"image_blocks,omitempty" yaml:"image_blocks,omitempty"`
+	// WorkflowVariables, if set, are the variables sent to a workflow webhook as a flat
+	// JSON object, instead of a message. See https://slack.com/help/articles/360041352714.
+	WorkflowVariables []slackWorkflowVariable `json:"workflow_variables,omitempty" yaml:"workflow_variables,omitempty"`
+	// AnnotationFields are the names of annotations shown as short fields of the attachment,
+	// in order. The annotations are removed from the template data of the text, so they
+	// are not shown twice.
+	AnnotationFields channels.CommaSeparatedStrings `json:"annotation_fields,omitempty" yaml:"annotation_fields,omitempty"`
+}
+
+// slackWorkflowVariable is a variable of a workflow webhook with a templated value.
+type slackWorkflowVariable struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Type is text, user or channel. It defaults to text. The values of user and channel
+	// variables are user and channel IDs.
+	Type  string `json:"type,omitempty" yaml:"type,omitempty"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
 }
 
 // isIncomingWebhook returns true if the settings are for an incoming webhook.
@@ -91,12 +172,22 @@ func isIncomingWebhook(s slackSettings) bool {
 
 // uploadURL returns the upload URL for Slack.
 func uploadURL(s slackSettings) (string, error) {
+	return methodURL(s, "files.upload")
+}
+
+// authTestURL returns the URL of auth.test for Slack.
+func authTestURL(s slackSettings) (string, error) {
+	return methodURL(s, "auth.test")
+}
+
+// methodURL returns the URL of the Slack API method next to the URL in the settings.
+func methodURL(s slackSettings, method string) (string, error) {
 	u, err := url.Parse(s.URL)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse URL: %w", err)
 	}
 	dir, _ := path.Split(u.Path)
-	u.Path = path.Join(dir, "files.upload")
+	u.Path = path.Join(dir, method)
 	return u.String(), nil
 }
 
@@ -141,12 +232,64 @@ func buildSlackNotifier(factoryConfig channels.FactoryConfig) (*SlackNotifier, e
 	if settings.MentionChannel != "" && settings.MentionChannel != "here" && settings.MentionChannel != "channel" {
 		return nil, fmt.Errorf("invalid value for mentionChannel: %q", settings.MentionChannel)
 	}
+	if settings.RecipientAnnotation != "" && !model.LabelName(settings.RecipientAnnotation).IsValid() {
+		return nil, fmt.Errorf("invalid value for recipient_annotation: %q", settings.RecipientAnnotation)
+	}
+	settings.ResolvedChannel = strings.TrimSpace(settings.ResolvedChannel)
+	if settings.ResolvedChannel != "" && !strings.Contains(settings.ResolvedChannel, "{{") && !slackRecipientRegexp.MatchString(settings.ResolvedChannel) {
+		return nil, fmt.Errorf("invalid value for resolved_channel: %q", settings.ResolvedChannel)
+	}
 	settings.Token = decryptFunc(context.Background(), factoryConfig.Config.SecureSettings, "token", settings.Token)
 	if settings.Token == "" && settings.URL == SlackAPIEndpoint {
 		return nil, errors.New("token must be specified when using the Slack chat API")
 	}
 	if settings.Username == "" {
-		settings.Username = "Grafana"
+		senderName, err := buildSenderName(factoryConfig)
+		if err != nil {
+			return nil, err
+		}
+		settings.Username = senderName
+	}
+	if settings.Username == "" {
+		settings.Username = defaultSenderName
+	}
+	names := make(map[string]struct{}, len(settings.WorkflowVariables))
+	for i, v := range settings.WorkflowVariables {
+		if !slackWorkflowVariableNameRegexp.MatchString(v.Name) {
+			return nil, fmt.Errorf("invalid variable name in workflow_variables: %q", v.Name)
+		}
+		if _, ok := names[v.Name]; ok {
+			return nil, fmt.Errorf("duplicate variable name in workflow_variables: %q", v.Name)
+		}
+		names[v.Name] = struct{}{}
+		if v.Type == "" {
+			settings.WorkflowVariables[i].Type = "text"
+		}
+		re, ok := slackWorkflowVariableTypes[settings.WorkflowVariables[i].Type]
+		if !ok {
+			return nil, fmt.Errorf("invalid type of variable %q in workflow_variables: %q, must be one of text, user, channel", v.Name, v.Type)
+		}
+		if re != nil && !strings.Contains(v.Value, "{{") && !re.MatchString(v.Value) {
+			return nil, fmt.Errorf("invalid value of %s variable %q in workflow_variables: %q", v.Type, v.Name, v.Value)
+		}
+	}
+	if len(settings.WorkflowVariables) > 0 && !isIncomingWebhook(settings) {
+		return nil, errors.New("workflow_variables cannot be used with a token, the url of the workflow webhook must be used instead")
+	}
+	var imageBlocks int
+	if settings.ImageBlocks != "" {
+		imageBlocks, err = strconv.Atoi(settings.ImageBlocks.String())
+		if err != nil || imageBlocks < 0 || imageBlocks > slackMaxBlocks {
+			return nil, fmt.Errorf("invalid value for image_blocks: %q, must be an integer between 0 and %d", settings.ImageBlocks, slackMaxBlocks)
+		}
+	}
+	if len(settings.AnnotationFields) > slackMaxFields {
+		return nil, fmt.Errorf("at most %d annotation_fields are allowed", slackMaxFields)
+	}
+	for _, name := range settings.AnnotationFields {
+		if !model.LabelName(name).IsValid() {
+			return nil, fmt.Errorf("invalid annotation name in annotation_fields: %q", name)
+		}
 	}
 	if settings.Text == "" {
 		settings.Text = channels.DefaultMessageEmbed
@@ -154,11 +297,36 @@ func buildSlackNotifier(factoryConfig channels.FactoryConfig) (*SlackNotifier, e
 	if settings.Title == "" {
 		settings.Title = channels.DefaultMessageTitleEmbed
 	}
+	maxValueLen, err := buildMaxValueLen(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	includeFingerprints, err := buildIncludeFingerprints(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	runbookAnnotation, err := buildRunbookAnnotation(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	silenceDuration, err := buildSilenceDuration(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
 	return &SlackNotifier{
-		Base:     channels.NewBase(factoryConfig.Config),
-		settings: settings,
-
-		images:        factoryConfig.ImageStore,
+		Base:                channels.NewBase(factoryConfig.Config),
+		settings:            settings,
+		maxValueLen:         maxValueLen,
+		includeFingerprints: includeFingerprints,
+		runbookAnnotation:   runbookAnnotation,
+		imageBlocks:         imageBlocks,
+		silenceDuration:     silenceDuration,
+
+		images:        images,
 		webhookSender: factoryConfig.NotificationService,
 		sendFn:        sendSlackRequest,
 		log:           factoryConfig.Logger,
@@ -177,6 +345,15 @@ type slackMessage struct {
 	Attachments []attachment             `json:"attachments"`
 	Blocks      []map[string]interface{} `json:"blocks,omitempty"`
 	ThreadTs    string                   `json:"thread_ts,omitempty"`
+	Metadata    *slackMetadata           `json:"metadata,omitempty"`
+	UnfurlLinks *bool                    `json:"unfurl_links,omitempty"`
+	UnfurlMedia *bool                    `json:"unfurl_media,omitempty"`
+}
+
+// slackMetadata is the metadata of the message, see https://api.slack.com/metadata/using.
+type slackMetadata struct {
+	EventType    string                 `json:"event_type"`
+	EventPayload map[string]interface{} `json:"event_payload"`
 }
 
 // attachment is used to display a richly-formatted message block.
@@ -193,13 +370,81 @@ type attachment struct {
 	Ts         int64               `json:"ts,omitempty"`
 	Pretext    string              `json:"pretext,omitempty"`
 	MrkdwnIn   []string            `json:"mrkdwn_in,omitempty"`
+	Actions    []slackAction       `json:"actions,omitempty"`
 }
 
-// Notify sends an alert notification to Slack.
+// slackAction is a link button of an attachment, see
+// https://api.slack.com/reference/messaging/attachments#legacy_fields.
+type slackAction struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Notify sends an alert notification to Slack. If recipient_annotation is set,
+// the alerts are grouped by their recipient and a message is sent to each one.
+// Notifications with only resolved alerts are sent to resolved_channel, if set,
+// instead of the recipient.
 func (sn *SlackNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+	defaultRecipient := sn.settings.Recipient
+	if sn.settings.ResolvedChannel != "" && types.Alerts(alerts...).Status() == model.AlertResolved {
+		defaultRecipient = sn.settings.ResolvedChannel
+	}
+	if sn.settings.RecipientAnnotation == "" {
+		return sn.notify(ctx, defaultRecipient, alerts)
+	}
+
+	var (
+		recipients []string
+		groups     = make(map[string][]*types.Alert)
+	)
+	for _, a := range alerts {
+		recipient := sn.alertRecipient(a, defaultRecipient)
+		if _, ok := groups[recipient]; !ok {
+			recipients = append(recipients, recipient)
+		}
+		groups[recipient] = append(groups[recipient], a)
+	}
+
+	var errs *multierror.Error
+	for _, recipient := range recipients {
+		if _, err := sn.notify(ctx, recipient, groups[recipient]); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// alertRecipient returns the recipient in the recipient_annotation annotation or label
+// of the alert, or defaultRecipient if the alert does not have a valid one.
+func (sn *SlackNotifier) alertRecipient(a *types.Alert, defaultRecipient string) string {
+	name := model.LabelName(sn.settings.RecipientAnnotation)
+	recipient, ok := a.Annotations[name]
+	if !ok {
+		recipient, ok = a.Labels[name]
+	}
+	if !ok {
+		return defaultRecipient
+	}
+	if !slackRecipientRegexp.MatchString(string(recipient)) {
+		sn.log.Warn("Ignoring invalid Slack recipient of alert", "alert", a.Name(), "recipient", recipient)
+		return defaultRecipient
+	}
+	return string(recipient)
+}
+
+// notify sends the alerts to the recipient.
+func (sn *SlackNotifier) notify(ctx context.Context, recipient string, alerts []*types.Alert) (bool, error) {
+	if len(sn.settings.WorkflowVariables) > 0 {
+		return sn.notifyWorkflow(ctx, alerts)
+	}
+
 	sn.log.Debug("Creating slack message", "alerts", len(alerts))
 
-	m, err := sn.createSlackMessage(ctx, alerts)
+	m, err := sn.createSlackMessage(ctx, recipient, alerts)
 	if err != nil {
 		sn.log.Error("Failed to create Slack message", "err", err)
 		return false, fmt.Errorf("failed to create Slack message: %w", err)
@@ -214,11 +459,15 @@ func (sn *SlackNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bo
 	// Do not upload images if using an incoming webhook as incoming webhooks cannot upload files
 	if !isIncomingWebhook(sn.settings) {
 		if err := withStoredImages(ctx, sn.log, sn.images, func(index int, image channels.Image) error {
+			// Images with a URL are shown as image blocks in the message instead
+			if sn.imageBlocks > 0 && image.URL != "" {
+				return nil
+			}
 			// If we have exceeded the maximum number of images for this thread_ts
 			// then tell the recipient and stop iterating subsequent images
 			if index >= maxImagesPerThreadTs {
 				if _, err := sn.sendSlackMessage(ctx, &slackMessage{
-					Channel:  sn.settings.Recipient,
+					Channel:  recipient,
 					Text:     maxImagesPerThreadTsMessage,
 					ThreadTs: thread_ts,
 				}); err != nil {
@@ -227,7 +476,7 @@ func (sn *SlackNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bo
 				return channels.ErrImagesDone
 			}
 			comment := initialCommentForImage(alerts[index])
-			return sn.uploadImage(ctx, image, sn.settings.Recipient, comment, thread_ts)
+			return sn.uploadImage(ctx, image, recipient, comment, thread_ts)
 		}, alerts...); err != nil {
 			// Do not return an error here as we might have exceeded the rate limit for uploading files
 			sn.log.Error("Failed to upload image", "err", err)
@@ -240,7 +489,11 @@ func (sn *SlackNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bo
 // sendSlackRequest sends a request to the Slack API.
 // Stubbable by tests.
 var sendSlackRequest = func(ctx context.Context, req *http.Request, logger channels.Logger) (string, error) {
-	resp, err := slackClient.Do(req)
+	client := slackClient
+	if p := destinationPolicyFromContext(ctx); p != nil {
+		client = p.httpClient()
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %w", err)
 	}
@@ -347,11 +600,23 @@ func handleSlackJSONResponse(resp *http.Response, logger channels.Logger) (strin
 	return result.Ts, nil
 }
 
-func (sn *SlackNotifier) createSlackMessage(ctx context.Context, alerts []*types.Alert) (*slackMessage, error) {
+func (sn *SlackNotifier) createSlackMessage(ctx context.Context, recipient string, alerts []*types.Alert) (*slackMessage, error) {
 	var tmplErr error
-	tmpl, _ := channels.TmplText(ctx, sn.tmpl, alerts, sn.log, &tmplErr)
-
-	ruleURL := joinUrlPath(sn.tmpl.ExternalURL.String(), "/alerting/list", sn.log)
+	tmpl, data := tmplText(ctx, sn.tmpl, alerts, sn.log, &tmplErr, sn.maxValueLen)
+	setSilenceDuration(data, sn.silenceDuration, sn.log)
+	fields := sn.annotationFields(data)
+
+	ruleURL := grafanaURL(sn.tmpl.ExternalURL, "/alerting/list")
+	titleLink := ruleURL
+	if sn.settings.TitleLink != "" {
+		titleLink = tmpl(sn.settings.TitleLink)
+		if tmplErr != nil || titleLink == "" {
+			sn.log.Warn("failed to template Slack title link", "error", tmplErr, "fallback", ruleURL)
+			titleLink = ruleURL
+			// Reset tmplErr for templating other fields.
+			tmplErr = nil
+		}
+	}
 
 	title, truncated := channels.TruncateInRunes(tmpl(sn.settings.Title), slackMaxTitleLenRunes)
 	if truncated {
@@ -363,7 +628,7 @@ func (sn *SlackNotifier) createSlackMessage(ctx context.Context, alerts []*types
 	}
 
 	req := &slackMessage{
-		Channel:   tmpl(sn.settings.Recipient),
+		Channel:   tmpl(recipient),
 		Username:  tmpl(sn.settings.Username),
 		IconEmoji: tmpl(sn.settings.IconEmoji),
 		IconURL:   tmpl(sn.settings.IconURL),
@@ -377,14 +642,34 @@ func (sn *SlackNotifier) createSlackMessage(ctx context.Context, alerts []*types
 				Footer:     "Grafana v" + sn.appVersion,
 				FooterIcon: channels.FooterIconURL,
 				Ts:         time.Now().Unix(),
-				TitleLink:  ruleURL,
-				Text:       tmpl(sn.settings.Text),
-				Fields:     nil, // TODO. Should be a config.
+				TitleLink:  titleLink,
+				Text:       tmpl(messageForAlerts(sn.settings.Text, sn.settings.ResolvedText, alerts)),
+				Fields:     fields,
 			},
 		},
+		UnfurlLinks: sn.settings.UnfurlLinks,
+		UnfurlMedia: sn.settings.UnfurlMedia,
 	}
 
-	if isIncomingWebhook(sn.settings) {
+	if u := runbookURL(alerts, sn.runbookAnnotation); u != "" {
+		req.Attachments[0].Actions = append(req.Attachments[0].Actions, slackAction{Type: "button", Text: "Open Runbook", URL: u})
+	}
+	if u := generatorURL(data); u != "" {
+		req.Attachments[0].Actions = append(req.Attachments[0].Actions, slackAction{Type: "button", Text: "View Source", URL: u})
+	}
+
+	if sn.includeFingerprints {
+		req.Metadata = &slackMetadata{
+			EventType: "grafana_alert",
+			EventPayload: map[string]interface{}{
+				"fingerprints": alertFingerprints(alerts),
+			},
+		}
+	}
+
+	if sn.imageBlocks > 0 {
+		req.Blocks = sn.imageBlocksForAlerts(ctx, alerts)
+	} else if isIncomingWebhook(sn.settings) {
 		// Incoming webhooks cannot upload files, instead share images via their URL
 		_ = withStoredImages(ctx, sn.log, sn.images, func(index int, image channels.Image) error {
 			if image.URL != "" {
@@ -434,8 +719,76 @@ func (sn *SlackNotifier) createSlackMessage(ctx context.Context, alerts []*types
 	return req, nil
 }
 
+// annotationFields returns the short fields of the annotation_fields setting, and removes
+// their annotations from the template data. The value of a field is the distinct values of
+// its annotation in the alerts, and fields without values are omitted.
+func (sn *SlackNotifier) annotationFields(data *channels.ExtendedData) []config.SlackField {
+	var fields []config.SlackField
+	short := true
+	for _, name := range sn.settings.AnnotationFields {
+		var values []string
+		seen := make(map[string]struct{})
+		for _, alert := range data.Alerts {
+			value := alert.Annotations[name]
+			delete(alert.Annotations, name)
+			if _, ok := seen[value]; ok || value == "" {
+				continue
+			}
+			seen[value] = struct{}{}
+			values = append(values, value)
+		}
+		delete(data.CommonAnnotations, name)
+		if len(values) == 0 {
+			continue
+		}
+		title, _ := channels.TruncateInRunes(name, slackMaxFieldLenRunes)
+		value, _ := channels.TruncateInRunes(strings.Join(values, ", "), slackMaxFieldLenRunes)
+		fields = append(fields, config.SlackField{Title: title, Value: value, Short: &short})
+	}
+	return fields
+}
+
+// notifyWorkflow sends the templated variables of the alerts to the workflow webhook.
+// Text values are truncated, and user and channel values that are not IDs are omitted,
+// as Slack rejects the request otherwise.
+func (sn *SlackNotifier) notifyWorkflow(ctx context.Context, alerts []*types.Alert) (bool, error) {
+	var tmplErr error
+	tmpl, data := tmplText(ctx, sn.tmpl, alerts, sn.log, &tmplErr, sn.maxValueLen)
+	setSilenceDuration(data, sn.silenceDuration, sn.log)
+
+	variables := make(map[string]string, len(sn.settings.WorkflowVariables))
+	for _, v := range sn.settings.WorkflowVariables {
+		value := tmpl(v.Value)
+		if tmplErr != nil {
+			sn.log.Warn("failed to template Slack workflow variable", "variable", v.Name, "error", tmplErr.Error())
+			tmplErr = nil
+		}
+		if re := slackWorkflowVariableTypes[v.Type]; re != nil {
+			value = strings.TrimSpace(value)
+			if !re.MatchString(value) {
+				sn.log.Warn("omitting Slack workflow variable with invalid value", "variable", v.Name, "type", v.Type, "value", value)
+				continue
+			}
+		} else {
+			value, _ = channels.TruncateInRunes(value, slackWorkflowMaxTextLenRunes)
+		}
+		variables[v.Name] = value
+	}
+
+	if _, err := sn.sendSlackJSON(ctx, variables); err != nil {
+		sn.log.Error("Failed to send Slack workflow webhook", "err", err)
+		return false, fmt.Errorf("failed to send Slack workflow webhook: %w", err)
+	}
+	return true, nil
+}
+
 func (sn *SlackNotifier) sendSlackMessage(ctx context.Context, m *slackMessage) (string, error) {
-	b, err := json.Marshal(m)
+	return sn.sendSlackJSON(ctx, m)
+}
+
+// sendSlackJSON sends v as JSON to the URL of the notifier.
+func (sn *SlackNotifier) sendSlackJSON(ctx context.Context, v interface{}) (string, error) {
+	b, err := json.Marshal(v)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal Slack message: %w", err)
 	}
@@ -488,13 +841,8 @@ func (sn *SlackNotifier) createImageMultipart(image channels.Image, channel, com
 		}
 	}()
 
-	fw, err := w.CreateFormFile("file", image.Path)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create form file: %w", err)
-	}
-
-	if _, err := io.Copy(fw, f); err != nil {
-		return nil, nil, fmt.Errorf("failed to copy file to form: %w", err)
+	if err := writeMultipartFile(w, "file", image.Path, f); err != nil {
+		return nil, nil, err
 	}
 
 	if err := w.WriteField("channels", channel); err != nil {
@@ -546,6 +894,28 @@ func (sn *SlackNotifier) sendMultipart(ctx context.Context, headers http.Header,
 // uploadImage shares the image to the channel names or IDs. It returns an error if the file
 // does not exist, or if there was an error either preparing or sending the multipart/form-data
 // request.
+// HealthCheck checks the token with auth.test. Incoming webhooks cannot be checked
+// without posting a message.
+func (sn *SlackNotifier) HealthCheck(ctx context.Context) error {
+	if isIncomingWebhook(sn.settings) {
+		return ErrHealthCheckNotSupported
+	}
+	u, err := authTestURL(sn.settings)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Grafana")
+	req.Header.Set("Authorization", "Bearer "+sn.settings.Token)
+	if _, err := sn.sendFn(ctx, req, sn.log); err != nil {
+		return fmt.Errorf("failed to check Slack token: %w", err)
+	}
+	return nil
+}
+
 func (sn *SlackNotifier) uploadImage(ctx context.Context, image channels.Image, channel, comment, thread_ts string) error {
 	sn.log.Debug("Uploadimg image", "image", image.Token)
 	headers, data, err := sn.createImageMultipart(image, channel, comment, thread_ts)
@@ -560,6 +930,54 @@ func (sn *SlackNotifier) SendResolved() bool {
 	return !sn.GetDisableResolveMessage()
 }
 
+// imageBlocksForAlerts returns an image block for each of the first imageBlocks images of
+// the alerts that have a URL. The title of each image is the caption of its alert.
+func (sn *SlackNotifier) imageBlocksForAlerts(ctx context.Context, alerts []*types.Alert) []map[string]interface{} {
+	var blocks []map[string]interface{}
+	_ = withStoredImages(ctx, sn.log, sn.images, func(index int, image channels.Image) error {
+		if image.URL == "" {
+			return nil
+		}
+		caption, _ := channels.TruncateInRunes(captionForImage(alerts[index]), slackMaxImageTitleLenRunes)
+		blocks = append(blocks, map[string]interface{}{
+			"type":      "image",
+			"image_url": image.URL,
+			"alt_text":  alerts[index].Name(),
+			"title": map[string]interface{}{
+				"type": "plain_text",
+				"text": caption,
+			},
+		})
+		if len(blocks) >= sn.imageBlocks {
+			return channels.ErrImagesDone
+		}
+		return nil
+	}, alerts...)
+	return blocks
+}
+
+// captionForImage returns the caption of the image of the alert, with the name of the
+// alert and its other labels sorted by name:
+//
+//	AlertName: A=B, C=D
+func captionForImage(alert *types.Alert) string {
+	names := make([]string, 0, len(alert.Labels))
+	for name := range alert.Labels {
+		if name != model.AlertNameLabel {
+			names = append(names, string(name))
+		}
+	}
+	sort.Strings(names)
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		labels = append(labels, name+"="+string(alert.Labels[model.LabelName(name)]))
+	}
+	if len(labels) == 0 {
+		return alert.Name()
+	}
+	return alert.Name() + ": " + strings.Join(labels, ", ")
+}
+
 // initialCommentForImage returns the initial comment for the image.
 // Here is an example of the initial comment for an alert called
 // AlertName with two labels:
@@ -13,8 +13,11 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
@@ -169,6 +172,7 @@ func TestSlackIncomingWebhook(t *testing.T) {
 }
 
 func TestSlackPostMessage(t *testing.T) {
+	unfurlLinks, unfurlMedia := false, true
 	tests := []struct {
 		name            string
 		alerts          []*types.Alert
@@ -206,6 +210,123 @@ func TestSlackPostMessage(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		name: "Message is sent with title link",
+		settings: `{
+			"title_link": "{{ (index .Alerts 0).DashboardURL }}",
+			"recipient": "#test",
+			"token": "1234"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+				Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd"},
+			},
+		}},
+		expectedMessage: &slackMessage{
+			Channel:  "#test",
+			Username: "Grafana",
+			Attachments: []attachment{
+				{
+					Title:      "[FIRING:1]  (val1)",
+					TitleLink:  "http://localhost/d/abcd",
+					Text:       "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\nDashboard: http://localhost/d/abcd\n",
+					Fallback:   "[FIRING:1]  (val1)",
+					Fields:     nil,
+					Footer:     "Grafana v" + appVersion,
+					FooterIcon: "https://grafana.com/static/assets/img/fav32.png",
+					Color:      "#D63232",
+				},
+			},
+		},
+	}, {
+		name: "Message is sent with sender name",
+		settings: `{
+			"sender_name": "Acme Alerts",
+			"recipient": "#test",
+			"token": "1234"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+				Annotations: model.LabelSet{"ann1": "annv1"},
+			},
+		}},
+		expectedMessage: &slackMessage{
+			Channel:  "#test",
+			Username: "Acme Alerts",
+			Attachments: []attachment{
+				{
+					Title:      "[FIRING:1]  (val1)",
+					TitleLink:  "http://localhost/alerting/list",
+					Text:       "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+					Fallback:   "[FIRING:1]  (val1)",
+					Fields:     nil,
+					Footer:     "Grafana v" + appVersion,
+					FooterIcon: "https://grafana.com/static/assets/img/fav32.png",
+					Color:      "#D63232",
+				},
+			},
+		},
+	}, {
+		name: "Message is sent with username overriding sender name",
+		settings: `{
+			"sender_name": "Acme Alerts",
+			"username": "Acme Bot",
+			"recipient": "#test",
+			"token": "1234"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+				Annotations: model.LabelSet{"ann1": "annv1"},
+			},
+		}},
+		expectedMessage: &slackMessage{
+			Channel:  "#test",
+			Username: "Acme Bot",
+			Attachments: []attachment{
+				{
+					Title:      "[FIRING:1]  (val1)",
+					TitleLink:  "http://localhost/alerting/list",
+					Text:       "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+					Fallback:   "[FIRING:1]  (val1)",
+					Fields:     nil,
+					Footer:     "Grafana v" + appVersion,
+					FooterIcon: "https://grafana.com/static/assets/img/fav32.png",
+					Color:      "#D63232",
+				},
+			},
+		},
+	}, {
+		name: "Message is sent with default title link if template fails",
+		settings: `{
+			"title_link": "{{ .Invalid }",
+			"recipient": "#test",
+			"token": "1234"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+				Annotations: model.LabelSet{"ann1": "annv1"},
+			},
+		}},
+		expectedMessage: &slackMessage{
+			Channel:  "#test",
+			Username: "Grafana",
+			Attachments: []attachment{
+				{
+					Title:      "[FIRING:1]  (val1)",
+					TitleLink:  "http://localhost/alerting/list",
+					Text:       "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+					Fallback:   "[FIRING:1]  (val1)",
+					Fields:     nil,
+					Footer:     "Grafana v" + appVersion,
+					FooterIcon: "https://grafana.com/static/assets/img/fav32.png",
+					Color:      "#D63232",
+				},
+			},
+		},
 	}, {
 		name: "Message is sent with two firing alerts",
 		settings: `{
@@ -242,6 +363,39 @@ func TestSlackPostMessage(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		name: "Message is sent with resolved message for resolved alerts",
+		settings: `{
+			"title": "{{ .CommonLabels.alertname }}",
+			"text": "{{ .Alerts.Firing | len }} alerts are firing",
+			"resolved_message": "{{ .Alerts.Resolved | len }} alerts are resolved",
+			"icon_emoji": ":emoji:",
+			"recipient": "#test",
+			"token": "1234"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": "alert1"},
+				EndsAt: time.Now().Add(-time.Minute),
+			},
+		}},
+		expectedMessage: &slackMessage{
+			Channel:   "#test",
+			Username:  "Grafana",
+			IconEmoji: ":emoji:",
+			Attachments: []attachment{
+				{
+					Title:      "alert1",
+					TitleLink:  "http://localhost/alerting/list",
+					Text:       "1 alerts are resolved",
+					Fallback:   "alert1",
+					Fields:     nil,
+					Footer:     "Grafana v" + appVersion,
+					FooterIcon: "https://grafana.com/static/assets/img/fav32.png",
+					Color:      "#36a64f",
+				},
+			},
+		},
 	}, {
 		name: "Message is sent and image is uploaded",
 		settings: `{
@@ -312,6 +466,140 @@ func TestSlackPostMessage(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		name: "Message is sent with unfurl_links and unfurl_media",
+		settings: `{
+			"recipient": "#test",
+			"token": "1234",
+			"unfurl_links": false,
+			"unfurl_media": true
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+			},
+		}},
+		expectedMessage: &slackMessage{
+			Channel:  "#test",
+			Username: "Grafana",
+			Attachments: []attachment{
+				{
+					Title:      "[FIRING:1]  (val1)",
+					TitleLink:  "http://localhost/alerting/list",
+					Text:       "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+					Fallback:   "[FIRING:1]  (val1)",
+					Fields:     nil,
+					Footer:     "Grafana v" + appVersion,
+					FooterIcon: "https://grafana.com/static/assets/img/fav32.png",
+					Color:      "#D63232",
+				},
+			},
+			UnfurlLinks: &unfurlLinks,
+			UnfurlMedia: &unfurlMedia,
+		},
+	}, {
+		name: "Message is sent with runbook button",
+		settings: `{
+			"recipient": "#test",
+			"token": "1234",
+			"text": "{{ len .Alerts.Firing }} alerts are firing"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+				Annotations: model.LabelSet{"runbook_url": "https://runbooks.example.com/alert1"},
+			},
+		}},
+		expectedMessage: &slackMessage{
+			Channel:  "#test",
+			Username: "Grafana",
+			Attachments: []attachment{
+				{
+					Title:      "[FIRING:1]  (val1)",
+					TitleLink:  "http://localhost/alerting/list",
+					Text:       "1 alerts are firing",
+					Fallback:   "[FIRING:1]  (val1)",
+					Fields:     nil,
+					Footer:     "Grafana v" + appVersion,
+					FooterIcon: "https://grafana.com/static/assets/img/fav32.png",
+					Color:      "#D63232",
+					Actions: []slackAction{{
+						Type: "button",
+						Text: "Open Runbook",
+						URL:  "https://runbooks.example.com/alert1",
+					}},
+				},
+			},
+		},
+	}, {
+		name: "Message is sent with runbook and source buttons",
+		settings: `{
+			"recipient": "#test",
+			"token": "1234",
+			"text": "{{ len .Alerts.Firing }} alerts are firing"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels:       model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+				Annotations:  model.LabelSet{"runbook_url": "https://runbooks.example.com/alert1"},
+				GeneratorURL: "http://localhost/alerting/grafana/abcd/view",
+			},
+		}},
+		expectedMessage: &slackMessage{
+			Channel:  "#test",
+			Username: "Grafana",
+			Attachments: []attachment{
+				{
+					Title:      "[FIRING:1]  (val1)",
+					TitleLink:  "http://localhost/alerting/list",
+					Text:       "1 alerts are firing",
+					Fallback:   "[FIRING:1]  (val1)",
+					Fields:     nil,
+					Footer:     "Grafana v" + appVersion,
+					FooterIcon: "https://grafana.com/static/assets/img/fav32.png",
+					Color:      "#D63232",
+					Actions: []slackAction{{
+						Type: "button",
+						Text: "Open Runbook",
+						URL:  "https://runbooks.example.com/alert1",
+					}, {
+						Type: "button",
+						Text: "View Source",
+						URL:  "http://localhost/alerting/grafana/abcd/view",
+					}},
+				},
+			},
+		},
+	}, {
+		name: "Message is sent without runbook button if the annotation is not a URL",
+		settings: `{
+			"recipient": "#test",
+			"token": "1234",
+			"text": "{{ len .Alerts.Firing }} alerts are firing",
+			"runbook_annotation": "runbook"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+				Annotations: model.LabelSet{"runbook": "see the wiki"},
+			},
+		}},
+		expectedMessage: &slackMessage{
+			Channel:  "#test",
+			Username: "Grafana",
+			Attachments: []attachment{
+				{
+					Title:      "[FIRING:1]  (val1)",
+					TitleLink:  "http://localhost/alerting/list",
+					Text:       "1 alerts are firing",
+					Fallback:   "[FIRING:1]  (val1)",
+					Fields:     nil,
+					Footer:     "Grafana v" + appVersion,
+					FooterIcon: "https://grafana.com/static/assets/img/fav32.png",
+					Color:      "#D63232",
+				},
+			},
+		},
 	}}
 
 	for _, test := range tests {
@@ -374,6 +662,66 @@ func TestSlackPostMessage(t *testing.T) {
 	}
 }
 
+func TestSlackRecipientAnnotation(t *testing.T) {
+	notifier, recorder, err := setupSlackForTests(t, `{
+		"title": "{{ len .Alerts }} alerts",
+		"recipient": "#default",
+		"recipient_annotation": "slack_channel",
+		"token": "1234"
+	}`)
+	require.NoError(t, err)
+
+	alerts := []*types.Alert{{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1"},
+			Annotations: model.LabelSet{"slack_channel": "#team-a"},
+		},
+	}, {
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "alert2", "slack_channel": "#team-b"},
+		},
+	}, {
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert3"},
+			Annotations: model.LabelSet{"slack_channel": "#team-a"},
+		},
+	}, {
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "alert4"},
+		},
+	}, {
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert5"},
+			Annotations: model.LabelSet{"slack_channel": "Team A!"},
+		},
+	}}
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	ok, err := notifier.Notify(ctx, alerts...)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The alerts are grouped by channel, and alerts without a valid channel are
+	// sent to the recipient.
+	expected := []struct {
+		channel string
+		title   string
+	}{
+		{channel: "#team-a", title: "2 alerts"},
+		{channel: "#team-b", title: "1 alerts"},
+		{channel: "#default", title: "2 alerts"},
+	}
+	require.Len(t, recorder.requests, len(expected))
+	for i, r := range recorder.requests {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var message slackMessage
+		require.NoError(t, json.Unmarshal(b, &message))
+		assert.Equal(t, expected[i].channel, message.Channel)
+		assert.Equal(t, expected[i].title, message.Attachments[0].Title)
+	}
+}
+
 // slackRequestRecorder is used in tests to record all requests.
 type slackRequestRecorder struct {
 	requests []*http.Request
@@ -452,6 +800,234 @@ func setupSlackForTests(t *testing.T, settings string) (*SlackNotifier, *slackRe
 	return sn, sr, nil
 }
 
+func TestSlackImageBlocks(t *testing.T) {
+	alerts := make([]*types.Alert, 0, 3)
+	for i := 1; i <= 3; i++ {
+		alerts = append(alerts, &types.Alert{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1", "panel": model.LabelValue(fmt.Sprintf("panel%d", i)), "env": "prod"},
+				Annotations: model.LabelSet{"__alertImageToken__": model.LabelValue(fmt.Sprintf("test-image-%d", i))},
+			},
+		})
+	}
+	imageBlock := func(i int) map[string]interface{} {
+		return map[string]interface{}{
+			"type":      "image",
+			"image_url": fmt.Sprintf("https://www.example.com/test-image-%d.jpg", i),
+			"alt_text":  "alert1",
+			"title": map[string]interface{}{
+				"type": "plain_text",
+				"text": fmt.Sprintf("alert1: env=prod, panel=panel%d", i),
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		settings       string
+		expectedBlocks []map[string]interface{}
+	}{{
+		name: "Images are shown as image blocks",
+		settings: `{
+			"recipient": "#test",
+			"url": "https://example.com/hooks/xxxx",
+			"image_blocks": 5
+		}`,
+		expectedBlocks: []map[string]interface{}{imageBlock(1), imageBlock(2), imageBlock(3)},
+	}, {
+		name: "Number of image blocks is limited",
+		settings: `{
+			"recipient": "#test",
+			"url": "https://example.com/hooks/xxxx",
+			"image_blocks": "2"
+		}`,
+		expectedBlocks: []map[string]interface{}{imageBlock(1), imageBlock(2)},
+	}, {
+		name: "Images are shown as image blocks instead of being uploaded",
+		settings: `{
+			"recipient": "#test",
+			"token": "1234",
+			"image_blocks": 2
+		}`,
+		expectedBlocks: []map[string]interface{}{imageBlock(1), imageBlock(2)},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notifier, recorder, err := setupSlackForTests(t, test.settings)
+			require.NoError(t, err)
+			notifier.images = newFakeImageStore(3)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := notifier.Notify(ctx, alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			// All images have a URL, so none are uploaded
+			require.Len(t, recorder.requests, 1)
+			b, err := io.ReadAll(recorder.requests[0].Body)
+			require.NoError(t, err)
+
+			message := slackMessage{}
+			require.NoError(t, json.Unmarshal(b, &message))
+			assert.Equal(t, test.expectedBlocks, message.Blocks)
+			require.Len(t, message.Attachments, 1)
+			assert.Empty(t, message.Attachments[0].ImageURL)
+		})
+	}
+}
+
+func TestSlackResolvedChannel(t *testing.T) {
+	firing := &types.Alert{
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}},
+	}
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "alert2"},
+			EndsAt: time.Now().Add(-time.Minute),
+		},
+	}
+	resolvedWithRecipient := &types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert3"},
+			Annotations: model.LabelSet{"slack_channel": "#team"},
+			EndsAt:      time.Now().Add(-time.Minute),
+		},
+	}
+
+	tests := []struct {
+		name             string
+		settings         string
+		alerts           []*types.Alert
+		expectedChannels []string
+	}{{
+		name:             "Firing alerts are sent to the recipient",
+		settings:         `{"recipient": "#urgent", "resolved_channel": "#log", "token": "1234"}`,
+		alerts:           []*types.Alert{firing, resolved},
+		expectedChannels: []string{"#urgent"},
+	}, {
+		name:             "Resolved alerts are sent to the resolved channel",
+		settings:         `{"recipient": "#urgent", "resolved_channel": "#log", "token": "1234"}`,
+		alerts:           []*types.Alert{resolved},
+		expectedChannels: []string{"#log"},
+	}, {
+		name:             "Resolved alerts are sent to the recipient without resolved channel",
+		settings:         `{"recipient": "#urgent", "token": "1234"}`,
+		alerts:           []*types.Alert{resolved},
+		expectedChannels: []string{"#urgent"},
+	}, {
+		name:             "Recipient annotation overrides the resolved channel",
+		settings:         `{"recipient": "#urgent", "resolved_channel": "#log", "recipient_annotation": "slack_channel", "token": "1234"}`,
+		alerts:           []*types.Alert{resolved, resolvedWithRecipient},
+		expectedChannels: []string{"#log", "#team"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notifier, recorder, err := setupSlackForTests(t, test.settings)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := notifier.Notify(ctx, test.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			recipients := make([]string, 0, len(recorder.requests))
+			for _, r := range recorder.requests {
+				b, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				message := slackMessage{}
+				require.NoError(t, json.Unmarshal(b, &message))
+				recipients = append(recipients, message.Channel)
+			}
+			assert.Equal(t, test.expectedChannels, recipients)
+		})
+	}
+}
+
+func TestSlackWorkflowVariables(t *testing.T) {
+	notifier, recorder, err := setupSlackForTests(t, `{
+		"url": "https://hooks.slack.com/workflows/T00000000/A00000000/123/abc",
+		"workflow_variables": [
+			{"name": "alertname", "value": "{{ .CommonLabels.alertname }}"},
+			{"name": "status", "type": "text", "value": "{{ .Status }}"},
+			{"name": "oncall", "type": "user", "value": "{{ .CommonLabels.oncall }}"},
+			{"name": "team_channel", "type": "channel", "value": "{{ .CommonLabels.team }}"},
+			{"name": "description", "value": "{{ .CommonAnnotations.description }}"}
+		]
+	}`)
+	require.NoError(t, err)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+	ok, err := notifier.Notify(ctx, &types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1", "oncall": "U0123ABCDEF", "team": "#team"},
+			Annotations: model.LabelSet{"description": model.LabelValue(strings.Repeat("a", 3001))},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Len(t, recorder.requests, 1)
+	r := recorder.requests[0]
+	assert.Equal(t, "https://hooks.slack.com/workflows/T00000000/A00000000/123/abc", r.URL.String())
+	b, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+
+	// The channel variable is omitted as its value is not a channel ID.
+	assert.JSONEq(t, `{
+		"alertname": "alert1",
+		"status": "firing",
+		"oncall": "U0123ABCDEF",
+		"description": "`+strings.Repeat("a", 2999)+`…"
+	}`, string(b))
+}
+
+func TestSlackAnnotationFields(t *testing.T) {
+	notifier, recorder, err := setupSlackForTests(t, `{
+		"recipient": "#test",
+		"url": "https://example.com/hooks/xxxx",
+		"text": "{{ range .Alerts }}{{ range .Annotations.SortedPairs }}{{ .Name }}={{ .Value }} {{ end }}{{ end }}",
+		"annotation_fields": "team, summary, dashboard"
+	}`)
+	require.NoError(t, err)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+	ok, err := notifier.Notify(ctx, &types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1", "instance": "db-1"},
+			Annotations: model.LabelSet{"summary": "High CPU", "team": "infra", "description": "CPU of db-1"},
+		},
+	}, &types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1", "instance": "db-2"},
+			Annotations: model.LabelSet{"summary": "High CPU", "team": "db", "description": "CPU of db-2"},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Len(t, recorder.requests, 1)
+	b, err := io.ReadAll(recorder.requests[0].Body)
+	require.NoError(t, err)
+	message := slackMessage{}
+	require.NoError(t, json.Unmarshal(b, &message))
+	require.Len(t, message.Attachments, 1)
+
+	// Fields are in the order of the setting, and fields without values are omitted.
+	short := true
+	assert.Equal(t, []config.SlackField{
+		{Title: "team", Value: "infra, db", Short: &short},
+		{Title: "summary", Value: "High CPU", Short: &short},
+	}, message.Attachments[0].Fields)
+	// The other annotations remain in the text.
+	assert.Equal(t, "description=CPU of db-1 description=CPU of db-2 ", message.Attachments[0].Text)
+}
+
 func TestCreateSlackNotifierFromConfig(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -469,6 +1045,82 @@ func TestCreateSlackNotifierFromConfig(t *testing.T) {
 			"token": "1234"
 		}`,
 		expectedError: "recipient must be specified when using the Slack chat API",
+	}, {
+		name: "Invalid recipient annotation",
+		settings: `{
+			"recipient": "#testchannel",
+			"recipient_annotation": "slack-channel",
+			"token": "1234"
+		}`,
+		expectedError: `invalid value for recipient_annotation: "slack-channel"`,
+	}, {
+		name: "Invalid image blocks",
+		settings: `{
+			"recipient": "#testchannel",
+			"image_blocks": 51,
+			"token": "1234"
+		}`,
+		expectedError: `invalid value for image_blocks: "51", must be an integer between 0 and 50`,
+	}, {
+		name: "Invalid resolved channel",
+		settings: `{
+			"recipient": "#testchannel",
+			"resolved_channel": "#Log Channel",
+			"token": "1234"
+		}`,
+		expectedError: `invalid value for resolved_channel: "#Log Channel"`,
+	}, {
+		name: "Invalid workflow variable name",
+		settings: `{
+			"url": "https://hooks.slack.com/workflows/T00000000/A00000000/123/abc",
+			"workflow_variables": [{"name": "alert-name", "value": "{{ .CommonLabels.alertname }}"}]
+		}`,
+		expectedError: `invalid variable name in workflow_variables: "alert-name"`,
+	}, {
+		name: "Duplicate workflow variable name",
+		settings: `{
+			"url": "https://hooks.slack.com/workflows/T00000000/A00000000/123/abc",
+			"workflow_variables": [{"name": "alertname", "value": "a"}, {"name": "alertname", "value": "b"}]
+		}`,
+		expectedError: `duplicate variable name in workflow_variables: "alertname"`,
+	}, {
+		name: "Invalid workflow variable type",
+		settings: `{
+			"url": "https://hooks.slack.com/workflows/T00000000/A00000000/123/abc",
+			"workflow_variables": [{"name": "count", "type": "number", "value": "1"}]
+		}`,
+		expectedError: `invalid type of variable "count" in workflow_variables: "number", must be one of text, user, channel`,
+	}, {
+		name: "Invalid workflow user variable",
+		settings: `{
+			"url": "https://hooks.slack.com/workflows/T00000000/A00000000/123/abc",
+			"workflow_variables": [{"name": "oncall", "type": "user", "value": "@oncall"}]
+		}`,
+		expectedError: `invalid value of user variable "oncall" in workflow_variables: "@oncall"`,
+	}, {
+		name: "Workflow variables with token",
+		settings: `{
+			"recipient": "#testchannel",
+			"token": "1234",
+			"workflow_variables": [{"name": "alertname", "value": "{{ .CommonLabels.alertname }}"}]
+		}`,
+		expectedError: "workflow_variables cannot be used with a token, the url of the workflow webhook must be used instead",
+	}, {
+		name: "Invalid annotation field",
+		settings: `{
+			"recipient": "#testchannel",
+			"token": "1234",
+			"annotation_fields": "summary,runbook-url"
+		}`,
+		expectedError: `invalid annotation name in annotation_fields: "runbook-url"`,
+	}, {
+		name: "Too many annotation fields",
+		settings: `{
+			"recipient": "#testchannel",
+			"token": "1234",
+			"annotation_fields": "a1,a2,a3,a4,a5,a6,a7,a8,a9,a10,a11"
+		}`,
+		expectedError: "at most 10 annotation_fields are allowed",
 	}}
 
 	for _, test := range tests {
@@ -576,3 +1228,34 @@ func TestSendSlackRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestSlackNotifier_HealthCheck(t *testing.T) {
+	t.Run("token is checked with auth.test", func(t *testing.T) {
+		notifier, recorder, err := setupSlackForTests(t, `{"recipient": "#test", "token": "1234"}`)
+		require.NoError(t, err)
+
+		require.NoError(t, notifier.HealthCheck(context.Background()))
+		require.Len(t, recorder.requests, 1)
+		assert.Equal(t, "https://slack.com/api/auth.test", recorder.requests[0].URL.String())
+		assert.Equal(t, "Bearer 1234", recorder.requests[0].Header.Get("Authorization"))
+	})
+
+	t.Run("error is returned if the token is invalid", func(t *testing.T) {
+		notifier, _, err := setupSlackForTests(t, `{"recipient": "#test", "token": "1234"}`)
+		require.NoError(t, err)
+		notifier.sendFn = func(_ context.Context, _ *http.Request, _ channels.Logger) (string, error) {
+			return "", errors.New("failed to send request: invalid_auth")
+		}
+
+		err = notifier.HealthCheck(context.Background())
+		require.EqualError(t, err, "failed to check Slack token: failed to send request: invalid_auth")
+	})
+
+	t.Run("incoming webhooks are not supported", func(t *testing.T) {
+		notifier, recorder, err := setupSlackForTests(t, `{"recipient": "#test", "url": "https://example.com/hooks/xxxx"}`)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, notifier.HealthCheck(context.Background()), ErrHealthCheckNotSupported)
+		require.Len(t, recorder.requests, 0)
+	})
+}
@@ -0,0 +1,163 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+// Constants and models are set according to the official documentation https://support.squadcast.com/integrations/incident-webhook-incident-webhook-api
+
+const (
+	squadcastStatusTrigger = "trigger"
+	squadcastStatusResolve = "resolve"
+)
+
+type squadcastMessage struct {
+	Message     string            `json:"message"`
+	Description string            `json:"description"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Status      string            `json:"status"`
+	EventID     string            `json:"event_id"`
+}
+
+// SquadcastNotifier is responsible for sending
+// alert notifications to Squadcast.
+type SquadcastNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	ns          channels.WebhookSender
+	tmpl        *template.Template
+	settings    *squadcastSettings
+	maxValueLen int
+}
+
+type squadcastSettings struct {
+	URL             string                         `json:"url,omitempty" yaml:"url,omitempty"`
+	Message         string                         `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage string                         `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+	Description     string                         `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags            channels.CommaSeparatedStrings `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+func buildSquadcastSettings(fc channels.FactoryConfig) (*squadcastSettings, error) {
+	var settings squadcastSettings
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	settings.URL = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "url", settings.URL)
+	if settings.URL == "" {
+		return nil, errors.New("could not find webhook url property in settings")
+	}
+	if !isHTTPURL(settings.URL) {
+		return nil, errors.New("webhook url must be a valid http or https URL")
+	}
+	if settings.Message == "" {
+		settings.Message = channels.DefaultMessageTitleEmbed
+	}
+	if settings.Description == "" {
+		settings.Description = channels.DefaultMessageEmbed
+	}
+	return &settings, nil
+}
+
+func SquadcastFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	sn, err := newSquadcastNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return sn, nil
+}
+
+// newSquadcastNotifier is the constructor for the Squadcast notifier.
+func newSquadcastNotifier(fc channels.FactoryConfig) (*SquadcastNotifier, error) {
+	settings, err := buildSquadcastSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	return &SquadcastNotifier{
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		ns:          fc.NotificationService,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+	}, nil
+}
+
+// Notify sends an alert notification to Squadcast.
+func (sn *SquadcastNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var tmplErr error
+	tmpl, data := tmplText(ctx, sn.tmpl, as, sn.log, &tmplErr, sn.maxValueLen)
+
+	status := squadcastStatusTrigger
+	if types.Alerts(as...).Status() == model.AlertResolved {
+		status = squadcastStatusResolve
+	}
+
+	msg := squadcastMessage{
+		Message:     tmpl(messageForAlerts(sn.settings.Message, sn.settings.ResolvedMessage, as)),
+		Description: tmpl(sn.settings.Description),
+		Status:      status,
+		// Squadcast resolves the incident with the same event ID.
+		EventID: key.Hash(),
+	}
+
+	// Only labels common to all alerts are added as tags as there is one incident per group.
+	for _, name := range sn.settings.Tags {
+		if value, ok := data.CommonLabels[name]; ok {
+			if msg.Tags == nil {
+				msg.Tags = make(map[string]string)
+			}
+			msg.Tags[name] = value
+		}
+	}
+
+	if tmplErr != nil {
+		sn.log.Warn("failed to template Squadcast message", "error", tmplErr.Error())
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal Squadcast message: %w", err)
+	}
+
+	cmd := &channels.SendWebhookSettings{
+		URL:        sn.settings.URL,
+		HTTPMethod: "POST",
+		HTTPHeader: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(body),
+	}
+	if err := sn.ns.SendWebhook(ctx, cmd); err != nil {
+		sn.log.Error("failed to send notification to Squadcast", "error", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (sn *SquadcastNotifier) SendResolved() bool {
+	return !sn.GetDisableResolveMessage()
+}
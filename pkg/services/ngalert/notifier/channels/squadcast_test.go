@@ -0,0 +1,195 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSquadcastNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	// The event ID is the hash of the group key.
+	eventID := "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733"
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expMsg       map[string]interface{}
+		expInitError string
+		expMsgError  error
+	}{
+		{
+			name:     "Default config with one alert",
+			settings: `{"url": "https://api.squadcast.com/v2/incidents/api/abcd"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"message":     "[FIRING:1]  (val1)",
+				"description": "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+				"status":      "trigger",
+				"event_id":    eventID,
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Custom config with tags",
+			settings: `{
+				"url": "https://api.squadcast.com/v2/incidents/api/abcd",
+				"message": "{{ .CommonLabels.alertname }}",
+				"description": "{{ len .Alerts.Firing }} alerts are firing",
+				"tags": "severity,team,instance"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1", "severity": "critical", "team": "ops", "instance": "a"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1", "severity": "critical", "team": "ops", "instance": "b"},
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"message":     "alert1",
+				"description": "2 alerts are firing",
+				"tags": map[string]string{
+					"severity": "critical",
+					"team":     "ops",
+				},
+				"status":   "trigger",
+				"event_id": eventID,
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Resolved alert",
+			settings: `{
+				"url": "https://api.squadcast.com/v2/incidents/api/abcd",
+				"message": "{{ .CommonLabels.alertname }}",
+				"description": "{{ len .Alerts.Resolved }} alerts are resolved",
+				"tags": "severity"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:   model.LabelSet{"alertname": "alert1", "severity": "critical"},
+						StartsAt: time.Now().Add(-2 * time.Hour),
+						EndsAt:   time.Now().Add(-time.Hour),
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"message":     "alert1",
+				"description": "1 alerts are resolved",
+				"tags": map[string]string{
+					"severity": "critical",
+				},
+				"status":   "resolve",
+				"event_id": eventID,
+			},
+			expMsgError: nil,
+		},
+		{
+			name: "Resolved alert with resolved message",
+			settings: `{
+				"url": "https://api.squadcast.com/v2/incidents/api/abcd",
+				"message": "{{ .CommonLabels.alertname }} is firing",
+				"resolved_message": "{{ .CommonLabels.alertname }} is resolved",
+				"description": "{{ len .Alerts.Resolved }} alerts are resolved"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:   model.LabelSet{"alertname": "alert1"},
+						StartsAt: time.Now().Add(-2 * time.Hour),
+						EndsAt:   time.Now().Add(-time.Hour),
+					},
+				},
+			},
+			expMsg: map[string]interface{}{
+				"message":     "alert1 is resolved",
+				"description": "1 alerts are resolved",
+				"status":      "resolve",
+				"event_id":    eventID,
+			},
+			expMsgError: nil,
+		},
+		{
+			name:         "Error in initialization",
+			settings:     `{}`,
+			expInitError: `could not find webhook url property in settings`,
+		},
+		{
+			name:         "Error in initialization, invalid URL",
+			settings:     `{"url": "api.squadcast.com/v2/incidents/api/abcd"}`,
+			expInitError: `webhook url must be a valid http or https URL`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "squadcast_testing",
+					Type:     "squadcast",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			sn, err := newSquadcastNotifier(fc)
+			if c.expInitError != "" {
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := sn.Notify(ctx, c.alerts...)
+			if c.expMsgError != nil {
+				require.False(t, ok)
+				require.Error(t, err)
+				require.Equal(t, c.expMsgError.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.Equal(t, "https://api.squadcast.com/v2/incidents/api/abcd", webhookSender.Webhook.URL)
+
+			expBody, err := json.Marshal(c.expMsg)
+			require.NoError(t, err)
+
+			require.JSONEq(t, string(expBody), webhookSender.Webhook.Body)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+type staticLabelsSettings struct {
+	// StaticLabels are labels added to all alerts of the notifications, such as the
+	// environment or cluster.
+	StaticLabels map[string]string `json:"static_labels,omitempty" yaml:"static_labels,omitempty"`
+	// StaticLabelsOverride is true if static labels replace the labels of alerts with
+	// the same name. Otherwise, the labels of alerts are kept.
+	StaticLabelsOverride bool `json:"static_labels_override,omitempty" yaml:"static_labels_override,omitempty"`
+}
+
+func buildStaticLabelsSettings(fc channels.FactoryConfig) (*staticLabelsSettings, error) {
+	var settings staticLabelsSettings
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	names := make([]string, 0, len(settings.StaticLabels))
+	for name := range settings.StaticLabels {
+		names = append(names, name)
+	}
+	// Sort the names so the error is the same for the same settings.
+	sort.Strings(names)
+	for _, name := range names {
+		if !model.LabelName(name).IsValid() {
+			return nil, fmt.Errorf("invalid label name in static_labels: %q", name)
+		}
+		if !model.LabelValue(settings.StaticLabels[name]).IsValid() {
+			return nil, fmt.Errorf("invalid value for label %q in static_labels", name)
+		}
+	}
+	return &settings, nil
+}
+
+// staticLabelsNotifier adds static labels to the alerts of the notifications of a notifier.
+type staticLabelsNotifier struct {
+	channels.NotificationChannel
+	labels   model.LabelSet
+	override bool
+}
+
+// withStaticLabels wraps the factory so that notifiers with the static_labels setting
+// add the labels to all alerts, so they are in the template data and the payloads.
+func withStaticLabels(factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		settings, err := buildStaticLabelsSettings(fc)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: err.Error(),
+				Cfg:    *fc.Config,
+			}
+		}
+		n, err := factory(fc)
+		if err != nil || len(settings.StaticLabels) == 0 {
+			return n, err
+		}
+		labels := make(model.LabelSet, len(settings.StaticLabels))
+		for name, value := range settings.StaticLabels {
+			labels[model.LabelName(name)] = model.LabelValue(value)
+		}
+		return &staticLabelsNotifier{
+			NotificationChannel: n,
+			labels:              labels,
+			override:            settings.StaticLabelsOverride,
+		}, nil
+	}
+}
+
+// Notify sends the notification with the static labels added to copies of the alerts,
+// as the alerts are shared with the other notifiers of the contact point.
+func (sn *staticLabelsNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	alerts := make([]*types.Alert, 0, len(as))
+	for _, a := range as {
+		alert := *a
+		alert.Labels = a.Labels.Clone()
+		for name, value := range sn.labels {
+			if _, ok := alert.Labels[name]; ok && !sn.override {
+				continue
+			}
+			alert.Labels[name] = value
+		}
+		alerts = append(alerts, &alert)
+	}
+	return sn.NotificationChannel.Notify(ctx, alerts...)
+}
+
+// HealthCheck checks the wrapped notifier, as static labels do not apply to health checks.
+func (sn *staticLabelsNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, sn.NotificationChannel)
+}
+
+func (sn *staticLabelsNotifier) Close() {
+	Close(sn.NotificationChannel)
+}
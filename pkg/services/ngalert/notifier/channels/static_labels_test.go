@@ -0,0 +1,107 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticLabels(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	cases := []struct {
+		name         string
+		settings     string
+		expLabels    map[string]string
+		expMessage   string
+		expInitError string
+	}{{
+		name:       "Alerts are sent without static_labels",
+		settings:   `{"url": "http://localhost/test", "message": "{{ .CommonLabels.env }}"}`,
+		expLabels:  map[string]string{"alertname": "alert1", "env": "staging"},
+		expMessage: "staging",
+	}, {
+		name: "Static labels are added without replacing labels of alerts",
+		settings: `{
+			"url": "http://localhost/test",
+			"message": "{{ .CommonLabels.env }} {{ .CommonLabels.cluster }}",
+			"static_labels": {"env": "prod", "cluster": "eu-west-1"}
+		}`,
+		expLabels:  map[string]string{"alertname": "alert1", "env": "staging", "cluster": "eu-west-1"},
+		expMessage: "staging eu-west-1",
+	}, {
+		name: "Static labels replace labels of alerts with static_labels_override",
+		settings: `{
+			"url": "http://localhost/test",
+			"message": "{{ .CommonLabels.env }} {{ .CommonLabels.cluster }}",
+			"static_labels": {"env": "prod", "cluster": "eu-west-1"},
+			"static_labels_override": true
+		}`,
+		expLabels:  map[string]string{"alertname": "alert1", "env": "prod", "cluster": "eu-west-1"},
+		expMessage: "prod eu-west-1",
+	}, {
+		name:         "Error in initialization, invalid label name",
+		settings:     `{"url": "http://localhost/test", "static_labels": {"env": "prod", "the cluster": "eu-west-1"}}`,
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": invalid label name in static_labels: "the cluster"`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			factory, ok := Factory("webhook")
+			require.True(t, ok)
+			n, err := factory(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			alert := &types.Alert{
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1", "env": "staging"},
+				},
+			}
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err = n.Notify(ctx, alert)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			var msg struct {
+				Alerts []struct {
+					Labels map[string]string `json:"labels"`
+				} `json:"alerts"`
+				Message string `json:"message"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(webhookSender.Webhook.Body), &msg))
+			require.Len(t, msg.Alerts, 1)
+			require.Equal(t, c.expLabels, msg.Alerts[0].Labels)
+			require.Equal(t, c.expMessage, msg.Message)
+
+			// The alert is shared with other notifiers, so it must not be changed.
+			require.Equal(t, model.LabelSet{"alertname": "alert1", "env": "staging"}, alert.Labels)
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package channels
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+const (
+	syslogProtocolTCP = "tcp"
+	syslogProtocolTLS = "tls"
+
+	syslogAppName = "grafana"
+)
+
+// syslogFacilities are the facilities defined in https://www.rfc-editor.org/rfc/rfc5424#section-6.2.1.
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogSeverities are the severities defined in https://www.rfc-editor.org/rfc/rfc5424#section-6.2.1.
+var syslogSeverities = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+// defaultSyslogSeverityMapping maps the severity label of alerts to syslog severities.
+var defaultSyslogSeverityMapping = map[string]string{
+	"critical": "crit",
+	"error":    "err",
+	"warning":  "warning",
+	"info":     "info",
+}
+
+// SyslogNotifier is responsible for sending alert notifications as
+// RFC 5424 syslog messages over TCP or TLS.
+type SyslogNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	tmpl        *template.Template
+	settings    *syslogSettings
+	hostname    string
+	maxValueLen int
+	clock       clock.Clock
+	// tlsConfig has the CA and client certificates for the tls protocol.
+	tlsConfig *tls.Config
+}
+
+type syslogSettings struct {
+	Address            string `json:"address,omitempty" yaml:"address,omitempty"`
+	Protocol           string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty" yaml:"insecureSkipVerify,omitempty"`
+	Facility           string `json:"facility,omitempty" yaml:"facility,omitempty"`
+	// SeverityMapping maps the severity label of alerts to syslog severities. Alerts
+	// without a mapped severity are sent with DefaultSeverity.
+	SeverityMapping map[string]string `json:"severity_mapping,omitempty" yaml:"severity_mapping,omitempty"`
+	DefaultSeverity string            `json:"default_severity,omitempty" yaml:"default_severity,omitempty"`
+	Message         string            `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage string            `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+}
+
+func buildSyslogSettings(fc channels.FactoryConfig) (*syslogSettings, error) {
+	var settings syslogSettings
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.Address == "" {
+		return nil, errors.New("could not find address property in settings")
+	}
+	host, port, err := net.SplitHostPort(settings.Address)
+	if err != nil || host == "" {
+		return nil, fmt.Errorf("invalid value for address: %q, must be host:port", settings.Address)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return nil, fmt.Errorf("invalid value for address: %q, must be host:port", settings.Address)
+	}
+	switch settings.Protocol {
+	case "":
+		settings.Protocol = syslogProtocolTCP
+	case syslogProtocolTCP, syslogProtocolTLS:
+	default:
+		return nil, fmt.Errorf("invalid value for protocol: %q, must be one of tcp, tls", settings.Protocol)
+	}
+	if settings.Facility == "" {
+		settings.Facility = "local0"
+	}
+	if _, ok := syslogFacilities[settings.Facility]; !ok {
+		return nil, fmt.Errorf("invalid value for facility: %q", settings.Facility)
+	}
+	if settings.SeverityMapping == nil {
+		settings.SeverityMapping = defaultSyslogSeverityMapping
+	}
+	// Severity labels are matched case-insensitively.
+	severityMapping := make(map[string]string, len(settings.SeverityMapping))
+	for label, severity := range settings.SeverityMapping {
+		if _, ok := syslogSeverities[severity]; !ok {
+			return nil, fmt.Errorf("invalid value for severity_mapping: %q for severity %q", severity, label)
+		}
+		severityMapping[strings.ToLower(label)] = severity
+	}
+	settings.SeverityMapping = severityMapping
+	if settings.DefaultSeverity == "" {
+		settings.DefaultSeverity = "warning"
+	}
+	if _, ok := syslogSeverities[settings.DefaultSeverity]; !ok {
+		return nil, fmt.Errorf("invalid value for default_severity: %q", settings.DefaultSeverity)
+	}
+	if settings.Message == "" {
+		settings.Message = channels.DefaultMessageTitleEmbed
+	}
+	return &settings, nil
+}
+
+func SyslogFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	sn, err := newSyslogNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return sn, nil
+}
+
+// newSyslogNotifier is the constructor for the syslog notifier.
+func newSyslogNotifier(fc channels.FactoryConfig) (*SyslogNotifier, error) {
+	settings, err := buildSyslogSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := buildTLSConfig(fc)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil && settings.Protocol != syslogProtocolTLS {
+		return nil, errors.New("tls_ca_cert, tls_client_cert and tls_client_key require the tls protocol")
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	tlsConfig.InsecureSkipVerify = settings.InsecureSkipVerify
+	// The hostname is the NILVALUE if it is unknown.
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogNotifier{
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		tmpl:        fc.Template,
+		settings:    settings,
+		hostname:    hostname,
+		maxValueLen: maxValueLen,
+		clock:       clock.New(),
+		tlsConfig:   tlsConfig,
+	}, nil
+}
+
+// Notify sends the alerts as one syslog message.
+func (sn *SyslogNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	var tmplErr error
+	tmpl, _ := tmplText(ctx, sn.tmpl, as, sn.log, &tmplErr, sn.maxValueLen)
+	msg := tmpl(messageForAlerts(sn.settings.Message, sn.settings.ResolvedMessage, as))
+	if tmplErr != nil {
+		sn.log.Warn("failed to template syslog message", "error", tmplErr.Error())
+	}
+
+	if err := sn.send(ctx, sn.formatMessage(as, msg)); err != nil {
+		sn.log.Error("failed to send syslog message", "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// formatMessage returns the RFC 5424 message with octet counting framing, as defined
+// in https://www.rfc-editor.org/rfc/rfc6587#section-3.4.1.
+func (sn *SyslogNotifier) formatMessage(as []*types.Alert, msg string) string {
+	pri := syslogFacilities[sn.settings.Facility]*8 + sn.severity(as)
+	msgID := string(types.Alerts(as...).Status())
+	// The message has no structured data.
+	m := fmt.Sprintf("<%d>1 %s %s %s - %s - %s",
+		pri, sn.clock.Now().UTC().Format("2006-01-02T15:04:05.000000Z07:00"), sn.hostname, syslogAppName, msgID, msg)
+	return strconv.Itoa(len(m)) + " " + m
+}
+
+// severity returns the highest syslog severity of the alerts, or the default severity
+// if none of the alerts have a mapped severity label.
+func (sn *SyslogNotifier) severity(as []*types.Alert) int {
+	severity := syslogSeverities[sn.settings.DefaultSeverity]
+	found := false
+	for _, a := range as {
+		s, ok := sn.settings.SeverityMapping[strings.ToLower(string(a.Labels["severity"]))]
+		if !ok {
+			continue
+		}
+		// Lower values are more severe.
+		if v := syslogSeverities[s]; !found || v < severity {
+			severity = v
+			found = true
+		}
+	}
+	return severity
+}
+
+// send writes the message to the syslog server over a new connection.
+func (sn *SyslogNotifier) send(ctx context.Context, msg string) error {
+	var tlsConfig *tls.Config
+	if sn.settings.Protocol == syslogProtocolTLS {
+		tlsConfig = sn.tlsConfig
+	}
+	conn, err := dialWithDestinationPolicy(ctx, &net.Dialer{Timeout: defaultHTTPTimeout}, "tcp", sn.settings.Address, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog server: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			sn.log.Warn("failed to close syslog connection", "error", err)
+		}
+	}()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultHTTPTimeout)
+	}
+	if err := conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("failed to write syslog message: %w", err)
+	}
+	return nil
+}
+
+func (sn *SyslogNotifier) SendResolved() bool {
+	return !sn.GetDisableResolveMessage()
+}
@@ -0,0 +1,174 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	now := time.Date(2023, 1, 2, 3, 4, 5, 678000000, time.UTC)
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expMsg       string
+		expInitError string
+	}{{
+		name:     "Default config with one alert",
+		settings: `{"address": "%s", "message": "{{ .CommonLabels.alertname }} is {{ .Status }}"}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": "alert1", "severity": "critical"},
+			},
+		}},
+		// local0 (16) * 8 + crit (2)
+		expMsg: "<130>1 2023-01-02T03:04:05.678000Z " + hostname + " grafana - firing - alert1 is firing",
+	}, {
+		name: "Custom facility and severity mapping",
+		settings: `{
+			"address": "%s",
+			"facility": "daemon",
+			"severity_mapping": {"P1": "alert", "P2": "err"},
+			"message": "{{ len .Alerts.Firing }} alerts are firing"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": "alert1", "severity": "p2"},
+			},
+		}, {
+			Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": "alert2", "severity": "p1"},
+			},
+		}},
+		// daemon (3) * 8 + alert (1)
+		expMsg: "<25>1 2023-01-02T03:04:05.678000Z " + hostname + " grafana - firing - 2 alerts are firing",
+	}, {
+		name:     "Default severity for resolved alert without severity",
+		settings: `{"address": "%s", "message": "{{ .CommonLabels.alertname }} is {{ .Status }}"}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "alert1"},
+				StartsAt: now.Add(-2 * time.Hour),
+				EndsAt:   now.Add(-time.Hour),
+			},
+		}},
+		// local0 (16) * 8 + warning (4)
+		expMsg: "<132>1 2023-01-02T03:04:05.678000Z " + hostname + " grafana - resolved - alert1 is resolved",
+	}, {
+		name: "Resolved message for resolved alert",
+		settings: `{
+			"address": "%s",
+			"message": "{{ len .Alerts.Firing }} alerts are firing",
+			"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+		}`,
+		alerts: []*types.Alert{{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "alert1", "severity": "critical"},
+				StartsAt: now.Add(-2 * time.Hour),
+				EndsAt:   now.Add(-time.Hour),
+			},
+		}},
+		// local0 (16) * 8 + crit (2)
+		expMsg: "<130>1 2023-01-02T03:04:05.678000Z " + hostname + " grafana - resolved - 1 alerts are resolved",
+	}, {
+		name:         "Error in initialization, missing address",
+		settings:     `{}`,
+		expInitError: `could not find address property in settings`,
+	}, {
+		name:         "Error in initialization, invalid address",
+		settings:     `{"address": "localhost"}`,
+		expInitError: `invalid value for address: "localhost", must be host:port`,
+	}, {
+		name:         "Error in initialization, invalid port",
+		settings:     `{"address": "localhost:syslog"}`,
+		expInitError: `invalid value for address: "localhost:syslog", must be host:port`,
+	}, {
+		name:         "Error in initialization, invalid protocol",
+		settings:     `{"address": "localhost:6514", "protocol": "udp"}`,
+		expInitError: `invalid value for protocol: "udp", must be one of tcp, tls`,
+	}, {
+		name:         "Error in initialization, invalid facility",
+		settings:     `{"address": "localhost:6514", "facility": "local8"}`,
+		expInitError: `invalid value for facility: "local8"`,
+	}, {
+		name:         "Error in initialization, invalid severity mapping",
+		settings:     `{"address": "localhost:6514", "severity_mapping": {"critical": "fatal"}}`,
+		expInitError: `invalid value for severity_mapping: "fatal" for severity "critical"`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = l.Close() })
+
+			received := make(chan string, 1)
+			go func() {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+				b, _ := io.ReadAll(conn)
+				received <- string(b)
+			}()
+
+			settings := c.settings
+			if c.expInitError == "" {
+				settings = fmt.Sprintf(c.settings, l.Addr().String())
+			}
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "syslog_testing",
+					Type:     "syslog",
+					Settings: json.RawMessage(settings),
+				},
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			sn, err := newSyslogNotifier(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+			sn.clock = mockClock(now)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := sn.Notify(ctx, c.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			select {
+			case msg := <-received:
+				// The message is framed with octet counting.
+				require.Equal(t, fmt.Sprintf("%d %s", len(c.expMsg), c.expMsg), msg)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for syslog message")
+			}
+		})
+	}
+}
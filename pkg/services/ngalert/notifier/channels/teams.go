@@ -0,0 +1,588 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	ImageSizeSmall  = "small"
+	ImageSizeMedium = "medium"
+	ImageSizeLarge  = "large"
+
+	TextColorDark      = "dark"
+	TextColorLight     = "light"
+	TextColorAccent    = "accent"
+	TextColorGood      = "good"
+	TextColorWarning   = "warning"
+	TextColorAttention = "attention"
+
+	TextSizeSmall      = "small"
+	TextSizeMedium     = "medium"
+	TextSizeLarge      = "large"
+	TextSizeExtraLarge = "extraLarge"
+	TextSizeDefault    = "default"
+
+	TextWeightLighter = "lighter"
+	TextWeightBolder  = "bolder"
+	TextWeightDefault = "default"
+)
+
+// teamsMaxMessageSize is the maximum size of messages to incoming webhooks.
+// https://learn.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using#rate-limiting-for-connectors
+const teamsMaxMessageSize = 28 * 1024
+
+// AdaptiveCardsMessage represents a message for adaptive cards.
+type AdaptiveCardsMessage struct {
+	Attachments []AdaptiveCardsAttachment `json:"attachments"`
+	Summary     string                    `json:"summary,omitempty"` // Summary is the text shown in notifications
+	Type        string                    `json:"type"`
+}
+
+// NewAdaptiveCardsMessage returns a message prepared for adaptive cards.
+// https://docs.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using#send-adaptive-cards-using-an-incoming-webhook
+func NewAdaptiveCardsMessage(card AdaptiveCard) AdaptiveCardsMessage {
+	return AdaptiveCardsMessage{
+		Attachments: []AdaptiveCardsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+		Type: "message",
+	}
+}
+
+// AdaptiveCardsAttachment contains an adaptive card.
+type AdaptiveCardsAttachment struct {
+	Content     AdaptiveCard `json:"content"`
+	ContentType string       `json:"contentType"`
+	ContentURL  string       `json:"contentUrl,omitempty"`
+}
+
+// AdapativeCard repesents an Adaptive Card.
+// https://adaptivecards.io/explorer/AdaptiveCard.html
+type AdaptiveCard struct {
+	Body     []AdaptiveCardItem
+	Entities []AdaptiveCardMention
+	Schema   string
+	Type     string
+	Version  string
+}
+
+// NewAdaptiveCard returns a prepared Adaptive Card.
+func NewAdaptiveCard() AdaptiveCard {
+	return AdaptiveCard{
+		Body:    make([]AdaptiveCardItem, 0),
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+	}
+}
+
+func (c *AdaptiveCard) MarshalJSON() ([]byte, error) {
+	msTeams := map[string]interface{}{"width": "Full"}
+	if len(c.Entities) > 0 {
+		msTeams["entities"] = c.Entities
+	}
+	return json.Marshal(struct {
+		Body    []AdaptiveCardItem     `json:"body"`
+		Schema  string                 `json:"$schema"`
+		Type    string                 `json:"type"`
+		Version string                 `json:"version"`
+		MsTeams map[string]interface{} `json:"msTeams,omitempty"`
+	}{
+		Body:    c.Body,
+		Schema:  c.Schema,
+		Type:    c.Type,
+		Version: c.Version,
+		MsTeams: msTeams,
+	})
+}
+
+// AppendItem appends an item, such as text or an image, to the Adaptive Card.
+func (c *AdaptiveCard) AppendItem(i AdaptiveCardItem) {
+	c.Body = append(c.Body, i)
+}
+
+// AppendMention appends a mention entity to the Adaptive Card. The text of the mention
+// must also be present in one of the items of the card.
+func (c *AdaptiveCard) AppendMention(m AdaptiveCardMention) {
+	c.Entities = append(c.Entities, m)
+}
+
+// AdaptiveCardMention is a mention of a user.
+// https://docs.microsoft.com/en-us/microsoftteams/platform/task-modules-and-cards/cards/cards-format#mention-support-within-adaptive-cards
+type AdaptiveCardMention struct {
+	ID   string
+	Name string
+}
+
+// Text returns the <at> markup for the mention.
+func (m AdaptiveCardMention) Text() string {
+	return fmt.Sprintf("<at>%s</at>", m.Name)
+}
+
+func (m AdaptiveCardMention) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type      string            `json:"type"`
+		Text      string            `json:"text"`
+		Mentioned map[string]string `json:"mentioned"`
+	}{
+		Type:      "mention",
+		Text:      m.Text(),
+		Mentioned: map[string]string{"id": m.ID, "name": m.Name},
+	})
+}
+
+// AdaptiveCardItem is an interface for adaptive card items such as containers, elements and inputs.
+type AdaptiveCardItem interface {
+	MarshalJSON() ([]byte, error)
+}
+
+// AdaptiveCardTextBlockItem is a TextBlock.
+type AdaptiveCardTextBlockItem struct {
+	Color  string
+	Size   string
+	Text   string
+	Weight string
+	Wrap   bool
+}
+
+func (i AdaptiveCardTextBlockItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type   string `json:"type"`
+		Text   string `json:"text"`
+		Color  string `json:"color,omitempty"`
+		Size   string `json:"size,omitempty"`
+		Weight string `json:"weight,omitempty"`
+		Wrap   bool   `json:"wrap,omitempty"`
+	}{
+		Type:   "TextBlock",
+		Text:   i.Text,
+		Color:  i.Color,
+		Size:   i.Size,
+		Weight: i.Weight,
+		Wrap:   i.Wrap,
+	})
+}
+
+// AdaptiveCardFactSetItem is a FactSet.
+type AdaptiveCardFactSetItem struct {
+	Facts []AdaptiveCardFact
+}
+
+func (i AdaptiveCardFactSetItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type  string             `json:"type"`
+		Facts []AdaptiveCardFact `json:"facts"`
+	}{
+		Type:  "FactSet",
+		Facts: i.Facts,
+	})
+}
+
+// AdaptiveCardFact is a single title and value pair in a FactSet.
+type AdaptiveCardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// AdaptiveCardImageSetItem is an ImageSet.
+type AdaptiveCardImageSetItem struct {
+	Images []AdaptiveCardImageItem
+	Size   string
+}
+
+// AppendImage appends an image to image set.
+func (i *AdaptiveCardImageSetItem) AppendImage(image AdaptiveCardImageItem) {
+	i.Images = append(i.Images, image)
+}
+
+func (i AdaptiveCardImageSetItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type   string                  `json:"type"`
+		Images []AdaptiveCardImageItem `json:"images"`
+		Size   string                  `json:"imageSize"`
+	}{
+		Type:   "ImageSet",
+		Images: i.Images,
+		Size:   i.Size,
+	})
+}
+
+// AdaptiveCardImageItem is an Image.
+type AdaptiveCardImageItem struct {
+	AltText string
+	Size    string
+	URL     string
+}
+
+func (i AdaptiveCardImageItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string                 `json:"type"`
+		URL     string                 `json:"url"`
+		AltText string                 `json:"altText,omitempty"`
+		Size    string                 `json:"size,omitempty"`
+		MsTeams map[string]interface{} `json:"msTeams,omitempty"`
+	}{
+		Type:    "Image",
+		URL:     i.URL,
+		AltText: i.AltText,
+		Size:    i.Size,
+		MsTeams: map[string]interface{}{"allowExpand": true},
+	})
+}
+
+// AdaptiveCardActionSetItem is an ActionSet.
+type AdaptiveCardActionSetItem struct {
+	Actions []AdaptiveCardActionItem
+}
+
+func (i AdaptiveCardActionSetItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string                   `json:"type"`
+		Actions []AdaptiveCardActionItem `json:"actions"`
+	}{
+		Type:    "ActionSet",
+		Actions: i.Actions,
+	})
+}
+
+type AdaptiveCardActionItem interface {
+	MarshalJSON() ([]byte, error)
+}
+
+// AdapativeCardOpenURLActionItem is an Action.OpenUrl action.
+type AdaptiveCardOpenURLActionItem struct {
+	IconURL string
+	Title   string
+	URL     string
+}
+
+func (i AdaptiveCardOpenURLActionItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string `json:"type"`
+		Title   string `json:"title"`
+		URL     string `json:"url"`
+		IconURL string `json:"iconUrl,omitempty"`
+	}{
+		Type:    "Action.OpenUrl",
+		Title:   i.Title,
+		URL:     i.URL,
+		IconURL: i.IconURL,
+	})
+}
+
+type teamsSettings struct {
+	URL             string                         `json:"url,omitempty" yaml:"url,omitempty"`
+	Message         string                         `json:"message,omitempty" yaml:"message,omitempty"`
+	Title           string                         `json:"title,omitempty" yaml:"title,omitempty"`
+	SectionTitle    string                         `json:"sectiontitle,omitempty" yaml:"sectiontitle,omitempty"`
+	Facts           channels.CommaSeparatedStrings `json:"facts,omitempty" yaml:"facts,omitempty"`
+	ResolvedMessage string                         `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+	// Mentions are the Azure AD object IDs or user principal names of the users to mention.
+	Mentions channels.CommaSeparatedStrings `json:"mentions,omitempty" yaml:"mentions,omitempty"`
+	// MentionSeverity, if set, restricts mentions to notifications that contain a firing
+	// alert with a severity label of the same value.
+	MentionSeverity string `json:"mentionSeverity,omitempty" yaml:"mentionSeverity,omitempty"`
+}
+
+func buildTeamsSettings(fc channels.FactoryConfig) (teamsSettings, error) {
+	settings := teamsSettings{}
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return settings, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.URL == "" {
+		return settings, errors.New("could not find url property in settings")
+	}
+	if settings.Message == "" {
+		settings.Message = `{{ template "teams.default.message" .}}`
+	}
+	if settings.Title == "" {
+		settings.Title = channels.DefaultMessageTitleEmbed
+	}
+	if settings.MentionSeverity != "" && len(settings.Mentions) == 0 {
+		return settings, errors.New("at least one mention is required when mentionSeverity is set")
+	}
+	return settings, nil
+}
+
+type TeamsNotifier struct {
+	*channels.Base
+	tmpl        *template.Template
+	log         channels.Logger
+	ns          channels.WebhookSender
+	images      channels.ImageStore
+	settings    teamsSettings
+	maxValueLen int
+	// runbookAnnotation is the annotation with the URL of the runbook action.
+	runbookAnnotation string
+}
+
+// newTeamsNotifier is the constructor for Teams notifier.
+func newTeamsNotifier(fc channels.FactoryConfig) (*TeamsNotifier, error) {
+	settings, err := buildTeamsSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
+	runbookAnnotation, err := buildRunbookAnnotation(fc)
+	if err != nil {
+		return nil, err
+	}
+	return &TeamsNotifier{
+		Base:              channels.NewBase(fc.Config),
+		log:               fc.Logger,
+		ns:                fc.NotificationService,
+		images:            images,
+		tmpl:              fc.Template,
+		settings:          settings,
+		maxValueLen:       maxValueLen,
+		runbookAnnotation: runbookAnnotation,
+	}, nil
+}
+
+func TeamsFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	notifier, err := newTeamsNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return notifier, nil
+}
+
+func (tn *TeamsNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	var tmplErr error
+	tmpl, data := tmplText(ctx, tn.tmpl, as, tn.log, &tmplErr, tn.maxValueLen)
+
+	card := NewAdaptiveCard()
+	card.AppendItem(AdaptiveCardTextBlockItem{
+		Color:  getTeamsTextColor(types.Alerts(as...)),
+		Text:   tmpl(tn.settings.Title),
+		Size:   TextSizeLarge,
+		Weight: TextWeightBolder,
+		Wrap:   true,
+	})
+	card.AppendItem(AdaptiveCardTextBlockItem{
+		Text: tmpl(messageForAlerts(tn.settings.Message, tn.settings.ResolvedMessage, as)),
+		Wrap: true,
+	})
+
+	if tn.shouldMention(as) {
+		texts := make([]string, 0, len(tn.settings.Mentions))
+		for _, m := range tn.settings.Mentions {
+			id := tmpl(m)
+			mention := AdaptiveCardMention{ID: id, Name: id}
+			card.AppendMention(mention)
+			texts = append(texts, mention.Text())
+		}
+		card.AppendItem(AdaptiveCardTextBlockItem{
+			Text: strings.Join(texts, " "),
+			Wrap: true,
+		})
+	}
+
+	if facts := buildTeamsFacts(data.Alerts, tn.settings.Facts); len(facts) > 0 {
+		card.AppendItem(AdaptiveCardFactSetItem{Facts: facts})
+	}
+
+	// Images can only be shown from their URL, so images that are only on disk are skipped.
+	var images []AdaptiveCardImageItem
+	_ = withStoredImages(ctx, tn.log, tn.images,
+		func(_ int, image channels.Image) error {
+			if image.URL != "" {
+				images = append(images, AdaptiveCardImageItem{URL: image.URL})
+			}
+			return nil
+		},
+		as...)
+
+	action := AdaptiveCardActionSetItem{
+		Actions: []AdaptiveCardActionItem{
+			AdaptiveCardOpenURLActionItem{
+				Title: "View URL",
+				URL:   grafanaURL(tn.tmpl.ExternalURL, "/alerting/list"),
+			},
+		},
+	}
+	if u := runbookURL(as, tn.runbookAnnotation); u != "" {
+		action.Actions = append(action.Actions, AdaptiveCardOpenURLActionItem{
+			Title: "Open Runbook",
+			URL:   u,
+		})
+	}
+	if u := generatorURL(data); u != "" {
+		action.Actions = append(action.Actions, AdaptiveCardOpenURLActionItem{
+			Title: "View Source",
+			URL:   u,
+		})
+	}
+	summary := tmpl(tn.settings.Title)
+
+	// This check for tmplErr must happen before templating the URL
+	if tmplErr != nil {
+		tn.log.Warn("failed to template Teams message", "error", tmplErr.Error())
+		tmplErr = nil
+	}
+
+	u := tmpl(tn.settings.URL)
+	if tmplErr != nil {
+		tn.log.Warn("failed to template Teams URL", "error", tmplErr.Error(), "fallback", tn.settings.URL)
+		u = tn.settings.URL
+	}
+
+	b, err := tn.buildMessage(card, images, action, summary)
+	if err != nil {
+		return false, err
+	}
+
+	cmd := &channels.SendWebhookSettings{URL: u, Body: string(b)}
+	// Teams sometimes does not use status codes to show when a request has failed. Instead, the
+	// response can contain an error message, irrespective of status code (i.e. https://docs.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using?tabs=cURL#rate-limiting-for-connectors)
+	cmd.Validation = validateResponse
+
+	if err := tn.ns.SendWebhook(ctx, cmd); err != nil {
+		return false, errors.Wrap(err, "send notification to Teams")
+	}
+
+	return true, nil
+}
+
+// buildMessage returns the message for the card with the images and action. If the message
+// exceeds teamsMaxMessageSize, only the first image is kept, or none if it is still too large.
+func (tn *TeamsNotifier) buildMessage(card AdaptiveCard, images []AdaptiveCardImageItem, action AdaptiveCardActionSetItem, summary string) ([]byte, error) {
+	body := card.Body
+	for {
+		card.Body = append(body[:len(body):len(body)], imageSetItem(images)...)
+		card.Body = append(card.Body, action)
+		msg := NewAdaptiveCardsMessage(card)
+		msg.Summary = summary
+		b, err := json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		if len(b) <= teamsMaxMessageSize || len(images) == 0 {
+			return b, nil
+		}
+		tn.log.Warn("Teams message is too large, removing images", "size", len(b), "max_size", teamsMaxMessageSize, "images", len(images))
+		if len(images) > 1 {
+			images = images[:1]
+		} else {
+			images = nil
+		}
+	}
+}
+
+// imageSetItem returns the items to show the images in the card.
+func imageSetItem(images []AdaptiveCardImageItem) []AdaptiveCardItem {
+	if len(images) == 0 {
+		return nil
+	}
+	s := AdaptiveCardImageSetItem{Images: images, Size: ImageSizeLarge}
+	if len(images) > 2 {
+		s.Size = ImageSizeMedium
+	}
+	return []AdaptiveCardItem{s}
+}
+
+func validateResponse(b []byte, statusCode int) error {
+	// The request succeeded if the response is "1"
+	// https://docs.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using?tabs=cURL#send-messages-using-curl-and-powershell
+	if !bytes.Equal(b, []byte("1")) {
+		return errors.New(string(b))
+	}
+	return nil
+}
+
+func (tn *TeamsNotifier) SendResolved() bool {
+	return !tn.GetDisableResolveMessage()
+}
+
+// shouldMention returns true if the configured users should be mentioned for the alerts.
+func (tn *TeamsNotifier) shouldMention(as []*types.Alert) bool {
+	if len(tn.settings.Mentions) == 0 {
+		return false
+	}
+	if tn.settings.MentionSeverity == "" {
+		return true
+	}
+	for _, alert := range as {
+		if alert.Status() != model.AlertFiring {
+			continue
+		}
+		if strings.EqualFold(string(alert.Labels["severity"]), tn.settings.MentionSeverity) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildTeamsFacts returns a fact for each of the label names, in the order
+// they are given. If no label names are given then a fact is returned for
+// each label of the alerts, sorted by name. The value of a fact is the
+// distinct values of the label across all alerts, and labels that are absent
+// from all alerts are skipped.
+func buildTeamsFacts(alerts channels.ExtendedAlerts, names []string) []AdaptiveCardFact {
+	if len(names) == 0 {
+		seen := make(map[string]struct{})
+		for _, alert := range alerts {
+			for name := range alert.Labels {
+				if _, ok := seen[name]; !ok {
+					seen[name] = struct{}{}
+					names = append(names, name)
+				}
+			}
+		}
+		sort.Strings(names)
+	}
+
+	facts := make([]AdaptiveCardFact, 0, len(names))
+	for _, name := range names {
+		var values []string
+		seen := make(map[string]struct{})
+		for _, alert := range alerts {
+			value, ok := alert.Labels[name]
+			if !ok {
+				continue
+			}
+			if _, ok := seen[value]; !ok {
+				seen[value] = struct{}{}
+				values = append(values, value)
+			}
+		}
+		if len(values) > 0 {
+			facts = append(facts, AdaptiveCardFact{
+				Title: name,
+				Value: strings.Join(values, ", "),
+			})
+		}
+	}
+	return facts
+}
+
+// getTeamsTextColor returns the text color for the message title.
+func getTeamsTextColor(alerts model.Alerts) string {
+	if getAlertStatusColor(alerts.Status()) == channels.ColorAlertFiring {
+		return TextColorAttention
+	}
+	return TextColorGood
+}
@@ -0,0 +1,710 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestTeamsNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expMsg       map[string]interface{}
+		expInitError string
+		expMsgError  error
+	}{{
+		name:     "Default config with one alert",
+		settings: `{"url": "http://localhost"}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+					Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+				},
+			},
+		},
+		expMsg: map[string]interface{}{
+			"attachments": []map[string]interface{}{{
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"body": []map[string]interface{}{{
+						"color":  "attention",
+						"size":   "large",
+						"text":   "[FIRING:1]  (val1)",
+						"type":   "TextBlock",
+						"weight": "bolder",
+						"wrap":   true,
+					}, {
+						"text": "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+						"type": "TextBlock",
+						"wrap": true,
+					}, {
+						"facts": []map[string]interface{}{{
+							"title": "alertname",
+							"value": "alert1",
+						}, {
+							"title": "lbl1",
+							"value": "val1",
+						}},
+						"type": "FactSet",
+					}, {
+						"actions": []map[string]interface{}{{
+							"title": "View URL",
+							"type":  "Action.OpenUrl",
+							"url":   "http://localhost/alerting/list",
+						}},
+						"type": "ActionSet",
+					}},
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msTeams": map[string]interface{}{
+						"width": "Full",
+					},
+				},
+				"contentType": "application/vnd.microsoft.card.adaptive",
+			}},
+			"summary": "[FIRING:1]  (val1)",
+			"type":    "message",
+		},
+		expMsgError: nil,
+	}, {
+		name: "Resolved message for resolved alerts",
+		settings: `{
+			"url": "http://localhost",
+			"title": "{{ .CommonLabels.alertname }}",
+			"message": "{{ len .Alerts.Firing }} alerts are firing",
+			"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+		}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1"},
+					EndsAt: time.Now().Add(-time.Minute),
+				},
+			},
+		},
+		expMsg: map[string]interface{}{
+			"attachments": []map[string]interface{}{{
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"body": []map[string]interface{}{{
+						"color":  "good",
+						"size":   "large",
+						"text":   "alert1",
+						"type":   "TextBlock",
+						"weight": "bolder",
+						"wrap":   true,
+					}, {
+						"text": "1 alerts are resolved",
+						"type": "TextBlock",
+						"wrap": true,
+					}, {
+						"facts": []map[string]interface{}{{
+							"title": "alertname",
+							"value": "alert1",
+						}},
+						"type": "FactSet",
+					}, {
+						"actions": []map[string]interface{}{{
+							"title": "View URL",
+							"type":  "Action.OpenUrl",
+							"url":   "http://localhost/alerting/list",
+						}},
+						"type": "ActionSet",
+					}},
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msTeams": map[string]interface{}{
+						"width": "Full",
+					},
+				},
+				"contentType": "application/vnd.microsoft.card.adaptive",
+			}},
+			"summary": "alert1",
+			"type":    "message",
+		},
+		expMsgError: nil,
+	}, {
+		name: "Custom config with multiple alerts",
+		settings: `{
+	"url": "http://localhost",
+	"title": "{{ .CommonLabels.alertname }}",
+	"sectiontitle": "Details",
+	"message": "{{ len .Alerts.Firing }} alerts are firing, {{ len .Alerts.Resolved }} are resolved"
+}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+					Annotations: model.LabelSet{"ann1": "annv1"},
+				},
+			}, {
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+					Annotations: model.LabelSet{"ann1": "annv2"},
+				},
+			},
+		},
+		expMsg: map[string]interface{}{
+			"attachments": []map[string]interface{}{{
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"body": []map[string]interface{}{{
+						"color":  "attention",
+						"size":   "large",
+						"text":   "alert1",
+						"type":   "TextBlock",
+						"weight": "bolder",
+						"wrap":   true,
+					}, {
+						"text": "2 alerts are firing, 0 are resolved",
+						"type": "TextBlock",
+						"wrap": true,
+					}, {
+						"facts": []map[string]interface{}{{
+							"title": "alertname",
+							"value": "alert1",
+						}, {
+							"title": "lbl1",
+							"value": "val1, val2",
+						}},
+						"type": "FactSet",
+					}, {
+						"actions": []map[string]interface{}{{
+							"title": "View URL",
+							"type":  "Action.OpenUrl",
+							"url":   "http://localhost/alerting/list",
+						}},
+						"type": "ActionSet",
+					}},
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msTeams": map[string]interface{}{
+						"width": "Full",
+					},
+				},
+				"contentType": "application/vnd.microsoft.card.adaptive",
+			}},
+			"summary": "alert1",
+			"type":    "message",
+		},
+		expMsgError: nil,
+	}, {
+		name: "Custom config with facts",
+		settings: `{
+	"url": "http://localhost",
+	"title": "{{ .CommonLabels.alertname }}",
+	"message": "{{ len .Alerts.Firing }} alerts are firing, {{ len .Alerts.Resolved }} are resolved",
+	"facts": "lbl1, missing, alertname"
+}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1", "lbl2": "val3"},
+					Annotations: model.LabelSet{"ann1": "annv1"},
+				},
+			}, {
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2", "lbl2": "val3"},
+					Annotations: model.LabelSet{"ann1": "annv2"},
+				},
+			},
+		},
+		expMsg: map[string]interface{}{
+			"attachments": []map[string]interface{}{{
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"body": []map[string]interface{}{{
+						"color":  "attention",
+						"size":   "large",
+						"text":   "alert1",
+						"type":   "TextBlock",
+						"weight": "bolder",
+						"wrap":   true,
+					}, {
+						"text": "2 alerts are firing, 0 are resolved",
+						"type": "TextBlock",
+						"wrap": true,
+					}, {
+						"facts": []map[string]interface{}{{
+							"title": "lbl1",
+							"value": "val1, val2",
+						}, {
+							"title": "alertname",
+							"value": "alert1",
+						}},
+						"type": "FactSet",
+					}, {
+						"actions": []map[string]interface{}{{
+							"title": "View URL",
+							"type":  "Action.OpenUrl",
+							"url":   "http://localhost/alerting/list",
+						}},
+						"type": "ActionSet",
+					}},
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msTeams": map[string]interface{}{
+						"width": "Full",
+					},
+				},
+				"contentType": "application/vnd.microsoft.card.adaptive",
+			}},
+			"summary": "alert1",
+			"type":    "message",
+		},
+		expMsgError: nil,
+	}, {
+		name: "Custom config with mentions",
+		settings: `{
+	"url": "http://localhost",
+	"title": "{{ .CommonLabels.alertname }}",
+	"message": "{{ len .Alerts.Firing }} alerts are firing, {{ len .Alerts.Resolved }} are resolved",
+	"facts": "severity",
+	"mentions": "user1@example.com, 24fe3b9c-7cc8-4c3e-9a8e-5ab0d6a2c6b1",
+	"mentionSeverity": "critical"
+}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "severity": "critical"},
+					Annotations: model.LabelSet{"ann1": "annv1"},
+				},
+			}, {
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "severity": "warning"},
+					Annotations: model.LabelSet{"ann1": "annv2"},
+				},
+			},
+		},
+		expMsg: map[string]interface{}{
+			"attachments": []map[string]interface{}{{
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"body": []map[string]interface{}{{
+						"color":  "attention",
+						"size":   "large",
+						"text":   "alert1",
+						"type":   "TextBlock",
+						"weight": "bolder",
+						"wrap":   true,
+					}, {
+						"text": "2 alerts are firing, 0 are resolved",
+						"type": "TextBlock",
+						"wrap": true,
+					}, {
+						"text": "<at>user1@example.com</at> <at>24fe3b9c-7cc8-4c3e-9a8e-5ab0d6a2c6b1</at>",
+						"type": "TextBlock",
+						"wrap": true,
+					}, {
+						"facts": []map[string]interface{}{{
+							"title": "severity",
+							"value": "critical, warning",
+						}},
+						"type": "FactSet",
+					}, {
+						"actions": []map[string]interface{}{{
+							"title": "View URL",
+							"type":  "Action.OpenUrl",
+							"url":   "http://localhost/alerting/list",
+						}},
+						"type": "ActionSet",
+					}},
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msTeams": map[string]interface{}{
+						"width": "Full",
+						"entities": []map[string]interface{}{{
+							"type": "mention",
+							"text": "<at>user1@example.com</at>",
+							"mentioned": map[string]interface{}{
+								"id":   "user1@example.com",
+								"name": "user1@example.com",
+							},
+						}, {
+							"type": "mention",
+							"text": "<at>24fe3b9c-7cc8-4c3e-9a8e-5ab0d6a2c6b1</at>",
+							"mentioned": map[string]interface{}{
+								"id":   "24fe3b9c-7cc8-4c3e-9a8e-5ab0d6a2c6b1",
+								"name": "24fe3b9c-7cc8-4c3e-9a8e-5ab0d6a2c6b1",
+							},
+						}},
+					},
+				},
+				"contentType": "application/vnd.microsoft.card.adaptive",
+			}},
+			"summary": "alert1",
+			"type":    "message",
+		},
+		expMsgError: nil,
+	}, {
+		name: "Custom config with mentions below severity",
+		settings: `{
+	"url": "http://localhost",
+	"title": "{{ .CommonLabels.alertname }}",
+	"message": "{{ len .Alerts.Firing }} alerts are firing, {{ len .Alerts.Resolved }} are resolved",
+	"facts": "severity",
+	"mentions": "user1@example.com",
+	"mentionSeverity": "critical"
+}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "severity": "warning"},
+					Annotations: model.LabelSet{"ann1": "annv1"},
+				},
+			},
+		},
+		expMsg: map[string]interface{}{
+			"attachments": []map[string]interface{}{{
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"body": []map[string]interface{}{{
+						"color":  "attention",
+						"size":   "large",
+						"text":   "alert1",
+						"type":   "TextBlock",
+						"weight": "bolder",
+						"wrap":   true,
+					}, {
+						"text": "1 alerts are firing, 0 are resolved",
+						"type": "TextBlock",
+						"wrap": true,
+					}, {
+						"facts": []map[string]interface{}{{
+							"title": "severity",
+							"value": "warning",
+						}},
+						"type": "FactSet",
+					}, {
+						"actions": []map[string]interface{}{{
+							"title": "View URL",
+							"type":  "Action.OpenUrl",
+							"url":   "http://localhost/alerting/list",
+						}},
+						"type": "ActionSet",
+					}},
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msTeams": map[string]interface{}{
+						"width": "Full",
+					},
+				},
+				"contentType": "application/vnd.microsoft.card.adaptive",
+			}},
+			"summary": "alert1",
+			"type":    "message",
+		},
+		expMsgError: nil,
+	}, {
+		name: "Missing field in template",
+		settings: `{
+	"url": "http://localhost",
+	"title": "{{ .CommonLabels.alertname }}",
+	"sectiontitle": "Details",
+	"message": "I'm a custom template {{ .NotAField }} bad template"
+}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+					Annotations: model.LabelSet{"ann1": "annv1"},
+				},
+			}, {
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+					Annotations: model.LabelSet{"ann1": "annv2"},
+				},
+			},
+		},
+		expMsg: map[string]interface{}{
+			"attachments": []map[string]interface{}{{
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"body": []map[string]interface{}{{
+						"color":  "attention",
+						"size":   "large",
+						"text":   "alert1",
+						"type":   "TextBlock",
+						"weight": "bolder",
+						"wrap":   true,
+					}, {
+						"text": "I'm a custom template ",
+						"type": "TextBlock",
+						"wrap": true,
+					}, {
+						"facts": []map[string]interface{}{{
+							"title": "alertname",
+							"value": "alert1",
+						}, {
+							"title": "lbl1",
+							"value": "val1, val2",
+						}},
+						"type": "FactSet",
+					}, {
+						"actions": []map[string]interface{}{{
+							"title": "View URL",
+							"type":  "Action.OpenUrl",
+							"url":   "http://localhost/alerting/list",
+						}},
+						"type": "ActionSet",
+					}},
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msTeams": map[string]interface{}{
+						"width": "Full",
+					},
+				},
+				"contentType": "application/vnd.microsoft.card.adaptive",
+			}},
+			"type": "message",
+		},
+		expMsgError: nil,
+	}, {
+		name: "Invalid template",
+		settings: `{
+				"url": "http://localhost",
+				"title": "{{ .CommonLabels.alertname }}",
+				"sectiontitle": "Details",
+				"message": "I'm a custom template {{ {.NotAField }} bad template"
+			}`,
+		alerts: []*types.Alert{
+			{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+					Annotations: model.LabelSet{"ann1": "annv1"},
+				},
+			}, {
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+					Annotations: model.LabelSet{"ann1": "annv2"},
+				},
+			},
+		},
+		expMsg: map[string]interface{}{
+			"attachments": []map[string]interface{}{{
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"body": []map[string]interface{}{{
+						"color":  "attention",
+						"size":   "large",
+						"text":   "alert1",
+						"type":   "TextBlock",
+						"weight": "bolder",
+						"wrap":   true,
+					}, {
+						"text": "",
+						"type": "TextBlock",
+						"wrap": true,
+					}, {
+						"facts": []map[string]interface{}{{
+							"title": "alertname",
+							"value": "alert1",
+						}, {
+							"title": "lbl1",
+							"value": "val1, val2",
+						}},
+						"type": "FactSet",
+					}, {
+						"actions": []map[string]interface{}{{
+							"title": "View URL",
+							"type":  "Action.OpenUrl",
+							"url":   "http://localhost/alerting/list",
+						}},
+						"type": "ActionSet",
+					}},
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msTeams": map[string]interface{}{
+						"width": "Full",
+					},
+				},
+				"contentType": "application/vnd.microsoft.card.adaptive",
+			}},
+			"type": "message",
+		},
+		expMsgError: nil,
+	}, {
+		name:         "Error in initing",
+		settings:     `{}`,
+		expInitError: `could not find url property in settings`,
+	}, {
+		name:         "Error in initing, mention severity without mentions",
+		settings:     `{"url": "http://localhost", "mentionSeverity": "critical"}`,
+		expInitError: `at least one mention is required when mentionSeverity is set`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settingsJSON := json.RawMessage(c.settings)
+
+			m := &channels.NotificationChannelConfig{
+				Name:     "teams_testing",
+				Type:     "teams",
+				Settings: settingsJSON,
+			}
+
+			webhookSender := mockNotificationService()
+
+			fc := channels.FactoryConfig{
+				Config:              m,
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				Template:            tmpl,
+				Logger:              &channels.FakeLogger{},
+			}
+
+			pn, err := newTeamsNotifier(fc)
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+
+			ok, err := pn.Notify(ctx, c.alerts...)
+			if c.expMsgError != nil {
+				require.False(t, ok)
+				require.Error(t, err)
+				require.Equal(t, c.expMsgError.Error(), err.Error())
+				return
+			}
+			require.True(t, ok)
+			require.NoError(t, err)
+
+			require.NotNil(t, webhookSender.Webhook)
+			lastRequest := webhookSender.Webhook
+
+			require.NotEmpty(t, lastRequest.URL)
+
+			expBody, err := json.Marshal(c.expMsg)
+			require.NoError(t, err)
+
+			require.JSONEq(t, string(expBody), lastRequest.Body)
+
+			require.NotNil(t, lastRequest.Validation)
+		})
+	}
+}
+
+func Test_ValidateResponse(t *testing.T) {
+	require.NoError(t, validateResponse([]byte("1"), rand.Int()))
+	err := validateResponse([]byte("some error message"), rand.Int())
+	require.Error(t, err)
+	require.Equal(t, "some error message", err.Error())
+}
+
+func TestTeamsNotifier_Images(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	images := &fakeImageStore{Images: []*channels.Image{{
+		Token: "image-with-url",
+		URL:   "https://www.example.com/test.png",
+	}, {
+		Token: "image-on-disk",
+		Path:  "/tmp/test.png",
+	}}}
+
+	cases := []struct {
+		name      string
+		settings  string
+		expImages []interface{}
+	}{{
+		name:     "image with URL is shown",
+		settings: `{"url": "http://localhost"}`,
+		expImages: []interface{}{map[string]interface{}{
+			"type":    "Image",
+			"url":     "https://www.example.com/test.png",
+			"msTeams": map[string]interface{}{"allowExpand": true},
+		}},
+	}, {
+		name:     "images are removed if the message is too large",
+		settings: fmt.Sprintf(`{"url": "http://localhost", "message": "%s"}`, strings.Repeat("a", teamsMaxMessageSize)),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "teams_testing",
+					Type:     "teams",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          images,
+				NotificationService: webhookSender,
+				Template:            tmpl,
+				Logger:              &channels.FakeLogger{},
+			}
+
+			tn, err := newTeamsNotifier(fc)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := tn.Notify(ctx, &types.Alert{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert1"},
+					Annotations: model.LabelSet{models.ImageTokenAnnotation: "image-with-url"},
+				},
+			}, &types.Alert{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "alert2"},
+					Annotations: model.LabelSet{models.ImageTokenAnnotation: "image-on-disk"},
+				},
+			})
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			var msg struct {
+				Attachments []struct {
+					Content struct {
+						Body []map[string]interface{} `json:"body"`
+					} `json:"content"`
+				} `json:"attachments"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(webhookSender.Webhook.Body), &msg))
+
+			var imageSets []map[string]interface{}
+			for _, item := range msg.Attachments[0].Content.Body {
+				if item["type"] == "ImageSet" {
+					imageSets = append(imageSets, item)
+				}
+			}
+			if c.expImages == nil {
+				require.Empty(t, imageSets)
+				return
+			}
+			require.Len(t, imageSets, 1)
+			require.Equal(t, "large", imageSets[0]["imageSize"])
+			require.Equal(t, c.expImages, imageSets[0]["images"])
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/hashicorp/go-multierror"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+var (
+	TelegramAPIURL = "https://api.telegram.org/bot%s/%s"
+)
+
+// Telegram supports 4096 chars max - from https://limits.tginfo.me/en.
+const telegramMaxMessageLenRunes = 4096
+
+// telegramAckCallbackPrefixRegexp matches the prefix of the callback data of the
+// acknowledge button. The callback data is limited to 64 bytes, the group key hash
+// takes 33 of them.
+var telegramAckCallbackPrefixRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,31}$`)
+
+const (
+	telegramDefaultAckButtonText     = "Acknowledge"
+	telegramDefaultAckCallbackPrefix = "ack"
+)
+
+// telegramChatIDRegexp matches the ID of a chat, or the username of a channel such as @channelname.
+var telegramChatIDRegexp = regexp.MustCompile(`^(-?[0-9]+|@[A-Za-z][A-Za-z0-9_]{4,31})$`)
+
+// TelegramNotifier is responsible for sending
+// alert notifications to Telegram.
+type TelegramNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	images      channels.ImageStore
+	ns          channels.WebhookSender
+	tmpl        *template.Template
+	settings    telegramSettings
+	maxValueLen int
+}
+
+type telegramSettings struct {
+	BotToken string `json:"bottoken,omitempty" yaml:"bottoken,omitempty"`
+	// ChatID can be templated, such as {{ .CommonLabels.telegram_chat }}, to send alerts
+	// to different chats. It is then templated for each alert.
+	ChatID               string      `json:"chatid,omitempty" yaml:"chatid,omitempty"`
+	MessageThreadID      json.Number `json:"message_thread_id,omitempty" yaml:"message_thread_id,omitempty"`
+	Message              string      `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage      string      `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+	ParseMode            string      `json:"parse_mode,omitempty" yaml:"parse_mode,omitempty"`
+	DisableNotifications bool        `json:"disable_notifications,omitempty" yaml:"disable_notifications,omitempty"`
+	// APIURL is the base URL of a self-hosted Telegram Bot API server. If empty, the public API is used.
+	APIURL string `json:"api_url,omitempty" yaml:"api_url,omitempty"`
+	// AckButton adds an inline button to messages with firing alerts, so a bot can
+	// handle acknowledgements. Its callback data is AckCallbackPrefix, a colon and
+	// the first 32 characters of the hash of the group key.
+	AckButton         bool   `json:"ack_button,omitempty" yaml:"ack_button,omitempty"`
+	AckButtonText     string `json:"ack_button_text,omitempty" yaml:"ack_button_text,omitempty"`
+	AckCallbackPrefix string `json:"ack_callback_prefix,omitempty" yaml:"ack_callback_prefix,omitempty"`
+}
+
+// telegramInlineKeyboardMarkup is the reply_markup of a message with inline buttons.
+// See https://core.telegram.org/bots/api#inlinekeyboardmarkup.
+type telegramInlineKeyboardMarkup struct {
+	InlineKeyboard [][]telegramInlineKeyboardButton `json:"inline_keyboard"`
+}
+
+type telegramInlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+func buildTelegramSettings(fc channels.FactoryConfig) (telegramSettings, error) {
+	settings := telegramSettings{}
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return settings, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	settings.BotToken = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "bottoken", settings.BotToken)
+	if settings.BotToken == "" {
+		return settings, errors.New("could not find Bot Token in settings")
+	}
+	if settings.ChatID == "" {
+		return settings, errors.New("could not find Chat Id in settings")
+	}
+	if settings.MessageThreadID != "" {
+		if id, err := strconv.ParseInt(settings.MessageThreadID.String(), 10, 64); err != nil || id <= 0 {
+			return settings, errors.New("message_thread_id must be a positive integer")
+		}
+	}
+	if settings.APIURL != "" && !isHTTPURL(settings.APIURL) {
+		return settings, errors.New("api_url must be a valid http or https URL")
+	}
+	if settings.Message == "" {
+		settings.Message = channels.DefaultMessageEmbed
+	}
+	if settings.AckButtonText == "" {
+		settings.AckButtonText = telegramDefaultAckButtonText
+	}
+	if settings.AckCallbackPrefix == "" {
+		settings.AckCallbackPrefix = telegramDefaultAckCallbackPrefix
+	}
+	if !telegramAckCallbackPrefixRegexp.MatchString(settings.AckCallbackPrefix) {
+		return settings, fmt.Errorf("invalid value for ack_callback_prefix: %q, must be at most 31 letters, digits, underscores or dashes", settings.AckCallbackPrefix)
+	}
+	// if field is missing, then we fall back to the previous default: HTML
+	if settings.ParseMode == "" {
+		settings.ParseMode = channels.DefaultParseMode
+	}
+	found := false
+	for parseMode, value := range channels.SupportedParseMode {
+		if strings.EqualFold(settings.ParseMode, parseMode) {
+			settings.ParseMode = value
+			found = true
+			break
+		}
+	}
+	if !found {
+		return settings, fmt.Errorf("unknown parse_mode, must be Markdown, MarkdownV2, HTML or None")
+	}
+	return settings, nil
+}
+
+func TelegramFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	notifier, err := newTelegramNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return notifier, nil
+}
+
+// newTelegramNotifier is the constructor for the Telegram notifier
+func newTelegramNotifier(fc channels.FactoryConfig) (*TelegramNotifier, error) {
+	settings, err := buildTelegramSettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(fc)
+	if err != nil {
+		return nil, err
+	}
+	return &TelegramNotifier{
+		Base:        channels.NewBase(fc.Config),
+		tmpl:        fc.Template,
+		log:         fc.Logger,
+		images:      images,
+		ns:          fc.NotificationService,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+	}, nil
+}
+
+// Notify send an alert notification to Telegram. If the chat ID is templated, the
+// alerts are grouped by their chat ID and a message is sent to each chat.
+func (tn *TelegramNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	if !strings.Contains(tn.settings.ChatID, "{{") {
+		return tn.notify(ctx, tn.settings.ChatID, as)
+	}
+
+	var (
+		chatIDs []string
+		groups  = make(map[string][]*types.Alert)
+		errs    *multierror.Error
+	)
+	for _, a := range as {
+		chatID, err := tn.alertChatID(ctx, a)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		if _, ok := groups[chatID]; !ok {
+			chatIDs = append(chatIDs, chatID)
+		}
+		groups[chatID] = append(groups[chatID], a)
+	}
+
+	for _, chatID := range chatIDs {
+		if _, err := tn.notify(ctx, chatID, groups[chatID]); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// alertChatID returns the chat ID templated for the alert. It returns an error if
+// the chat ID is neither an integer nor the username of a channel.
+func (tn *TelegramNotifier) alertChatID(ctx context.Context, a *types.Alert) (string, error) {
+	var tmplErr error
+	tmpl, _ := tmplText(ctx, tn.tmpl, []*types.Alert{a}, tn.log, &tmplErr, tn.maxValueLen)
+	chatID := strings.TrimSpace(tmpl(tn.settings.ChatID))
+	if tmplErr != nil {
+		return "", fmt.Errorf("failed to template chat_id of alert %s: %w", a.Name(), tmplErr)
+	}
+	if !telegramChatIDRegexp.MatchString(chatID) {
+		return "", fmt.Errorf("invalid chat_id %q of alert %s, must be an integer or @channelname", chatID, a.Name())
+	}
+	return chatID, nil
+}
+
+func (tn *TelegramNotifier) notify(ctx context.Context, chatID string, as []*types.Alert) (bool, error) {
+	// Create the cmd for sendMessage
+	cmd, err := tn.newWebhookSyncCmd(chatID, "sendMessage", func(w *multipart.Writer) error {
+		msg, err := tn.buildTelegramMessage(ctx, as)
+		if err != nil {
+			return fmt.Errorf("failed to build message: %w", err)
+		}
+		for k, v := range msg {
+			fw, err := w.CreateFormField(k)
+			if err != nil {
+				return fmt.Errorf("failed to create form field: %w", err)
+			}
+			if _, err := fw.Write([]byte(v)); err != nil {
+				return fmt.Errorf("failed to write value: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create telegram message: %w", err)
+	}
+	chunks := []messageChunk{func(ctx context.Context) error {
+		if err := tn.ns.SendWebhook(ctx, cmd); err != nil {
+			return fmt.Errorf("failed to send telegram message: %w", err)
+		}
+		return nil
+	}}
+
+	// Each image is uploaded in a message after the message of the alerts.
+	_ = withStoredImages(ctx, tn.log, tn.images, func(index int, image channels.Image) error {
+		chunks = append(chunks, func(ctx context.Context) error {
+			cmd, err := tn.newWebhookSyncCmd(chatID, "sendPhoto", func(w *multipart.Writer) error {
+				f, err := os.Open(image.Path)
+				if err != nil {
+					return fmt.Errorf("failed to open image: %w", err)
+				}
+				defer func() {
+					if err := f.Close(); err != nil {
+						tn.log.Warn("failed to close image", "error", err)
+					}
+				}()
+				return writeMultipartFile(w, "photo", image.Path, f)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create image: %w", err)
+			}
+			if err := tn.ns.SendWebhook(ctx, cmd); err != nil {
+				return fmt.Errorf("failed to upload image to telegram: %w", err)
+			}
+			return nil
+		})
+		return nil
+	}, as...)
+
+	// The alerts are delivered once their message is sent, so images that fail to upload
+	// do not fail the notification.
+	delivered, err := sendChunks(ctx, tn.log.New("chat_id", chatID), chunks...)
+	if !delivered {
+		return false, err
+	}
+	if err != nil {
+		tn.log.Warn("failed to upload images to telegram", "error", err)
+	}
+	return true, nil
+}
+
+func (tn *TelegramNotifier) buildTelegramMessage(ctx context.Context, as []*types.Alert) (map[string]string, error) {
+	var tmplErr error
+	defer func() {
+		if tmplErr != nil {
+			tn.log.Warn("failed to template Telegram message", "error", tmplErr)
+		}
+	}()
+
+	tmpl, _ := tmplText(ctx, tn.tmpl, as, tn.log, &tmplErr, tn.maxValueLen)
+	// Telegram supports 4096 chars max
+	messageText, truncated := channels.TruncateInRunes(tmpl(messageForAlerts(tn.settings.Message, tn.settings.ResolvedMessage, as)), telegramMaxMessageLenRunes)
+	if truncated {
+		key, err := notify.ExtractGroupKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tn.log.Warn("Truncated message", "alert", key, "max_runes", telegramMaxMessageLenRunes)
+	}
+
+	m := make(map[string]string)
+	m["text"] = messageText
+	if tn.settings.ParseMode != "" {
+		m["parse_mode"] = tn.settings.ParseMode
+	}
+	if tn.settings.DisableNotifications {
+		m["disable_notification"] = "true"
+	}
+	// Resolved alerts do not need to be acknowledged.
+	if tn.settings.AckButton && types.Alerts(as...).Status() == model.AlertFiring {
+		replyMarkup, err := tn.ackReplyMarkup(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m["reply_markup"] = replyMarkup
+	}
+	return m, nil
+}
+
+// ackReplyMarkup returns the reply_markup with the acknowledge button for the group.
+func (tn *TelegramNotifier) ackReplyMarkup(ctx context.Context) (string, error) {
+	key, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(telegramInlineKeyboardMarkup{
+		InlineKeyboard: [][]telegramInlineKeyboardButton{{{
+			Text:         tn.settings.AckButtonText,
+			CallbackData: tn.settings.AckCallbackPrefix + ":" + key.Hash()[:32],
+		}}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal reply_markup: %w", err)
+	}
+	return string(b), nil
+}
+
+func (tn *TelegramNotifier) newWebhookSyncCmd(chatID, action string, fn func(writer *multipart.Writer) error) (*channels.SendWebhookSettings, error) {
+	b := bytes.Buffer{}
+	w := multipart.NewWriter(&b)
+
+	boundary := GetBoundary()
+	if boundary != "" {
+		if err := w.SetBoundary(boundary); err != nil {
+			return nil, err
+		}
+	}
+
+	fw, err := w.CreateFormField("chat_id")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write([]byte(chatID)); err != nil {
+		return nil, err
+	}
+
+	// The message_thread_id is the topic in a supergroup that both messages and photos are sent to.
+	if tn.settings.MessageThreadID != "" {
+		fw, err := w.CreateFormField("message_thread_id")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write([]byte(tn.settings.MessageThreadID.String())); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := fn(w); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart: %w", err)
+	}
+
+	u := fmt.Sprintf(TelegramAPIURL, tn.settings.BotToken, action)
+	if tn.settings.APIURL != "" {
+		u = joinUrlPath(tn.settings.APIURL, fmt.Sprintf("bot%s/%s", tn.settings.BotToken, action), tn.log)
+	}
+
+	cmd := &channels.SendWebhookSettings{
+		URL:        u,
+		Body:       b.String(),
+		HTTPMethod: "POST",
+		HTTPHeader: map[string]string{
+			"Content-Type": w.FormDataContentType(),
+		},
+	}
+	return cmd, nil
+}
+
+func (tn *TelegramNotifier) SendResolved() bool {
+	return !tn.GetDisableResolveMessage()
+}
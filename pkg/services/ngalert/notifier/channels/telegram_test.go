@@ -0,0 +1,497 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegramNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	// The callback data of the acknowledge button encodes the hash of the group key.
+	groupKeyHash := notify.Key("alertname").Hash()[:32]
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expMsg       map[string]string
+		expThreadID  string
+		expURL       string
+		expInitError string
+		expMsgError  error
+	}{
+		{
+			name: "A single alert with default template",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"parse_mode": "markdown",
+				"disable_notifications": true
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:       model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations:  model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh", "__alertImageToken__": "test-image-1"},
+						GeneratorURL: "a URL",
+					},
+				},
+			},
+			expMsg: map[string]string{
+				"parse_mode":           "Markdown",
+				"text":                 "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSource: a URL\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+				"disable_notification": "true",
+			},
+			expMsgError: nil,
+		}, {
+			name: "Multiple alerts with custom template",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"message": "__Custom Firing__\n{{len .Alerts.Firing}} Firing\n{{ template \"__text_alert_list\" .Alerts.Firing }}"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:       model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations:  model.LabelSet{"ann1": "annv1", "__alertImageToken__": "test-image-1"},
+						GeneratorURL: "a URL",
+					},
+				}, {
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+						Annotations: model.LabelSet{"ann1": "annv2", "__alertImageToken__": "test-image-2"},
+					},
+				},
+			},
+			expMsg: map[string]string{
+				"parse_mode": "HTML",
+				"text":       "__Custom Firing__\n2 Firing\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSource: a URL\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val2\nAnnotations:\n - ann1 = annv2\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval2\n",
+			},
+			expMsgError: nil,
+		}, {
+			name: "Truncate long message",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"message": "{{ .CommonLabels.alertname }}"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": model.LabelValue(strings.Repeat("1", 4097))},
+					},
+				},
+			},
+			expMsg: map[string]string{
+				"parse_mode": "HTML",
+				"text":       strings.Repeat("1", 4096-1) + "…",
+			},
+			expMsgError: nil,
+		}, {
+			name: "Message thread ID",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"message_thread_id": "12",
+				"message": "{{ .CommonLabels.alertname }}"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1"},
+					},
+				},
+			},
+			expMsg: map[string]string{
+				"parse_mode": "HTML",
+				"text":       "alert1",
+			},
+			expThreadID: "12",
+			expMsgError: nil,
+		}, {
+			name: "Custom API URL",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"api_url": "http://telegram-bot-api:8081/base",
+				"message": "{{ .CommonLabels.alertname }}"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1"},
+					},
+				},
+			},
+			expMsg: map[string]string{
+				"parse_mode": "HTML",
+				"text":       "alert1",
+			},
+			expURL:      "http://telegram-bot-api:8081/base/botabcdefgh0123456789/sendMessage",
+			expMsgError: nil,
+		}, {
+			name: "Acknowledge button",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"message": "{{ .CommonLabels.alertname }}",
+				"ack_button": true
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1"},
+					},
+				},
+			},
+			expMsg: map[string]string{
+				"parse_mode":   "HTML",
+				"text":         "alert1",
+				"reply_markup": `{"inline_keyboard":[[{"text":"Acknowledge","callback_data":"ack:` + groupKeyHash + `"}]]}`,
+			},
+		}, {
+			name: "Acknowledge button with custom text and callback prefix",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"message": "{{ .CommonLabels.alertname }}",
+				"ack_button": true,
+				"ack_button_text": "Ack 👍",
+				"ack_callback_prefix": "grafana-ack"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1"},
+					},
+				},
+			},
+			expMsg: map[string]string{
+				"parse_mode":   "HTML",
+				"text":         "alert1",
+				"reply_markup": `{"inline_keyboard":[[{"text":"Ack 👍","callback_data":"grafana-ack:` + groupKeyHash + `"}]]}`,
+			},
+		}, {
+			name: "No acknowledge button for resolved alerts",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"message": "{{ .CommonLabels.alertname }}",
+				"ack_button": true
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1"},
+						EndsAt: time.Now().Add(-time.Minute),
+					},
+				},
+			},
+			expMsg: map[string]string{
+				"parse_mode": "HTML",
+				"text":       "alert1",
+			},
+		}, {
+			name: "Resolved message for resolved alerts",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"message": "{{ len .Alerts.Firing }} alerts are firing",
+				"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1"},
+						EndsAt: time.Now().Add(-time.Minute),
+					},
+				},
+			},
+			expMsg: map[string]string{
+				"parse_mode": "HTML",
+				"text":       "1 alerts are resolved",
+			},
+		}, {
+			name:         "Error in initing",
+			settings:     `{}`,
+			expInitError: `could not find Bot Token in settings`,
+		}, {
+			name: "Invalid parse mode",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"parse_mode": "test"
+			}`,
+			expInitError: "unknown parse_mode, must be Markdown, MarkdownV2, HTML or None",
+		}, {
+			name: "Invalid message thread ID",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"message_thread_id": -1
+			}`,
+			expInitError: "message_thread_id must be a positive integer",
+		}, {
+			name: "Invalid API URL",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"api_url": "telegram-bot-api:8081"
+			}`,
+			expInitError: "api_url must be a valid http or https URL",
+		}, {
+			name: "Invalid acknowledge callback prefix",
+			settings: `{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "someid",
+				"ack_button": true,
+				"ack_callback_prefix": "ack:"
+			}`,
+			expInitError: `invalid value for ack_callback_prefix: "ack:", must be at most 31 letters, digits, underscores or dashes`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settingsJSON := json.RawMessage(c.settings)
+			require.NoError(t, err)
+			secureSettings := make(map[string][]byte)
+
+			notificationService := mockNotificationService()
+
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:           "telegram_tests",
+					Type:           "telegram",
+					Settings:       settingsJSON,
+					SecureSettings: secureSettings,
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: notificationService,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			n, err := newTelegramNotifier(fc)
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := n.Notify(ctx, c.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			_, params, err := mime.ParseMediaType(notificationService.Webhook.HTTPHeader["Content-Type"])
+			require.NoError(t, err)
+			form, err := multipart.NewReader(strings.NewReader(notificationService.Webhook.Body), params["boundary"]).ReadForm(1024)
+			require.NoError(t, err)
+			require.Equal(t, []string{"someid"}, form.Value["chat_id"])
+			if c.expURL != "" {
+				require.Equal(t, c.expURL, notificationService.Webhook.URL)
+			} else {
+				require.Equal(t, "https://api.telegram.org/botabcdefgh0123456789/sendMessage", notificationService.Webhook.URL)
+			}
+			if c.expThreadID != "" {
+				require.Equal(t, []string{c.expThreadID}, form.Value["message_thread_id"])
+			} else {
+				require.NotContains(t, form.Value, "message_thread_id")
+			}
+
+			msg, err := n.buildTelegramMessage(ctx, c.alerts)
+			if c.expMsgError != nil {
+				require.Error(t, err)
+				require.Equal(t, c.expMsgError.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expMsg, msg)
+		})
+	}
+}
+
+func TestTelegramNotifier_TemplatedChatID(t *testing.T) {
+	tmpl := templateForTests(t)
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	sender := &webhookRecordingSender{}
+	n, err := newTelegramNotifier(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Name: "telegram_tests",
+			Type: "telegram",
+			Settings: json.RawMessage(`{
+				"bottoken": "abcdefgh0123456789",
+				"chatid": "{{ .CommonLabels.telegram_chat }}",
+				"message": "{{ range .Alerts }}{{ .Labels.alertname }} {{ end }}"
+			}`),
+			SecureSettings: map[string][]byte{},
+		},
+		ImageStore:          &channels.UnavailableImageStore{},
+		NotificationService: sender,
+		DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+			return fallback
+		},
+		Template: tmpl,
+		Logger:   &channels.FakeLogger{},
+	})
+	require.NoError(t, err)
+
+	alerts := []*types.Alert{{
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "telegram_chat": "-1001234"}},
+	}, {
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2", "telegram_chat": "@ops_alerts"}},
+	}, {
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert3", "telegram_chat": "-1001234"}},
+	}, {
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert4", "telegram_chat": "ops"}},
+	}}
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+	ok, err := n.Notify(ctx, alerts...)
+	require.EqualError(t, err, "1 error occurred:\n\t* invalid chat_id \"ops\" of alert alert4, must be an integer or @channelname\n\n")
+	require.False(t, ok)
+
+	type message struct {
+		chatID string
+		text   string
+	}
+	var messages []message
+	for _, r := range sender.requests {
+		_, params, err := mime.ParseMediaType(r.HTTPHeader["Content-Type"])
+		require.NoError(t, err)
+		form, err := multipart.NewReader(strings.NewReader(r.Body), params["boundary"]).ReadForm(1024)
+		require.NoError(t, err)
+		messages = append(messages, message{chatID: form.Value["chat_id"][0], text: form.Value["text"][0]})
+	}
+	require.Equal(t, []message{
+		{chatID: "-1001234", text: "alert1 alert3 "},
+		{chatID: "@ops_alerts", text: "alert2 "},
+	}, messages)
+}
+
+// failingRequestSender records the requests and fails the request with the number, starting at 1.
+type failingRequestSender struct {
+	webhookRecordingSender
+	failRequest int
+}
+
+func (ns *failingRequestSender) SendWebhook(ctx context.Context, cmd *channels.SendWebhookSettings) error {
+	ns.requests = append(ns.requests, *cmd)
+	if len(ns.requests) == ns.failRequest {
+		return errors.New("failed to send request")
+	}
+	return nil
+}
+
+func TestTelegramNotifier_Images(t *testing.T) {
+	images := &fakeImageStore{}
+	var alerts []*types.Alert
+	for i := 1; i <= 3; i++ {
+		f, err := os.Create(filepath.Join(t.TempDir(), fmt.Sprintf("test-image-%d.png", i)))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		images.Images = append(images.Images, &channels.Image{Token: fmt.Sprintf("test-image-%d", i), Path: f.Name()})
+		alerts = append(alerts, &types.Alert{Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": model.LabelValue(fmt.Sprintf("alert%d", i))},
+			Annotations: model.LabelSet{"__alertImageToken__": model.LabelValue(fmt.Sprintf("test-image-%d", i))},
+		}})
+	}
+
+	cases := []struct {
+		name        string
+		failRequest int
+		expURLs     []string
+		expError    string
+	}{{
+		name: "Images are uploaded after the message",
+		expURLs: []string{
+			"https://api.telegram.org/botabcdefgh0123456789/sendMessage",
+			"https://api.telegram.org/botabcdefgh0123456789/sendPhoto",
+			"https://api.telegram.org/botabcdefgh0123456789/sendPhoto",
+			"https://api.telegram.org/botabcdefgh0123456789/sendPhoto",
+		},
+	}, {
+		name:        "Images after an image that fails to upload are uploaded",
+		failRequest: 3,
+		expURLs: []string{
+			"https://api.telegram.org/botabcdefgh0123456789/sendMessage",
+			"https://api.telegram.org/botabcdefgh0123456789/sendPhoto",
+			"https://api.telegram.org/botabcdefgh0123456789/sendPhoto",
+			"https://api.telegram.org/botabcdefgh0123456789/sendPhoto",
+		},
+	}, {
+		name:        "Images are not uploaded if the message fails",
+		failRequest: 1,
+		expURLs:     []string{"https://api.telegram.org/botabcdefgh0123456789/sendMessage"},
+		expError:    "failed to send telegram message: failed to send request",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sender := &failingRequestSender{failRequest: c.failRequest}
+			n, err := newTelegramNotifier(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:           "telegram_tests",
+					Type:           "telegram",
+					Settings:       json.RawMessage(`{"bottoken": "abcdefgh0123456789", "chatid": "someid"}`),
+					SecureSettings: map[string][]byte{},
+				},
+				ImageStore:          images,
+				NotificationService: sender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: templateForTests(t),
+				Logger:   &channels.FakeLogger{},
+			})
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := n.Notify(ctx, alerts...)
+			if c.expError != "" {
+				require.EqualError(t, err, c.expError)
+				require.False(t, ok)
+			} else {
+				require.NoError(t, err)
+				require.True(t, ok)
+			}
+
+			var urls []string
+			for _, r := range sender.requests {
+				urls = append(urls, r.URL)
+			}
+			require.Equal(t, c.expURLs, urls)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package channels
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	tmpltext "text/template"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// templateDelims are the delimiters of the actions in the templates of the settings of
+// a notifier, such as [[ and ]] for templates with literal {{ and }}. The zero value
+// is the default delimiters {{ and }}.
+type templateDelims struct {
+	left  string
+	right string
+}
+
+// buildTemplateDelims returns the left_delim and right_delim settings of the notifier.
+// They must be set together.
+func buildTemplateDelims(fc channels.FactoryConfig) (templateDelims, error) {
+	var settings struct {
+		LeftDelim  string `json:"left_delim,omitempty" yaml:"left_delim,omitempty"`
+		RightDelim string `json:"right_delim,omitempty" yaml:"right_delim,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return templateDelims{}, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if (settings.LeftDelim == "") != (settings.RightDelim == "") {
+		return templateDelims{}, errors.New("left_delim and right_delim must be set together")
+	}
+	for name, delim := range map[string]string{"left_delim": settings.LeftDelim, "right_delim": settings.RightDelim} {
+		if strings.ContainsAny(delim, " \t\r\n") {
+			return templateDelims{}, fmt.Errorf("invalid value for %s: %q, must not contain whitespace", name, delim)
+		}
+	}
+	return templateDelims{left: settings.LeftDelim, right: settings.RightDelim}, nil
+}
+
+// isDefault returns true if the delimiters are {{ and }}.
+func (d templateDelims) isDefault() bool {
+	return (d.left == "" || d.left == "{{") && (d.right == "" || d.right == "}}")
+}
+
+// convert returns the template with its delimiters replaced by {{ and }}, as templates
+// are executed with the templates of the notifier, which are parsed with the default
+// delimiters. Text between actions is replaced by actions that print it as a string,
+// so literal {{ and }} are kept as text.
+func (d templateDelims) convert(text string) (string, error) {
+	if text == "" || d.isDefault() {
+		return text, nil
+	}
+	if _, err := tmpltext.New("").Delims(d.left, d.right).Funcs(tmpltext.FuncMap(template.DefaultFuncs)).Parse(text); err != nil {
+		return "", err
+	}
+
+	var (
+		b        strings.Builder
+		trimNext bool
+	)
+	for text != "" {
+		i := strings.Index(text, d.left)
+		if i < 0 {
+			writeTemplateText(&b, text, trimNext, false)
+			break
+		}
+		action, rest, ok := cutAction(text[i+len(d.left):], d.right)
+		if !ok {
+			return "", errors.New("unclosed action")
+		}
+		// Trim markers trim the text next to the action, which is no longer trimmed by
+		// the parser once it is printed by an action.
+		writeTemplateText(&b, text[:i], trimNext, hasLeftTrimMarker(action))
+		b.WriteString("{{" + action + "}}")
+		trimNext = hasRightTrimMarker(action)
+		text = rest
+	}
+	return b.String(), nil
+}
+
+// cutAction returns the action before the right delimiter, and the text after it.
+// The right delimiter is not matched in strings and comments.
+func cutAction(s, right string) (string, string, bool) {
+	// Comments are the only content of their action, and can have trim markers.
+	body := s
+	if hasLeftTrimMarker(s) {
+		body = s[2:]
+	}
+	if strings.HasPrefix(body, "/*") {
+		end := strings.Index(s, "*/")
+		if end < 0 {
+			return "", "", false
+		}
+		i := strings.Index(s[end:], right)
+		if i < 0 {
+			return "", "", false
+		}
+		return s[:end+i], s[end+i+len(right):], true
+	}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			quote := s[i]
+			for i++; i < len(s) && s[i] != quote; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			end := strings.IndexByte(s[i+1:], '`')
+			if end < 0 {
+				return "", "", false
+			}
+			i += end + 1
+		default:
+			if strings.HasPrefix(s[i:], right) {
+				return s[:i], s[i+len(right):], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// hasLeftTrimMarker returns true if the action starts with a trim marker, such as {{- .X }}.
+func hasLeftTrimMarker(action string) bool {
+	return len(action) >= 2 && action[0] == '-' && strings.ContainsRune(" \t\r\n", rune(action[1]))
+}
+
+// hasRightTrimMarker returns true if the action ends with a trim marker, such as {{ .X -}}.
+func hasRightTrimMarker(action string) bool {
+	n := len(action)
+	return n >= 2 && action[n-1] == '-' && strings.ContainsRune(" \t\r\n", rune(action[n-2]))
+}
+
+// writeTemplateText writes the text of a template, trimmed of leading or trailing
+// whitespace. Text with braces is written as an action that prints it as a string.
+func writeTemplateText(b *strings.Builder, text string, trimLeft, trimRight bool) {
+	if trimLeft {
+		text = strings.TrimLeft(text, " \t\r\n")
+	}
+	if trimRight {
+		text = strings.TrimRight(text, " \t\r\n")
+	}
+	if strings.ContainsAny(text, "{}") {
+		text = "{{" + strconv.Quote(text) + "}}"
+	}
+	b.WriteString(text)
+}
@@ -0,0 +1,66 @@
+package channels
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateDelims_Convert(t *testing.T) {
+	tmpl := templateForTests(t)
+	delims := templateDelims{left: "[[", right: "]]"}
+
+	cases := []struct {
+		name     string
+		template string
+		expText  string
+		expError string
+	}{{
+		name:     "Actions with custom delimiters",
+		template: `[[ .Status ]]: [[ range .Alerts ]][[ .Labels.alertname ]] [[ end ]]`,
+		expText:  `firing: alert1 alert2 `,
+	}, {
+		name:     "Default delimiters are text",
+		template: `{{ .Status }} {"a": {"b": [[ len .Alerts ]]}}{`,
+		expText:  `{{ .Status }} {"a": {"b": 2}}{`,
+	}, {
+		name:     "Trim markers",
+		template: "{{ a }}  \n[[- .Status -]]\n  {{ b }}",
+		expText:  "{{ a }}firing{{ b }}",
+	}, {
+		name:     "Delimiters in strings and comments",
+		template: `[[ "]]" ]] [[ ` + "`[[ ]]`" + ` ]] [[/* ]] */]]x [[- /* ]] */ -]] y`,
+		expText:  `]] [[ ]] xy`,
+	}, {
+		name:     "Defined templates",
+		template: `[[ define "name" ]]{[[ .Labels.alertname ]]}[[ end ]][[ range .Alerts ]][[ template "name" . ]][[ end ]]`,
+		expText:  `{alert1}{alert2}`,
+	}, {
+		name:     "Invalid template",
+		template: `[[ .Status `,
+		expError: "template: :1: unclosed action",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			text, err := delims.convert(c.template)
+			if c.expError != "" {
+				require.EqualError(t, err, c.expError)
+				return
+			}
+			require.NoError(t, err)
+
+			var tmplErr error
+			fn, _ := tmplText(context.Background(), tmpl, []*types.Alert{
+				{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}},
+				{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2"}}},
+			}, &channels.FakeLogger{}, &tmplErr, 0)
+			require.Equal(t, c.expText, fn(text))
+			require.NoError(t, tmplErr)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package channels
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+)
+
+func init() {
+	// Functions must be added to the default functions as these are the functions
+	// available to templates when they are parsed.
+	template.DefaultFuncs["alertsTable"] = alertsTable
+	template.DefaultFuncs["formatValue"] = formatValue
+	template.DefaultFuncs["formatValueWithUnit"] = formatValueWithUnit
+	template.DefaultFuncs["severityIcon"] = severityIcon
+	template.DefaultFuncs["statusColor"] = statusColor
+}
+
+// markdownTableEscaper escapes text so it can be used in a cell of a Markdown table.
+var markdownTableEscaper = strings.NewReplacer(
+	`|`, `\|`,
+	"\r\n", " ",
+	"\n", " ",
+)
+
+// alertsTable returns a Markdown table of the alerts with a row for each alert
+// containing its name, severity and value.
+func alertsTable(alerts []channels.ExtendedAlert) string {
+	var b strings.Builder
+	b.WriteString("| Alert | Severity | Value |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	for _, alert := range alerts {
+		b.WriteString("| ")
+		b.WriteString(markdownTableEscaper.Replace(alert.Labels["alertname"]))
+		b.WriteString(" | ")
+		b.WriteString(markdownTableEscaper.Replace(alert.Labels["severity"]))
+		b.WriteString(" | ")
+		b.WriteString(markdownTableEscaper.Replace(alert.ValueString))
+		b.WriteString(" |\n")
+	}
+	return b.String()
+}
+
+// formatValue formats the value of an alert with precision decimal places, or returns
+// placeholder if there is no value. The value can be a number, such as a value in
+// .Values, or all of .Values. In that case the values are formatted as a comma separated
+// list of refID=value sorted by refID. NaN and infinite values are formatted as NaN,
+// +Inf and -Inf.
+//
+//	{{ formatValue 2 "[no value]" .Values }}
+func formatValue(precision int, placeholder string, value interface{}) (string, error) {
+	if precision < 0 {
+		return "", fmt.Errorf("invalid precision %d, must be a non-negative integer", precision)
+	}
+	switch v := value.(type) {
+	case nil:
+		return placeholder, nil
+	case map[string]float64:
+		if len(v) == 0 {
+			return placeholder, nil
+		}
+		refIDs := make([]string, 0, len(v))
+		for refID := range v {
+			refIDs = append(refIDs, refID)
+		}
+		sort.Strings(refIDs)
+		values := make([]string, 0, len(v))
+		for _, refID := range refIDs {
+			values = append(values, refID+"="+formatFloat(v[refID], precision))
+		}
+		return strings.Join(values, ", "), nil
+	case float64:
+		return formatFloat(v, precision), nil
+	case float32:
+		return formatFloat(float64(v), precision), nil
+	case int:
+		return formatFloat(float64(v), precision), nil
+	case int64:
+		return formatFloat(float64(v), precision), nil
+	default:
+		return "", fmt.Errorf("cannot format value of type %T", value)
+	}
+}
+
+func formatFloat(v float64, precision int) string {
+	if math.IsNaN(v) {
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'f', precision, 64)
+}
+
+// unitAnnotation is the annotation with the unit of the values of an alert, such as
+// bytes, used by formatValueWithUnit.
+const unitAnnotation = "unit"
+
+// unitScale is a unit that a value is scaled to, such as GB for bytes.
+type unitScale struct {
+	factor float64
+	suffix string
+}
+
+// unitScales are the units that values in the units are scaled to, from the smallest
+// to the largest. Values are scaled to the largest unit they are at least 1 of.
+var unitScales = map[string][]unitScale{
+	"bytes":   {{1, " B"}, {1e3, " kB"}, {1e6, " MB"}, {1e9, " GB"}, {1e12, " TB"}, {1e15, " PB"}},
+	"ibytes":  {{1, " B"}, {1 << 10, " KiB"}, {1 << 20, " MiB"}, {1 << 30, " GiB"}, {1 << 40, " TiB"}, {1 << 50, " PiB"}},
+	"bits":    {{1, " b"}, {1e3, " kb"}, {1e6, " Mb"}, {1e9, " Gb"}, {1e12, " Tb"}},
+	"seconds": {{1e-9, " ns"}, {1e-6, " µs"}, {1e-3, " ms"}, {1, " s"}, {60, " min"}, {3600, " h"}, {86400, " d"}},
+	"percent": {{1, "%"}},
+	// percentunit is a ratio, such as 0.5 for 50%.
+	"percentunit": {{0.01, "%"}},
+}
+
+// unitAliases are other names of the units in unitScales.
+var unitAliases = map[string]string{
+	"decbytes": "bytes",
+	"s":        "seconds",
+	"%":        "percent",
+}
+
+// formatValueWithUnit formats the value of an alert with its unit, such as 1.5 GB for
+// 1500000000 bytes. The value can be an alert, in which case its values are formatted
+// with the unit in its unit annotation, or a number or map of values with the unit
+// as the second argument. The unit of an alert can be overridden by the second
+// argument. Values are formatted with at most 2 decimal places, and values in unknown
+// units are formatted followed by the unit.
+//
+//	{{ range .Alerts }}{{ .Labels.alertname }}: {{ formatValueWithUnit . }}{{ end }}
+//	{{ formatValueWithUnit 0.25 "percentunit" }}
+func formatValueWithUnit(value interface{}, unit ...string) (string, error) {
+	if len(unit) > 1 {
+		return "", fmt.Errorf("invalid unit, must be at most one unit")
+	}
+	var u string
+	if alert, ok := value.(channels.ExtendedAlert); ok {
+		u = alert.Annotations[unitAnnotation]
+		value = alert.Values
+	}
+	if len(unit) == 1 {
+		u = unit[0]
+	}
+
+	switch v := value.(type) {
+	case map[string]float64:
+		if len(v) == 0 {
+			return "[no value]", nil
+		}
+		refIDs := make([]string, 0, len(v))
+		for refID := range v {
+			refIDs = append(refIDs, refID)
+		}
+		sort.Strings(refIDs)
+		values := make([]string, 0, len(v))
+		for _, refID := range refIDs {
+			values = append(values, refID+"="+formatFloatWithUnit(v[refID], u))
+		}
+		return strings.Join(values, ", "), nil
+	case float64:
+		return formatFloatWithUnit(v, u), nil
+	case float32:
+		return formatFloatWithUnit(float64(v), u), nil
+	case int:
+		return formatFloatWithUnit(float64(v), u), nil
+	case int64:
+		return formatFloatWithUnit(float64(v), u), nil
+	default:
+		return "", fmt.Errorf("cannot format value of type %T", value)
+	}
+}
+
+// formatFloatWithUnit formats the value scaled to the largest unit of the unit that it is
+// at least 1 of, or followed by the unit if the unit is not known.
+func formatFloatWithUnit(v float64, unit string) string {
+	name := strings.ToLower(strings.TrimSpace(unit))
+	if alias, ok := unitAliases[name]; ok {
+		name = alias
+	}
+	scales, ok := unitScales[name]
+	if !ok {
+		s := trimDecimals(formatFloat(v, 2))
+		if unit == "" {
+			return s
+		}
+		return s + " " + unit
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return formatFloat(v, 2) + scales[0].suffix
+	}
+	scale := scales[0]
+	for _, s := range scales[1:] {
+		if math.Abs(v) >= s.factor {
+			scale = s
+		}
+	}
+	// Values smaller than the smallest unit, other than 0, are scaled to it.
+	return trimDecimals(formatFloat(v/scale.factor, 2)) + scale.suffix
+}
+
+// trimDecimals removes trailing zeros of the decimals of a formatted number, such as
+// 1.50 to 1.5 and 2.00 to 2.
+func trimDecimals(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// defaultSeverityIcons are the icons of severities when no mapping is given to severityIcon.
+var defaultSeverityIcons = map[string]string{
+	"critical": "🔴",
+	"warning":  "🟠",
+	"info":     "🟢",
+}
+
+// severityIcon returns the icon for the severity label of the notification, an alert
+// or a set of labels, or an empty string if there is no icon for the severity. For a
+// notification the severity is taken from the common labels. The default icons can be
+// overridden, and icons for other severities added, with pairs of severity and icon.
+// Severities are case insensitive.
+//
+//	{{ severityIcon . }} {{ .CommonLabels.alertname }}
+//	{{ range .Alerts }}{{ severityIcon . "critical" "🔥" "major" "🟠" }}{{ end }}
+func severityIcon(value interface{}, mapping ...string) (string, error) {
+	if len(mapping)%2 != 0 {
+		return "", fmt.Errorf("invalid severity mapping, must be pairs of severity and icon")
+	}
+	var severity string
+	switch v := value.(type) {
+	case *channels.ExtendedData:
+		severity = v.CommonLabels["severity"]
+	case channels.ExtendedData:
+		severity = v.CommonLabels["severity"]
+	case channels.ExtendedAlert:
+		severity = v.Labels["severity"]
+	case template.KV:
+		severity = v["severity"]
+	default:
+		return "", fmt.Errorf("cannot get severity of type %T", value)
+	}
+	severity = strings.ToLower(severity)
+	for i := 0; i < len(mapping); i += 2 {
+		if strings.EqualFold(mapping[i], severity) {
+			return mapping[i+1], nil
+		}
+	}
+	return defaultSeverityIcons[severity], nil
+}
+
+// statusColor returns the color of the status of a notification or an alert, such as
+// firing, as a hex color for styling HTML and Markdown messages.
+//
+//	<h1 style="color: {{ statusColor .Status }}">{{ .CommonLabels.alertname }}</h1>
+func statusColor(status string) string {
+	return getAlertStatusColor(model.AlertStatus(status))
+}
@@ -0,0 +1,329 @@
+package channels
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertsTable(t *testing.T) {
+	tmpl := templateForTests(t)
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	alerts := []*types.Alert{{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1", "severity": "critical"},
+			Annotations: model.LabelSet{"__value_string__": "[ var='A' labels={} value=1 ]"},
+		},
+	}, {
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "alert2", "severity": "warning|info"},
+		},
+	}, {
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "alert3"},
+		},
+	}}
+
+	var tmplErr error
+	fn, _ := tmplText(ctx, tmpl, alerts, &channels.FakeLogger{}, &tmplErr, 0)
+
+	expected := "| Alert | Severity | Value |\n" +
+		"| --- | --- | --- |\n" +
+		"| alert1 | critical | [ var='A' labels={} value=1 ] |\n" +
+		"| alert2 | warning\\|info |  |\n" +
+		"| alert3 |  |  |\n"
+	require.Equal(t, expected, fn(`{{ alertsTable .Alerts }}`))
+	require.NoError(t, tmplErr)
+
+	// should also accept a subset of the alerts
+	require.Equal(t, expected, fn(`{{ alertsTable .Alerts.Firing }}`))
+	require.NoError(t, tmplErr)
+}
+
+func TestFormatValue(t *testing.T) {
+	tmpl := templateForTests(t)
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+
+	cases := []struct {
+		name     string
+		value    interface{}
+		template string
+		expected string
+		expError bool
+	}{{
+		name:     "float",
+		value:    3.14159,
+		template: `{{ formatValue 2 "[no value]" .Value }}`,
+		expected: "3.14",
+	}, {
+		name:     "float is rounded",
+		value:    2.005001,
+		template: `{{ formatValue 2 "[no value]" .Value }}`,
+		expected: "2.01",
+	}, {
+		name:     "integer with precision 0",
+		value:    42,
+		template: `{{ formatValue 0 "[no value]" .Value }}`,
+		expected: "42",
+	}, {
+		name:     "NaN",
+		value:    math.NaN(),
+		template: `{{ formatValue 2 "[no value]" .Value }}`,
+		expected: "NaN",
+	}, {
+		name:     "infinity",
+		value:    math.Inf(-1),
+		template: `{{ formatValue 2 "[no value]" .Value }}`,
+		expected: "-Inf",
+	}, {
+		name:     "missing value",
+		value:    nil,
+		template: `{{ formatValue 2 "n/a" .Value }}`,
+		expected: "n/a",
+	}, {
+		name:     "values",
+		value:    map[string]float64{"B": 1, "A": 0.123456, "C": math.NaN()},
+		template: `{{ formatValue 3 "[no value]" .Value }}`,
+		expected: "A=0.123, B=1.000, C=NaN",
+	}, {
+		name:     "no values",
+		value:    map[string]float64{},
+		template: `{{ formatValue 2 "[no value]" .Value }}`,
+		expected: "[no value]",
+	}, {
+		name:     "negative precision",
+		value:    1.0,
+		template: `{{ formatValue -1 "[no value]" .Value }}`,
+		expError: true,
+	}, {
+		name:     "unsupported type",
+		value:    "1.0",
+		template: `{{ formatValue 2 "[no value]" .Value }}`,
+		expError: true,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := tmpl.ExecuteTextString(c.template, struct{ Value interface{} }{c.value})
+			if c.expError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, s)
+		})
+	}
+
+	t.Run("alert values", func(t *testing.T) {
+		var tmplErr error
+		fn, _ := tmplText(ctx, tmpl, []*types.Alert{{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1"},
+				Annotations: model.LabelSet{"__values__": `{"A": 1.23456}`},
+			},
+		}, {
+			Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": "alert2"},
+			},
+		}}, &channels.FakeLogger{}, &tmplErr, 0)
+		require.Equal(t, "A=1.23 [no value] ", fn(`{{ range .Alerts }}{{ formatValue 2 "[no value]" .Values }} {{ end }}`))
+		require.NoError(t, tmplErr)
+	})
+}
+
+func TestFormatValueWithUnit(t *testing.T) {
+	tmpl := templateForTests(t)
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+
+	cases := []struct {
+		name     string
+		value    interface{}
+		template string
+		expected string
+		expError bool
+	}{{
+		name:     "bytes",
+		value:    1500000000,
+		template: `{{ formatValueWithUnit .Value "bytes" }}`,
+		expected: "1.5 GB",
+	}, {
+		name:     "small bytes",
+		value:    512.0,
+		template: `{{ formatValueWithUnit .Value "bytes" }}`,
+		expected: "512 B",
+	}, {
+		name:     "IEC bytes",
+		value:    1536.0,
+		template: `{{ formatValueWithUnit .Value "ibytes" }}`,
+		expected: "1.5 KiB",
+	}, {
+		name:     "percent",
+		value:    42.1234,
+		template: `{{ formatValueWithUnit .Value "percent" }}`,
+		expected: "42.12%",
+	}, {
+		name:     "percent unit",
+		value:    0.5,
+		template: `{{ formatValueWithUnit .Value "percentunit" }}`,
+		expected: "50%",
+	}, {
+		name:     "seconds",
+		value:    0.25,
+		template: `{{ formatValueWithUnit .Value "seconds" }}`,
+		expected: "250 ms",
+	}, {
+		name:     "minutes",
+		value:    90.0,
+		template: `{{ formatValueWithUnit .Value "s" }}`,
+		expected: "1.5 min",
+	}, {
+		name:     "unknown unit",
+		value:    12.5,
+		template: `{{ formatValueWithUnit .Value "req/s" }}`,
+		expected: "12.5 req/s",
+	}, {
+		name:     "no unit",
+		value:    12.0,
+		template: `{{ formatValueWithUnit .Value "" }}`,
+		expected: "12",
+	}, {
+		name:     "NaN",
+		value:    math.NaN(),
+		template: `{{ formatValueWithUnit .Value "bytes" }}`,
+		expected: "NaN B",
+	}, {
+		name:     "values",
+		value:    map[string]float64{"B": 2e6, "A": 1e3},
+		template: `{{ formatValueWithUnit .Value "bytes" }}`,
+		expected: "A=1 kB, B=2 MB",
+	}, {
+		name:     "unsupported type",
+		value:    "1.0",
+		template: `{{ formatValueWithUnit .Value "bytes" }}`,
+		expError: true,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := tmpl.ExecuteTextString(c.template, struct{ Value interface{} }{c.value})
+			if c.expError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, s)
+		})
+	}
+
+	t.Run("unit annotation", func(t *testing.T) {
+		var tmplErr error
+		fn, _ := tmplText(ctx, tmpl, []*types.Alert{{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert1"},
+				Annotations: model.LabelSet{"__values__": `{"A": 1500000000}`, "unit": "bytes"},
+			},
+		}, {
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "alert2"},
+				Annotations: model.LabelSet{"__values__": `{"A": 87.5}`, "unit": "percent"},
+			},
+		}, {
+			Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": "alert3"},
+			},
+		}}, &channels.FakeLogger{}, &tmplErr, 0)
+		require.Equal(t, "A=1.5 GB;A=87.5%;[no value];", fn(`{{ range .Alerts }}{{ formatValueWithUnit . }};{{ end }}`))
+		require.NoError(t, tmplErr)
+	})
+}
+
+func TestSeverityIcon(t *testing.T) {
+	tmpl := templateForTests(t)
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+
+	alertWithSeverity := func(name, severity string) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": model.LabelValue(name), "severity": model.LabelValue(severity)},
+			},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		alerts   []*types.Alert
+		template string
+		expected string
+		expError bool
+	}{{
+		name:     "critical",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "critical")},
+		template: `{{ severityIcon . }} {{ .CommonLabels.alertname }}`,
+		expected: "🔴 alert1",
+	}, {
+		name:     "warning is case insensitive",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "Warning")},
+		template: `{{ severityIcon . }} {{ .CommonLabels.alertname }}`,
+		expected: "🟠 alert1",
+	}, {
+		name:     "info",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "info")},
+		template: `{{ severityIcon . }} {{ .CommonLabels.alertname }}`,
+		expected: "🟢 alert1",
+	}, {
+		name:     "unknown severity",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "page")},
+		template: `{{ severityIcon . }}`,
+		expected: "",
+	}, {
+		name:     "no common severity",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "critical"), alertWithSeverity("alert2", "info")},
+		template: `{{ severityIcon . }}`,
+		expected: "",
+	}, {
+		name:     "each alert",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "critical"), alertWithSeverity("alert2", "info")},
+		template: `{{ range .Alerts }}{{ severityIcon . }} {{ .Labels.alertname }} {{ end }}`,
+		expected: "🔴 alert1 🟢 alert2 ",
+	}, {
+		name:     "labels",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "warning")},
+		template: `{{ severityIcon .CommonLabels }}`,
+		expected: "🟠",
+	}, {
+		name:     "custom mapping",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "critical"), alertWithSeverity("alert2", "major"), alertWithSeverity("alert3", "info")},
+		template: `{{ range .Alerts }}{{ severityIcon . "critical" "🔥" "major" "🟡" }}{{ end }}`,
+		expected: "🔥🟡🟢",
+	}, {
+		name:     "invalid mapping",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "critical")},
+		template: `{{ severityIcon . "critical" }}`,
+		expError: true,
+	}, {
+		name:     "unsupported type",
+		alerts:   []*types.Alert{alertWithSeverity("alert1", "critical")},
+		template: `{{ severityIcon .CommonLabels.severity }}`,
+		expError: true,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var tmplErr error
+			fn, _ := tmplText(ctx, tmpl, c.alerts, &channels.FakeLogger{}, &tmplErr, 0)
+			s := fn(c.template)
+			if c.expError {
+				require.Error(t, tmplErr)
+				return
+			}
+			require.NoError(t, tmplErr)
+			require.Equal(t, c.expected, s)
+		})
+	}
+}
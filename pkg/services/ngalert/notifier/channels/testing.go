@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/grafana/alerting/alerting/notifier/channels"
 )
 
@@ -36,25 +37,12 @@ func newFakeImageStore(n int) channels.ImageStore {
 	return &s
 }
 
-// mockTimeNow replaces function timeNow to return constant time.
-// It returns a function that resets the variable back to its original value.
-// This allows usage of this function with defer:
-//
-//	func Test (t *testing.T) {
-//	   now := time.Now()
-//	   defer mockTimeNow(now)()
-//	   ...
-//	}
-func mockTimeNow(constTime time.Time) func() {
-	timeNow = func() time.Time {
-		return constTime
-	}
-	return resetTimeNow
-}
-
-// resetTimeNow resets the global variable timeNow to the default value, which is time.Now
-func resetTimeNow() {
-	timeNow = time.Now
+// mockClock returns a mock clock that is set to constTime. It can be assigned
+// to the clock of notifiers to test time-dependent features in isolation.
+func mockClock(constTime time.Time) *clock.Mock {
+	c := clock.NewMock()
+	c.Set(constTime)
+	return c
 }
 
 type notificationServiceMock struct {
@@ -0,0 +1,191 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// timeWindow is a weekly schedule during which notifications are sent.
+type timeWindow struct {
+	days     map[time.Weekday]bool
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+	// minSeverity is the level of the lowest severity of alerts that are sent outside of
+	// the time window, or 0 if no alerts are sent outside of the time window.
+	minSeverity int
+}
+
+type timeWindowSettings struct {
+	// Days are the days of the week, such as monday. Notifications are sent on
+	// all days if empty.
+	Days []string `json:"days,omitempty" yaml:"days,omitempty"`
+	// StartTime and EndTime are the start and end of the window as hh:mm. The
+	// end is exclusive and must be after the start, or 24:00 for the end of the day.
+	StartTime string `json:"start_time,omitempty" yaml:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty" yaml:"end_time,omitempty"`
+	// Timezone is an IANA timezone, such as Europe/Berlin. It defaults to UTC.
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	// MinSeverity is the lowest severity of alerts, such as critical, that are sent
+	// outside of the window too.
+	MinSeverity string `json:"min_severity,omitempty" yaml:"min_severity,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// buildSendOnlyDuring returns the send_only_during setting of the notifier, or nil if
+// notifications should be sent at all times.
+func buildSendOnlyDuring(fc channels.FactoryConfig) (*timeWindow, error) {
+	var settings struct {
+		SendOnlyDuring *timeWindowSettings `json:"send_only_during,omitempty" yaml:"send_only_during,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.SendOnlyDuring == nil {
+		return nil, nil
+	}
+	s := settings.SendOnlyDuring
+
+	w := timeWindow{days: make(map[time.Weekday]bool, len(s.Days))}
+	for _, day := range s.Days {
+		d, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]
+		if !ok {
+			return nil, fmt.Errorf("invalid value for send_only_during: invalid day %q", day)
+		}
+		w.days[d] = true
+	}
+
+	var err error
+	if w.start, err = parseTimeOfDay(s.StartTime, 0); err != nil {
+		return nil, fmt.Errorf("invalid value for send_only_during: invalid start_time %q", s.StartTime)
+	}
+	if w.end, err = parseTimeOfDay(s.EndTime, 24*time.Hour); err != nil {
+		return nil, fmt.Errorf("invalid value for send_only_during: invalid end_time %q", s.EndTime)
+	}
+	if w.end <= w.start {
+		return nil, fmt.Errorf("invalid value for send_only_during: end_time %q must be after start_time %q", s.EndTime, s.StartTime)
+	}
+
+	w.location = time.UTC
+	if s.Timezone != "" {
+		if w.location, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid value for send_only_during: invalid timezone %q", s.Timezone)
+		}
+	}
+
+	if s.MinSeverity != "" {
+		if w.minSeverity = severityLevel(s.MinSeverity); w.minSeverity == 0 {
+			return nil, fmt.Errorf("invalid value for send_only_during: invalid min_severity %q, must be one of %s", s.MinSeverity, strings.Join(severities, ", "))
+		}
+	}
+	return &w, nil
+}
+
+// parseTimeOfDay parses s as hh:mm and returns the time since midnight, or def if s
+// is empty. 24:00 is accepted as the end of the day.
+func parseTimeOfDay(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains returns true if t is within the time window.
+func (w *timeWindow) contains(t time.Time) bool {
+	t = t.In(w.location)
+	if len(w.days) > 0 && !w.days[t.Weekday()] {
+		return false
+	}
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	return sinceMidnight >= w.start && sinceMidnight < w.end
+}
+
+// timeWindowNotifier suppresses the notifications of a notifier outside of a time window.
+type timeWindowNotifier struct {
+	channels.NotificationChannel
+	log    channels.Logger
+	window *timeWindow
+	clock  clock.Clock
+}
+
+// withSendOnlyDuring wraps the factory so that notifiers with the send_only_during
+// setting only send notifications during the time window.
+func withSendOnlyDuring(factory func(channels.FactoryConfig) (channels.NotificationChannel, error)) func(channels.FactoryConfig) (channels.NotificationChannel, error) {
+	return func(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+		window, err := buildSendOnlyDuring(fc)
+		if err != nil {
+			return nil, receiverInitError{
+				Reason: err.Error(),
+				Cfg:    *fc.Config,
+			}
+		}
+		n, err := factory(fc)
+		if err != nil || window == nil {
+			return n, err
+		}
+		return &timeWindowNotifier{
+			NotificationChannel: n,
+			log:                 fc.Logger,
+			window:              window,
+			clock:               clock.New(),
+		}, nil
+	}
+}
+
+// Notify sends the notification if the current time is within the time window. Otherwise,
+// it sends the alerts with at least the minimum severity, if any, and succeeds without
+// sending the other alerts.
+func (tn *timeWindowNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	if tn.window.contains(tn.clock.Now()) {
+		return tn.NotificationChannel.Notify(ctx, as...)
+	}
+	var alerts []*types.Alert
+	if tn.window.minSeverity > 0 {
+		for _, a := range as {
+			if severityLevel(string(a.Labels["severity"])) >= tn.window.minSeverity {
+				alerts = append(alerts, a)
+			}
+		}
+	}
+	if len(alerts) == 0 {
+		tn.log.Debug("not sending notification outside of send_only_during", "alerts", len(as))
+		return true, nil
+	}
+	if len(alerts) < len(as) {
+		tn.log.Debug("not sending alerts below min_severity outside of send_only_during", "alerts", len(as)-len(alerts))
+	}
+	return tn.NotificationChannel.Notify(ctx, alerts...)
+}
+
+// HealthCheck checks the wrapped notifier, as the time window does not apply to health checks.
+func (tn *timeWindowNotifier) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, tn.NotificationChannel)
+}
+
+func (tn *timeWindowNotifier) Close() {
+	Close(tn.NotificationChannel)
+}
@@ -0,0 +1,259 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendOnlyDuring(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	cases := []struct {
+		name         string
+		settings     string
+		now          time.Time
+		expSent      bool
+		expInitError string
+	}{{
+		name:     "Notification is sent without send_only_during",
+		settings: `{"url": "http://localhost/test"}`,
+		now:      time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC),
+		expSent:  true,
+	}, {
+		name: "Notification is sent within the time window",
+		settings: `{"url": "http://localhost/test", "send_only_during": {
+			"days": ["Monday", "Tuesday"], "start_time": "09:00", "end_time": "17:00", "timezone": "Europe/Berlin"
+		}}`,
+		// Monday 09:00 in Europe/Berlin.
+		now:     time.Date(2023, 1, 2, 8, 0, 0, 0, time.UTC),
+		expSent: true,
+	}, {
+		name: "Notification is suppressed before the start time",
+		settings: `{"url": "http://localhost/test", "send_only_during": {
+			"days": ["Monday", "Tuesday"], "start_time": "09:00", "end_time": "17:00", "timezone": "Europe/Berlin"
+		}}`,
+		// Monday 08:59 in Europe/Berlin.
+		now:     time.Date(2023, 1, 2, 7, 59, 0, 0, time.UTC),
+		expSent: false,
+	}, {
+		name: "Notification is suppressed at the end time",
+		settings: `{"url": "http://localhost/test", "send_only_during": {
+			"days": ["Monday", "Tuesday"], "start_time": "09:00", "end_time": "17:00", "timezone": "Europe/Berlin"
+		}}`,
+		// Monday 17:00 in Europe/Berlin.
+		now:     time.Date(2023, 1, 2, 16, 0, 0, 0, time.UTC),
+		expSent: false,
+	}, {
+		name: "Notification is suppressed on other days",
+		settings: `{"url": "http://localhost/test", "send_only_during": {
+			"days": ["Monday", "Tuesday"], "start_time": "09:00", "end_time": "17:00", "timezone": "Europe/Berlin"
+		}}`,
+		// Wednesday 12:00 in Europe/Berlin.
+		now:     time.Date(2023, 1, 4, 11, 0, 0, 0, time.UTC),
+		expSent: false,
+	}, {
+		name:     "Notification is sent all day without start and end times",
+		settings: `{"url": "http://localhost/test", "send_only_during": {"days": ["sunday"]}}`,
+		now:      time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC),
+		expSent:  true,
+	}, {
+		name:         "Error in initialization, invalid day",
+		settings:     `{"url": "http://localhost/test", "send_only_during": {"days": ["mon"]}}`,
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": invalid value for send_only_during: invalid day "mon"`,
+	}, {
+		name:         "Error in initialization, invalid start time",
+		settings:     `{"url": "http://localhost/test", "send_only_during": {"start_time": "9am"}}`,
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": invalid value for send_only_during: invalid start_time "9am"`,
+	}, {
+		name:         "Error in initialization, end time before start time",
+		settings:     `{"url": "http://localhost/test", "send_only_during": {"start_time": "17:00", "end_time": "09:00"}}`,
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": invalid value for send_only_during: end_time "09:00" must be after start_time "17:00"`,
+	}, {
+		name:         "Error in initialization, invalid timezone",
+		settings:     `{"url": "http://localhost/test", "send_only_during": {"timezone": "Mars/Olympus_Mons"}}`,
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": invalid value for send_only_during: invalid timezone "Mars/Olympus_Mons"`,
+	}, {
+		name:         "Error in initialization, invalid min severity",
+		settings:     `{"url": "http://localhost/test", "send_only_during": {"min_severity": "page"}}`,
+		expInitError: `failed to validate receiver "webhook_testing" of type "webhook": invalid value for send_only_during: invalid min_severity "page", must be one of critical, error, warning, info`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			factory, ok := Factory("webhook")
+			require.True(t, ok)
+			n, err := factory(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+			// The time window is wrapped by the classification of notification errors.
+			if tn, ok := n.(*notifyErrorNotifier).NotificationChannel.(*timeWindowNotifier); ok {
+				tn.clock = mockClock(c.now)
+			}
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err = n.Notify(ctx, &types.Alert{
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1"},
+				},
+			})
+			require.NoError(t, err)
+			require.True(t, ok)
+			if c.expSent {
+				require.Equal(t, "http://localhost/test", webhookSender.Webhook.URL)
+			} else {
+				require.Empty(t, webhookSender.Webhook.URL)
+			}
+		})
+	}
+}
+
+func TestSendOnlyDuring_Clocks(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	newNotifier := func(c *clock.Mock) (*timeWindowNotifier, *notificationServiceMock) {
+		webhookSender := mockNotificationService()
+		fc := channels.FactoryConfig{
+			Config: &channels.NotificationChannelConfig{
+				Name:     "webhook_testing",
+				Type:     "webhook",
+				Settings: json.RawMessage(`{"url": "http://localhost/test", "send_only_during": {"start_time": "09:00", "end_time": "17:00"}}`),
+			},
+			ImageStore:          &channels.UnavailableImageStore{},
+			NotificationService: webhookSender,
+			DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+				return fallback
+			},
+			Template: tmpl,
+			Logger:   &channels.FakeLogger{},
+		}
+		n, err := withSendOnlyDuring(WebHookFactory)(fc)
+		require.NoError(t, err)
+		tn, ok := n.(*timeWindowNotifier)
+		require.True(t, ok)
+		tn.clock = c
+		return tn, webhookSender
+	}
+
+	// The notifiers have the same time window but different clocks, one within
+	// and one outside of the time window.
+	duringClock := mockClock(time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC))
+	outsideClock := mockClock(time.Date(2023, 1, 2, 20, 0, 0, 0, time.UTC))
+	during, duringSender := newNotifier(duringClock)
+	outside, outsideSender := newNotifier(outsideClock)
+
+	notifyAll := func() {
+		ctx := notify.WithGroupKey(context.Background(), "alertname")
+		ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+		for _, n := range []*timeWindowNotifier{during, outside} {
+			ok, err := n.Notify(ctx, &types.Alert{
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1"},
+				},
+			})
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+	}
+
+	notifyAll()
+	require.Equal(t, "http://localhost/test", duringSender.Webhook.URL)
+	require.Empty(t, outsideSender.Webhook.URL)
+
+	// Moving one clock does not affect the other notifier.
+	duringClock.Add(6 * time.Hour)
+	duringSender.Webhook = channels.SendWebhookSettings{}
+	notifyAll()
+	require.Empty(t, duringSender.Webhook.URL)
+	require.Empty(t, outsideSender.Webhook.URL)
+
+	outsideClock.Add(14 * time.Hour)
+	notifyAll()
+	require.Empty(t, duringSender.Webhook.URL)
+	require.Equal(t, "http://localhost/test", outsideSender.Webhook.URL)
+}
+
+func TestSendOnlyDuring_MinSeverity(t *testing.T) {
+	tmpl := templateForTests(t)
+	webhookSender := mockNotificationService()
+	fc := channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Name:     "webhook_testing",
+			Type:     "webhook",
+			Settings: json.RawMessage(`{"url": "http://localhost/test", "send_only_during": {"start_time": "09:00", "end_time": "17:00", "min_severity": "Critical"}}`),
+		},
+		ImageStore:          &channels.UnavailableImageStore{},
+		NotificationService: webhookSender,
+		DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+			return fallback
+		},
+		Template: tmpl,
+		Logger:   &channels.FakeLogger{},
+	}
+	n, err := withSendOnlyDuring(WebHookFactory)(fc)
+	require.NoError(t, err)
+	tn, ok := n.(*timeWindowNotifier)
+	require.True(t, ok)
+
+	critical := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "severity": "critical"}}}
+	warning := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2", "severity": "warning"}}}
+	notifyAlerts := func(now time.Time, as ...*types.Alert) []string {
+		webhookSender.Webhook = channels.SendWebhookSettings{}
+		tn.clock = mockClock(now)
+		ctx := notify.WithGroupKey(context.Background(), "alertname")
+		ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+		ok, err := tn.Notify(ctx, as...)
+		require.NoError(t, err)
+		require.True(t, ok)
+		if webhookSender.Webhook.Body == "" {
+			return nil
+		}
+		var msg struct {
+			Alerts []struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"alerts"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(webhookSender.Webhook.Body), &msg))
+		names := make([]string, 0, len(msg.Alerts))
+		for _, a := range msg.Alerts {
+			names = append(names, a.Labels["alertname"])
+		}
+		return names
+	}
+
+	// All alerts are sent within the time window.
+	require.Equal(t, []string{"alert1", "alert2"}, notifyAlerts(time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC), critical, warning))
+
+	// During quiet hours, critical alerts are sent and warnings are suppressed.
+	quietHours := time.Date(2023, 1, 2, 22, 0, 0, 0, time.UTC)
+	require.Equal(t, []string{"alert1"}, notifyAlerts(quietHours, critical, warning))
+	require.Nil(t, notifyAlerts(quietHours, warning))
+}
@@ -0,0 +1,126 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+)
+
+// The formats of TLS material. With tlsFormatAuto, values are inline PEM if they
+// start with a PEM header, base64-encoded PEM if they decode to PEM, and file paths
+// otherwise.
+const (
+	tlsFormatAuto   = "auto"
+	tlsFormatFile   = "file"
+	tlsFormatBase64 = "base64"
+)
+
+var pemHeader = []byte("-----BEGIN ")
+
+// buildTLSConfig returns the TLS configuration in the tls_ca_cert, tls_client_cert
+// and tls_client_key settings of the notifier, or nil if none of them is set. The
+// client key is a secure setting. The certificates and key are files or base64-encoded
+// PEM, as set by tls_format, and are loaded when the notifier is created.
+func buildTLSConfig(fc channels.FactoryConfig) (*tls.Config, error) {
+	var settings struct {
+		CACert     string `json:"tls_ca_cert,omitempty" yaml:"tls_ca_cert,omitempty"`
+		ClientCert string `json:"tls_client_cert,omitempty" yaml:"tls_client_cert,omitempty"`
+		ClientKey  string `json:"tls_client_key,omitempty" yaml:"tls_client_key,omitempty"`
+		Format     string `json:"tls_format,omitempty" yaml:"tls_format,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	settings.ClientKey = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "tls_client_key", settings.ClientKey)
+
+	switch settings.Format {
+	case "":
+		settings.Format = tlsFormatAuto
+	case tlsFormatAuto, tlsFormatFile, tlsFormatBase64:
+	default:
+		return nil, fmt.Errorf("invalid value for tls_format: %q, must be one of auto, file, base64", settings.Format)
+	}
+	if settings.CACert == "" && settings.ClientCert == "" && settings.ClientKey == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if settings.CACert != "" {
+		caCert, err := loadTLSMaterial("tls_ca_cert", settings.CACert, settings.Format)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("invalid value for tls_ca_cert: no PEM certificates found")
+		}
+	}
+	if settings.ClientCert != "" || settings.ClientKey != "" {
+		if settings.ClientCert == "" || settings.ClientKey == "" {
+			return nil, errors.New("tls_client_cert and tls_client_key must be set together")
+		}
+		clientCert, err := loadTLSMaterial("tls_client_cert", settings.ClientCert, settings.Format)
+		if err != nil {
+			return nil, err
+		}
+		clientKey, err := loadTLSMaterial("tls_client_key", settings.ClientKey, settings.Format)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls_client_cert or tls_client_key: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// loadTLSMaterial returns the PEM in the value of the setting, which is a file path or
+// base64-encoded PEM depending on the format.
+func loadTLSMaterial(name, value, format string) ([]byte, error) {
+	if format == tlsFormatAuto {
+		if bytes.HasPrefix([]byte(strings.TrimSpace(value)), pemHeader) {
+			return []byte(value), nil
+		}
+		if b, err := decodeBase64PEM(value); err == nil {
+			return b, nil
+		}
+		format = tlsFormatFile
+	}
+
+	if format == tlsFormatBase64 {
+		b, err := decodeBase64PEM(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		return b, nil
+	}
+	b, err := os.ReadFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// decodeBase64PEM decodes base64-encoded PEM. Whitespace is ignored, as encoded values
+// are often wrapped.
+func decodeBase64PEM(value string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil {
+		return nil, fmt.Errorf("not valid base64: %w", err)
+	}
+	if !bytes.Contains(b, pemHeader) {
+		return nil, errors.New("base64 does not decode to PEM")
+	}
+	return b, nil
+}
@@ -0,0 +1,192 @@
+package channels
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCertificate returns a self-signed certificate and its key as PEM.
+func newTestCertificate(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "grafana"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	certPEM, keyPEM := newTestCertificate(t)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	certBase64 := base64.StdEncoding.EncodeToString(certPEM)
+	keyBase64 := base64.StdEncoding.EncodeToString(keyPEM)
+
+	cases := []struct {
+		name           string
+		settings       string
+		secureSettings map[string]string
+		expNil         bool
+		expCA          bool
+		expClientCert  bool
+		expErr         string
+	}{{
+		name:     "no TLS settings",
+		settings: `{}`,
+		expNil:   true,
+	}, {
+		name:     "CA certificate file",
+		settings: fmt.Sprintf(`{"tls_ca_cert": %q}`, certFile),
+		expCA:    true,
+	}, {
+		name:     "CA certificate file with file format",
+		settings: fmt.Sprintf(`{"tls_ca_cert": %q, "tls_format": "file"}`, certFile),
+		expCA:    true,
+	}, {
+		name:     "base64 CA certificate",
+		settings: fmt.Sprintf(`{"tls_ca_cert": %q}`, certBase64),
+		expCA:    true,
+	}, {
+		name:     "base64 CA certificate with base64 format",
+		settings: fmt.Sprintf(`{"tls_ca_cert": %q, "tls_format": "base64"}`, certBase64),
+		expCA:    true,
+	}, {
+		name:     "inline PEM CA certificate",
+		settings: fmt.Sprintf(`{"tls_ca_cert": %q}`, certPEM),
+		expCA:    true,
+	}, {
+		name:          "client certificate and key files",
+		settings:      fmt.Sprintf(`{"tls_client_cert": %q, "tls_client_key": %q}`, certFile, keyFile),
+		expClientCert: true,
+	}, {
+		name:           "base64 client certificate and secure key",
+		settings:       fmt.Sprintf(`{"tls_client_cert": %q, "tls_format": "base64"}`, certBase64),
+		secureSettings: map[string]string{"tls_client_key": keyBase64},
+		expClientCert:  true,
+	}, {
+		name:     "invalid format",
+		settings: `{"tls_ca_cert": "ca.pem", "tls_format": "der"}`,
+		expErr:   `invalid value for tls_format: "der", must be one of auto, file, base64`,
+	}, {
+		name:     "missing file",
+		settings: fmt.Sprintf(`{"tls_ca_cert": %q}`, filepath.Join(dir, "missing.pem")),
+		expErr:   fmt.Sprintf("failed to read tls_ca_cert: open %s: no such file or directory", filepath.Join(dir, "missing.pem")),
+	}, {
+		name:     "invalid base64",
+		settings: `{"tls_ca_cert": "not base64!", "tls_format": "base64"}`,
+		expErr:   "invalid value for tls_ca_cert: not valid base64: illegal base64 data at input byte 9",
+	}, {
+		name:     "base64 without PEM",
+		settings: fmt.Sprintf(`{"tls_ca_cert": %q, "tls_format": "base64"}`, base64.StdEncoding.EncodeToString([]byte("certificate"))),
+		expErr:   "invalid value for tls_ca_cert: base64 does not decode to PEM",
+	}, {
+		name:     "CA certificate without certificates",
+		settings: fmt.Sprintf(`{"tls_ca_cert": %q}`, keyBase64),
+		expErr:   "invalid value for tls_ca_cert: no PEM certificates found",
+	}, {
+		name:     "client certificate without key",
+		settings: fmt.Sprintf(`{"tls_client_cert": %q}`, certFile),
+		expErr:   "tls_client_cert and tls_client_key must be set together",
+	}, {
+		name:     "client certificate with mismatched key",
+		settings: fmt.Sprintf(`{"tls_client_cert": %q, "tls_client_key": %q}`, certBase64, certBase64),
+		expErr:   "invalid tls_client_cert or tls_client_key: tls: found a certificate rather than a key in the PEM for the private key",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			secureSettings := make(map[string][]byte, len(c.secureSettings))
+			for k, v := range c.secureSettings {
+				secureSettings[k] = []byte(v)
+			}
+			cfg, err := buildTLSConfig(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Settings:       json.RawMessage(c.settings),
+					SecureSettings: secureSettings,
+				},
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					if v, ok := sjd[key]; ok {
+						return string(v)
+					}
+					return fallback
+				},
+			})
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			if c.expNil {
+				require.Nil(t, cfg)
+				return
+			}
+			require.NotNil(t, cfg)
+			require.Equal(t, c.expCA, cfg.RootCAs != nil)
+			if c.expClientCert {
+				require.Len(t, cfg.Certificates, 1)
+			} else {
+				require.Empty(t, cfg.Certificates)
+			}
+		})
+	}
+}
+
+func TestSendHTTPRequest_TLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// The certificate of the server is not trusted without the CA certificate.
+	_, err = sendHTTPRequest(context.Background(), u, httpCfg{}, &channels.FakeLogger{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "certificate")
+
+	caCert := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	tlsConfig, err := buildTLSConfig(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Settings: json.RawMessage(fmt.Sprintf(`{"tls_ca_cert": %q}`, caCert)),
+		},
+		DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+			return fallback
+		},
+	})
+	require.NoError(t, err)
+	_, err = sendHTTPRequest(context.Background(), u, httpCfg{tlsConfig: tlsConfig}, &channels.FakeLogger{})
+	require.NoError(t, err)
+}
@@ -2,53 +2,138 @@ package channels
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
 
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 )
 
-var (
-	// Provides current time. Can be overwritten in tests.
-	timeNow = time.Now
-)
+// defaultHTTPTimeout is the default timeout for both connecting to the server
+// and the entire HTTP request.
+const defaultHTTPTimeout = 30 * time.Second
+
+// defaultResponseHeaderTimeout is the default timeout for receiving the response
+// headers after the request has been sent.
+const defaultResponseHeaderTimeout = 10 * time.Second
+
+// defaultIdleConnTimeout is the default time after which idle connections are closed.
+const defaultIdleConnTimeout = 90 * time.Second
+
+// defaultMaxBodyLogLen is the default maximum number of bytes of the response body that
+// are logged when a request fails, so large error pages do not flood the logs.
+const defaultMaxBodyLogLen = 256
+
+// maxResponseBodyLen is the maximum number of bytes of response bodies, before and after
+// they are decompressed, so a large or highly compressed response cannot exhaust memory.
+const maxResponseBodyLen = 10 << 20
+
+// defaultImageFetchBackoff is the default time to wait before retrying a transient error
+// getting an image. It doubles with each retry.
+const defaultImageFetchBackoff = 100 * time.Millisecond
+
+// maxImageFetchRetries is the maximum number of retries of transient errors getting an
+// image, as images delay the notifications.
+const maxImageFetchRetries = 5
 
 type forEachImageFunc func(index int, image channels.Image) error
 
+// imageRetryStore is the image store of a notifier that retries transient errors getting
+// images. The retries are done by getImage, so each attempt has its own timeout.
+type imageRetryStore struct {
+	channels.ImageStore
+	retries int
+	backoff time.Duration
+}
+
 // getImage returns the image for the alert or an error. It returns a nil
 // image if the alert does not have an image token or the image does not exist.
+// Transient errors are retried with exponential backoff if imageStore is an
+// imageRetryStore, other errors are returned immediately.
 func getImage(ctx context.Context, l channels.Logger, imageStore channels.ImageStore, alert types.Alert) (*channels.Image, error) {
 	token := getTokenFromAnnotations(alert.Annotations)
 	if token == "" {
 		return nil, nil
 	}
 
+	var retries int
+	var backoff time.Duration
+	if s, ok := imageStore.(*imageRetryStore); ok {
+		retries, backoff = s.retries, s.backoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		img, err := getImageWithTimeout(ctx, imageStore, token)
+		if errors.Is(err, channels.ErrImageNotFound) || errors.Is(err, channels.ErrImagesUnavailable) {
+			return nil, nil
+		} else if err == nil {
+			return img, nil
+		}
+
+		if attempt >= retries || !isTransientImageError(err) || ctx.Err() != nil {
+			l.Warn("failed to get image with token", "token", token, "error", err, "attempts", attempt+1)
+			return nil, err
+		}
+		wait := backoff << attempt
+		l.Debug("retrying transient error getting image with token", "token", token, "error", err, "wait", wait)
+		select {
+		case <-ctx.Done():
+			l.Warn("failed to get image with token", "token", token, "error", err, "attempts", attempt+1)
+			return nil, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// getImageWithTimeout returns the image with the token from the image store, or an
+// error if the image store does not return it within channels.ImageStoreTimeout.
+func getImageWithTimeout(ctx context.Context, imageStore channels.ImageStore, token string) (*channels.Image, error) {
 	ctx, cancelFunc := context.WithTimeout(ctx, channels.ImageStoreTimeout)
 	defer cancelFunc()
+	return imageStore.GetImage(ctx, token)
+}
 
-	img, err := imageStore.GetImage(ctx, token)
-	if errors.Is(err, channels.ErrImageNotFound) || errors.Is(err, channels.ErrImagesUnavailable) {
-		return nil, nil
-	} else if err != nil {
-		l.Warn("failed to get image with token", "token", token, "error", err)
-		return nil, err
-	} else {
-		return img, nil
+// isTransientImageError returns true if the error getting an image can be retried, such
+// as timeouts of the image store. Other errors, such as permission errors, are terminal.
+func isTransientImageError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
 	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var tempErr interface{ Temporary() bool }
+	return errors.As(err, &tempErr) && tempErr.Temporary()
 }
 
 // withStoredImages retrieves the image for each alert and then calls forEachFunc
@@ -57,8 +142,12 @@ func getImage(ctx context.Context, l channels.Logger, imageStore channels.ImageS
 // called for that alert. If forEachFunc returns an error, withStoredImages will return
 // the error and not iterate the remaining alerts. A forEachFunc can return ErrImagesDone
 // to stop the iteration of remaining alerts if the intended image or maximum number of
-// images have been found.
+// images have been found. If imageStore is nil, as images are disabled for the notifier,
+// withStoredImages does nothing.
 func withStoredImages(ctx context.Context, l channels.Logger, imageStore channels.ImageStore, forEachFunc forEachImageFunc, alerts ...*types.Alert) error {
+	if imageStore == nil {
+		return nil
+	}
 	for index, alert := range alerts {
 		logger := l.New("alert", alert.String())
 		img, err := getImage(ctx, logger, imageStore, *alert)
@@ -77,6 +166,49 @@ func withStoredImages(ctx context.Context, l channels.Logger, imageStore channel
 	return nil
 }
 
+// messageChunk sends one message of a notification that is sent as multiple messages.
+type messageChunk func(ctx context.Context) error
+
+// chunksError is the combined error of the chunks of a notification that failed to send.
+type chunksError struct {
+	total int
+	// failed are the numbers of the chunks that failed, starting at 1, and errs are their errors.
+	failed []int
+	errs   []error
+}
+
+func (e chunksError) Error() string {
+	msgs := make([]string, 0, len(e.failed))
+	for i, n := range e.failed {
+		msgs = append(msgs, fmt.Sprintf("chunk %d: %s", n, e.errs[i]))
+	}
+	return fmt.Sprintf("failed to send %d of %d chunks: %s", len(e.failed), e.total, strings.Join(msgs, "; "))
+}
+
+// sendChunks sends the chunks of a notification in order and logs the progress of each
+// chunk. The first chunk is critical, as the other chunks add to it: if it fails, no other
+// chunk is sent and its error is returned with delivered false. Otherwise all chunks are
+// sent, delivered is true and the chunks that failed are returned in a chunksError.
+func sendChunks(ctx context.Context, l channels.Logger, chunks ...messageChunk) (delivered bool, err error) {
+	chunksErr := chunksError{total: len(chunks)}
+	for i, chunk := range chunks {
+		if err := chunk(ctx); err != nil {
+			l.Warn("failed to send chunk", "chunk", i+1, "total", len(chunks), "status", "failed", "error", err)
+			if i == 0 {
+				return false, err
+			}
+			chunksErr.failed = append(chunksErr.failed, i+1)
+			chunksErr.errs = append(chunksErr.errs, err)
+			continue
+		}
+		l.Debug("sent chunk", "chunk", i+1, "total", len(chunks), "status", "sent")
+	}
+	if len(chunksErr.failed) > 0 {
+		return true, chunksErr
+	}
+	return true, nil
+}
+
 // The path argument here comes from reading internal image storage, not user
 // input, so we ignore the security check here.
 //
@@ -103,6 +235,272 @@ func getTokenFromAnnotations(annotations model.LabelSet) string {
 	return ""
 }
 
+// buildImageStore returns the image store of the notifier, or nil if the include_images
+// setting is false and so the notifier should not include images in notifications. The
+// image store retries transient errors image_fetch_retries times, waiting
+// image_fetch_backoff before the first retry. Errors are not retried by default.
+func buildImageStore(fc channels.FactoryConfig) (channels.ImageStore, error) {
+	var settings struct {
+		IncludeImages     *bool       `json:"include_images,omitempty" yaml:"include_images,omitempty"`
+		ImageFetchRetries json.Number `json:"image_fetch_retries,omitempty" yaml:"image_fetch_retries,omitempty"`
+		ImageFetchBackoff string      `json:"image_fetch_backoff,omitempty" yaml:"image_fetch_backoff,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.IncludeImages != nil && !*settings.IncludeImages {
+		return nil, nil
+	}
+	var retries int
+	if settings.ImageFetchRetries != "" {
+		n, err := strconv.Atoi(settings.ImageFetchRetries.String())
+		if err != nil || n < 0 || n > maxImageFetchRetries {
+			return nil, fmt.Errorf("invalid value for image_fetch_retries: %q, must be an integer between 0 and %d", settings.ImageFetchRetries, maxImageFetchRetries)
+		}
+		retries = n
+	}
+	backoff, err := parseHTTPTimeout("image_fetch_backoff", settings.ImageFetchBackoff, defaultImageFetchBackoff)
+	if err != nil {
+		return nil, err
+	}
+	if retries == 0 || fc.ImageStore == nil {
+		return fc.ImageStore, nil
+	}
+	return &imageRetryStore{
+		ImageStore: fc.ImageStore,
+		retries:    retries,
+		backoff:    backoff,
+	}, nil
+}
+
+// buildIncludeFingerprints returns the include_fingerprints setting of the notifier. It
+// is true if the notifier should include the fingerprints of the alerts in notifications.
+func buildIncludeFingerprints(fc channels.FactoryConfig) (bool, error) {
+	var settings struct {
+		IncludeFingerprints bool `json:"include_fingerprints,omitempty" yaml:"include_fingerprints,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return false, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	return settings.IncludeFingerprints, nil
+}
+
+// alertFingerprints returns the fingerprints of the alerts. They are computed from the
+// labels of the alerts using the same algorithm as Prometheus, so they can be used to
+// correlate alerts with series, and are the same as {{ .Fingerprint }} in templates.
+func alertFingerprints(as []*types.Alert) []string {
+	fingerprints := make([]string, 0, len(as))
+	for _, a := range as {
+		fingerprints = append(fingerprints, a.Labels.Fingerprint().String())
+	}
+	return fingerprints
+}
+
+// defaultRunbookAnnotation is the annotation with the URL of the runbook of alerts.
+const defaultRunbookAnnotation = "runbook_url"
+
+// buildResolvedURL returns the resolved_url setting of the notifier, or an empty string if
+// it is not set. It is the URL that notifications are sent to instead of the URL of the
+// notifier when all of their alerts are resolved. It can be templated.
+func buildResolvedURL(fc channels.FactoryConfig) (string, error) {
+	var settings struct {
+		ResolvedURL string `json:"resolved_url,omitempty" yaml:"resolved_url,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return "", fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	resolvedURL := strings.TrimSpace(settings.ResolvedURL)
+	// The URL is not in the error as it can contain a token, such as for Discord webhooks.
+	if resolvedURL != "" && !strings.Contains(resolvedURL, "{{") && !isHTTPURL(resolvedURL) {
+		return "", errors.New("invalid value for resolved_url, must be an http or https URL")
+	}
+	return resolvedURL, nil
+}
+
+// severities are the known values of the severity label of alerts, ordered from the
+// highest to the lowest severity.
+var severities = []string{"critical", "error", "warning", "info"}
+
+// severityLevel returns the level of the severity, which is higher for higher severities,
+// or 0 if the severity is not known. Severities are case insensitive.
+func severityLevel(severity string) int {
+	for i, s := range severities {
+		if strings.EqualFold(severity, s) {
+			return len(severities) - i
+		}
+	}
+	return 0
+}
+
+// highestSeverity returns the index of the highest severity in mapped that is the
+// severity label of one of the alerts, or -1 if there is none. Severities are ranked by
+// severityLevel, so the order of mapped does not matter for known severities, and
+// severities that are not known are ranked below them in the order of mapped.
+func highestSeverity(as []*types.Alert, mapped []string) int {
+	highest := -1
+	for i, severity := range mapped {
+		if highest >= 0 && severityLevel(severity) <= severityLevel(mapped[highest]) {
+			continue
+		}
+		for _, a := range as {
+			if strings.EqualFold(string(a.Labels["severity"]), severity) {
+				highest = i
+				break
+			}
+		}
+	}
+	return highest
+}
+
+// defaultSenderName is the name that notifications are sent as if sender_name is not set
+// and the notifier does not have a setting of its own.
+const defaultSenderName = "Grafana"
+
+// buildSenderName returns the sender_name setting of the notifier, or an empty string if
+// it is not set. It is the name that notifications are sent as, such as the username of
+// Discord and Slack messages, and is overridden by the settings of the notifier.
+func buildSenderName(fc channels.FactoryConfig) (string, error) {
+	var settings struct {
+		SenderName string `json:"sender_name,omitempty" yaml:"sender_name,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return "", fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	return strings.TrimSpace(settings.SenderName), nil
+}
+
+// buildRunbookAnnotation returns the runbook_annotation setting of the notifier. It is
+// the annotation with the URL of the runbook of alerts, and defaults to runbook_url.
+func buildRunbookAnnotation(fc channels.FactoryConfig) (string, error) {
+	var settings struct {
+		RunbookAnnotation string `json:"runbook_annotation,omitempty" yaml:"runbook_annotation,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return "", fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.RunbookAnnotation == "" {
+		return defaultRunbookAnnotation, nil
+	}
+	if !model.LabelName(settings.RunbookAnnotation).IsValid() {
+		return "", fmt.Errorf("invalid value for runbook_annotation: %q", settings.RunbookAnnotation)
+	}
+	return settings.RunbookAnnotation, nil
+}
+
+// runbookURL returns the URL in the runbook annotation of the first alert that has one,
+// or an empty string if none of the alerts have a runbook annotation with an http or
+// https URL.
+func runbookURL(as []*types.Alert, annotation string) string {
+	for _, a := range as {
+		if u := string(a.Annotations[model.LabelName(annotation)]); isHTTPURL(u) {
+			return u
+		}
+	}
+	return ""
+}
+
+// generatorURL returns the generator URL of the first alert that has one, which links to
+// the source of the alert, or an empty string if none of the alerts have an http or
+// https generator URL.
+func generatorURL(data *channels.ExtendedData) string {
+	for _, a := range data.Alerts {
+		if isHTTPURL(a.GeneratorURL) {
+			return a.GeneratorURL
+		}
+	}
+	return ""
+}
+
+// buildMaxValueLen returns the max_value_len setting of the notifier. It is the maximum
+// length in runes of label and annotation values in messages, or 0 if values should not
+// be truncated.
+func buildMaxValueLen(fc channels.FactoryConfig) (int, error) {
+	var settings struct {
+		MaxValueLen json.Number `json:"max_value_len,omitempty" yaml:"max_value_len,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.MaxValueLen == "" {
+		return 0, nil
+	}
+	maxValueLen, err := strconv.Atoi(settings.MaxValueLen.String())
+	if err != nil || maxValueLen < 0 {
+		return 0, fmt.Errorf("invalid value for max_value_len: %q", settings.MaxValueLen)
+	}
+	return maxValueLen, nil
+}
+
+// tmplText is channels.TmplText but truncates the values of labels and annotations in the
+// template data to maxValueLen runes. Values are not truncated if maxValueLen is 0.
+func tmplText(ctx context.Context, tmpl *template.Template, alerts []*types.Alert, l channels.Logger, tmplErr *error, maxValueLen int) (func(string) string, *channels.ExtendedData) {
+	fn, data := channels.TmplText(ctx, tmpl, alerts, l, tmplErr)
+	if maxValueLen > 0 {
+		for _, alert := range data.Alerts {
+			truncateValues(alert.Labels, maxValueLen)
+			truncateValues(alert.Annotations, maxValueLen)
+		}
+		truncateValues(data.GroupLabels, maxValueLen)
+		truncateValues(data.CommonLabels, maxValueLen)
+		truncateValues(data.CommonAnnotations, maxValueLen)
+	}
+	return fn, data
+}
+
+// truncateValues truncates each value in kv to n runes.
+func truncateValues(kv template.KV, n int) {
+	for k, v := range kv {
+		kv[k], _ = channels.TruncateInRunes(v, n)
+	}
+}
+
+// silenceDurationRegexp matches the units of a duration, such as 1d and 12h in 1d12h.
+var silenceDurationRegexp = regexp.MustCompile(`[0-9]+[a-z]+`)
+
+// buildSilenceDuration returns the silence_duration setting of the notifier. It is the
+// duration pre-filled in the form of silence links, such as 1h, or an empty string if
+// the form should use its default duration. The duration is returned in the format of
+// the form, which separates units with spaces.
+func buildSilenceDuration(fc channels.FactoryConfig) (string, error) {
+	var settings struct {
+		SilenceDuration string `json:"silence_duration,omitempty" yaml:"silence_duration,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return "", fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.SilenceDuration == "" {
+		return "", nil
+	}
+	d, err := model.ParseDuration(settings.SilenceDuration)
+	if err != nil || d <= 0 || time.Duration(d)%time.Second != 0 {
+		return "", fmt.Errorf("invalid value for silence_duration: %q, must be a positive duration in seconds or more, such as 1h or 1d12h", settings.SilenceDuration)
+	}
+	return strings.Join(silenceDurationRegexp.FindAllString(d.String(), -1), " "), nil
+}
+
+// setSilenceDuration adds the duration to the silence URLs of the alerts in the template
+// data, so the form of the silence links is pre-filled with it. The URLs are not
+// changed if the duration is empty.
+func setSilenceDuration(data *channels.ExtendedData, duration string, l channels.Logger) {
+	if duration == "" {
+		return
+	}
+	for i := range data.Alerts {
+		if data.Alerts[i].SilenceURL == "" {
+			continue
+		}
+		u, err := url.Parse(data.Alerts[i].SilenceURL)
+		if err != nil {
+			l.Debug("failed to parse silence URL", "url", data.Alerts[i].SilenceURL, "error", err.Error())
+			continue
+		}
+		query := u.Query()
+		query.Set("duration", duration)
+		u.RawQuery = query.Encode()
+		data.Alerts[i].SilenceURL = u.String()
+	}
+}
+
 type receiverInitError struct {
 	Reason string
 	Err    error
@@ -125,6 +523,15 @@ func (e receiverInitError) Error() string {
 
 func (e receiverInitError) Unwrap() error { return e.Err }
 
+// messageForAlerts returns resolvedMessage if it is set and all of the alerts are
+// resolved, otherwise it returns message.
+func messageForAlerts(message, resolvedMessage string, as []*types.Alert) string {
+	if resolvedMessage != "" && types.Alerts(as...).Status() == model.AlertResolved {
+		return resolvedMessage
+	}
+	return message
+}
+
 func getAlertStatusColor(status model.AlertStatus) string {
 	if status == model.AlertFiring {
 		return channels.ColorAlertFiring
@@ -133,19 +540,419 @@ func getAlertStatusColor(status model.AlertStatus) string {
 }
 
 type httpCfg struct {
+	// method is the HTTP method of the request. It defaults to POST.
+	method   string
 	body     []byte
 	user     string
 	password string
+	// connectTimeout is the timeout for connecting to the server. It defaults
+	// to defaultHTTPTimeout.
+	connectTimeout time.Duration
+	// requestTimeout is the timeout for the entire request, including connecting
+	// to the server and reading the response. It defaults to defaultHTTPTimeout.
+	requestTimeout time.Duration
+	// responseHeaderTimeout is the timeout for receiving the response headers after
+	// the request has been sent, so slow servers fail fast independent of the time
+	// to read the body. It defaults to defaultResponseHeaderTimeout.
+	responseHeaderTimeout time.Duration
+	// idleConnTimeout is the time after which idle connections to the server are
+	// closed, so sockets are released between notifications. It defaults to
+	// defaultIdleConnTimeout.
+	idleConnTimeout time.Duration
+	// expectedStatusCodes, if set, are the only status codes for which the request
+	// is successful. Otherwise, the request is successful for all 2xx status codes.
+	expectedStatusCodes []int
+	// resolver, if set, is used to resolve the host of the URL instead of the
+	// system resolver.
+	resolver *net.Resolver
+	// maxRedirects is the maximum number of redirects to follow. The method and
+	// body of the request are preserved on redirect. Redirects are refused if it
+	// is 0, as otherwise a 302 turns a POST into a GET and loses the body.
+	maxRedirects int
+	// redirectHosts are the hosts that redirects are followed to. Hosts starting with
+	// "*." match all subdomains. Redirects are only followed to the host of the request
+	// if it is empty, so open redirects cannot send requests to other hosts.
+	redirectHosts []string
+	// trace is true if the time spent in DNS, connect, TLS and waiting for the
+	// first byte of the response is logged at debug level.
+	trace bool
+	// host, if set, overrides the Host of the request, so servers behind a shared
+	// load balancer can be addressed by virtual host independent of the URL.
+	host string
+	// tlsConfig, if set, has the CA and client certificates of the request.
+	tlsConfig *tls.Config
+	// maxBodyLogLen is the maximum number of bytes of the response body that are
+	// logged when the request fails. It defaults to defaultMaxBodyLogLen.
+	maxBodyLogLen int
+	// sigV4, if set, signs the request with AWS Signature Version 4.
+	sigV4 *sigV4Config
+	// destinationPolicy, if set, restricts the hosts that the request is sent to. It
+	// defaults to the destination policy of the context of the request.
+	destinationPolicy *DestinationPolicy
+	// clients, if set, has the client that sends the request, so connections are reused
+	// between the requests of a notifier. Otherwise, the connections of the request are
+	// closed once the response is read.
+	clients *httpClients
+}
+
+// httpClients has the clients of a notifier, so connections are reused between its
+// requests and closed after idleConnTimeout. There is a client for each destination
+// policy, as the policy is only known from the context of requests.
+type httpClients struct {
+	mtx     sync.Mutex
+	clients map[*DestinationPolicy]*http.Client
+}
+
+// get returns the client for the configuration, which must be the same for all requests
+// except for the destination policy.
+func (c *httpClients) get(cfg httpCfg) *http.Client {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if client, ok := c.clients[cfg.destinationPolicy]; ok {
+		return client
+	}
+	if c.clients == nil {
+		c.clients = make(map[*DestinationPolicy]*http.Client)
+	}
+	client := newHTTPClient(cfg)
+	c.clients[cfg.destinationPolicy] = client
+	return client
+}
+
+// hostnameRegexp matches hostnames as described in RFC 1123.
+var hostnameRegexp = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+
+// buildHostHeader returns the host_header setting of the notifier, or an empty string
+// if it is not set. It is a hostname or IP address with an optional port.
+func buildHostHeader(fc channels.FactoryConfig) (string, error) {
+	var settings struct {
+		HostHeader string `json:"host_header,omitempty" yaml:"host_header,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return "", fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.HostHeader == "" {
+		return "", nil
+	}
+
+	host, port, err := net.SplitHostPort(settings.HostHeader)
+	if err != nil {
+		host, port = settings.HostHeader, ""
+	}
+	validPort := true
+	if port != "" {
+		p, err := strconv.Atoi(port)
+		validPort = err == nil && p >= 1 && p <= 65535
+	}
+	if !validPort || (!hostnameRegexp.MatchString(host) && net.ParseIP(strings.Trim(host, "[]")) == nil) {
+		return "", fmt.Errorf("invalid value for host_header: %q, must be a hostname with an optional port", settings.HostHeader)
+	}
+	return settings.HostHeader, nil
+}
+
+// buildHTTPResolver returns a resolver that uses the DNS server in the dns_server
+// setting of the notifier, or nil if the system resolver should be used.
+func buildHTTPResolver(fc channels.FactoryConfig) (*net.Resolver, error) {
+	var settings struct {
+		DNSServer string `json:"dns_server,omitempty" yaml:"dns_server,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.DNSServer == "" {
+		return nil, nil
+	}
+
+	// The port is optional and defaults to 53.
+	addr := settings.DNSServer
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = strings.Trim(addr, "[]"), "53"
+	}
+	if p, err := strconv.Atoi(port); net.ParseIP(host) == nil || err != nil || p < 1 || p > 65535 {
+		return nil, fmt.Errorf("invalid value for dns_server: %q, must be an IP address with an optional port", settings.DNSServer)
+	}
+	addr = net.JoinHostPort(host, port)
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialWithDestinationPolicy(ctx, &net.Dialer{}, network, addr, nil)
+		},
+	}, nil
+}
+
+// buildExpectedStatusCodes returns the expected_status_codes setting of the notifier,
+// or nil if the setting is not set.
+func buildExpectedStatusCodes(fc channels.FactoryConfig) ([]int, error) {
+	var settings struct {
+		ExpectedStatusCodes channels.CommaSeparatedStrings `json:"expected_status_codes,omitempty" yaml:"expected_status_codes,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	var codes []int
+	for _, s := range settings.ExpectedStatusCodes {
+		code, err := strconv.Atoi(s)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status code in expected_status_codes: %q", s)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// isExpectedStatusCode returns true if the status code is one of the expected status codes,
+// or a 2xx status code if there are no expected status codes.
+func isExpectedStatusCode(statusCode int, expectedStatusCodes []int) bool {
+	if len(expectedStatusCodes) == 0 {
+		return statusCode/100 == 2
+	}
+	for _, code := range expectedStatusCodes {
+		if statusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// buildHTTPTimeouts returns the connect_timeout and request_timeout settings of the
+// notifier. Each timeout is defaultHTTPTimeout if not set.
+func buildHTTPTimeouts(fc channels.FactoryConfig) (connectTimeout time.Duration, requestTimeout time.Duration, err error) {
+	var settings struct {
+		ConnectTimeout string `json:"connect_timeout,omitempty" yaml:"connect_timeout,omitempty"`
+		RequestTimeout string `json:"request_timeout,omitempty" yaml:"request_timeout,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return 0, 0, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	if connectTimeout, err = parseHTTPTimeout("connect_timeout", settings.ConnectTimeout, defaultHTTPTimeout); err != nil {
+		return 0, 0, err
+	}
+	if requestTimeout, err = parseHTTPTimeout("request_timeout", settings.RequestTimeout, defaultHTTPTimeout); err != nil {
+		return 0, 0, err
+	}
+	if connectTimeout > requestTimeout {
+		return 0, 0, fmt.Errorf("connect_timeout %s must not be greater than request_timeout %s", connectTimeout, requestTimeout)
+	}
+	return connectTimeout, requestTimeout, nil
+}
+
+// buildResponseHeaderTimeout returns the response_header_timeout setting of the notifier.
+func buildResponseHeaderTimeout(fc channels.FactoryConfig) (time.Duration, error) {
+	var settings struct {
+		ResponseHeaderTimeout string `json:"response_header_timeout,omitempty" yaml:"response_header_timeout,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	return parseHTTPTimeout("response_header_timeout", settings.ResponseHeaderTimeout, defaultResponseHeaderTimeout)
+}
+
+// buildIdleConnTimeout returns the idle_conn_timeout setting of the notifier.
+func buildIdleConnTimeout(fc channels.FactoryConfig) (time.Duration, error) {
+	var settings struct {
+		IdleConnTimeout string `json:"idle_conn_timeout,omitempty" yaml:"idle_conn_timeout,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	return parseHTTPTimeout("idle_conn_timeout", settings.IdleConnTimeout, defaultIdleConnTimeout)
+}
+
+// buildHTTPTrace returns the debug_http_trace setting of the notifier. It is true if the
+// latency breakdown of HTTP requests should be logged at debug level.
+func buildHTTPTrace(fc channels.FactoryConfig) (bool, error) {
+	var settings struct {
+		DebugHTTPTrace bool `json:"debug_http_trace,omitempty" yaml:"debug_http_trace,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return false, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	return settings.DebugHTTPTrace, nil
+}
+
+// buildMaxRedirects returns the max_redirects setting of the notifier, or 0 if
+// redirects should be refused.
+func buildMaxRedirects(fc channels.FactoryConfig) (int, error) {
+	var settings struct {
+		MaxRedirects json.Number `json:"max_redirects,omitempty" yaml:"max_redirects,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.MaxRedirects == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(settings.MaxRedirects.String())
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid value for max_redirects: %q, must be a non-negative integer", settings.MaxRedirects)
+	}
+	return n, nil
+}
+
+// buildMaxBodyLogLen returns the max_body_log_len setting of the notifier, or 0 if the
+// default should be used.
+func buildMaxBodyLogLen(fc channels.FactoryConfig) (int, error) {
+	var settings struct {
+		MaxBodyLogLen json.Number `json:"max_body_log_len,omitempty" yaml:"max_body_log_len,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if settings.MaxBodyLogLen == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(settings.MaxBodyLogLen.String())
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid value for max_body_log_len: %q, must be a positive integer", settings.MaxBodyLogLen)
+	}
+	return n, nil
+}
+
+// truncateBody returns the body for logging, truncated to at most n bytes with the
+// number of bytes that were truncated.
+func truncateBody(body []byte, n int) string {
+	if len(body) <= n {
+		return string(body)
+	}
+	// Multi-byte characters are not split.
+	i := n
+	for i > 0 && !utf8.RuneStart(body[i]) {
+		i--
+	}
+	return fmt.Sprintf("%s... (%d bytes truncated)", body[:i], len(body)-i)
+}
+
+// buildRedirectHosts returns the max_redirects_follow_host_allowlist setting of the
+// notifier, or nil if redirects should only be followed to the same host.
+func buildRedirectHosts(fc channels.FactoryConfig) ([]string, error) {
+	var settings struct {
+		RedirectHosts channels.CommaSeparatedStrings `json:"max_redirects_follow_host_allowlist,omitempty" yaml:"max_redirects_follow_host_allowlist,omitempty"`
+	}
+	if err := json.Unmarshal(fc.Config.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	for _, host := range settings.RedirectHosts {
+		if !hostnameRegexp.MatchString(strings.TrimPrefix(host, "*.")) {
+			return nil, fmt.Errorf("invalid host in max_redirects_follow_host_allowlist: %q, must be a hostname or *.domain", host)
+		}
+	}
+	return settings.RedirectHosts, nil
+}
+
+// isRedirectHostAllowed returns true if redirects can be followed to the host. If there
+// are no allowed hosts, only the host of the original request is allowed.
+func isRedirectHostAllowed(host, origHost string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return strings.EqualFold(host, origHost)
+	}
+	return matchesHosts(host, allowed)
+}
+
+// parseHTTPTimeout parses the timeout setting with the name, or returns def if s is empty.
+func parseHTTPTimeout(name, s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid value for %s: %q", name, s)
+	}
+	return d, nil
+}
+
+// idempotencyKey returns a key that identifies the notification for the group key in ctx
+// and the firing alerts in as. The key is the same for retries of the notification, and
+// changes when the set of firing alerts changes.
+func idempotencyKey(ctx context.Context, as ...*types.Alert) (string, error) {
+	groupKey, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	fingerprints := make([]string, 0, len(as))
+	for _, alert := range as {
+		if alert.Status() == model.AlertFiring {
+			fingerprints = append(fingerprints, alert.Fingerprint().String())
+		}
+	}
+	sort.Strings(fingerprints)
+
+	h := sha256.New()
+	_, _ = h.Write([]byte(groupKey.Hash()))
+	for _, fingerprint := range fingerprints {
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(fingerprint))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// httpStatusError is returned by sendHTTPRequest when the status code of the response
+// is not expected.
+type httpStatusError struct {
+	StatusCode int
+}
+
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("failed to send HTTP request - status code %d", e.StatusCode)
+}
+
+// httpSendFunc sends an HTTP request and returns the response body.
+type httpSendFunc func(ctx context.Context, url *url.URL, cfg httpCfg, logger channels.Logger) ([]byte, error)
+
+// sendMiddleware wraps an httpSendFunc to add a concern to sending requests, such as
+// retries or rate limiting. The returned httpSendFunc must call next to send the request.
+type sendMiddleware func(next httpSendFunc) httpSendFunc
+
+// wrapSend returns send wrapped in the middlewares. The first middleware is the
+// outermost, so it is called first and returns last.
+func wrapSend(send httpSendFunc, middlewares ...sendMiddleware) httpSendFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		send = middlewares[i](send)
+	}
+	return send
 }
 
 // sendHTTPRequest sends an HTTP request.
 // Stubbable by tests.
-var sendHTTPRequest = func(ctx context.Context, url *url.URL, cfg httpCfg, logger channels.Logger) ([]byte, error) {
+var sendHTTPRequest = wrapSend(doHTTPRequest, withHTTPTrace, withContextDestinationPolicy)
+
+// withHTTPTrace logs the latency breakdown of the request if cfg.trace is set.
+func withHTTPTrace(next httpSendFunc) httpSendFunc {
+	return func(ctx context.Context, url *url.URL, cfg httpCfg, logger channels.Logger) ([]byte, error) {
+		if cfg.trace {
+			trace := &httpTrace{start: time.Now()}
+			ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+			defer trace.log(logger, url)
+		}
+		return next(ctx, url, cfg, logger)
+	}
+}
+
+// withContextDestinationPolicy enforces the destination policy in ctx if cfg has none.
+func withContextDestinationPolicy(next httpSendFunc) httpSendFunc {
+	return func(ctx context.Context, url *url.URL, cfg httpCfg, logger channels.Logger) ([]byte, error) {
+		if cfg.destinationPolicy == nil {
+			cfg.destinationPolicy = destinationPolicyFromContext(ctx)
+		}
+		return next(ctx, url, cfg, logger)
+	}
+}
+
+// doHTTPRequest sends an HTTP request without the middlewares of sendHTTPRequest.
+func doHTTPRequest(ctx context.Context, url *url.URL, cfg httpCfg, logger channels.Logger) ([]byte, error) {
 	var reader io.Reader
 	if len(cfg.body) > 0 {
 		reader = bytes.NewReader(cfg.body)
 	}
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url.String(), reader)
+	method := cfg.method
+	if method == "" {
+		method = http.MethodPost
+	}
+	request, err := http.NewRequestWithContext(ctx, method, url.String(), reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -155,19 +962,18 @@ var sendHTTPRequest = func(ctx context.Context, url *url.URL, cfg httpCfg, logge
 
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("User-Agent", "Grafana")
-	netTransport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			Renegotiation: tls.RenegotiateFreelyAsClient,
-		},
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout: 30 * time.Second,
-		}).DialContext,
-		TLSHandshakeTimeout: 5 * time.Second,
-	}
-	netClient := &http.Client{
-		Timeout:   time.Second * 30,
-		Transport: netTransport,
+	// The transport only decompresses gzip responses if it sets Accept-Encoding
+	// itself, so compressed responses are decoded in decodeResponseBody instead.
+	request.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	var netClient *http.Client
+	if cfg.clients != nil {
+		netClient = cfg.clients.get(cfg)
+	} else {
+		// The client is only used for this request, so its connections are closed once
+		// the response is read instead of being kept idle until idleConnTimeout.
+		netClient = newHTTPClient(cfg)
+		defer netClient.CloseIdleConnections()
 	}
 	resp, err := netClient.Do(request)
 	if err != nil {
@@ -179,21 +985,287 @@ var sendHTTPRequest = func(ctx context.Context, url *url.URL, cfg httpCfg, logge
 		}
 	}()
 
-	respBody, err := io.ReadAll(resp.Body)
+	respBody, err := readResponseBody(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	respBody, err = decodeResponseBody(resp.Header.Get("Content-Encoding"), respBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
 
-	if resp.StatusCode/100 != 2 {
+	if !isExpectedStatusCode(resp.StatusCode, cfg.expectedStatusCodes) {
+		maxBodyLogLen := cfg.maxBodyLogLen
+		if maxBodyLogLen == 0 {
+			maxBodyLogLen = defaultMaxBodyLogLen
+		}
 		logger.Warn("HTTP request failed", "url", request.URL.String(), "statusCode", resp.Status, "body",
-			string(respBody))
-		return nil, fmt.Errorf("failed to send HTTP request - status code %d", resp.StatusCode)
+			truncateBody(respBody, maxBodyLogLen))
+		return nil, httpStatusError{StatusCode: resp.StatusCode}
 	}
 
 	logger.Debug("sending HTTP request succeeded", "url", request.URL.String(), "statusCode", resp.Status)
 	return respBody, nil
 }
 
+// newHTTPClient returns the client that sends requests with the configuration. The
+// Host of requests is overridden and requests are signed with SigV4 by its transport,
+// so redirected requests are signed too.
+func newHTTPClient(cfg httpCfg) *http.Client {
+	requestTimeout := cfg.requestTimeout
+	if requestTimeout == 0 {
+		requestTimeout = defaultHTTPTimeout
+	}
+	var transport http.RoundTripper = newHTTPTransport(cfg)
+	if cfg.host != "" || cfg.sigV4 != nil {
+		transport = &requestRoundTripper{next: transport, host: cfg.host, sigV4: cfg.sigV4}
+	}
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if cfg.maxRedirects == 0 {
+				// The redirect response is returned, so the request fails with its status code.
+				return http.ErrUseLastResponse
+			}
+			if len(via) > cfg.maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", cfg.maxRedirects)
+			}
+			orig := via[0]
+			if !isRedirectHostAllowed(req.URL.Hostname(), orig.URL.Hostname(), cfg.redirectHosts) {
+				return fmt.Errorf("redirect to host %q is not allowed", req.URL.Hostname())
+			}
+			// Go changes the method to GET and drops the body for 301, 302 and 303 redirects.
+			req.Method = orig.Method
+			if orig.GetBody != nil {
+				body, err := orig.GetBody()
+				if err != nil {
+					return err
+				}
+				req.Body = body
+				req.GetBody = orig.GetBody
+				req.ContentLength = orig.ContentLength
+				req.Header.Set("Content-Type", orig.Header.Get("Content-Type"))
+			}
+			return nil
+		},
+	}
+}
+
+// requestRoundTripper overrides the Host of requests and signs them with SigV4 before
+// they are sent with next.
+type requestRoundTripper struct {
+	next  http.RoundTripper
+	host  string
+	sigV4 *sigV4Config
+}
+
+func (rt *requestRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The request must not be modified by the transport.
+	req = req.Clone(req.Context())
+
+	// The Host header field is ignored by the client, only request.Host is sent. As by
+	// the client, the Host is only kept on redirects to the host of the first request.
+	first := req
+	for first.Response != nil {
+		first = first.Response.Request
+	}
+	if rt.host != "" && req.URL.Host == first.URL.Host {
+		req.Host = rt.host
+	}
+
+	if rt.sigV4 != nil {
+		if err := rt.sign(req); err != nil {
+			// The body must be closed by the transport, even on errors.
+			if req.Body != nil {
+				_ = req.Body.Close()
+			}
+			return nil, err
+		}
+	}
+	return rt.next.RoundTrip(req)
+}
+
+// sign signs the request with SigV4. The body that is signed is read with GetBody,
+// so the body of the request can still be sent.
+func (rt *requestRoundTripper) sign(req *http.Request) error {
+	var body []byte
+	if req.GetBody != nil {
+		r, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		if body, err = io.ReadAll(r); err != nil {
+			return err
+		}
+	}
+	return rt.sigV4.sign(req, body, time.Now())
+}
+
+// newHTTPTransport returns the transport for a request with the configuration.
+func newHTTPTransport(cfg httpCfg) *http.Transport {
+	connectTimeout := cfg.connectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = defaultHTTPTimeout
+	}
+	responseHeaderTimeout := cfg.responseHeaderTimeout
+	if responseHeaderTimeout == 0 {
+		responseHeaderTimeout = defaultResponseHeaderTimeout
+	}
+	idleConnTimeout := cfg.idleConnTimeout
+	if idleConnTimeout == 0 {
+		idleConnTimeout = defaultIdleConnTimeout
+	}
+	tlsConfig := &tls.Config{}
+	if cfg.tlsConfig != nil {
+		tlsConfig = cfg.tlsConfig.Clone()
+	}
+	tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+	dialer := &net.Dialer{
+		Timeout:  connectTimeout,
+		Resolver: cfg.resolver,
+	}
+	transport := &http.Transport{
+		TLSClientConfig:       tlsConfig,
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		IdleConnTimeout:       idleConnTimeout,
+	}
+	if cfg.destinationPolicy != nil {
+		transport.Proxy = cfg.destinationPolicy.proxy(cfg.resolver)
+		transport.DialContext = cfg.destinationPolicy.dialContext(dialer)
+	}
+	return transport
+}
+
+// httpTrace records the time spent in each phase of an HTTP request.
+type httpTrace struct {
+	start time.Time
+
+	// mtx protects the fields below, as connections to multiple addresses of
+	// a host can be attempted in parallel.
+	mtx          sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	dns          time.Duration
+	connect      time.Duration
+	tls          time.Duration
+	ttfb         time.Duration
+}
+
+func (t *httpTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mtx.Lock()
+			defer t.mtx.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mtx.Lock()
+			defer t.mtx.Unlock()
+			t.dns = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mtx.Lock()
+			defer t.mtx.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mtx.Lock()
+			defer t.mtx.Unlock()
+			if err == nil {
+				t.connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mtx.Lock()
+			defer t.mtx.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mtx.Lock()
+			defer t.mtx.Unlock()
+			t.tls = time.Since(t.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			t.mtx.Lock()
+			defer t.mtx.Unlock()
+			t.ttfb = time.Since(t.start)
+		},
+	}
+}
+
+// log logs the time spent in each phase. Phases that did not happen, such as DNS
+// for IP addresses or TLS for http URLs, are logged as 0s.
+func (t *httpTrace) log(logger channels.Logger, u *url.URL) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	logger.Debug("HTTP request trace", "url", u.String(), "dns", t.dns, "connect", t.connect, "tls", t.tls,
+		"ttfb", t.ttfb, "total", time.Since(t.start))
+}
+
+// decodeResponseBody decompresses the response body if the Content-Encoding
+// is gzip or deflate. Other encodings are returned as is.
+func decodeResponseBody(encoding string, body []byte) ([]byte, error) {
+	if len(body) == 0 {
+		return body, nil
+	}
+	var r io.ReadCloser
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	case "deflate":
+		// deflate should be zlib, but some servers send raw deflate data instead.
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			zr = flate.NewReader(bytes.NewReader(body))
+		}
+		r = zr
+	default:
+		return body, nil
+	}
+	defer func() { _ = r.Close() }()
+	return readResponseBody(r)
+}
+
+// readResponseBody reads the response body, and returns an error if it is longer than
+// maxResponseBodyLen.
+func readResponseBody(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, maxResponseBodyLen+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxResponseBodyLen {
+		return nil, fmt.Errorf("response body is longer than %d bytes", maxResponseBodyLen)
+	}
+	return b, nil
+}
+
+// isHTTPURL returns true if s is an absolute http or https URL.
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// grafanaURL returns the URL of the Grafana page at the path, such as /alerting/list or
+// /d/<uid>. The path is joined to the path of the external URL, so links keep the subpath
+// of Grafana when it is served from one.
+func grafanaURL(externalURL *url.URL, p string) string {
+	u := *externalURL
+	u.Path = path.Join("/", u.Path, p)
+	u.RawPath = ""
+	return u.String()
+}
+
 func joinUrlPath(base, additionalPath string, logger channels.Logger) string {
 	u, err := url.Parse(base)
 	if err != nil {
@@ -206,8 +1278,90 @@ func joinUrlPath(base, additionalPath string, logger channels.Logger) string {
 	return u.String()
 }
 
+// writeMultipartFile writes the contents of r to the multipart form as a file with the
+// field name and filename, as receivers expect files in specific fields.
+func writeMultipartFile(w *multipart.Writer, fieldName, filename string, r io.Reader) error {
+	fw, err := w.CreateFormFile(fieldName, filename)
+	if err != nil {
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(fw, r); err != nil {
+		return fmt.Errorf("failed to write to form file: %w", err)
+	}
+	return nil
+}
+
 // GetBoundary is used for overriding the behaviour for tests
 // and set a boundary for multipart body. DO NOT set this outside tests.
 var GetBoundary = func() string {
 	return ""
 }
+
+// envReferenceRegexp matches references to environment variables in settings, such
+// as ${env:DISCORD_WEBHOOK_URL}.
+var envReferenceRegexp = regexp.MustCompile(`\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// lookupEnv returns the value of the environment variable and whether it is set.
+// Stubbable by tests.
+var lookupEnv = os.LookupEnv
+
+// resolveEnvReferences returns the URL in the setting with references to environment
+// variables, such as ${env:DISCORD_WEBHOOK_URL}, replaced by their values, so secrets
+// that are mounted as environment variables are not stored in the database. Values
+// without references are returned unchanged.
+//
+// Only the environment variables allowed by the operator can be referenced, and only as
+// the whole URL or after its host, so that the settings of contact points cannot send
+// other environment variables, such as the secret key of Grafana, to any host.
+func resolveEnvReferences(setting, value string) (string, error) {
+	refs := envReferenceRegexp.FindAllStringSubmatchIndex(value, -1)
+	if len(refs) == 0 {
+		return value, nil
+	}
+	if !(len(refs) == 1 && refs[0][0] == 0 && refs[0][1] == len(value)) && !isAfterHost(value[:refs[0][0]]) {
+		return "", fmt.Errorf("invalid value for %s: environment variables can only be referenced as the whole URL or after its host", setting)
+	}
+	allowed := getOptions().EnvironmentVariables
+	var err error
+	resolved := envReferenceRegexp.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envReferenceRegexp.FindStringSubmatch(ref)[1]
+		if err != nil {
+			return ""
+		}
+		if !isEnvironmentVariableAllowed(name, allowed) {
+			err = fmt.Errorf("environment variable %q in %s is not allowed", name, setting)
+			return ""
+		}
+		v, ok := lookupEnv(name)
+		if !ok {
+			err = fmt.Errorf("environment variable %q in %s is not set", name, setting)
+		}
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// isAfterHost returns true if the prefix of a URL has a scheme and a host that are
+// followed by a path, query or fragment, so what follows the prefix cannot change them.
+func isAfterHost(prefix string) bool {
+	u, err := url.Parse(prefix)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	rest := strings.TrimPrefix(prefix, u.Scheme+"://")
+	return rest != prefix && strings.ContainsAny(rest, "/?#")
+}
+
+// isEnvironmentVariableAllowed returns true if the environment variable is one of the
+// allowed names, or starts with an allowed name that ends with "*".
+func isEnvironmentVariableAllowed(name string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == name || strings.HasSuffix(a, "*") && strings.HasPrefix(name, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,31 @@
 package channels
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
 
 	"github.com/grafana/alerting/alerting/notifier/channels"
 
@@ -62,3 +79,1442 @@ func TestWithStoredImages(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 1, i)
 }
+
+func TestSendChunks(t *testing.T) {
+	var sent []int
+	chunk := func(n int, err error) messageChunk {
+		return func(ctx context.Context) error {
+			sent = append(sent, n)
+			return err
+		}
+	}
+
+	// Chunks after a failed chunk are sent, and the failed chunks are combined.
+	delivered, err := sendChunks(context.Background(), &channels.FakeLogger{},
+		chunk(1, nil),
+		chunk(2, errors.New("image too large")),
+		chunk(3, nil),
+		chunk(4, errors.New("rate limited")),
+	)
+	require.True(t, delivered)
+	require.EqualError(t, err, "failed to send 2 of 4 chunks: chunk 2: image too large; chunk 4: rate limited")
+	var chunksErr chunksError
+	require.ErrorAs(t, err, &chunksErr)
+	require.Equal(t, []int{2, 4}, chunksErr.failed)
+	require.Equal(t, []int{1, 2, 3, 4}, sent)
+
+	// No chunks are sent after the first chunk fails.
+	sent = nil
+	delivered, err = sendChunks(context.Background(), &channels.FakeLogger{},
+		chunk(1, errors.New("unauthorized")),
+		chunk(2, nil),
+	)
+	require.False(t, delivered)
+	require.EqualError(t, err, "unauthorized")
+	require.Equal(t, []int{1}, sent)
+
+	// All chunks are sent.
+	sent = nil
+	delivered, err = sendChunks(context.Background(), &channels.FakeLogger{}, chunk(1, nil), chunk(2, nil))
+	require.True(t, delivered)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, sent)
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	alert1 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}}
+	alert2 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2"}}}
+	resolved := &types.Alert{Alert: model.Alert{
+		Labels: model.LabelSet{"alertname": "alert3"},
+		EndsAt: time.Now().Add(-time.Minute),
+	}}
+
+	key, err := idempotencyKey(ctx, alert1, alert2)
+	require.NoError(t, err)
+	require.NotEmpty(t, key)
+
+	// should be the same for retries, irrespective of the order of the alerts
+	// or of resolved alerts
+	other, err := idempotencyKey(ctx, alert2, alert1)
+	require.NoError(t, err)
+	assert.Equal(t, key, other)
+	other, err = idempotencyKey(ctx, alert1, alert2, resolved)
+	require.NoError(t, err)
+	assert.Equal(t, key, other)
+
+	// should change when the firing alerts change
+	other, err = idempotencyKey(ctx, alert1)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	// should change when the group key changes
+	other, err = idempotencyKey(notify.WithGroupKey(context.Background(), "other"), alert1, alert2)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	// should return an error without a group key
+	_, err = idempotencyKey(context.Background(), alert1)
+	require.Error(t, err)
+}
+
+func TestTmplText_MaxValueLen(t *testing.T) {
+	tmpl := templateForTests(t)
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	alerts := []*types.Alert{{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+			Annotations: model.LabelSet{"stacktrace": model.LabelValue(strings.Repeat("a", 100))},
+		},
+	}}
+
+	var tmplErr error
+	fn, data := tmplText(ctx, tmpl, alerts, &channels.FakeLogger{}, &tmplErr, 10)
+	require.Equal(t, "aaaaaaaaa…", fn(`{{ (index .Alerts 0).Annotations.stacktrace }}`))
+	require.Equal(t, "aaaaaaaaa…", fn(`{{ .CommonAnnotations.stacktrace }}`))
+	require.NoError(t, tmplErr)
+	assert.Equal(t, "val1", data.Alerts[0].Labels["lbl1"])
+
+	// should not truncate values if the maximum length is 0
+	fn, _ = tmplText(ctx, tmpl, alerts, &channels.FakeLogger{}, &tmplErr, 0)
+	require.Equal(t, strings.Repeat("a", 100), fn(`{{ (index .Alerts 0).Annotations.stacktrace }}`))
+	require.NoError(t, tmplErr)
+}
+
+func TestBuildSilenceDuration(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings string
+		exp      string
+		expErr   string
+	}{{
+		name:     "not set",
+		settings: `{}`,
+	}, {
+		name:     "single unit",
+		settings: `{"silence_duration": "1h"}`,
+		exp:      "1h",
+	}, {
+		name:     "units are separated with spaces",
+		settings: `{"silence_duration": "1d12h30m"}`,
+		exp:      "1d 12h 30m",
+	}, {
+		name:     "units are normalized",
+		settings: `{"silence_duration": "90m"}`,
+		exp:      "1h 30m",
+	}, {
+		name:     "invalid",
+		settings: `{"silence_duration": "1 hour"}`,
+		expErr:   `invalid value for silence_duration: "1 hour", must be a positive duration in seconds or more, such as 1h or 1d12h`,
+	}, {
+		name:     "zero",
+		settings: `{"silence_duration": "0s"}`,
+		expErr:   `invalid value for silence_duration: "0s", must be a positive duration in seconds or more, such as 1h or 1d12h`,
+	}, {
+		name:     "less than a second",
+		settings: `{"silence_duration": "1h500ms"}`,
+		expErr:   `invalid value for silence_duration: "1h500ms", must be a positive duration in seconds or more, such as 1h or 1d12h`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{Settings: json.RawMessage(c.settings)},
+			}
+			silenceDuration, err := buildSilenceDuration(fc)
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.exp, silenceDuration)
+		})
+	}
+}
+
+func TestSetSilenceDuration(t *testing.T) {
+	tmpl := templateForTests(t)
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	alerts := []*types.Alert{{
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "lbl1": "val1"}},
+	}}
+
+	var tmplErr error
+	fn, data := tmplText(ctx, tmpl, alerts, &channels.FakeLogger{}, &tmplErr, 0)
+	setSilenceDuration(data, "1d 12h", &channels.FakeLogger{})
+	require.Equal(t, "http://localhost/alerting/silence/new?alertmanager=grafana&duration=1d+12h&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1", data.Alerts[0].SilenceURL)
+	// The templates use the silence URL with the duration.
+	require.Equal(t, data.Alerts[0].SilenceURL, fn(`{{ (index .Alerts 0).SilenceURL }}`))
+	require.NoError(t, tmplErr)
+
+	// should not change the silence URL if the duration is empty
+	_, data = tmplText(ctx, tmpl, alerts, &channels.FakeLogger{}, &tmplErr, 0)
+	setSilenceDuration(data, "", &channels.FakeLogger{})
+	require.Equal(t, "http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1", data.Alerts[0].SilenceURL)
+}
+
+func TestAlertFingerprints(t *testing.T) {
+	alerts := []*types.Alert{{
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "lbl1": "val1"}},
+	}, {
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2"}},
+	}}
+
+	fingerprints := alertFingerprints(alerts)
+	require.Equal(t, []string{"fac0861a85de433a", "ea03c3f233bc9acc"}, fingerprints)
+
+	// The fingerprints must be the same as in templates.
+	var tmplErr error
+	_, data := tmplText(context.Background(), templateForTests(t), alerts, &channels.FakeLogger{}, &tmplErr, 0)
+	require.NoError(t, tmplErr)
+	for i, a := range data.Alerts {
+		require.Equal(t, a.Fingerprint, fingerprints[i])
+	}
+}
+
+func TestHighestSeverity(t *testing.T) {
+	alerts := func(severities ...string) []*types.Alert {
+		as := make([]*types.Alert, 0, len(severities))
+		for _, s := range severities {
+			as = append(as, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"severity": model.LabelValue(s)}}})
+		}
+		return as
+	}
+
+	cases := []struct {
+		name   string
+		alerts []*types.Alert
+		mapped []string
+		exp    int
+	}{{
+		name:   "highest severity in mapping order",
+		alerts: alerts("info", "critical"),
+		mapped: []string{"critical", "warning", "info"},
+		exp:    0,
+	}, {
+		name:   "highest severity in reverse mapping order",
+		alerts: alerts("info", "critical"),
+		mapped: []string{"info", "warning", "critical"},
+		exp:    2,
+	}, {
+		name:   "severities are case insensitive",
+		alerts: alerts("Warning"),
+		mapped: []string{"info", "WARNING"},
+		exp:    1,
+	}, {
+		name:   "unknown severities are ranked below known severities",
+		alerts: alerts("p1", "info"),
+		mapped: []string{"p1", "info"},
+		exp:    1,
+	}, {
+		name:   "unknown severities are ranked in mapping order",
+		alerts: alerts("p2", "p1"),
+		mapped: []string{"p1", "p2"},
+		exp:    0,
+	}, {
+		name:   "no severity in the mapping",
+		alerts: alerts("warning", ""),
+		mapped: []string{"critical"},
+		exp:    -1,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.exp, highestSeverity(c.alerts, c.mapped))
+		})
+	}
+}
+
+func TestIncludeFingerprints(t *testing.T) {
+	alerts := []*types.Alert{{
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "lbl1": "val1"}},
+	}}
+
+	cases := []struct {
+		name            string
+		settings        string
+		expFingerprints []string
+	}{{
+		name:     "fingerprints are not included by default",
+		settings: `{"url": "http://localhost/test"}`,
+	}, {
+		name:            "fingerprints are included when enabled",
+		settings:        `{"url": "http://localhost/test", "include_fingerprints": true}`,
+		expFingerprints: []string{"fac0861a85de433a"},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          &channels.UnavailableImageStore{},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: templateForTests(t),
+				Logger:   &channels.FakeLogger{},
+			}
+
+			wn, err := buildWebhookNotifier(fc)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := wn.Notify(ctx, alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			var msg WebhookMessage
+			require.NoError(t, json.Unmarshal([]byte(webhookSender.Webhook.Body), &msg))
+			require.Equal(t, c.expFingerprints, msg.Fingerprints)
+		})
+	}
+}
+
+func TestBuildMaxValueLen(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings string
+		exp      int
+		expErr   string
+	}{{
+		name:     "not set",
+		settings: `{}`,
+	}, {
+		name:     "number",
+		settings: `{"max_value_len": 100}`,
+		exp:      100,
+	}, {
+		name:     "string",
+		settings: `{"max_value_len": "100"}`,
+		exp:      100,
+	}, {
+		name:     "negative",
+		settings: `{"max_value_len": -1}`,
+		expErr:   `invalid value for max_value_len: "-1"`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{Settings: json.RawMessage(c.settings)},
+			}
+			maxValueLen, err := buildMaxValueLen(fc)
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.exp, maxValueLen)
+		})
+	}
+}
+
+func TestGeneratorURL(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	cases := []struct {
+		name   string
+		alerts []*types.Alert
+		expURL string
+	}{{
+		name: "Generator URL of the first alert that has one",
+		alerts: []*types.Alert{{
+			Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}},
+		}, {
+			Alert: model.Alert{
+				Labels:       model.LabelSet{"alertname": "alert2"},
+				GeneratorURL: "http://localhost/alerting/grafana/abcd/view",
+			},
+		}},
+		expURL: "http://localhost/alerting/grafana/abcd/view",
+	}, {
+		name: "Generator URLs that are not http URLs are ignored",
+		alerts: []*types.Alert{{
+			Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}, GeneratorURL: "/graph?g0.expr=up"},
+		}},
+	}, {
+		name: "Alerts without a generator URL",
+		alerts: []*types.Alert{{
+			Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}},
+		}},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var tmplErr error
+			_, data := tmplText(context.Background(), tmpl, c.alerts, &channels.FakeLogger{}, &tmplErr, 0)
+			require.NoError(t, tmplErr)
+			require.Equal(t, c.expURL, generatorURL(data))
+		})
+	}
+}
+
+func TestSendHTTPRequest_RequestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = sendHTTPRequest(context.Background(), u, httpCfg{
+		connectTimeout: 50 * time.Millisecond,
+		requestTimeout: 100 * time.Millisecond,
+	}, &channels.FakeLogger{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Client.Timeout exceeded")
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestSendHTTPRequest_ResponseHeaderTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-body" {
+			// The headers are sent immediately but the body is slow.
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		// The headers are never sent.
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// should fail fast when the headers are delayed
+	start := time.Now()
+	_, err = sendHTTPRequest(context.Background(), u, httpCfg{
+		responseHeaderTimeout: 100 * time.Millisecond,
+	}, &channels.FakeLogger{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "timeout awaiting response headers")
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	// should not fail when only the body is slower than the timeout
+	u.Path = "/slow-body"
+	b, err := sendHTTPRequest(context.Background(), u, httpCfg{
+		responseHeaderTimeout: 100 * time.Millisecond,
+	}, &channels.FakeLogger{})
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(b))
+}
+
+func TestNewHTTPTransport_IdleConnTimeout(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings string
+		exp      time.Duration
+		expErr   string
+	}{{
+		name:     "default",
+		settings: `{}`,
+		exp:      90 * time.Second,
+	}, {
+		name:     "configured",
+		settings: `{"idle_conn_timeout": "15s"}`,
+		exp:      15 * time.Second,
+	}, {
+		name:     "invalid",
+		settings: `{"idle_conn_timeout": "-1s"}`,
+		expErr:   `invalid value for idle_conn_timeout: "-1s"`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			idleConnTimeout, err := buildIdleConnTimeout(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{Settings: json.RawMessage(c.settings)},
+			})
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			transport := newHTTPTransport(httpCfg{idleConnTimeout: idleConnTimeout})
+			require.Equal(t, c.exp, transport.IdleConnTimeout)
+		})
+	}
+
+	// The default is used if the timeout is not set, as for notifiers without the setting.
+	require.Equal(t, defaultIdleConnTimeout, newHTTPTransport(httpCfg{}).IdleConnTimeout)
+}
+
+func TestSendHTTPRequest_Connections(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		states = make(map[http.ConnState]int)
+	)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		states[state]++
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	stateCount := func(state http.ConnState) func() int {
+		return func() int {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return states[state]
+		}
+	}
+
+	// The connection of the request is closed once the response is read.
+	_, err = sendHTTPRequest(context.Background(), u, httpCfg{}, &channels.FakeLogger{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return stateCount(http.StateClosed)() == 1 }, time.Second, 10*time.Millisecond)
+
+	// The connection is reused between the requests of a notifier.
+	var clients httpClients
+	for i := 0; i < 3; i++ {
+		_, err = sendHTTPRequest(context.Background(), u, httpCfg{clients: &clients}, &channels.FakeLogger{})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, stateCount(http.StateNew)())
+	require.Equal(t, 1, stateCount(http.StateClosed)())
+
+	// A client is created for each destination policy.
+	p, err := NewDestinationPolicy(nil, []string{"169.254.0.0/16"})
+	require.NoError(t, err)
+	_, err = sendHTTPRequest(withDestinationPolicy(context.Background(), p), u, httpCfg{clients: &clients}, &channels.FakeLogger{})
+	require.NoError(t, err)
+	require.Equal(t, 3, stateCount(http.StateNew)())
+	require.Len(t, clients.clients, 2)
+}
+
+func TestSendHTTPRequest_HostHeader(t *testing.T) {
+	var host string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		settings string
+		expHost  string
+		expErr   string
+	}{{
+		name:     "host of the URL by default",
+		settings: `{}`,
+		expHost:  u.Host,
+	}, {
+		name:     "hostname",
+		settings: `{"host_header": "alertmanager.example.com"}`,
+		expHost:  "alertmanager.example.com",
+	}, {
+		name:     "hostname with port",
+		settings: `{"host_header": "alertmanager.example.com:9093"}`,
+		expHost:  "alertmanager.example.com:9093",
+	}, {
+		name:     "IPv6 address with port",
+		settings: `{"host_header": "[::1]:9093"}`,
+		expHost:  "[::1]:9093",
+	}, {
+		name:     "blank",
+		settings: `{"host_header": " "}`,
+		expErr:   `invalid value for host_header: " ", must be a hostname with an optional port`,
+	}, {
+		name:     "invalid hostname",
+		settings: `{"host_header": "alertmanager_example.com"}`,
+		expErr:   `invalid value for host_header: "alertmanager_example.com", must be a hostname with an optional port`,
+	}, {
+		name:     "invalid port",
+		settings: `{"host_header": "alertmanager.example.com:0"}`,
+		expErr:   `invalid value for host_header: "alertmanager.example.com:0", must be a hostname with an optional port`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hostHeader, err := buildHostHeader(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{Settings: json.RawMessage(c.settings)},
+			})
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// The request is sent to the host of the URL with the overridden Host.
+			host = ""
+			_, err = sendHTTPRequest(context.Background(), u, httpCfg{host: hostHeader}, &channels.FakeLogger{})
+			require.NoError(t, err)
+			require.Equal(t, c.expHost, host)
+		})
+	}
+}
+
+// warnRecordingLogger records the key/value pairs of warning messages.
+type warnRecordingLogger struct {
+	channels.FakeLogger
+	warn map[string]map[string]interface{}
+}
+
+func (l *warnRecordingLogger) Warn(msg string, ctx ...interface{}) {
+	kv := make(map[string]interface{}, len(ctx)/2)
+	for i := 0; i+1 < len(ctx); i += 2 {
+		kv[ctx[i].(string)] = ctx[i+1]
+	}
+	l.warn[msg] = kv
+}
+
+func TestSendHTTPRequest_MaxBodyLogLen(t *testing.T) {
+	body := strings.Repeat("<p>Internal Server Error</p>", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name          string
+		maxBodyLogLen int
+		expBody       string
+	}{{
+		name:    "Body is truncated to the default length",
+		expBody: body[:256] + "... (2544 bytes truncated)",
+	}, {
+		name:          "Body is truncated to the max_body_log_len",
+		maxBodyLogLen: 10,
+		expBody:       "<p>Interna... (2790 bytes truncated)",
+	}, {
+		name:          "Body shorter than max_body_log_len is not truncated",
+		maxBodyLogLen: 10000,
+		expBody:       body,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			logger := &warnRecordingLogger{warn: make(map[string]map[string]interface{})}
+			_, err := sendHTTPRequest(context.Background(), u, httpCfg{maxBodyLogLen: c.maxBodyLogLen}, logger)
+			require.EqualError(t, err, "failed to send HTTP request - status code 500")
+			require.Equal(t, c.expBody, logger.warn["HTTP request failed"]["body"])
+		})
+	}
+}
+
+func TestTruncateBody(t *testing.T) {
+	require.Equal(t, "abc", truncateBody([]byte("abc"), 3))
+	require.Equal(t, "ab... (1 bytes truncated)", truncateBody([]byte("abc"), 2))
+	// The 3-byte character is not split.
+	require.Equal(t, "a... (4 bytes truncated)", truncateBody([]byte("a€b"), 2))
+}
+
+func TestBuildMaxBodyLogLen(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings string
+		exp      int
+		expErr   string
+	}{{
+		name:     "not set",
+		settings: `{}`,
+	}, {
+		name:     "number",
+		settings: `{"max_body_log_len": 1024}`,
+		exp:      1024,
+	}, {
+		name:     "string",
+		settings: `{"max_body_log_len": "64"}`,
+		exp:      64,
+	}, {
+		name:     "zero",
+		settings: `{"max_body_log_len": 0}`,
+		expErr:   `invalid value for max_body_log_len: "0", must be a positive integer`,
+	}, {
+		name:     "fraction",
+		settings: `{"max_body_log_len": 1.5}`,
+		expErr:   `invalid value for max_body_log_len: "1.5", must be a positive integer`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			n, err := buildMaxBodyLogLen(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{Settings: json.RawMessage(c.settings)},
+			})
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.exp, n)
+		})
+	}
+}
+
+func TestSendHTTPRequest_ExpectedStatusCodes(t *testing.T) {
+	cases := []struct {
+		name                string
+		statusCode          int
+		expectedStatusCodes []int
+		expErr              string
+	}{{
+		name:       "2xx is successful by default",
+		statusCode: http.StatusAccepted,
+	}, {
+		name:       "non-2xx fails by default",
+		statusCode: http.StatusFound,
+		expErr:     "failed to send HTTP request - status code 302",
+	}, {
+		name:                "status code in allowlist is successful",
+		statusCode:          http.StatusNoContent,
+		expectedStatusCodes: []int{http.StatusOK, http.StatusNoContent},
+	}, {
+		name:                "2xx not in allowlist fails",
+		statusCode:          http.StatusAccepted,
+		expectedStatusCodes: []int{http.StatusOK},
+		expErr:              "failed to send HTTP request - status code 202",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(c.statusCode)
+			}))
+			t.Cleanup(server.Close)
+
+			u, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			_, err = sendHTTPRequest(context.Background(), u, httpCfg{
+				expectedStatusCodes: c.expectedStatusCodes,
+			}, &channels.FakeLogger{})
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSendHTTPRequest_CompressedResponse(t *testing.T) {
+	compress := map[string]func(w io.Writer) io.WriteCloser{
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser {
+			return zlib.NewWriter(w)
+		},
+		"raw deflate": func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		},
+	}
+	cases := []struct {
+		name     string
+		encoding string
+		compress string
+		body     string
+		expErr   string
+	}{{
+		name: "uncompressed",
+		body: `{"ok": true}`,
+	}, {
+		name:   "uncompressed body is too long",
+		body:   strings.Repeat("a", maxResponseBodyLen+1),
+		expErr: "failed to read response body: response body is longer than 10485760 bytes",
+	}, {
+		name:     "gzip body is too long once decompressed",
+		encoding: "gzip",
+		compress: "gzip",
+		body:     strings.Repeat("a", maxResponseBodyLen+1),
+		expErr:   "failed to decode response body: response body is longer than 10485760 bytes",
+	}, {
+		name:     "gzip",
+		encoding: "gzip",
+		compress: "gzip",
+		body:     `{"ok": true}`,
+	}, {
+		name:     "deflate",
+		encoding: "deflate",
+		compress: "deflate",
+		body:     `{"ok": true}`,
+	}, {
+		name:     "raw deflate",
+		encoding: "deflate",
+		compress: "raw deflate",
+		body:     `{"ok": true}`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "gzip, deflate", r.Header.Get("Accept-Encoding"))
+				if c.encoding != "" {
+					w.Header().Set("Content-Encoding", c.encoding)
+				}
+				if c.compress == "" {
+					_, _ = w.Write([]byte(c.body))
+					return
+				}
+				cw := compress[c.compress](w)
+				_, _ = cw.Write([]byte(c.body))
+				_ = cw.Close()
+			}))
+			t.Cleanup(server.Close)
+
+			u, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			body, err := sendHTTPRequest(context.Background(), u, httpCfg{}, &channels.FakeLogger{})
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.body, string(body))
+		})
+	}
+}
+
+func TestSendHTTPRequest_Redirects(t *testing.T) {
+	cases := []struct {
+		name          string
+		path          string
+		maxRedirects  int
+		redirectHosts []string
+		expErr        string
+	}{{
+		name:   "redirects are refused by default",
+		path:   "/redirect",
+		expErr: "failed to send HTTP request - status code 302",
+	}, {
+		name:         "redirect is followed with the method and body",
+		path:         "/redirect",
+		maxRedirects: 1,
+	}, {
+		name:         "too many redirects",
+		path:         "/redirect/twice",
+		maxRedirects: 1,
+		expErr:       "stopped after 1 redirects",
+	}, {
+		name:         "redirects to other hosts are refused by default",
+		path:         "/redirect/localhost",
+		maxRedirects: 1,
+		expErr:       `redirect to host "localhost" is not allowed`,
+	}, {
+		name:          "redirect to an allowed host is followed",
+		path:          "/redirect/localhost",
+		maxRedirects:  1,
+		redirectHosts: []string{"alertmanager.example.com", "LOCALHOST"},
+	}, {
+		name:          "redirects to hosts that are not allowed are refused",
+		path:          "/redirect/localhost",
+		maxRedirects:  1,
+		redirectHosts: []string{"*.localhost", "127.0.0.1"},
+		expErr:        `redirect to host "localhost" is not allowed`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/redirect/twice", func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/redirect", http.StatusFound)
+			})
+			mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/target", http.StatusFound)
+			})
+			mux.HandleFunc("/redirect/localhost", func(w http.ResponseWriter, r *http.Request) {
+				_, port, err := net.SplitHostPort(r.Host)
+				assert.NoError(t, err)
+				http.Redirect(w, r, "http://localhost:"+port+"/target", http.StatusFound)
+			})
+			mux.HandleFunc("/target", func(w http.ResponseWriter, r *http.Request) {
+				b, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.Equal(t, `{"test": true}`, string(b))
+				w.WriteHeader(http.StatusOK)
+			})
+			server := httptest.NewServer(mux)
+			t.Cleanup(server.Close)
+
+			u, err := url.Parse(server.URL + c.path)
+			require.NoError(t, err)
+
+			_, err = sendHTTPRequest(context.Background(), u, httpCfg{
+				body:          []byte(`{"test": true}`),
+				maxRedirects:  c.maxRedirects,
+				redirectHosts: c.redirectHosts,
+			}, &channels.FakeLogger{})
+			if c.expErr != "" {
+				require.ErrorContains(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestIsRedirectHostAllowed(t *testing.T) {
+	allowed := []string{"alertmanager.example.com", "*.grafana.net"}
+
+	assert.True(t, isRedirectHostAllowed("alertmanager.example.com", "example.com", allowed))
+	assert.True(t, isRedirectHostAllowed("eu.alerts.grafana.net", "example.com", allowed))
+	assert.False(t, isRedirectHostAllowed("grafana.net", "example.com", allowed))
+	assert.False(t, isRedirectHostAllowed("evilgrafana.net", "example.com", allowed))
+	assert.False(t, isRedirectHostAllowed("example.com", "example.com", allowed))
+
+	// Only the same host is allowed without allowed hosts.
+	assert.True(t, isRedirectHostAllowed("Example.com", "example.com", nil))
+	assert.False(t, isRedirectHostAllowed("www.example.com", "example.com", nil))
+
+	_, err := buildRedirectHosts(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Settings: json.RawMessage(`{"max_redirects_follow_host_allowlist": "example.com,https://example.com"}`),
+		},
+	})
+	require.EqualError(t, err, `invalid host in max_redirects_follow_host_allowlist: "https://example.com", must be a hostname or *.domain`)
+}
+
+// countingImageStore counts the number of times images are requested.
+type countingImageStore struct {
+	channels.ImageStore
+	calls int
+}
+
+func (s *countingImageStore) GetImage(ctx context.Context, token string) (*channels.Image, error) {
+	s.calls++
+	return s.ImageStore.GetImage(ctx, token)
+}
+
+func TestIncludeImages(t *testing.T) {
+	tmpl := templateForTests(t)
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	alerts := []*types.Alert{{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1"},
+			Annotations: model.LabelSet{models.ImageTokenAnnotation: "test-image-1"},
+		},
+	}}
+
+	cases := []struct {
+		name     string
+		settings string
+		expCalls int
+	}{{
+		name:     "images are included by default",
+		settings: `{"url": "http://localhost"}`,
+		expCalls: 1,
+	}, {
+		name:     "images are included when enabled",
+		settings: `{"url": "http://localhost", "include_images": true}`,
+		expCalls: 1,
+	}, {
+		name:     "image store is not queried when disabled",
+		settings: `{"url": "http://localhost", "include_images": false}`,
+		expCalls: 0,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			imageStore := &countingImageStore{ImageStore: newFakeImageStore(1)}
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "discord_testing",
+					Type:     "discord",
+					Settings: json.RawMessage(c.settings),
+				},
+				ImageStore:          imageStore,
+				NotificationService: mockNotificationService(),
+				Template:            tmpl,
+				Logger:              &channels.FakeLogger{},
+			}
+
+			dn, err := newDiscordNotifier(fc)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := dn.Notify(ctx, alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, c.expCalls, imageStore.calls)
+		})
+	}
+}
+
+// flakyImageStore fails the first requests for images with err.
+type flakyImageStore struct {
+	channels.ImageStore
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *flakyImageStore) GetImage(ctx context.Context, token string) (*channels.Image, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return nil, s.err
+	}
+	return s.ImageStore.GetImage(ctx, token)
+}
+
+func TestGetImage_Retries(t *testing.T) {
+	alert := types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1"},
+			Annotations: model.LabelSet{models.ImageTokenAnnotation: "test-image-1"},
+		},
+	}
+	errAccessDenied := errors.New("access denied")
+
+	cases := []struct {
+		name     string
+		settings string
+		failures int
+		err      error
+		expImage bool
+		expErr   error
+		expCalls int
+	}{{
+		name:     "transient errors are not retried by default",
+		settings: `{}`,
+		failures: 1,
+		err:      context.DeadlineExceeded,
+		expErr:   context.DeadlineExceeded,
+		expCalls: 1,
+	}, {
+		name:     "transient errors are retried",
+		settings: `{"image_fetch_retries": 3, "image_fetch_backoff": "1ms"}`,
+		failures: 2,
+		err:      fmt.Errorf("get object: %w", context.DeadlineExceeded),
+		expImage: true,
+		expCalls: 3,
+	}, {
+		name:     "network timeouts are retried",
+		settings: `{"image_fetch_retries": 1, "image_fetch_backoff": "1ms"}`,
+		failures: 1,
+		err:      &net.OpError{Op: "read", Err: &timeoutError{}},
+		expImage: true,
+		expCalls: 2,
+	}, {
+		name:     "error is returned when retries are exhausted",
+		settings: `{"image_fetch_retries": 2, "image_fetch_backoff": "1ms"}`,
+		failures: 3,
+		err:      context.DeadlineExceeded,
+		expErr:   context.DeadlineExceeded,
+		expCalls: 3,
+	}, {
+		name:     "terminal errors are not retried",
+		settings: `{"image_fetch_retries": 3, "image_fetch_backoff": "1ms"}`,
+		failures: 1,
+		err:      errAccessDenied,
+		expErr:   errAccessDenied,
+		expCalls: 1,
+	}, {
+		name:     "missing images are not retried",
+		settings: `{"image_fetch_retries": 3, "image_fetch_backoff": "1ms"}`,
+		failures: 1,
+		err:      channels.ErrImageNotFound,
+		expCalls: 1,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := &flakyImageStore{ImageStore: newFakeImageStore(1), failures: c.failures, err: c.err}
+			imageStore, err := buildImageStore(channels.FactoryConfig{
+				Config:     &channels.NotificationChannelConfig{Settings: json.RawMessage(c.settings)},
+				ImageStore: store,
+			})
+			require.NoError(t, err)
+
+			img, err := getImage(context.Background(), &channels.FakeLogger{}, imageStore, alert)
+			if c.expErr != nil {
+				require.ErrorIs(t, err, c.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, c.expImage, img != nil)
+			require.Equal(t, c.expCalls, store.calls)
+		})
+	}
+}
+
+// timeoutError is a net.Error that is a timeout.
+type timeoutError struct{}
+
+func (e *timeoutError) Error() string   { return "i/o timeout" }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+func TestBuildImageStore(t *testing.T) {
+	cases := []struct {
+		name       string
+		settings   string
+		expRetries int
+		expBackoff time.Duration
+		expErr     string
+	}{{
+		name:     "no retries by default",
+		settings: `{}`,
+	}, {
+		name:       "retries with default backoff",
+		settings:   `{"image_fetch_retries": 2}`,
+		expRetries: 2,
+		expBackoff: defaultImageFetchBackoff,
+	}, {
+		name:       "retries with backoff",
+		settings:   `{"image_fetch_retries": "3", "image_fetch_backoff": "250ms"}`,
+		expRetries: 3,
+		expBackoff: 250 * time.Millisecond,
+	}, {
+		name:     "too many retries",
+		settings: `{"image_fetch_retries": 6}`,
+		expErr:   `invalid value for image_fetch_retries: "6", must be an integer between 0 and 5`,
+	}, {
+		name:     "invalid backoff",
+		settings: `{"image_fetch_retries": 1, "image_fetch_backoff": "0s"}`,
+		expErr:   `invalid value for image_fetch_backoff: "0s"`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := newFakeImageStore(1)
+			imageStore, err := buildImageStore(channels.FactoryConfig{
+				Config:     &channels.NotificationChannelConfig{Settings: json.RawMessage(c.settings)},
+				ImageStore: store,
+			})
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			if c.expRetries == 0 {
+				require.Equal(t, store, imageStore)
+				return
+			}
+			require.Equal(t, &imageRetryStore{ImageStore: store, retries: c.expRetries, backoff: c.expBackoff}, imageStore)
+		})
+	}
+}
+
+func TestBuildHTTPResolver(t *testing.T) {
+	cases := []struct {
+		name        string
+		settings    string
+		expResolver bool
+		expErr      string
+	}{{
+		name:     "system resolver by default",
+		settings: `{}`,
+	}, {
+		name:        "IPv4 address without port",
+		settings:    `{"dns_server": "10.0.0.1"}`,
+		expResolver: true,
+	}, {
+		name:        "IPv4 address with port",
+		settings:    `{"dns_server": "10.0.0.1:5353"}`,
+		expResolver: true,
+	}, {
+		name:        "IPv6 address without port",
+		settings:    `{"dns_server": "::1"}`,
+		expResolver: true,
+	}, {
+		name:        "IPv6 address with port",
+		settings:    `{"dns_server": "[::1]:5353"}`,
+		expResolver: true,
+	}, {
+		name:     "host name",
+		settings: `{"dns_server": "dns.example.com:53"}`,
+		expErr:   `invalid value for dns_server: "dns.example.com:53", must be an IP address with an optional port`,
+	}, {
+		name:     "invalid port",
+		settings: `{"dns_server": "10.0.0.1:0"}`,
+		expErr:   `invalid value for dns_server: "10.0.0.1:0", must be an IP address with an optional port`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resolver, err := buildHTTPResolver(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{Settings: json.RawMessage(c.settings)},
+			})
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expResolver, resolver != nil)
+		})
+	}
+}
+
+func TestSendHTTPRequest_Resolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// The DNS server resolves all names to 127.0.0.1.
+	var (
+		mtx     sync.Mutex
+		queried []string
+	)
+	addr := newTestDNSServer(t, func(name string) [4]byte {
+		mtx.Lock()
+		defer mtx.Unlock()
+		queried = append(queried, name)
+		return [4]byte{127, 0, 0, 1}
+	})
+
+	resolver, err := buildHTTPResolver(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Settings: json.RawMessage(fmt.Sprintf(`{"dns_server": %q}`, addr)),
+		},
+	})
+	require.NoError(t, err)
+
+	// The host can only be resolved with the custom resolver.
+	u.Host = net.JoinHostPort("alertmanager.grafana.test", u.Port())
+	_, err = sendHTTPRequest(context.Background(), u, httpCfg{
+		resolver: resolver,
+	}, &channels.FakeLogger{})
+	require.NoError(t, err)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Contains(t, queried, "alertmanager.grafana.test.")
+}
+
+// newTestDNSServer starts a DNS server that answers A queries with the address returned
+// by resolve for the name, and returns its address.
+func newTestDNSServer(t *testing.T, resolve func(name string) [4]byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			h, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true})
+			_ = b.StartQuestions()
+			_ = b.Question(q)
+			_ = b.StartAnswers()
+			if q.Type == dnsmessage.TypeA {
+				_ = b.AResource(dnsmessage.ResourceHeader{
+					Name:  q.Name,
+					Type:  dnsmessage.TypeA,
+					Class: dnsmessage.ClassINET,
+					TTL:   60,
+				}, dnsmessage.AResource{A: resolve(q.Name.String())})
+			}
+			msg, err := b.Finish()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(msg, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// debugRecordingLogger records the key/value pairs of debug messages.
+type debugRecordingLogger struct {
+	channels.FakeLogger
+	debug map[string]map[string]interface{}
+}
+
+func (l *debugRecordingLogger) Debug(msg string, ctx ...interface{}) {
+	kv := make(map[string]interface{}, len(ctx)/2)
+	for i := 0; i+1 < len(ctx); i += 2 {
+		kv[ctx[i].(string)] = ctx[i+1]
+	}
+	l.debug[msg] = kv
+}
+
+func TestSendHTTPRequest_Trace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(tlsServer.Close)
+
+	send := func(t *testing.T, serverURL string, trace bool) (map[string]interface{}, error) {
+		u, err := url.Parse(serverURL)
+		require.NoError(t, err)
+		// Use a host name so it is resolved.
+		u.Host = net.JoinHostPort("localhost", u.Port())
+		logger := &debugRecordingLogger{debug: make(map[string]map[string]interface{})}
+		_, err = sendHTTPRequest(context.Background(), u, httpCfg{trace: trace}, logger)
+		return logger.debug["HTTP request trace"], err
+	}
+
+	t.Run("trace is not logged by default", func(t *testing.T) {
+		kv, err := send(t, server.URL, false)
+		require.NoError(t, err)
+		require.Nil(t, kv)
+	})
+
+	t.Run("trace is logged for http", func(t *testing.T) {
+		kv, err := send(t, server.URL, true)
+		require.NoError(t, err)
+		require.NotNil(t, kv)
+		require.Greater(t, kv["dns"], time.Duration(0))
+		require.Greater(t, kv["connect"], time.Duration(0))
+		require.Equal(t, time.Duration(0), kv["tls"])
+		require.Greater(t, kv["ttfb"], time.Duration(0))
+		require.GreaterOrEqual(t, kv["total"], kv["ttfb"])
+	})
+
+	t.Run("trace is logged for https when the request fails", func(t *testing.T) {
+		// The certificate of the test server is not trusted, so the request fails
+		// after the TLS handshake.
+		kv, err := send(t, tlsServer.URL, true)
+		require.Error(t, err)
+		require.NotNil(t, kv)
+		require.Greater(t, kv["dns"], time.Duration(0))
+		require.Greater(t, kv["connect"], time.Duration(0))
+		require.Greater(t, kv["tls"], time.Duration(0))
+		require.Equal(t, time.Duration(0), kv["ttfb"])
+	})
+}
+
+func TestWrapSend(t *testing.T) {
+	var calls []string
+	record := func(name string) sendMiddleware {
+		return func(next httpSendFunc) httpSendFunc {
+			return func(ctx context.Context, url *url.URL, cfg httpCfg, logger channels.Logger) ([]byte, error) {
+				calls = append(calls, name+" before")
+				b, err := next(ctx, url, cfg, logger)
+				calls = append(calls, name+" after")
+				return append(b, []byte(" "+name)...), err
+			}
+		}
+	}
+	send := func(_ context.Context, _ *url.URL, cfg httpCfg, _ channels.Logger) ([]byte, error) {
+		calls = append(calls, "send")
+		return cfg.body, nil
+	}
+
+	u, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+
+	b, err := wrapSend(send, record("first"), record("second"))(context.Background(), u, httpCfg{body: []byte("body")}, &channels.FakeLogger{})
+	require.NoError(t, err)
+	require.Equal(t, "body second first", string(b))
+	require.Equal(t, []string{"first before", "second before", "send", "second after", "first after"}, calls)
+
+	// A middleware can return without sending the request.
+	calls = nil
+	skip := func(_ httpSendFunc) httpSendFunc {
+		return func(_ context.Context, _ *url.URL, _ httpCfg, _ channels.Logger) ([]byte, error) {
+			return nil, errors.New("rate limited")
+		}
+	}
+	_, err = wrapSend(send, record("first"), skip)(context.Background(), u, httpCfg{}, &channels.FakeLogger{})
+	require.EqualError(t, err, "rate limited")
+	require.Equal(t, []string{"first before", "first after"}, calls)
+
+	// Without middlewares the send function is returned as is.
+	calls = nil
+	_, err = wrapSend(send)(context.Background(), u, httpCfg{}, &channels.FakeLogger{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"send"}, calls)
+}
+
+func TestWriteMultipartFile(t *testing.T) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	require.NoError(t, writeMultipartFile(w, "attachment", "/tmp/test-image.png", strings.NewReader("image data")))
+	require.NoError(t, w.Close())
+
+	r := multipart.NewReader(&b, w.Boundary())
+	part, err := r.NextPart()
+	require.NoError(t, err)
+	require.Equal(t, "attachment", part.FormName())
+	require.Equal(t, "test-image.png", part.FileName())
+	require.Equal(t, "application/octet-stream", part.Header.Get("Content-Type"))
+	data, err := io.ReadAll(part)
+	require.NoError(t, err)
+	require.Equal(t, "image data", string(data))
+
+	_, err = r.NextPart()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestGrafanaURL(t *testing.T) {
+	cases := []struct {
+		name        string
+		externalURL string
+		path        string
+		expURL      string
+	}{{
+		name:        "Path is joined to the external URL",
+		externalURL: "http://localhost",
+		path:        "/alerting/list",
+		expURL:      "http://localhost/alerting/list",
+	}, {
+		name:        "Path is joined to the subpath of the external URL",
+		externalURL: "https://example.com/grafana/",
+		path:        "/d/abcd",
+		expURL:      "https://example.com/grafana/d/abcd",
+	}, {
+		name:        "Subpath without a trailing slash",
+		externalURL: "https://example.com:3000/grafana",
+		path:        "alerting/list",
+		expURL:      "https://example.com:3000/grafana/alerting/list",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			externalURL, err := url.Parse(c.externalURL)
+			require.NoError(t, err)
+			require.Equal(t, c.expURL, grafanaURL(externalURL, c.path))
+			require.Equal(t, c.externalURL, externalURL.String())
+		})
+	}
+}
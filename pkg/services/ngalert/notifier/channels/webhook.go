@@ -0,0 +1,721 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	tmplhtml "html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	tmpltext "text/template"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/hashicorp/go-multierror"
+	"github.com/jmespath/go-jmespath"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/http/httpguts"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/services/notifications"
+)
+
+// WebhookNotifier is responsible for sending
+// alert notifications as webhooks.
+type WebhookNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	ns          channels.WebhookSender
+	images      channels.ImageStore
+	tmpl        *template.Template
+	orgID       int64
+	settings    webhookSettings
+	maxValueLen int
+	// includeFingerprints is true if the fingerprints of the alerts are included in the message.
+	includeFingerprints bool
+	// runbookAnnotation is the annotation with the URL of the runbook in the message.
+	runbookAnnotation string
+	// resolvedURL, if set, is the URL of requests with only resolved alerts.
+	resolvedURL string
+	// httpCfg, if set, is the configuration of the client that requests are sent with
+	// instead of the client of the notification service.
+	httpCfg *httpCfg
+	clients httpClients
+}
+
+type webhookSettings struct {
+	URL        string
+	HTTPMethod string
+	MaxAlerts  int
+	// Authorization Header.
+	AuthorizationScheme      string
+	AuthorizationCredentials string
+	// HTTP Basic Authentication.
+	User     string
+	Password string
+
+	Title   string
+	Message string
+	// ResolvedMessage, if set, is the message of notifications with only resolved alerts.
+	ResolvedMessage string
+
+	// SuccessJMESPath is a JMESPath expression that must evaluate to true
+	// against the JSON response body for the request to be successful.
+	SuccessJMESPath *jmespath.JMESPath
+	// SuccessRegex must match the response body for the request to be successful.
+	SuccessRegex *regexp.Regexp
+
+	// Batch is true if all alerts are sent in one request, or false if
+	// one request is sent per alert.
+	Batch bool
+	// AllowPartialSuccess is true if the notification is successful when
+	// at least one of the requests sent per alert succeeds.
+	AllowPartialSuccess bool
+
+	// FlattenLabels is true if the message is sent form-encoded, with the labels and
+	// annotations of each alert flattened into fields with LabelPrefix and AnnotationPrefix.
+	FlattenLabels    bool
+	LabelPrefix      string
+	AnnotationPrefix string
+
+	// PayloadTemplate, if set, is the template of the body that is sent instead of
+	// the message. It is either payload_template or the contents of template_file.
+	PayloadTemplate string
+	// HTMLTemplate, if set, is the template of the HTML body that is sent instead of the
+	// message. Unlike the payload template, values are escaped for HTML.
+	HTMLTemplate string
+	// Encoding is the encoding of the message, either json or yaml. The YAML message
+	// has the same fields as the JSON message.
+	Encoding string
+
+	// IdempotencyKey is true if requests have the Idempotency-Key header, which is
+	// the same for retries of the request, see idempotencyKey.
+	IdempotencyKey bool
+
+	// PriorityHeader is the header set to the priority of the highest severity
+	// of the alerts in PriorityMapping.
+	PriorityHeader string
+	// PriorityMapping maps the severity label of alerts to priorities. The priority
+	// of the highest severity of the alerts is used, see highestSeverity.
+	PriorityMapping []webhookPriority
+}
+
+const (
+	webhookEncodingJSON = "json"
+	webhookEncodingYAML = "yaml"
+)
+
+// webhookPriority is the value of the priority header for alerts with the severity.
+type webhookPriority struct {
+	Severity string `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Priority string `json:"priority,omitempty" yaml:"priority,omitempty"`
+}
+
+func buildWebhookSettings(factoryConfig channels.FactoryConfig) (webhookSettings, error) {
+	settings := webhookSettings{}
+	rawSettings := struct {
+		URL                      string            `json:"url,omitempty" yaml:"url,omitempty"`
+		HTTPMethod               string            `json:"httpMethod,omitempty" yaml:"httpMethod,omitempty"`
+		MaxAlerts                json.Number       `json:"maxAlerts,omitempty" yaml:"maxAlerts,omitempty"`
+		AuthorizationScheme      string            `json:"authorization_scheme,omitempty" yaml:"authorization_scheme,omitempty"`
+		AuthorizationCredentials string            `json:"authorization_credentials,omitempty" yaml:"authorization_credentials,omitempty"`
+		User                     string            `json:"username,omitempty" yaml:"username,omitempty"`
+		Password                 string            `json:"password,omitempty" yaml:"password,omitempty"`
+		Title                    string            `json:"title,omitempty" yaml:"title,omitempty"`
+		Message                  string            `json:"message,omitempty" yaml:"message,omitempty"`
+		ResolvedMessage          string            `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+		SuccessJMESPath          string            `json:"success_jmespath,omitempty" yaml:"success_jmespath,omitempty"`
+		SuccessRegex             string            `json:"success_regex,omitempty" yaml:"success_regex,omitempty"`
+		Batch                    *bool             `json:"batch,omitempty" yaml:"batch,omitempty"`
+		AllowPartialSuccess      bool              `json:"allow_partial_success,omitempty" yaml:"allow_partial_success,omitempty"`
+		FlattenLabels            bool              `json:"flatten_labels,omitempty" yaml:"flatten_labels,omitempty"`
+		LabelPrefix              *string           `json:"label_prefix,omitempty" yaml:"label_prefix,omitempty"`
+		AnnotationPrefix         *string           `json:"annotation_prefix,omitempty" yaml:"annotation_prefix,omitempty"`
+		PayloadTemplate          string            `json:"payload_template,omitempty" yaml:"payload_template,omitempty"`
+		TemplateFile             string            `json:"template_file,omitempty" yaml:"template_file,omitempty"`
+		HTMLTemplate             string            `json:"html_template,omitempty" yaml:"html_template,omitempty"`
+		Encoding                 string            `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+		IdempotencyKey           bool              `json:"idempotency_key,omitempty" yaml:"idempotency_key,omitempty"`
+		PriorityHeader           string            `json:"priority_header,omitempty" yaml:"priority_header,omitempty"`
+		PriorityMapping          []webhookPriority `json:"priority_mapping,omitempty" yaml:"priority_mapping,omitempty"`
+	}{}
+
+	err := json.Unmarshal(factoryConfig.Config.Settings, &rawSettings)
+	if err != nil {
+		return settings, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	if rawSettings.URL == "" {
+		return settings, errors.New("required field 'url' is not specified")
+	}
+	settings.URL = rawSettings.URL
+
+	if rawSettings.HTTPMethod == "" {
+		rawSettings.HTTPMethod = http.MethodPost
+	}
+	settings.HTTPMethod = rawSettings.HTTPMethod
+
+	if rawSettings.MaxAlerts != "" {
+		settings.MaxAlerts, _ = strconv.Atoi(rawSettings.MaxAlerts.String())
+	}
+
+	settings.User = factoryConfig.DecryptFunc(context.Background(), factoryConfig.Config.SecureSettings, "username", rawSettings.User)
+	settings.Password = factoryConfig.DecryptFunc(context.Background(), factoryConfig.Config.SecureSettings, "password", rawSettings.Password)
+	settings.AuthorizationCredentials = factoryConfig.DecryptFunc(context.Background(), factoryConfig.Config.SecureSettings, "authorization_scheme", rawSettings.AuthorizationCredentials)
+
+	if settings.AuthorizationCredentials != "" && settings.AuthorizationScheme == "" {
+		settings.AuthorizationScheme = "Bearer"
+	}
+	if settings.User != "" && settings.Password != "" && settings.AuthorizationScheme != "" && settings.AuthorizationCredentials != "" {
+		return settings, errors.New("both HTTP Basic Authentication and Authorization Header are set, only 1 is permitted")
+	}
+	// Templates with the left_delim and right_delim settings are converted to the
+	// default delimiters, so only templates in the settings use the delimiters.
+	delims, err := buildTemplateDelims(factoryConfig)
+	if err != nil {
+		return settings, err
+	}
+	settings.Title, err = delims.convert(rawSettings.Title)
+	if err != nil {
+		return settings, fmt.Errorf("invalid title template: %w", err)
+	}
+	if settings.Title == "" {
+		settings.Title = channels.DefaultMessageTitleEmbed
+	}
+	settings.Message, err = delims.convert(rawSettings.Message)
+	if err != nil {
+		return settings, fmt.Errorf("invalid message template: %w", err)
+	}
+	if settings.Message == "" {
+		settings.Message = channels.DefaultMessageEmbed
+	}
+	settings.ResolvedMessage, err = delims.convert(rawSettings.ResolvedMessage)
+	if err != nil {
+		return settings, fmt.Errorf("invalid resolved_message template: %w", err)
+	}
+	// The title and message are templated independently into the title and message
+	// fields of the payload, so each must be a valid template on its own.
+	if _, err := tmpltext.New("").Funcs(tmpltext.FuncMap(template.DefaultFuncs)).Parse(settings.Title); err != nil {
+		return settings, fmt.Errorf("invalid title template: %w", err)
+	}
+	if _, err := tmpltext.New("").Funcs(tmpltext.FuncMap(template.DefaultFuncs)).Parse(settings.Message); err != nil {
+		return settings, fmt.Errorf("invalid message template: %w", err)
+	}
+	if _, err := tmpltext.New("").Funcs(tmpltext.FuncMap(template.DefaultFuncs)).Parse(settings.ResolvedMessage); err != nil {
+		return settings, fmt.Errorf("invalid resolved_message template: %w", err)
+	}
+	if rawSettings.SuccessJMESPath != "" {
+		settings.SuccessJMESPath, err = jmespath.Compile(rawSettings.SuccessJMESPath)
+		if err != nil {
+			return settings, fmt.Errorf("invalid JMESPath expression for success_jmespath %q: %w", rawSettings.SuccessJMESPath, err)
+		}
+	}
+	if rawSettings.SuccessRegex != "" {
+		settings.SuccessRegex, err = regexp.Compile(rawSettings.SuccessRegex)
+		if err != nil {
+			return settings, fmt.Errorf("invalid success_regex %q: %w", rawSettings.SuccessRegex, err)
+		}
+	}
+	settings.Batch = rawSettings.Batch == nil || *rawSettings.Batch
+	settings.AllowPartialSuccess = rawSettings.AllowPartialSuccess
+	settings.FlattenLabels = rawSettings.FlattenLabels
+	settings.LabelPrefix = "label_"
+	if rawSettings.LabelPrefix != nil {
+		settings.LabelPrefix = *rawSettings.LabelPrefix
+	}
+	settings.AnnotationPrefix = "annotation_"
+	if rawSettings.AnnotationPrefix != nil {
+		settings.AnnotationPrefix = *rawSettings.AnnotationPrefix
+	}
+	if settings.FlattenLabels && settings.LabelPrefix == settings.AnnotationPrefix {
+		return settings, errors.New("label_prefix and annotation_prefix must be different")
+	}
+	if rawSettings.PayloadTemplate != "" && rawSettings.TemplateFile != "" {
+		return settings, errors.New("only one of payload_template and template_file can be set")
+	}
+	settings.PayloadTemplate = rawSettings.PayloadTemplate
+	if rawSettings.TemplateFile != "" {
+		b, err := readWebhookTemplateFile(getOptions().WebhookTemplatesDirectory, rawSettings.TemplateFile)
+		if err != nil {
+			return settings, err
+		}
+		settings.PayloadTemplate = string(b)
+	}
+	settings.PayloadTemplate, err = delims.convert(settings.PayloadTemplate)
+	if err != nil {
+		return settings, fmt.Errorf("invalid payload template: %w", err)
+	}
+	if settings.PayloadTemplate != "" {
+		if settings.FlattenLabels {
+			return settings, errors.New("flatten_labels cannot be used with a payload template")
+		}
+		// Templates are parsed again when they are executed, so this only checks
+		// that they are valid. Templates they call are only known at that time.
+		if _, err := tmpltext.New("").Funcs(tmpltext.FuncMap(template.DefaultFuncs)).Parse(settings.PayloadTemplate); err != nil {
+			return settings, fmt.Errorf("invalid payload template: %w", err)
+		}
+	}
+	settings.HTMLTemplate, err = delims.convert(rawSettings.HTMLTemplate)
+	if err != nil {
+		return settings, fmt.Errorf("invalid html_template: %w", err)
+	}
+	if settings.HTMLTemplate != "" {
+		if settings.PayloadTemplate != "" {
+			return settings, errors.New("html_template cannot be used with a payload template")
+		}
+		if settings.FlattenLabels {
+			return settings, errors.New("flatten_labels cannot be used with html_template")
+		}
+		if _, err := tmplhtml.New("").Funcs(tmplhtml.FuncMap(template.DefaultFuncs)).Parse(settings.HTMLTemplate); err != nil {
+			return settings, fmt.Errorf("invalid html_template: %w", err)
+		}
+	}
+	switch rawSettings.Encoding {
+	case "", webhookEncodingJSON:
+		settings.Encoding = webhookEncodingJSON
+	case webhookEncodingYAML:
+		if settings.PayloadTemplate != "" || settings.HTMLTemplate != "" || settings.FlattenLabels {
+			return settings, errors.New("encoding yaml cannot be used with a payload template, html_template or flatten_labels")
+		}
+		settings.Encoding = webhookEncodingYAML
+	default:
+		return settings, fmt.Errorf("invalid value for encoding: %q, must be json or yaml", rawSettings.Encoding)
+	}
+	settings.IdempotencyKey = rawSettings.IdempotencyKey
+	settings.PriorityHeader = rawSettings.PriorityHeader
+	if settings.PriorityHeader == "" {
+		settings.PriorityHeader = "X-Priority"
+	}
+	if !httpguts.ValidHeaderFieldName(settings.PriorityHeader) {
+		return settings, fmt.Errorf("invalid value for priority_header: %q", settings.PriorityHeader)
+	}
+	for _, p := range rawSettings.PriorityMapping {
+		if p.Severity == "" || p.Priority == "" {
+			return settings, errors.New("severity and priority are required in priority_mapping")
+		}
+		if !httpguts.ValidHeaderFieldValue(p.Priority) {
+			return settings, fmt.Errorf("invalid priority in priority_mapping: %q", p.Priority)
+		}
+	}
+	settings.PriorityMapping = rawSettings.PriorityMapping
+	return settings, nil
+}
+
+// readWebhookTemplateFile reads the template file, which must be in the directory of
+// webhook templates. The error does not tell whether files outside the directory exist,
+// as the settings of contact points are set by users of Grafana.
+func readWebhookTemplateFile(dir, name string) ([]byte, error) {
+	if dir == "" {
+		return nil, errors.New("template_file cannot be used, as no directory is configured for webhook templates")
+	}
+	invalidErr := fmt.Errorf("invalid value for template_file: %q, must be a file in the directory of webhook templates", name)
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, invalidErr
+	}
+	p := name
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+	p = filepath.Clean(p)
+	if !isInDirectory(dir, p) {
+		return nil, invalidErr
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, invalidErr
+	}
+	realPath, err := filepath.EvalSymlinks(p)
+	if err != nil || !isInDirectory(realDir, realPath) {
+		return nil, invalidErr
+	}
+	// The path is checked to be in the directory of webhook templates.
+	//nolint:gosec
+	b, err := os.ReadFile(realPath)
+	if err != nil {
+		return nil, invalidErr
+	}
+	return b, nil
+}
+
+func WebHookFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	notifier, err := buildWebhookNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return notifier, nil
+}
+
+// buildWebhookNotifier is the constructor for
+// the WebHook notifier.
+func buildWebhookNotifier(factoryConfig channels.FactoryConfig) (*WebhookNotifier, error) {
+	settings, err := buildWebhookSettings(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	images, err := buildImageStore(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	includeFingerprints, err := buildIncludeFingerprints(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	runbookAnnotation, err := buildRunbookAnnotation(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	resolvedURL, err := buildResolvedURL(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	httpCfg, err := buildWebhookHTTPCfg(factoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookNotifier{
+		Base:                channels.NewBase(factoryConfig.Config),
+		orgID:               factoryConfig.Config.OrgID,
+		log:                 factoryConfig.Logger,
+		ns:                  factoryConfig.NotificationService,
+		images:              images,
+		tmpl:                factoryConfig.Template,
+		settings:            settings,
+		maxValueLen:         maxValueLen,
+		includeFingerprints: includeFingerprints,
+		runbookAnnotation:   runbookAnnotation,
+		resolvedURL:         resolvedURL,
+		httpCfg:             httpCfg,
+	}, nil
+}
+
+// webhookMaxRedirects is the maximum number of redirects that are followed by the
+// client of webhooks with a httpCfg, as by the client of the notification service.
+const webhookMaxRedirects = 10
+
+// buildWebhookHTTPCfg returns the configuration of the client that requests are sent
+// with if the dns_server, host_header, max_redirects_follow_host_allowlist or sigv4
+// settings are set, or nil if requests are sent with the client of the notification
+// service.
+func buildWebhookHTTPCfg(fc channels.FactoryConfig) (*httpCfg, error) {
+	resolver, err := buildHTTPResolver(fc)
+	if err != nil {
+		return nil, err
+	}
+	redirectHosts, err := buildRedirectHosts(fc)
+	if err != nil {
+		return nil, err
+	}
+	host, err := buildHostHeader(fc)
+	if err != nil {
+		return nil, err
+	}
+	sigV4, err := buildSigV4Config(fc)
+	if err != nil {
+		return nil, err
+	}
+	if resolver == nil && len(redirectHosts) == 0 && host == "" && sigV4 == nil {
+		return nil, nil
+	}
+	return &httpCfg{
+		resolver:      resolver,
+		maxRedirects:  webhookMaxRedirects,
+		redirectHosts: redirectHosts,
+		host:          host,
+		sigV4:         sigV4,
+	}, nil
+}
+
+// WebhookMessage defines the JSON object send to webhook endpoints.
+type WebhookMessage struct {
+	*channels.ExtendedData
+
+	// The protocol version.
+	Version         string `json:"version"`
+	GroupKey        string `json:"groupKey"`
+	TruncatedAlerts int    `json:"truncatedAlerts"`
+	OrgID           int64  `json:"orgId"`
+	Title           string `json:"title"`
+	State           string `json:"state"`
+	Message         string `json:"message"`
+	// Fingerprints are the fingerprints of the alerts, if include_fingerprints is true.
+	Fingerprints []string `json:"fingerprints,omitempty"`
+	// RunbookURL is the URL of the runbook of the alerts, if any of them have one.
+	RunbookURL string `json:"runbookURL,omitempty"`
+}
+
+// Notify implements the Notifier interface.
+func (wn *WebhookNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	groupKey, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	as, numTruncated := truncateAlerts(wn.settings.MaxAlerts, as)
+	if wn.settings.Batch {
+		if err := wn.send(ctx, groupKey, as, numTruncated); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	// Send one request per alert. The notification fails if any of the requests
+	// fail, unless partial success is allowed and at least one request succeeded.
+	var (
+		errs *multierror.Error
+		sent int
+	)
+	for _, alert := range as {
+		if err := wn.send(ctx, groupKey, []*types.Alert{alert}, numTruncated); err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		sent++
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		if wn.settings.AllowPartialSuccess && sent > 0 {
+			wn.log.Warn("failed to send some webhook requests", "sent", sent, "failed", len(errs.Errors), "error", err)
+			return true, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// send sends the alerts in a single webhook request.
+func (wn *WebhookNotifier) send(ctx context.Context, groupKey notify.Key, as []*types.Alert, numTruncated int) error {
+	var tmplErr error
+	tmpl, data := tmplText(ctx, wn.tmpl, as, wn.log, &tmplErr, wn.maxValueLen)
+
+	// Augment our Alert data with ImageURLs if available.
+	_ = withStoredImages(ctx, wn.log, wn.images,
+		func(index int, image channels.Image) error {
+			if len(image.URL) != 0 {
+				data.Alerts[index].ImageURL = image.URL
+			}
+			return nil
+		},
+		as...)
+
+	msg := &WebhookMessage{
+		Version:         "1",
+		ExtendedData:    data,
+		GroupKey:        groupKey.String(),
+		TruncatedAlerts: numTruncated,
+		OrgID:           wn.orgID,
+		Title:           tmpl(wn.settings.Title),
+		Message:         tmpl(messageForAlerts(wn.settings.Message, wn.settings.ResolvedMessage, as)),
+		RunbookURL:      runbookURL(as, wn.runbookAnnotation),
+	}
+	if wn.includeFingerprints {
+		msg.Fingerprints = alertFingerprints(as)
+	}
+	webhookURL := wn.settings.URL
+	if types.Alerts(as...).Status() == model.AlertFiring {
+		msg.State = string(channels.AlertStateAlerting)
+	} else {
+		msg.State = string(channels.AlertStateOK)
+		if wn.resolvedURL != "" {
+			webhookURL = wn.resolvedURL
+		}
+	}
+
+	if tmplErr != nil {
+		wn.log.Warn("failed to template webhook message", "error", tmplErr.Error())
+		tmplErr = nil
+	}
+
+	var (
+		body        []byte
+		contentType string
+	)
+	if wn.settings.PayloadTemplate != "" {
+		body = []byte(tmpl(wn.settings.PayloadTemplate))
+		if tmplErr != nil {
+			return fmt.Errorf("failed to template payload: %w", tmplErr)
+		}
+	} else if wn.settings.HTMLTemplate != "" {
+		s, err := wn.tmpl.ExecuteHTMLString(wn.settings.HTMLTemplate, data)
+		if err != nil {
+			return fmt.Errorf("failed to template HTML body: %w", err)
+		}
+		body = []byte(s)
+		contentType = "text/html; charset=utf-8"
+	} else if wn.settings.FlattenLabels {
+		body = []byte(wn.formValues(msg).Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else if wn.settings.Encoding == webhookEncodingYAML {
+		var err error
+		if body, err = marshalYAML(msg); err != nil {
+			return err
+		}
+		contentType = "text/yaml"
+	} else {
+		var err error
+		if body, err = json.Marshal(msg); err != nil {
+			return err
+		}
+	}
+
+	headers := make(map[string]string)
+	if wn.settings.AuthorizationScheme != "" && wn.settings.AuthorizationCredentials != "" {
+		headers["Authorization"] = fmt.Sprintf("%s %s", wn.settings.AuthorizationScheme, wn.settings.AuthorizationCredentials)
+	}
+	if priority := wn.priority(as); priority != "" {
+		headers[wn.settings.PriorityHeader] = priority
+	}
+	if wn.settings.IdempotencyKey {
+		key, err := idempotencyKey(ctx, as...)
+		if err != nil {
+			return err
+		}
+		headers["Idempotency-Key"] = key
+	}
+
+	parsedURL := tmpl(webhookURL)
+	if tmplErr != nil {
+		return tmplErr
+	}
+
+	cmd := &channels.SendWebhookSettings{
+		URL:         parsedURL,
+		User:        wn.settings.User,
+		Password:    wn.settings.Password,
+		Body:        string(body),
+		HTTPMethod:  wn.settings.HTTPMethod,
+		HTTPHeader:  headers,
+		ContentType: contentType,
+	}
+	if wn.settings.SuccessJMESPath != nil || wn.settings.SuccessRegex != nil {
+		cmd.Validation = wn.validateResponse
+	}
+
+	if wn.httpCfg != nil {
+		cfg := *wn.httpCfg
+		cfg.destinationPolicy = destinationPolicyFromContext(ctx)
+		ctx = notifications.WithWebhookClient(ctx, wn.clients.get(cfg))
+	}
+
+	return wn.ns.SendWebhook(ctx, cmd)
+}
+
+// marshalYAML returns the message as YAML. The message is marshaled to JSON first so
+// the YAML has the same fields as the JSON message, as only JSON tags are defined.
+func marshalYAML(msg *WebhookMessage) ([]byte, error) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// priority returns the priority for the highest severity of the alerts, or an empty
+// string if none of the alerts have a severity in the priority mapping.
+func (wn *WebhookNotifier) priority(as []*types.Alert) string {
+	mapped := make([]string, 0, len(wn.settings.PriorityMapping))
+	for _, p := range wn.settings.PriorityMapping {
+		mapped = append(mapped, p.Severity)
+	}
+	if i := highestSeverity(as, mapped); i >= 0 {
+		return wn.settings.PriorityMapping[i].Priority
+	}
+	return ""
+}
+
+// formValues returns the message as form fields for flatten_labels. The fields of
+// each alert are prefixed with its index, such as alert_0_label_alertname.
+func (wn *WebhookNotifier) formValues(msg *WebhookMessage) url.Values {
+	values := url.Values{}
+	values.Set("version", msg.Version)
+	values.Set("groupKey", msg.GroupKey)
+	values.Set("truncatedAlerts", strconv.Itoa(msg.TruncatedAlerts))
+	values.Set("orgId", strconv.FormatInt(msg.OrgID, 10))
+	values.Set("title", msg.Title)
+	values.Set("state", msg.State)
+	values.Set("message", msg.Message)
+	values.Set("status", msg.Status)
+	values.Set("receiver", msg.Receiver)
+	values.Set("externalURL", msg.ExternalURL)
+	for i, alert := range msg.Alerts {
+		prefix := fmt.Sprintf("alert_%d_", i)
+		values.Set(prefix+"status", alert.Status)
+		values.Set(prefix+"startsAt", alert.StartsAt.Format(time.RFC3339))
+		values.Set(prefix+"endsAt", alert.EndsAt.Format(time.RFC3339))
+		values.Set(prefix+"fingerprint", alert.Fingerprint)
+		values.Set(prefix+"generatorURL", alert.GeneratorURL)
+		values.Set(prefix+"silenceURL", alert.SilenceURL)
+		values.Set(prefix+"dashboardURL", alert.DashboardURL)
+		values.Set(prefix+"panelURL", alert.PanelURL)
+		values.Set(prefix+"valueString", alert.ValueString)
+		if alert.ImageURL != "" {
+			values.Set(prefix+"imageURL", alert.ImageURL)
+		}
+		for name, value := range alert.Labels {
+			values.Set(prefix+wn.settings.LabelPrefix+name, value)
+		}
+		for name, value := range alert.Annotations {
+			values.Set(prefix+wn.settings.AnnotationPrefix+name, value)
+		}
+	}
+	return values
+}
+
+// validateResponse checks the response body of a successful request against
+// success_jmespath and success_regex, as some webhooks return 200 and report
+// failures in the body instead.
+func (wn *WebhookNotifier) validateResponse(body []byte, statusCode int) error {
+	// Failed requests are reported by their status code.
+	if statusCode/100 != 2 {
+		return nil
+	}
+
+	if wn.settings.SuccessJMESPath != nil {
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return fmt.Errorf("failed to unmarshal response body: %w", err)
+		}
+		val, err := wn.settings.SuccessJMESPath.Search(data)
+		if err != nil {
+			return fmt.Errorf("failed to search response body: %w", err)
+		}
+		if val != true {
+			return fmt.Errorf("response body does not match success_jmespath: %s", truncateBody(body, defaultMaxBodyLogLen))
+		}
+	}
+
+	if wn.settings.SuccessRegex != nil && !wn.settings.SuccessRegex.Match(body) {
+		return fmt.Errorf("response body does not match success_regex: %s", truncateBody(body, defaultMaxBodyLogLen))
+	}
+
+	return nil
+}
+
+func truncateAlerts(maxAlerts int, alerts []*types.Alert) ([]*types.Alert, int) {
+	if maxAlerts > 0 && len(alerts) > maxAlerts {
+		return alerts[:maxAlerts], len(alerts) - maxAlerts
+	}
+
+	return alerts, 0
+}
+
+func (wn *WebhookNotifier) SendResolved() bool {
+	return !wn.GetDisableResolveMessage()
+}
@@ -0,0 +1,1483 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	orgID := int64(1)
+
+	cases := []struct {
+		name     string
+		settings string
+		alerts   []*types.Alert
+
+		expMsg        *WebhookMessage
+		expURL        string
+		expUsername   string
+		expPassword   string
+		expHeaders    map[string]string
+		expHTTPMethod string
+		expInitError  string
+		expMsgError   error
+	}{
+		{
+			name:     "Default config with one alert with custom message",
+			settings: `{"url": "http://localhost/test", "message": "Custom message"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expURL:        "http://localhost/test",
+			expHTTPMethod: "POST",
+			expMsg: &WebhookMessage{
+				ExtendedData: &channels.ExtendedData{
+					Receiver: "my_receiver",
+					Status:   "firing",
+					Alerts: channels.ExtendedAlerts{
+						{
+							Status: "firing",
+							Labels: template.KV{
+								"alertname": "alert1",
+								"lbl1":      "val1",
+							},
+							Annotations: template.KV{
+								"ann1": "annv1",
+							},
+							Fingerprint:  "fac0861a85de433a",
+							DashboardURL: "http://localhost/d/abcd",
+							PanelURL:     "http://localhost/d/abcd?viewPanel=efgh",
+							SilenceURL:   "http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1",
+						},
+					},
+					GroupLabels: template.KV{
+						"alertname": "",
+					},
+					CommonLabels: template.KV{
+						"alertname": "alert1",
+						"lbl1":      "val1",
+					},
+					CommonAnnotations: template.KV{
+						"ann1": "annv1",
+					},
+					ExternalURL: "http://localhost",
+				},
+				Version:  "1",
+				GroupKey: "alertname",
+				Title:    "[FIRING:1]  (val1)",
+				State:    "alerting",
+				Message:  "Custom message",
+				OrgID:    orgID,
+			},
+			expMsgError: nil,
+			expHeaders:  map[string]string{},
+		},
+		{
+			name: "Custom config with multiple alerts with custom title",
+			settings: `{
+				"url": "http://localhost/test1",
+				"title": "Alerts firing: {{ len .Alerts.Firing }}",
+				"username": "user1",
+				"password": "mysecret",
+				"httpMethod": "PUT",
+				"maxAlerts": "2"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+						Annotations: model.LabelSet{"ann1": "annv2"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val3"},
+						Annotations: model.LabelSet{"ann1": "annv3"},
+					},
+				},
+			},
+			expURL:        "http://localhost/test1",
+			expHTTPMethod: "PUT",
+			expUsername:   "user1",
+			expPassword:   "mysecret",
+			expMsg: &WebhookMessage{
+				ExtendedData: &channels.ExtendedData{
+					Receiver: "my_receiver",
+					Status:   "firing",
+					Alerts: channels.ExtendedAlerts{
+						{
+							Status: "firing",
+							Labels: template.KV{
+								"alertname": "alert1",
+								"lbl1":      "val1",
+							},
+							Annotations: template.KV{
+								"ann1": "annv1",
+							},
+							Fingerprint: "fac0861a85de433a",
+							SilenceURL:  "http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1",
+						}, {
+							Status: "firing",
+							Labels: template.KV{
+								"alertname": "alert1",
+								"lbl1":      "val2",
+							},
+							Annotations: template.KV{
+								"ann1": "annv2",
+							},
+							Fingerprint: "fab6861a85d5eeb5",
+							SilenceURL:  "http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval2",
+						},
+					},
+					GroupLabels: template.KV{
+						"alertname": "",
+					},
+					CommonLabels: template.KV{
+						"alertname": "alert1",
+					},
+					CommonAnnotations: template.KV{},
+					ExternalURL:       "http://localhost",
+				},
+				Version:         "1",
+				GroupKey:        "alertname",
+				TruncatedAlerts: 1,
+				Title:           "Alerts firing: 2",
+				State:           "alerting",
+				Message:         "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val2\nAnnotations:\n - ann1 = annv2\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval2\n",
+				OrgID:           orgID,
+			},
+			expMsgError: nil,
+			expHeaders:  map[string]string{},
+		},
+		{
+			name:     "Default config, template variables in URL",
+			settings: `{"url": "http://localhost/test?numAlerts={{len .Alerts}}&status={{.Status}}"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val2"},
+						Annotations: model.LabelSet{"ann1": "annv2"},
+					},
+				},
+			},
+			expURL:        "http://localhost/test?numAlerts=2&status=firing",
+			expHTTPMethod: "POST",
+			expMsg: &WebhookMessage{
+				ExtendedData: &channels.ExtendedData{
+					Receiver: "my_receiver",
+					Status:   "firing",
+					Alerts: channels.ExtendedAlerts{
+						{
+							Status: "firing",
+							Labels: template.KV{
+								"alertname": "alert1",
+								"lbl1":      "val1",
+							},
+							Annotations: template.KV{
+								"ann1": "annv1",
+							},
+							Fingerprint: "fac0861a85de433a",
+							SilenceURL:  "http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1",
+						}, {
+							Status: "firing",
+							Labels: template.KV{
+								"alertname": "alert1",
+								"lbl1":      "val2",
+							},
+							Annotations: template.KV{
+								"ann1": "annv2",
+							},
+							Fingerprint: "fab6861a85d5eeb5",
+							SilenceURL:  "http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval2",
+						},
+					},
+					GroupLabels: template.KV{
+						"alertname": "",
+					},
+					CommonLabels: template.KV{
+						"alertname": "alert1",
+					},
+					CommonAnnotations: template.KV{},
+					ExternalURL:       "http://localhost",
+				},
+				Version:         "1",
+				GroupKey:        "alertname",
+				TruncatedAlerts: 0,
+				Title:           "[FIRING:2]  ",
+				State:           "alerting",
+				Message:         "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val2\nAnnotations:\n - ann1 = annv2\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval2\n",
+				OrgID:           orgID,
+			},
+			expMsgError: nil,
+			expHeaders:  map[string]string{},
+		},
+		{
+			name: "with Authorization set",
+			settings: `{
+				"url": "http://localhost/test1",
+				"authorization_credentials": "mysecret",
+				"httpMethod": "POST",
+				"maxAlerts": 2
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsg: &WebhookMessage{
+				ExtendedData: &channels.ExtendedData{
+					Receiver: "my_receiver",
+					Status:   "firing",
+					Alerts: channels.ExtendedAlerts{
+						{
+							Status: "firing",
+							Labels: template.KV{
+								"alertname": "alert1",
+								"lbl1":      "val1",
+							},
+							Annotations: template.KV{
+								"ann1": "annv1",
+							},
+							Fingerprint:  "fac0861a85de433a",
+							DashboardURL: "http://localhost/d/abcd",
+							PanelURL:     "http://localhost/d/abcd?viewPanel=efgh",
+							SilenceURL:   "http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1",
+						},
+					},
+					GroupLabels: template.KV{
+						"alertname": "",
+					},
+					CommonLabels: template.KV{
+						"alertname": "alert1",
+						"lbl1":      "val1",
+					},
+					CommonAnnotations: template.KV{
+						"ann1": "annv1",
+					},
+					ExternalURL: "http://localhost",
+				},
+				Version:  "1",
+				GroupKey: "alertname",
+				Title:    "[FIRING:1]  (val1)",
+				State:    "alerting",
+				Message:  "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\nDashboard: http://localhost/d/abcd\nPanel: http://localhost/d/abcd?viewPanel=efgh\n",
+				OrgID:    orgID,
+			},
+			expURL:        "http://localhost/test1",
+			expHTTPMethod: "POST",
+			expHeaders:    map[string]string{"Authorization": "Bearer mysecret"},
+		},
+		{
+			name:     "bad template in url",
+			settings: `{"url": "http://localhost/test1?numAlerts={{len Alerts}}"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1", "__dashboardUid__": "abcd", "__panelId__": "efgh"},
+					},
+				},
+			},
+			expMsgError: fmt.Errorf("template: :1: function \"Alerts\" not defined"),
+		},
+		{
+			name: "with both HTTP basic auth and Authorization Header set",
+			settings: `{
+				"url": "http://localhost/test1",
+				"username": "user1",
+				"password": "mysecret",
+				"authorization_credentials": "mysecret",
+				"httpMethod": "POST",
+				"maxAlerts": "2"
+			}`,
+			expInitError: "both HTTP Basic Authentication and Authorization Header are set, only 1 is permitted",
+		},
+		{
+			name:         "Error in initing",
+			settings:     `{}`,
+			expInitError: `required field 'url' is not specified`,
+		},
+		{
+			name:         "Error invalid resolved_message",
+			settings:     `{"url": "http://localhost/test", "resolved_message": "{{ .Status"}`,
+			expInitError: `invalid resolved_message template: `,
+		},
+		{
+			name:         "Error invalid success_jmespath",
+			settings:     `{"url": "http://localhost/test", "success_jmespath": "ok =="}`,
+			expInitError: `invalid JMESPath expression for success_jmespath "ok ==": `,
+		},
+		{
+			name:         "Error invalid success_regex",
+			settings:     `{"url": "http://localhost/test", "success_regex": "ok("}`,
+			expInitError: "invalid success_regex \"ok(\": error parsing regexp: missing closing ): `ok(`",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settingsJSON := json.RawMessage(c.settings)
+			secureSettings := make(map[string][]byte)
+
+			m := &channels.NotificationChannelConfig{
+				OrgID:          orgID,
+				Name:           "webhook_testing",
+				Type:           "webhook",
+				Settings:       settingsJSON,
+				SecureSettings: secureSettings,
+			}
+
+			webhookSender := mockNotificationService()
+
+			fc := channels.FactoryConfig{
+				Config:              m,
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   tmpl,
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := buildWebhookNotifier(fc)
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.True(t, strings.HasPrefix(err.Error(), c.expInitError), err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ctx = notify.WithReceiverName(ctx, "my_receiver")
+			ok, err := pn.Notify(ctx, c.alerts...)
+			if c.expMsgError != nil {
+				require.False(t, ok)
+				require.Error(t, err)
+				require.Equal(t, c.expMsgError.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			expBody, err := json.Marshal(c.expMsg)
+			require.NoError(t, err)
+
+			require.JSONEq(t, string(expBody), webhookSender.Webhook.Body)
+			require.Equal(t, c.expURL, webhookSender.Webhook.URL)
+			require.Equal(t, c.expUsername, webhookSender.Webhook.User)
+			require.Equal(t, c.expPassword, webhookSender.Webhook.Password)
+			require.Equal(t, c.expHTTPMethod, webhookSender.Webhook.HTTPMethod)
+			require.Equal(t, c.expHeaders, webhookSender.Webhook.HTTPHeader)
+		})
+	}
+}
+
+func TestWebhookNotifier_ResolvedMessage(t *testing.T) {
+	webhookSender := mockNotificationService()
+	pn, err := buildWebhookNotifier(channels.FactoryConfig{
+		Config: &channels.NotificationChannelConfig{
+			Name: "webhook_testing",
+			Type: "webhook",
+			Settings: json.RawMessage(`{
+				"url": "http://localhost/test",
+				"message": "{{ len .Alerts.Firing }} alerts are firing",
+				"resolved_message": "{{ len .Alerts.Resolved }} alerts are resolved"
+			}`),
+		},
+		NotificationService: webhookSender,
+		DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+			return fallback
+		},
+		ImageStore: &channels.UnavailableImageStore{},
+		Template:   templateForTests(t),
+		Logger:     &channels.FakeLogger{},
+	})
+	require.NoError(t, err)
+
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	for _, c := range []struct {
+		endsAt     time.Time
+		expMessage string
+	}{
+		{expMessage: "1 alerts are firing"},
+		{endsAt: time.Now().Add(-time.Minute), expMessage: "1 alerts are resolved"},
+	} {
+		ok, err := pn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}, EndsAt: c.endsAt}})
+		require.NoError(t, err)
+		require.True(t, ok)
+		var msg WebhookMessage
+		require.NoError(t, json.Unmarshal([]byte(webhookSender.Webhook.Body), &msg))
+		require.Equal(t, c.expMessage, msg.Message)
+	}
+}
+
+func TestWebhookNotifier_ValidateResponse(t *testing.T) {
+	cases := []struct {
+		name       string
+		settings   string
+		body       string
+		statusCode int
+		expErr     string
+	}{{
+		name:       "success_jmespath is true",
+		settings:   `{"url": "http://localhost/test", "success_jmespath": "ok"}`,
+		body:       `{"ok": true}`,
+		statusCode: http.StatusOK,
+	}, {
+		name:       "success_jmespath is false",
+		settings:   `{"url": "http://localhost/test", "success_jmespath": "ok"}`,
+		body:       `{"ok": false}`,
+		statusCode: http.StatusOK,
+		expErr:     `response body does not match success_jmespath: {"ok": false}`,
+	}, {
+		name:       "success_jmespath with comparison",
+		settings:   `{"url": "http://localhost/test", "success_jmespath": "result.status == 'accepted'"}`,
+		body:       `{"result": {"status": "accepted"}}`,
+		statusCode: http.StatusOK,
+	}, {
+		name:       "success_jmespath with invalid JSON",
+		settings:   `{"url": "http://localhost/test", "success_jmespath": "ok"}`,
+		body:       `ok`,
+		statusCode: http.StatusOK,
+		expErr:     "failed to unmarshal response body: invalid character 'o' looking for beginning of value",
+	}, {
+		name:       "success_regex matches",
+		settings:   `{"url": "http://localhost/test", "success_regex": "^OK"}`,
+		body:       `OK: message received`,
+		statusCode: http.StatusOK,
+	}, {
+		name:       "success_regex does not match",
+		settings:   `{"url": "http://localhost/test", "success_regex": "^OK"}`,
+		body:       `ERROR: invalid token`,
+		statusCode: http.StatusOK,
+		expErr:     "response body does not match success_regex: ERROR: invalid token",
+	}, {
+		name:       "long response body is truncated in the error",
+		settings:   `{"url": "http://localhost/test", "success_regex": "^OK"}`,
+		body:       strings.Repeat("a", defaultMaxBodyLogLen+10),
+		statusCode: http.StatusOK,
+		expErr:     "response body does not match success_regex: " + strings.Repeat("a", defaultMaxBodyLogLen) + "... (10 bytes truncated)",
+	}, {
+		name:       "body is not checked for failed requests",
+		settings:   `{"url": "http://localhost/test", "success_regex": "^OK"}`,
+		body:       `Internal Server Error`,
+		statusCode: http.StatusInternalServerError,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   templateForTests(t),
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := buildWebhookNotifier(fc)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, &types.Alert{
+				Alert: model.Alert{
+					Labels: model.LabelSet{"alertname": "alert1"},
+				},
+			})
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.NotNil(t, webhookSender.Webhook.Validation)
+			err = webhookSender.Webhook.Validation([]byte(c.body), c.statusCode)
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// webhookRecordingSender records the webhook requests, and fails requests for
+// alerts with the alertname in fail.
+type webhookRecordingSender struct {
+	notificationServiceMock
+	fail     string
+	requests []channels.SendWebhookSettings
+}
+
+func (ns *webhookRecordingSender) SendWebhook(ctx context.Context, cmd *channels.SendWebhookSettings) error {
+	ns.requests = append(ns.requests, *cmd)
+	if ns.fail != "" && strings.Contains(cmd.Body, `"alertname":"`+ns.fail+`"`) {
+		return errors.New("failed to send request")
+	}
+	return nil
+}
+
+func TestWebhookNotifier_Batch(t *testing.T) {
+	alerts := []*types.Alert{{
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}},
+	}, {
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2"}},
+	}, {
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert3"}},
+	}}
+
+	cases := []struct {
+		name        string
+		settings    string
+		fail        string
+		expRequests int
+		expErr      string
+	}{{
+		name:        "all alerts are sent in one request by default",
+		settings:    `{"url": "http://localhost/test"}`,
+		expRequests: 1,
+	}, {
+		name:        "one request is sent per alert",
+		settings:    `{"url": "http://localhost/test", "batch": false}`,
+		expRequests: 3,
+	}, {
+		name:        "one request fails",
+		settings:    `{"url": "http://localhost/test", "batch": false}`,
+		fail:        "alert2",
+		expRequests: 3,
+		expErr:      "1 error occurred:\n\t* failed to send request\n\n",
+	}, {
+		name:        "one request fails with partial success",
+		settings:    `{"url": "http://localhost/test", "batch": false, "allow_partial_success": true}`,
+		fail:        "alert2",
+		expRequests: 3,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := &webhookRecordingSender{fail: c.fail}
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   templateForTests(t),
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := buildWebhookNotifier(fc)
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, alerts...)
+			require.Len(t, webhookSender.requests, c.expRequests)
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				require.False(t, ok)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			// Each request must contain just the alerts it was sent for.
+			var numAlerts int
+			for _, r := range webhookSender.requests {
+				var msg WebhookMessage
+				require.NoError(t, json.Unmarshal([]byte(r.Body), &msg))
+				numAlerts += len(msg.Alerts)
+			}
+			require.Equal(t, len(alerts), numAlerts)
+		})
+	}
+}
+
+func TestWebhookNotifier_IdempotencyKey(t *testing.T) {
+	newNotifier := func(t *testing.T, settings string) (*WebhookNotifier, *notificationServiceMock) {
+		webhookSender := mockNotificationService()
+		pn, err := buildWebhookNotifier(channels.FactoryConfig{
+			Config: &channels.NotificationChannelConfig{
+				Name:     "webhook_testing",
+				Type:     "webhook",
+				Settings: json.RawMessage(settings),
+			},
+			NotificationService: webhookSender,
+			DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+				return fallback
+			},
+			ImageStore: &channels.UnavailableImageStore{},
+			Template:   templateForTests(t),
+			Logger:     &channels.FakeLogger{},
+		})
+		require.NoError(t, err)
+		return pn, webhookSender
+	}
+	ctx := notify.WithGroupKey(context.Background(), "alertname")
+	alert1 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}}
+	alert2 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2"}}}
+
+	t.Run("no header by default", func(t *testing.T) {
+		pn, webhookSender := newNotifier(t, `{"url": "http://localhost/test"}`)
+		ok, err := pn.Notify(ctx, alert1)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.NotContains(t, webhookSender.Webhook.HTTPHeader, "Idempotency-Key")
+	})
+
+	t.Run("header is the same for retries and changes with the alerts", func(t *testing.T) {
+		pn, webhookSender := newNotifier(t, `{"url": "http://localhost/test", "idempotency_key": true}`)
+		headers := make([]string, 0, 3)
+		for _, as := range [][]*types.Alert{{alert1, alert2}, {alert2, alert1}, {alert1}} {
+			ok, err := pn.Notify(ctx, as...)
+			require.NoError(t, err)
+			require.True(t, ok)
+			headers = append(headers, webhookSender.Webhook.HTTPHeader["Idempotency-Key"])
+		}
+		expKey, err := idempotencyKey(ctx, alert1, alert2)
+		require.NoError(t, err)
+		require.Equal(t, expKey, headers[0])
+		require.Equal(t, headers[0], headers[1])
+		require.NotEqual(t, headers[0], headers[2])
+	})
+
+	t.Run("header for each alert when alerts are not batched", func(t *testing.T) {
+		pn, webhookSender := newNotifier(t, `{"url": "http://localhost/test", "idempotency_key": true, "batch": false}`)
+		ok, err := pn.Notify(ctx, alert1)
+		require.NoError(t, err)
+		require.True(t, ok)
+		expKey, err := idempotencyKey(ctx, alert1)
+		require.NoError(t, err)
+		require.Equal(t, expKey, webhookSender.Webhook.HTTPHeader["Idempotency-Key"])
+	})
+}
+
+func TestWebhookNotifier_Priority(t *testing.T) {
+	mapping := `[
+		{"severity": "critical", "priority": "1"},
+		{"severity": "warning", "priority": "3"}
+	]`
+
+	cases := []struct {
+		name         string
+		settings     string
+		severities   []string
+		expHeaders   map[string]string
+		expInitError string
+	}{{
+		name:       "no header without priority_mapping",
+		settings:   `{"url": "http://localhost/test"}`,
+		severities: []string{"critical"},
+		expHeaders: map[string]string{},
+	}, {
+		name:       "header for a critical alert",
+		settings:   `{"url": "http://localhost/test", "priority_mapping": ` + mapping + `}`,
+		severities: []string{"critical"},
+		expHeaders: map[string]string{"X-Priority": "1"},
+	}, {
+		name:       "header for the highest severity of the alerts",
+		settings:   `{"url": "http://localhost/test", "priority_mapping": ` + mapping + `}`,
+		severities: []string{"warning", "Critical", "info"},
+		expHeaders: map[string]string{"X-Priority": "1"},
+	}, {
+		name:       "header for the highest severity if the mapping is not ordered by severity",
+		settings:   `{"url": "http://localhost/test", "priority_mapping": [{"severity": "info", "priority": "5"}, {"severity": "critical", "priority": "1"}]}`,
+		severities: []string{"info", "critical"},
+		expHeaders: map[string]string{"X-Priority": "1"},
+	}, {
+		name:       "custom header",
+		settings:   `{"url": "http://localhost/test", "priority_header": "X-Urgency", "priority_mapping": ` + mapping + `}`,
+		severities: []string{"warning"},
+		expHeaders: map[string]string{"X-Urgency": "3"},
+	}, {
+		name:       "no header without a mapped severity",
+		settings:   `{"url": "http://localhost/test", "priority_mapping": ` + mapping + `}`,
+		severities: []string{"info", ""},
+		expHeaders: map[string]string{},
+	}, {
+		name:         "invalid header",
+		settings:     `{"url": "http://localhost/test", "priority_header": "X Priority"}`,
+		expInitError: `invalid value for priority_header: "X Priority"`,
+	}, {
+		name:         "missing priority",
+		settings:     `{"url": "http://localhost/test", "priority_mapping": [{"severity": "critical"}]}`,
+		expInitError: `severity and priority are required in priority_mapping`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   templateForTests(t),
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := buildWebhookNotifier(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			var alerts []*types.Alert
+			for i, severity := range c.severities {
+				alerts = append(alerts, &types.Alert{
+					Alert: model.Alert{Labels: model.LabelSet{
+						"alertname": model.LabelValue(fmt.Sprintf("alert%d", i)),
+						"severity":  model.LabelValue(severity),
+					}},
+				})
+			}
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, c.expHeaders, webhookSender.Webhook.HTTPHeader)
+		})
+	}
+}
+
+func TestWebhookNotifier_FlattenLabels(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	startsAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	alerts := []*types.Alert{{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+			Annotations: model.LabelSet{"ann1": "annv1"},
+			StartsAt:    startsAt,
+		},
+	}, {
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "alert2"},
+			StartsAt: startsAt,
+			EndsAt:   startsAt.Add(time.Hour),
+		},
+	}}
+
+	expValues := func(labelPrefix, annotationPrefix string) url.Values {
+		return url.Values{
+			"version":                              {"1"},
+			"groupKey":                             {"alertname"},
+			"truncatedAlerts":                      {"0"},
+			"orgId":                                {"1"},
+			"title":                                {"Title"},
+			"state":                                {"alerting"},
+			"message":                              {"Message"},
+			"status":                               {"firing"},
+			"receiver":                             {"my_receiver"},
+			"externalURL":                          {"http://localhost"},
+			"alert_0_status":                       {"firing"},
+			"alert_0_startsAt":                     {"2023-01-02T03:04:05Z"},
+			"alert_0_endsAt":                       {"0001-01-01T00:00:00Z"},
+			"alert_0_fingerprint":                  {"fac0861a85de433a"},
+			"alert_0_generatorURL":                 {""},
+			"alert_0_silenceURL":                   {"http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1"},
+			"alert_0_dashboardURL":                 {""},
+			"alert_0_panelURL":                     {""},
+			"alert_0_valueString":                  {""},
+			"alert_0_" + labelPrefix + "alertname": {"alert1"},
+			"alert_0_" + labelPrefix + "lbl1":      {"val1"},
+			"alert_0_" + annotationPrefix + "ann1": {"annv1"},
+			"alert_1_status":                       {"resolved"},
+			"alert_1_startsAt":                     {"2023-01-02T03:04:05Z"},
+			"alert_1_endsAt":                       {"2023-01-02T04:04:05Z"},
+			"alert_1_fingerprint":                  {"ea03c3f233bc9acc"},
+			"alert_1_generatorURL":                 {""},
+			"alert_1_silenceURL":                   {"http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert2"},
+			"alert_1_dashboardURL":                 {""},
+			"alert_1_panelURL":                     {""},
+			"alert_1_valueString":                  {""},
+			"alert_1_" + labelPrefix + "alertname": {"alert2"},
+		}
+	}
+
+	cases := []struct {
+		name         string
+		settings     string
+		expValues    url.Values
+		expInitError string
+	}{{
+		name:      "default prefixes",
+		settings:  `{"url": "http://localhost/test", "title": "Title", "message": "Message", "flatten_labels": true}`,
+		expValues: expValues("label_", "annotation_"),
+	}, {
+		name:      "custom prefixes",
+		settings:  `{"url": "http://localhost/test", "title": "Title", "message": "Message", "flatten_labels": true, "label_prefix": "l.", "annotation_prefix": "a."}`,
+		expValues: expValues("l.", "a."),
+	}, {
+		name:         "same prefixes",
+		settings:     `{"url": "http://localhost/test", "flatten_labels": true, "label_prefix": "", "annotation_prefix": ""}`,
+		expInitError: "label_prefix and annotation_prefix must be different",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					OrgID:    1,
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   tmpl,
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := buildWebhookNotifier(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ctx = notify.WithReceiverName(ctx, "my_receiver")
+			ok, err := pn.Notify(ctx, alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.Equal(t, "application/x-www-form-urlencoded", webhookSender.Webhook.ContentType)
+			values, err := url.ParseQuery(webhookSender.Webhook.Body)
+			require.NoError(t, err)
+			require.Equal(t, c.expValues, values)
+		})
+	}
+}
+
+func TestWebhookNotifier_TitleAndMessage(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	cases := []struct {
+		name         string
+		settings     string
+		expTitle     string
+		expMessage   string
+		expInitError string
+	}{{
+		name:       "title and message are templated independently",
+		settings:   `{"url": "http://localhost/test", "title": "{{ len .Alerts.Firing }} firing: {{ .CommonLabels.alertname }}", "message": "{{ range .Alerts }}{{ .Labels.alertname }} on {{ .Labels.instance }}\n{{ end }}"}`,
+		expTitle:   "2 firing: alert1",
+		expMessage: "alert1 on host1\nalert1 on host2\n",
+	}, {
+		name:       "static title",
+		settings:   `{"url": "http://localhost/test", "title": "Static title", "message": "{{ len .Alerts }} alerts"}`,
+		expTitle:   "Static title",
+		expMessage: "2 alerts",
+	}, {
+		name:         "invalid title",
+		settings:     `{"url": "http://localhost/test", "title": "{{ .Status "}`,
+		expInitError: "invalid title template: template: :1: unclosed action",
+	}, {
+		name:         "invalid message",
+		settings:     `{"url": "http://localhost/test", "message": "{{ end }}"}`,
+		expInitError: "invalid message template: template: :1: unexpected {{end}}",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   tmpl,
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := buildWebhookNotifier(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := pn.Notify(ctx, &types.Alert{
+				Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "instance": "host1"}},
+			}, &types.Alert{
+				Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "instance": "host2"}},
+			})
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			var msg struct {
+				Title   string `json:"title"`
+				Message string `json:"message"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(webhookSender.Webhook.Body), &msg))
+			require.Equal(t, c.expTitle, msg.Title)
+			require.Equal(t, c.expMessage, msg.Message)
+		})
+	}
+}
+
+func TestWebhookNotifier_PayloadTemplate(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	dir := t.TempDir()
+	prev := getOptions()
+	o := prev
+	o.WebhookTemplatesDirectory = dir
+	SetOptions(o)
+	t.Cleanup(func() { SetOptions(prev) })
+	templateFile := filepath.Join(dir, "payload.tmpl")
+	require.NoError(t, os.WriteFile(templateFile, []byte(`{"alerts": [{{ range $i, $a := .Alerts }}{{ if $i }}, {{ end }}"{{ $a.Labels.alertname }}"{{ end }}], "status": "{{ .Status }}"}`), 0600))
+	invalidTemplateFile := filepath.Join(dir, "invalid.tmpl")
+	require.NoError(t, os.WriteFile(invalidTemplateFile, []byte(`{{ .Status `), 0600))
+	outsideFile := filepath.Join(t.TempDir(), "grafana.ini")
+	require.NoError(t, os.WriteFile(outsideFile, []byte(`secret_key = secret`), 0600))
+	require.NoError(t, os.Symlink(outsideFile, filepath.Join(dir, "link.tmpl")))
+
+	cases := []struct {
+		name         string
+		settings     string
+		expBody      string
+		expInitError string
+	}{{
+		name:     "template_file",
+		settings: fmt.Sprintf(`{"url": "http://localhost/test", "template_file": %q}`, templateFile),
+		expBody:  `{"alerts": ["alert1", "alert2"], "status": "firing"}`,
+	}, {
+		name:     "payload_template",
+		settings: `{"url": "http://localhost/test", "payload_template": "{\"count\": {{ len .Alerts.Firing }}}"}`,
+		expBody:  `{"count": 2}`,
+	}, {
+		name:     "template_file relative to the directory",
+		settings: `{"url": "http://localhost/test", "template_file": "payload.tmpl"}`,
+		expBody:  `{"alerts": ["alert1", "alert2"], "status": "firing"}`,
+	}, {
+		name:         "template_file does not exist",
+		settings:     `{"url": "http://localhost/test", "template_file": "missing.tmpl"}`,
+		expInitError: `invalid value for template_file: "missing.tmpl", must be a file in the directory of webhook templates`,
+	}, {
+		name:         "template_file outside the directory",
+		settings:     fmt.Sprintf(`{"url": "http://localhost/test", "template_file": %q}`, outsideFile),
+		expInitError: fmt.Sprintf(`invalid value for template_file: %q, must be a file in the directory of webhook templates`, outsideFile),
+	}, {
+		name:         "template_file leaves the directory",
+		settings:     `{"url": "http://localhost/test", "template_file": "../grafana.ini"}`,
+		expInitError: `invalid value for template_file: "../grafana.ini", must be a file in the directory of webhook templates`,
+	}, {
+		name:         "template_file is a symbolic link out of the directory",
+		settings:     `{"url": "http://localhost/test", "template_file": "link.tmpl"}`,
+		expInitError: `invalid value for template_file: "link.tmpl", must be a file in the directory of webhook templates`,
+	}, {
+		name:         "template_file is invalid",
+		settings:     fmt.Sprintf(`{"url": "http://localhost/test", "template_file": %q}`, invalidTemplateFile),
+		expInitError: "invalid payload template: template: :1: unclosed action",
+	}, {
+		name:         "payload_template and template_file",
+		settings:     fmt.Sprintf(`{"url": "http://localhost/test", "payload_template": "{}", "template_file": %q}`, templateFile),
+		expInitError: "only one of payload_template and template_file can be set",
+	}, {
+		name: "payload_template with custom delimiters",
+		settings: `{
+			"url": "http://localhost/test",
+			"left_delim": "[[",
+			"right_delim": "]]",
+			"payload_template": "{\"text\": \"{{ name }} [[ len .Alerts.Firing ]] [[ range .Alerts ]][[ .Labels.alertname ]] [[ end ]]\"}"
+		}`,
+		expBody: `{"text": "{{ name }} 2 alert1 alert2 "}`,
+	}, {
+		name:         "invalid payload_template with custom delimiters",
+		settings:     `{"url": "http://localhost/test", "left_delim": "[[", "right_delim": "]]", "payload_template": "[[ .Status "}`,
+		expInitError: "invalid payload template: template: :1: unclosed action",
+	}, {
+		name:         "left_delim without right_delim",
+		settings:     `{"url": "http://localhost/test", "left_delim": "[["}`,
+		expInitError: "left_delim and right_delim must be set together",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   tmpl,
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := buildWebhookNotifier(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, &types.Alert{
+				Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}},
+			}, &types.Alert{
+				Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2"}},
+			})
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.JSONEq(t, c.expBody, webhookSender.Webhook.Body)
+		})
+	}
+
+	t.Run("template_file without a directory", func(t *testing.T) {
+		o := getOptions()
+		o.WebhookTemplatesDirectory = ""
+		SetOptions(o)
+		_, err := buildWebhookNotifier(channels.FactoryConfig{
+			Config: &channels.NotificationChannelConfig{
+				Name:     "webhook_testing",
+				Type:     "webhook",
+				Settings: json.RawMessage(fmt.Sprintf(`{"url": "http://localhost/test", "template_file": %q}`, templateFile)),
+			},
+			NotificationService: mockNotificationService(),
+			DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+				return fallback
+			},
+			ImageStore: &channels.UnavailableImageStore{},
+			Template:   tmpl,
+			Logger:     &channels.FakeLogger{},
+		})
+		require.EqualError(t, err, "template_file cannot be used, as no directory is configured for webhook templates")
+	})
+}
+
+func TestWebhookNotifier_HTMLTemplate(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	cases := []struct {
+		name         string
+		settings     string
+		expBody      string
+		expInitError string
+	}{{
+		name: "html_template escapes values",
+		settings: `{
+			"url": "http://localhost/test",
+			"html_template": "<h1 style=\"color: {{ statusColor .Status }}\">{{ len .Alerts.Firing }} firing</h1><ul>{{ range .Alerts }}<li>{{ .Labels.alertname }}: {{ .Labels.summary }}</li>{{ end }}</ul>"
+		}`,
+		expBody: `<h1 style="color: #D63232">2 firing</h1><ul><li>alert1: CPU &gt; 90% &amp; rising</li><li>alert2: &lt;script&gt;alert(1)&lt;/script&gt;</li></ul>`,
+	}, {
+		name:         "html_template is invalid",
+		settings:     `{"url": "http://localhost/test", "html_template": "<p>{{ .Status }</p>"}`,
+		expInitError: `invalid html_template: template: :1: unexpected "}" in operand`,
+	}, {
+		name:         "html_template and payload_template",
+		settings:     `{"url": "http://localhost/test", "html_template": "<p></p>", "payload_template": "{}"}`,
+		expInitError: "html_template cannot be used with a payload template",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   tmpl,
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := buildWebhookNotifier(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, &types.Alert{
+				Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "summary": "CPU > 90% & rising"}},
+			}, &types.Alert{
+				Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert2", "summary": "<script>alert(1)</script>"}},
+			})
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, c.expBody, webhookSender.Webhook.Body)
+			require.Equal(t, "text/html; charset=utf-8", webhookSender.Webhook.ContentType)
+		})
+	}
+}
+
+func TestWebhookNotifier_Encoding(t *testing.T) {
+	alerts := []*types.Alert{{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+			Annotations: model.LabelSet{"ann1": "annv1", "description": "line 1\nline 2: with colon"},
+			StartsAt:    time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}, {
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "alert2", "lbl1": "val2"},
+			StartsAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}}
+
+	send := func(t *testing.T, settings string) (*channels.SendWebhookSettings, error) {
+		webhookSender := mockNotificationService()
+		fc := channels.FactoryConfig{
+			Config: &channels.NotificationChannelConfig{
+				Name:     "webhook_testing",
+				Type:     "webhook",
+				Settings: json.RawMessage(settings),
+			},
+			NotificationService: webhookSender,
+			DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+				return fallback
+			},
+			ImageStore: &channels.UnavailableImageStore{},
+			Template:   templateForTests(t),
+			Logger:     &channels.FakeLogger{},
+		}
+
+		pn, err := buildWebhookNotifier(fc)
+		if err != nil {
+			return nil, err
+		}
+		ctx := notify.WithGroupKey(context.Background(), "alertname")
+		ok, err := pn.Notify(ctx, alerts...)
+		require.NoError(t, err)
+		require.True(t, ok)
+		return &webhookSender.Webhook, nil
+	}
+
+	t.Run("yaml has the same payload as json", func(t *testing.T) {
+		jsonWebhook, err := send(t, `{"url": "http://localhost/test", "encoding": "json"}`)
+		require.NoError(t, err)
+		require.Equal(t, "", jsonWebhook.ContentType)
+
+		yamlWebhook, err := send(t, `{"url": "http://localhost/test", "encoding": "yaml"}`)
+		require.NoError(t, err)
+		require.Equal(t, "text/yaml", yamlWebhook.ContentType)
+
+		var payload interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(yamlWebhook.Body), &payload))
+		b, err := json.Marshal(payload)
+		require.NoError(t, err)
+		require.JSONEq(t, jsonWebhook.Body, string(b))
+	})
+
+	cases := []struct {
+		name         string
+		settings     string
+		expInitError string
+	}{{
+		name:         "invalid encoding",
+		settings:     `{"url": "http://localhost/test", "encoding": "xml"}`,
+		expInitError: `invalid value for encoding: "xml", must be json or yaml`,
+	}, {
+		name:         "yaml and payload_template",
+		settings:     `{"url": "http://localhost/test", "encoding": "yaml", "payload_template": "{}"}`,
+		expInitError: "encoding yaml cannot be used with a payload template, html_template or flatten_labels",
+	}, {
+		name:         "yaml and flatten_labels",
+		settings:     `{"url": "http://localhost/test", "encoding": "yaml", "flatten_labels": true}`,
+		expInitError: "encoding yaml cannot be used with a payload template, html_template or flatten_labels",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := send(t, c.settings)
+			require.EqualError(t, err, c.expInitError)
+		})
+	}
+}
+
+func TestWebhookNotifier_ResolvedURL(t *testing.T) {
+	firing := &types.Alert{
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1", "team": "platform"}},
+	}
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "alert2", "team": "platform"},
+			EndsAt: time.Now().Add(-time.Minute),
+		},
+	}
+
+	cases := []struct {
+		name         string
+		settings     string
+		batch        []*types.Alert
+		expURLs      []string
+		expInitError string
+	}{{
+		name:     "firing alerts are sent to the URL",
+		settings: `{"url": "http://localhost/urgent", "resolved_url": "http://localhost/log"}`,
+		batch:    []*types.Alert{firing, resolved},
+		expURLs:  []string{"http://localhost/urgent"},
+	}, {
+		name:     "resolved alerts are sent to the resolved URL",
+		settings: `{"url": "http://localhost/urgent", "resolved_url": "http://localhost/log/{{ .CommonLabels.team }}"}`,
+		batch:    []*types.Alert{resolved},
+		expURLs:  []string{"http://localhost/log/platform"},
+	}, {
+		name:     "resolved alerts are sent to the URL without resolved URL",
+		settings: `{"url": "http://localhost/urgent"}`,
+		batch:    []*types.Alert{resolved},
+		expURLs:  []string{"http://localhost/urgent"},
+	}, {
+		name:     "each request is sent to the URL for its alert",
+		settings: `{"url": "http://localhost/urgent", "resolved_url": "http://localhost/log", "batch": false}`,
+		batch:    []*types.Alert{firing, resolved},
+		expURLs:  []string{"http://localhost/urgent", "http://localhost/log"},
+	}, {
+		name:         "invalid resolved URL",
+		settings:     `{"url": "http://localhost/urgent", "resolved_url": "localhost/log"}`,
+		expInitError: "invalid value for resolved_url, must be an http or https URL",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := &webhookRecordingSender{}
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   templateForTests(t),
+				Logger:     &channels.FakeLogger{},
+			}
+
+			pn, err := buildWebhookNotifier(fc)
+			if c.expInitError != "" {
+				require.EqualError(t, err, c.expInitError)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, c.batch...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			urls := make([]string, 0, len(webhookSender.requests))
+			for _, r := range webhookSender.requests {
+				urls = append(urls, r.URL)
+			}
+			require.Equal(t, c.expURLs, urls)
+		})
+	}
+}
+
+func TestWebhookNotifier_HTTPCfg(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		requests []*http.Request
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			// The port of the server, as the Host can be overridden.
+			_, port, _ := net.SplitHostPort(r.Context().Value(http.LocalAddrContextKey).(net.Addr).String())
+			http.Redirect(w, r, "http://localhost:"+port+"/target", http.StatusTemporaryRedirect)
+			return
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		requests = append(requests, r)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// The DNS server resolves all names to 127.0.0.1.
+	dnsServer := newTestDNSServer(t, func(string) [4]byte {
+		return [4]byte{127, 0, 0, 1}
+	})
+
+	cases := []struct {
+		name       string
+		settings   string
+		expHTTPCfg bool
+		expPath    string
+		expHost    string
+		expAuth    string
+		expError   string
+	}{{
+		name:     "client of the notification service by default",
+		settings: fmt.Sprintf(`{"url": %q}`, server.URL+"/webhook"),
+		expPath:  "/webhook",
+		expHost:  u.Host,
+	}, {
+		name:       "DNS server",
+		settings:   fmt.Sprintf(`{"url": "http://webhook.grafana.test:%s/webhook", "dns_server": %q}`, u.Port(), dnsServer),
+		expHTTPCfg: true,
+		expPath:    "/webhook",
+		expHost:    "webhook.grafana.test:" + u.Port(),
+	}, {
+		name:       "host header",
+		settings:   fmt.Sprintf(`{"url": %q, "host_header": "webhook.example.com"}`, server.URL+"/webhook"),
+		expHTTPCfg: true,
+		expPath:    "/webhook",
+		expHost:    "webhook.example.com",
+	}, {
+		name:       "SigV4",
+		settings:   fmt.Sprintf(`{"url": %q, "sigv4_region": "us-east-1", "sigv4_access_key": "AKID", "sigv4_secret_key": "SECRET"}`, server.URL+"/webhook"),
+		expHTTPCfg: true,
+		expPath:    "/webhook",
+		expHost:    u.Host,
+		expAuth:    `^AWS4-HMAC-SHA256 Credential=AKID/\d{8}/us-east-1/execute-api/aws4_request, SignedHeaders=[a-z0-9;-]+, Signature=[0-9a-f]{64}$`,
+	}, {
+		name:       "redirect to an allowed host",
+		settings:   fmt.Sprintf(`{"url": %q, "max_redirects_follow_host_allowlist": "localhost"}`, server.URL+"/redirect"),
+		expHTTPCfg: true,
+		expPath:    "/target",
+		expHost:    "localhost:" + u.Port(),
+	}, {
+		name:       "host header is not kept on redirects to another host",
+		settings:   fmt.Sprintf(`{"url": %q, "host_header": "webhook.example.com", "max_redirects_follow_host_allowlist": "localhost"}`, server.URL+"/redirect"),
+		expHTTPCfg: true,
+		expPath:    "/target",
+		expHost:    "localhost:" + u.Port(),
+	}, {
+		name:       "redirect to another host",
+		settings:   fmt.Sprintf(`{"url": %q, "max_redirects_follow_host_allowlist": "*.example.com"}`, server.URL+"/redirect"),
+		expHTTPCfg: true,
+		expError:   `redirect to host "localhost" is not allowed`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mtx.Lock()
+			requests = nil
+			mtx.Unlock()
+
+			pn, err := buildWebhookNotifier(channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "webhook_testing",
+					Type:     "webhook",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: newNotificationServiceSender(t),
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				ImageStore: &channels.UnavailableImageStore{},
+				Template:   templateForTests(t),
+				Logger:     &channels.FakeLogger{},
+			})
+			require.NoError(t, err)
+			require.Equal(t, c.expHTTPCfg, pn.httpCfg != nil)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ok, err := pn.Notify(ctx, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "alert1"}}})
+			if c.expError != "" {
+				require.ErrorContains(t, err, c.expError)
+				require.False(t, ok)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			require.Len(t, requests, 1)
+			require.Equal(t, c.expPath, requests[0].URL.Path)
+			require.Equal(t, c.expHost, requests[0].Host)
+			if c.expAuth != "" {
+				require.Regexp(t, c.expAuth, requests[0].Header.Get("Authorization"))
+			}
+		})
+	}
+}
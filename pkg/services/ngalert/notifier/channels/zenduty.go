@@ -0,0 +1,188 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// https://docs.zenduty.com/docs/api#events
+	zendutyAlertTypeCritical = "critical"
+	zendutyAlertTypeResolved = "resolved"
+)
+
+// ZendutyNotifier is responsible for sending
+// alert notifications to a Zenduty integration.
+type ZendutyNotifier struct {
+	*channels.Base
+	log         channels.Logger
+	ns          channels.WebhookSender
+	tmpl        *template.Template
+	settings    *zendutySettings
+	maxValueLen int
+}
+
+type zendutySettings struct {
+	URL             string `json:"url,omitempty" yaml:"url,omitempty"`
+	Message         string `json:"message,omitempty" yaml:"message,omitempty"`
+	ResolvedMessage string `json:"resolved_message,omitempty" yaml:"resolved_message,omitempty"`
+	Summary         string `json:"summary,omitempty" yaml:"summary,omitempty"`
+	EntityID        string `json:"entity_id,omitempty" yaml:"entity_id,omitempty"`
+}
+
+// zendutyMessage is the event sent to the Zenduty integration.
+type zendutyMessage struct {
+	AlertType string         `json:"alert_type"`
+	Message   string         `json:"message"`
+	Summary   string         `json:"summary"`
+	EntityID  string         `json:"entity_id"`
+	Payload   zendutyPayload `json:"payload"`
+	URLs      []zendutyURL   `json:"urls,omitempty"`
+}
+
+type zendutyPayload struct {
+	Status            string      `json:"status"`
+	GroupLabels       template.KV `json:"groupLabels"`
+	CommonLabels      template.KV `json:"commonLabels"`
+	CommonAnnotations template.KV `json:"commonAnnotations"`
+	NumFiring         int         `json:"numFiring"`
+	NumResolved       int         `json:"numResolved"`
+}
+
+type zendutyURL struct {
+	LinkURL  string `json:"link_url"`
+	LinkText string `json:"link_text"`
+}
+
+func buildZendutySettings(fc channels.FactoryConfig) (*zendutySettings, error) {
+	var settings zendutySettings
+	err := json.Unmarshal(fc.Config.Settings, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	settings.URL = fc.DecryptFunc(context.Background(), fc.Config.SecureSettings, "url", settings.URL)
+	if settings.URL == "" {
+		return nil, errors.New("could not find url property in settings")
+	}
+	if !isHTTPURL(settings.URL) {
+		return nil, errors.New("url must be a valid http or https URL of a Zenduty integration")
+	}
+	if settings.Message == "" {
+		settings.Message = channels.DefaultMessageTitleEmbed
+	}
+	if settings.Summary == "" {
+		settings.Summary = channels.DefaultMessageEmbed
+	}
+	return &settings, nil
+}
+
+func ZendutyFactory(fc channels.FactoryConfig) (channels.NotificationChannel, error) {
+	zn, err := newZendutyNotifier(fc)
+	if err != nil {
+		return nil, receiverInitError{
+			Reason: err.Error(),
+			Cfg:    *fc.Config,
+		}
+	}
+	return zn, nil
+}
+
+// newZendutyNotifier is the constructor for the Zenduty notifier.
+func newZendutyNotifier(fc channels.FactoryConfig) (*ZendutyNotifier, error) {
+	settings, err := buildZendutySettings(fc)
+	if err != nil {
+		return nil, err
+	}
+	maxValueLen, err := buildMaxValueLen(fc)
+	if err != nil {
+		return nil, err
+	}
+	return &ZendutyNotifier{
+		Base:        channels.NewBase(fc.Config),
+		log:         fc.Logger,
+		ns:          fc.NotificationService,
+		tmpl:        fc.Template,
+		settings:    settings,
+		maxValueLen: maxValueLen,
+	}, nil
+}
+
+// Notify sends an event to the Zenduty integration.
+func (zn *ZendutyNotifier) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, err := notify.ExtractGroupKey(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var tmplErr error
+	tmpl, data := tmplText(ctx, zn.tmpl, as, zn.log, &tmplErr, zn.maxValueLen)
+
+	alertType := zendutyAlertTypeCritical
+	if types.Alerts(as...).Status() == model.AlertResolved {
+		alertType = zendutyAlertTypeResolved
+	}
+
+	// Zenduty uses the entity ID to deduplicate events and to resolve incidents,
+	// so by default it is the same for all notifications of the alert group.
+	entityID := key.Hash()
+	if zn.settings.EntityID != "" {
+		if id := tmpl(zn.settings.EntityID); id != "" {
+			entityID = id
+		}
+	}
+
+	msg := zendutyMessage{
+		AlertType: alertType,
+		Message:   tmpl(messageForAlerts(zn.settings.Message, zn.settings.ResolvedMessage, as)),
+		Summary:   tmpl(zn.settings.Summary),
+		EntityID:  entityID,
+		Payload: zendutyPayload{
+			Status:            data.Status,
+			GroupLabels:       data.GroupLabels,
+			CommonLabels:      data.CommonLabels,
+			CommonAnnotations: data.CommonAnnotations,
+			NumFiring:         len(data.Alerts.Firing()),
+			NumResolved:       len(data.Alerts.Resolved()),
+		},
+	}
+	if ruleURL := grafanaURL(zn.tmpl.ExternalURL, "/alerting/list"); ruleURL != "" {
+		msg.URLs = []zendutyURL{{LinkURL: ruleURL, LinkText: "Open in Grafana"}}
+	}
+
+	if tmplErr != nil {
+		zn.log.Warn("failed to template Zenduty message", "error", tmplErr.Error())
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal Zenduty message: %w", err)
+	}
+
+	cmd := &channels.SendWebhookSettings{
+		URL:        zn.settings.URL,
+		HTTPMethod: "POST",
+		HTTPHeader: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(body),
+	}
+	if err := zn.ns.SendWebhook(ctx, cmd); err != nil {
+		zn.log.Error("failed to send notification to Zenduty", "error", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (zn *ZendutyNotifier) SendResolved() bool {
+	return !zn.GetDisableResolveMessage()
+}
@@ -0,0 +1,205 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grafana/alerting/alerting/notifier/channels"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZendutyNotifier(t *testing.T) {
+	tmpl := templateForTests(t)
+
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	tmpl.ExternalURL = externalURL
+
+	// The entity ID defaults to the hash of the group key.
+	defaultEntityID := "6e3538104c14b583da237e9693b76debbc17f0f8058ef20492e5853096cf8733"
+
+	cases := []struct {
+		name         string
+		settings     string
+		alerts       []*types.Alert
+		expMsg       *zendutyMessage
+		expInitError string
+	}{
+		{
+			name:     "Default config with one alert",
+			settings: `{"url": "https://www.zenduty.com/api/events/abcd/"}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:      model.LabelSet{"alertname": "alert1", "lbl1": "val1"},
+						Annotations: model.LabelSet{"ann1": "annv1"},
+					},
+				},
+			},
+			expMsg: &zendutyMessage{
+				AlertType: "critical",
+				Message:   "[FIRING:1]  (val1)",
+				Summary:   "**Firing**\n\nValue: [no value]\nLabels:\n - alertname = alert1\n - lbl1 = val1\nAnnotations:\n - ann1 = annv1\nSilence: http://localhost/alerting/silence/new?alertmanager=grafana&matcher=alertname%3Dalert1&matcher=lbl1%3Dval1\n",
+				EntityID:  defaultEntityID,
+				Payload: zendutyPayload{
+					Status:            "firing",
+					GroupLabels:       template.KV{"alertname": ""},
+					CommonLabels:      template.KV{"alertname": "alert1", "lbl1": "val1"},
+					CommonAnnotations: template.KV{"ann1": "annv1"},
+					NumFiring:         1,
+				},
+				URLs: []zendutyURL{{LinkURL: "http://localhost/alerting/list", LinkText: "Open in Grafana"}},
+			},
+		}, {
+			name: "Custom config with templated summary and entity ID",
+			settings: `{
+				"url": "https://www.zenduty.com/api/events/abcd/",
+				"message": "{{ .CommonLabels.alertname }} is {{ .Status }}",
+				"summary": "{{ len .Alerts.Firing }} alerts are firing",
+				"entity_id": "{{ .CommonLabels.alertname }}-{{ .CommonLabels.cluster }}"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1", "cluster": "prod"},
+					},
+				}, {
+					Alert: model.Alert{
+						Labels: model.LabelSet{"alertname": "alert1", "cluster": "prod", "instance": "a"},
+					},
+				},
+			},
+			expMsg: &zendutyMessage{
+				AlertType: "critical",
+				Message:   "alert1 is firing",
+				Summary:   "2 alerts are firing",
+				EntityID:  "alert1-prod",
+				Payload: zendutyPayload{
+					Status:            "firing",
+					GroupLabels:       template.KV{"alertname": ""},
+					CommonLabels:      template.KV{"alertname": "alert1", "cluster": "prod"},
+					CommonAnnotations: template.KV{},
+					NumFiring:         2,
+				},
+				URLs: []zendutyURL{{LinkURL: "http://localhost/alerting/list", LinkText: "Open in Grafana"}},
+			},
+		}, {
+			name: "Resolved alert with empty templated entity ID",
+			settings: `{
+				"url": "https://www.zenduty.com/api/events/abcd/",
+				"message": "{{ .CommonLabels.alertname }} is {{ .Status }}",
+				"summary": "{{ len .Alerts.Resolved }} alerts are resolved",
+				"entity_id": "{{ .CommonLabels.missing }}"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:   model.LabelSet{"alertname": "alert1"},
+						StartsAt: time.Now().Add(-2 * time.Hour),
+						EndsAt:   time.Now().Add(-time.Hour),
+					},
+				},
+			},
+			expMsg: &zendutyMessage{
+				AlertType: "resolved",
+				Message:   "alert1 is resolved",
+				Summary:   "1 alerts are resolved",
+				EntityID:  defaultEntityID,
+				Payload: zendutyPayload{
+					Status:            "resolved",
+					GroupLabels:       template.KV{"alertname": ""},
+					CommonLabels:      template.KV{"alertname": "alert1"},
+					CommonAnnotations: template.KV{},
+					NumResolved:       1,
+				},
+				URLs: []zendutyURL{{LinkURL: "http://localhost/alerting/list", LinkText: "Open in Grafana"}},
+			},
+		}, {
+			name: "Resolved alert with resolved message",
+			settings: `{
+				"url": "https://www.zenduty.com/api/events/abcd/",
+				"message": "{{ .CommonLabels.alertname }} is firing",
+				"resolved_message": "{{ .CommonLabels.alertname }} is resolved",
+				"summary": "{{ len .Alerts.Resolved }} alerts are resolved"
+			}`,
+			alerts: []*types.Alert{
+				{
+					Alert: model.Alert{
+						Labels:   model.LabelSet{"alertname": "alert1"},
+						StartsAt: time.Now().Add(-2 * time.Hour),
+						EndsAt:   time.Now().Add(-time.Hour),
+					},
+				},
+			},
+			expMsg: &zendutyMessage{
+				AlertType: "resolved",
+				Message:   "alert1 is resolved",
+				Summary:   "1 alerts are resolved",
+				EntityID:  defaultEntityID,
+				Payload: zendutyPayload{
+					Status:            "resolved",
+					GroupLabels:       template.KV{"alertname": ""},
+					CommonLabels:      template.KV{"alertname": "alert1"},
+					CommonAnnotations: template.KV{},
+					NumResolved:       1,
+				},
+				URLs: []zendutyURL{{LinkURL: "http://localhost/alerting/list", LinkText: "Open in Grafana"}},
+			},
+		}, {
+			name:         "Error in initing, missing URL",
+			settings:     `{}`,
+			expInitError: `could not find url property in settings`,
+		}, {
+			name:         "Error in initing, invalid URL",
+			settings:     `{"url": "www.zenduty.com/api/events/abcd/"}`,
+			expInitError: `url must be a valid http or https URL of a Zenduty integration`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			webhookSender := mockNotificationService()
+			fc := channels.FactoryConfig{
+				Config: &channels.NotificationChannelConfig{
+					Name:     "zenduty_testing",
+					Type:     "zenduty",
+					Settings: json.RawMessage(c.settings),
+				},
+				NotificationService: webhookSender,
+				DecryptFunc: func(ctx context.Context, sjd map[string][]byte, key string, fallback string) string {
+					return fallback
+				},
+				Template: tmpl,
+				Logger:   &channels.FakeLogger{},
+			}
+
+			pn, err := newZendutyNotifier(fc)
+			if c.expInitError != "" {
+				require.Error(t, err)
+				require.Equal(t, c.expInitError, err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := notify.WithGroupKey(context.Background(), "alertname")
+			ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": ""})
+			ok, err := pn.Notify(ctx, c.alerts...)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			require.Equal(t, "https://www.zenduty.com/api/events/abcd/", webhookSender.Webhook.URL)
+			require.Equal(t, "POST", webhookSender.Webhook.HTTPMethod)
+
+			expBody, err := json.Marshal(c.expMsg)
+			require.NoError(t, err)
+			require.JSONEq(t, string(expBody), webhookSender.Webhook.Body)
+		})
+	}
+}
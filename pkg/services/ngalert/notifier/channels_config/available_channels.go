@@ -1,15 +1,11 @@
 package channels_config
 
 import (
-	"os"
-
 	"github.com/grafana/alerting/alerting/notifier/channels"
 )
 
 // GetAvailableNotifiers returns the metadata of all the notification channels that can be configured.
 func GetAvailableNotifiers() []*NotifierPlugin {
-	hostname, _ := os.Hostname()
-
 	pushoverSoundOptions := []SelectOption{
 		{
 			Value: "default",
@@ -151,6 +147,12 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					Placeholder:  channels.DefaultMessageEmbed,
 					PropertyName: "message",
 				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
 			},
 		},
 		{
@@ -190,6 +192,377 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					PropertyName: "details",
 					Placeholder:  channels.DefaultMessageEmbed,
 				},
+				{
+					Label:        "Compression",
+					Element:      ElementTypeSelect,
+					Description:  "Compress the requests to the Kafka REST Proxy with gzip. Records are compressed as configured by the compression.type of the proxy.",
+					PropertyName: "compression",
+					SelectOptions: []SelectOption{
+						{
+							Value: "gzip",
+							Label: "gzip",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type:        "pubsub",
+			Name:        "Google Cloud Pub/Sub",
+			Description: "Publishes notifications to a Google Cloud Pub/Sub topic",
+			Heading:     "Pub/Sub settings",
+			Options: []NotifierOption{
+				{
+					Label:        "Project ID",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "my-project",
+					PropertyName: "projectId",
+					Required:     true,
+				},
+				{
+					Label:        "Topic",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "alerts",
+					PropertyName: "topic",
+					Required:     true,
+				},
+				{
+					Label:        "Credentials",
+					Element:      ElementTypeTextArea,
+					Description:  "Service account key in JSON format. Leave blank to use Application Default Credentials.",
+					PropertyName: "credentials",
+					Secure:       true,
+				},
+				{
+					Label:        "Title",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated title of the message",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "title",
+				},
+				{
+					Label:        "Message",
+					Element:      ElementTypeTextArea,
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+			},
+		},
+		{
+			Type:        "cloudlogging",
+			Name:        "Google Cloud Logging",
+			Description: "Writes notifications to a Google Cloud Logging log",
+			Heading:     "Cloud Logging settings",
+			Options: []NotifierOption{
+				{
+					Label:        "Project ID",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "my-project",
+					PropertyName: "projectId",
+					Required:     true,
+				},
+				{
+					Label:        "Log name",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "grafana-alerts",
+					PropertyName: "logName",
+					Required:     true,
+				},
+				{
+					Label:        "Credentials",
+					Element:      ElementTypeTextArea,
+					Description:  "Service account key in JSON format. Leave blank to use Application Default Credentials.",
+					PropertyName: "credentials",
+					Secure:       true,
+				},
+				{
+					Label:        "Title",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated title of the log entry",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "title",
+				},
+				{
+					Label:        "Message",
+					Element:      ElementTypeTextArea,
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+			},
+		},
+		{
+			Type:        "amqp",
+			Name:        "AMQP",
+			Description: "Publishes notifications to an AMQP broker such as RabbitMQ",
+			Heading:     "AMQP settings",
+			Options: []NotifierOption{
+				{
+					Label:        "URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "AMQP URI of the broker. Use amqps for TLS.",
+					Placeholder:  "amqps://rabbitmq.example.com:5671/vhost",
+					PropertyName: "url",
+					Required:     true,
+					Secure:       true,
+				},
+				{
+					Label:        "Username",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Overrides the username in the URL",
+					PropertyName: "user",
+				},
+				{
+					Label:        "Password",
+					Element:      ElementTypeInput,
+					InputType:    InputTypePassword,
+					Description:  "Overrides the password in the URL",
+					PropertyName: "password",
+					Secure:       true,
+				},
+				{
+					Label:        "Disable TLS certificate verification",
+					Element:      ElementTypeCheckbox,
+					Description:  "Do not verify the TLS certificate of the broker",
+					PropertyName: "insecureSkipVerify",
+				},
+				{
+					Label:        "TLS CA Certificate",
+					Element:      ElementTypeTextArea,
+					Description:  "CA certificate to verify the certificate of the broker, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_ca_cert",
+				},
+				{
+					Label:        "TLS Client Certificate",
+					Element:      ElementTypeTextArea,
+					Description:  "Client certificate, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_client_cert",
+				},
+				{
+					Label:        "TLS Client Key",
+					Element:      ElementTypeTextArea,
+					Description:  "Client key, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_client_key",
+					Secure:       true,
+				},
+				{
+					Label:   "TLS Format",
+					Element: ElementTypeSelect,
+					SelectOptions: []SelectOption{
+						{
+							Value: "auto",
+							Label: "Auto-detect",
+						},
+						{
+							Value: "file",
+							Label: "File path",
+						},
+						{
+							Value: "base64",
+							Label: "Base64",
+						},
+					},
+					Description:  "Whether the TLS certificates and key are file paths or base64-encoded PEM. Defaults to auto-detect.",
+					PropertyName: "tls_format",
+				},
+				{
+					Label:        "Exchange",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Exchange to publish to. Leave blank to use the default exchange.",
+					Placeholder:  "alerts",
+					PropertyName: "exchange",
+				},
+				{
+					Label:        "Routing key",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated routing key of the message",
+					Placeholder:  "{{ .CommonLabels.alertname }}",
+					PropertyName: "routingKey",
+				},
+				{
+					Label:        "Title",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated title of the message",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "title",
+				},
+				{
+					Label:        "Message",
+					Element:      ElementTypeTextArea,
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+			},
+		},
+		{
+			Type:        "mqtt",
+			Name:        "MQTT",
+			Description: "Publishes notifications to a topic of an MQTT broker",
+			Heading:     "MQTT settings",
+			Options: []NotifierOption{
+				{
+					Label:        "Broker URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "URL of the broker. Use ssl or mqtts for TLS.",
+					Placeholder:  "ssl://mqtt.example.com:8883",
+					PropertyName: "brokerUrl",
+					Required:     true,
+				},
+				{
+					Label:        "Topic",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Topic to publish to",
+					Placeholder:  "grafana/alerts",
+					PropertyName: "topic",
+					Required:     true,
+				},
+				{
+					Label:        "Client ID",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Client identifier of the connection. Leave blank to let the broker assign one.",
+					PropertyName: "clientId",
+				},
+				{
+					Label:        "Username",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "username",
+				},
+				{
+					Label:        "Password",
+					Element:      ElementTypeInput,
+					InputType:    InputTypePassword,
+					PropertyName: "password",
+					Secure:       true,
+				},
+				{
+					Label:   "QoS",
+					Element: ElementTypeSelect,
+					SelectOptions: []SelectOption{
+						{
+							Value: "0",
+							Label: "At most once (0)",
+						},
+						{
+							Value: "1",
+							Label: "At least once (1)",
+						},
+						{
+							Value: "2",
+							Label: "Exactly once (2)",
+						},
+					},
+					Description:  "Quality of service of the messages. Defaults to at most once.",
+					PropertyName: "qos",
+				},
+				{
+					Label:        "Retain",
+					Element:      ElementTypeCheckbox,
+					Description:  "Retain the last message of the topic for new subscribers",
+					PropertyName: "retain",
+				},
+				{
+					Label:        "Clear retained message on resolve",
+					Element:      ElementTypeCheckbox,
+					Description:  "Publish an empty retained message after resolved notifications, so new subscribers do not receive resolved alerts",
+					PropertyName: "clear_retained_on_resolve",
+				},
+				{
+					Label:        "Disable TLS certificate verification",
+					Element:      ElementTypeCheckbox,
+					Description:  "Do not verify the TLS certificate of the broker",
+					PropertyName: "insecureSkipVerify",
+				},
+				{
+					Label:        "TLS CA Certificate",
+					Element:      ElementTypeTextArea,
+					Description:  "CA certificate to verify the certificate of the broker, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_ca_cert",
+				},
+				{
+					Label:        "TLS Client Certificate",
+					Element:      ElementTypeTextArea,
+					Description:  "Client certificate, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_client_cert",
+				},
+				{
+					Label:        "TLS Client Key",
+					Element:      ElementTypeTextArea,
+					Description:  "Client key, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_client_key",
+					Secure:       true,
+				},
+				{
+					Label:   "TLS Format",
+					Element: ElementTypeSelect,
+					SelectOptions: []SelectOption{
+						{
+							Value: "auto",
+							Label: "Auto-detect",
+						},
+						{
+							Value: "file",
+							Label: "File path",
+						},
+						{
+							Value: "base64",
+							Label: "Base64",
+						},
+					},
+					Description:  "Whether the TLS certificates and key are file paths or base64-encoded PEM. Defaults to auto-detect.",
+					PropertyName: "tls_format",
+				},
+				{
+					Label:        "Title",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated title of the message",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "title",
+				},
+				{
+					Label:        "Message",
+					Element:      ElementTypeTextArea,
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
 			},
 		},
 		{
@@ -217,6 +590,12 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					Element:      ElementTypeTextArea,
 					PropertyName: "message",
 				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
 				{ // New in 9.0.
 					Label:        "Subject",
 					Element:      ElementTypeInput,
@@ -252,7 +631,7 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 				},
 				{ // New in 8.0.
 					Label:        "Class",
-					Description:  "The class/type of the event, for example 'ping failure' or 'cpu load'",
+					Description:  "The class/type of the event, for example 'ping failure' or 'cpu load'. You can use templates",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
 					PropertyName: "class",
@@ -267,7 +646,7 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 				},
 				{ // New in 8.0.
 					Label:        "Group",
-					Description:  "Logical grouping of components of a service, for example 'app-stack'",
+					Description:  "Logical grouping of components of a service, for example 'app-stack'. You can use templates",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
 					PropertyName: "group",
@@ -282,10 +661,9 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 				},
 				{ // New in 9.4.
 					Label:        "Source",
-					Description:  "The unique location of the affected system, preferably a hostname or FQDN. You can use templates",
+					Description:  "The unique location of the affected system, preferably a hostname or FQDN. It is templated for each alert, for example {{ .CommonLabels.instance }}. Defaults to the host of the Grafana URL",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Placeholder:  hostname,
 					PropertyName: "source",
 				},
 				{ // New in 9.4.
@@ -304,19 +682,51 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					Placeholder:  "{{ .ExternalURL }}",
 					PropertyName: "client_url",
 				},
-			},
-		},
-		{
-			Type:        "victorops",
-			Name:        "VictorOps",
-			Description: "Sends notifications to VictorOps",
-			Heading:     "VictorOps settings",
-			Options: []NotifierOption{
 				{
-					Label:        "URL",
+					Label:        "Event action label",
+					Description:  "Name of a label whose value is the event action of firing alerts: trigger, acknowledge or resolve",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Placeholder:  "VictorOps url",
+					Placeholder:  "pagerduty_action",
+					PropertyName: "event_action_label",
+				},
+				{
+					Label:        "Event type",
+					Description:  "Send alerts as alert events, which trigger incidents, or as change events, such as deploys",
+					Element:      ElementTypeSelect,
+					PropertyName: "event_type",
+					SelectOptions: []SelectOption{
+						{
+							Value: "alert",
+							Label: "Alert",
+						},
+						{
+							Value: "change",
+							Label: "Change",
+						},
+					},
+				},
+				{
+					Label:        "Event type label",
+					Description:  "Name of a label whose value is the event type of the alerts: alert or change",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "pagerduty_event",
+					PropertyName: "event_type_label",
+				},
+			},
+		},
+		{
+			Type:        "victorops",
+			Name:        "VictorOps",
+			Description: "Sends notifications to VictorOps",
+			Heading:     "VictorOps settings",
+			Options: []NotifierOption{
+				{
+					Label:        "URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "VictorOps url",
 					PropertyName: "url",
 					Required:     true,
 				},
@@ -453,6 +863,20 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					Required:     true,
 					DependsOn:    "url",
 				},
+				{
+					Label:        "Recipient Annotation",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Name of the annotation, or label, of alerts that overrides the recipient, for example slack_channel. Alerts are sent to the recipient if they do not have a valid channel.",
+					PropertyName: "recipient_annotation",
+				},
+				{
+					Label:        "Resolved channel",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Channel, private group, or IM channel to send notifications to when all alerts are resolved, instead of the recipient. Can be a channel name or an ID.",
+					PropertyName: "resolved_channel",
+				},
 				// Logically, this field should be required when not using a webhook, since the Slack API needs a token.
 				// However, since the UI doesn't allow to say that a field is required or not depending on another field,
 				// we've gone with the compromise of making this field optional and instead return a validation error
@@ -549,6 +973,13 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					PropertyName: "title",
 					Placeholder:  `{{ template "slack.default.title" . }}`,
 				},
+				{
+					Label:        "Title Link",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated link of the title of the slack message, for example {{ (index .Alerts 0).DashboardURL }}. Defaults to the alert rules page.",
+					PropertyName: "title_link",
+				},
 				{ // New in 8.0.
 					Label:        "Text Body",
 					Element:      ElementTypeTextArea,
@@ -556,6 +987,39 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					PropertyName: "text",
 					Placeholder:  `{{ template "slack.default.text" . }}`,
 				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+				{
+					Label:        "Unfurl links",
+					Element:      ElementTypeCheckbox,
+					Description:  "Show previews of links in the message. Uses the default of Slack if not set.",
+					PropertyName: "unfurl_links",
+				},
+				{
+					Label:        "Unfurl media",
+					Element:      ElementTypeCheckbox,
+					Description:  "Show previews of media in the message. Uses the default of Slack if not set.",
+					PropertyName: "unfurl_media",
+				},
+				{
+					Label:        "Annotation fields",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Comma-separated names of annotations shown as fields of the message, in order, instead of in the text. At most 10 annotations.",
+					PropertyName: "annotation_fields",
+				},
+				{
+					Label:        "Silence duration",
+					Description:  "Duration pre-filled in the form of silence links, such as 1h",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "2h",
+					PropertyName: "silence_duration",
+				},
 			},
 		},
 		{
@@ -651,6 +1115,33 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					Placeholder:  channels.DefaultMessageEmbed,
 					PropertyName: "message",
 				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+				{
+					Label:        "Facts",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Label names (comma separated) to show as facts, in display order. Leave blank to show all labels.",
+					PropertyName: "facts",
+				},
+				{
+					Label:        "Mentions",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Mention one or more users (comma separated) by Azure AD object ID or user principal name.",
+					PropertyName: "mentions",
+				},
+				{
+					Label:        "Mention Severity",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Only mention users when a firing alert has a severity label with this value. Leave blank to always mention.",
+					PropertyName: "mentionSeverity",
+				},
 			},
 		},
 		{
@@ -672,16 +1163,29 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					Label:        "Chat ID",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Description:  "Integer Telegram Chat Identifier",
+					Description:  "Integer Telegram Chat Identifier or @channelname. You can use template variables to send alerts to different chats.",
 					PropertyName: "chatid",
 					Required:     true,
 				},
+				{
+					Label:        "Message Thread ID",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Integer Telegram Message Thread Identifier. Use it to send messages to a topic in a supergroup.",
+					PropertyName: "message_thread_id",
+				},
 				{ // New in 8.0.
 					Label:        "Message",
 					Element:      ElementTypeTextArea,
 					Placeholder:  channels.DefaultMessageEmbed,
 					PropertyName: "message",
 				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
 				{
 					Label:   "Parse Mode",
 					Element: ElementTypeSelect,
@@ -712,6 +1216,35 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					Element:      ElementTypeCheckbox,
 					PropertyName: "disable_notification",
 				},
+				{
+					Label:        "API URL",
+					Description:  "Base URL of a self-hosted Telegram Bot API server. Defaults to https://api.telegram.org",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "https://api.telegram.org",
+					PropertyName: "api_url",
+				},
+				{
+					Label:        "Acknowledge Button",
+					Description:  "Adds an inline button to messages with firing alerts, for bots that handle acknowledgements.",
+					Element:      ElementTypeCheckbox,
+					PropertyName: "ack_button",
+				},
+				{
+					Label:        "Acknowledge Button Text",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "Acknowledge",
+					PropertyName: "ack_button_text",
+				},
+				{
+					Label:        "Acknowledge Callback Prefix",
+					Description:  "Prefix of the callback data of the button, followed by a colon and the hash of the group key.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "ack",
+					PropertyName: "ack_callback_prefix",
+				},
 			},
 		},
 		{
@@ -727,6 +1260,13 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					PropertyName: "url",
 					Required:     true,
 				},
+				{
+					Label:        "Resolved URL",
+					Description:  "URL to send notifications to when all alerts are resolved, instead of the URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "resolved_url",
+				},
 				{
 					Label:   "HTTP Method",
 					Element: ElementTypeSelect,
@@ -793,6 +1333,157 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					PropertyName: "message",
 					Placeholder:  channels.DefaultMessageEmbed,
 				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+				{
+					Label:        "Success JMESPath",
+					Description:  "JMESPath expression that must evaluate to true against the JSON response body for the notification to be successful, for example ok.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "success_jmespath",
+				},
+				{
+					Label:        "Success Regex",
+					Description:  "Regular expression that must match the response body for the notification to be successful.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "success_regex",
+				},
+				{
+					Label:        "Idempotency key",
+					Description:  "Send the Idempotency-Key header, which is the same for retries of a notification and changes when its firing alerts change.",
+					Element:      ElementTypeCheckbox,
+					PropertyName: "idempotency_key",
+				},
+				{
+					Label:        "Flatten labels",
+					Description:  "Send the message form-encoded, with the labels and annotations of each alert as fields such as alert_0_label_alertname.",
+					Element:      ElementTypeCheckbox,
+					PropertyName: "flatten_labels",
+				},
+				{
+					Label:        "Label prefix",
+					Description:  "Prefix of the label fields when labels are flattened.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "label_prefix",
+					Placeholder:  "label_",
+				},
+				{
+					Label:        "Annotation prefix",
+					Description:  "Prefix of the annotation fields when labels are flattened.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "annotation_prefix",
+					Placeholder:  "annotation_",
+				},
+				{
+					Label:        "Payload template",
+					Description:  "Template of the request body, sent instead of the default JSON message. You can use template variables.",
+					Element:      ElementTypeTextArea,
+					PropertyName: "payload_template",
+				},
+				{
+					Label:        "Payload template file",
+					Description:  "Path of a file in the webhook templates directory of the Grafana server with the template of the request body. Only one of payload template and payload template file can be set.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "template_file",
+				},
+				{
+					Label:        "HTML template",
+					Description:  "Template of an HTML request body, sent instead of the default JSON message with the text/html content type. Label and annotation values are escaped. Cannot be used with a payload template.",
+					Element:      ElementTypeTextArea,
+					PropertyName: "html_template",
+				},
+				{
+					Label:        "Left template delimiter",
+					Description:  "Delimiter that starts actions in the title, message and payload templates, such as [[ for payloads with literal {{ }}. Defaults to {{.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "left_delim",
+					Placeholder:  "{{",
+				},
+				{
+					Label:        "Right template delimiter",
+					Description:  "Delimiter that ends actions in the title, message and payload templates, such as ]]. Defaults to }}.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "right_delim",
+					Placeholder:  "}}",
+				},
+				{
+					Label:        "Encoding",
+					Description:  "Encoding of the default message. The YAML message has the same fields as the JSON message and is sent with the text/yaml content type.",
+					Element:      ElementTypeSelect,
+					PropertyName: "encoding",
+					SelectOptions: []SelectOption{
+						{
+							Value: "json",
+							Label: "JSON",
+						},
+						{
+							Value: "yaml",
+							Label: "YAML",
+						},
+					},
+				},
+				{
+					Label:        "DNS Server",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "IP address and optional port of the DNS server used to resolve the host of the URL, for example 10.0.0.1:53. Defaults to the system resolver.",
+					PropertyName: "dns_server",
+				},
+				{
+					Label:        "Redirect Host Allowlist",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Comma-separated list of hosts that redirects are followed to, for example webhook.example.com,*.example.com. Defaults to any host, or to the host of the URL if the DNS server, Host header or SigV4 is set.",
+					PropertyName: "max_redirects_follow_host_allowlist",
+				},
+				{
+					Label:        "Host Header",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Overrides the Host header of requests, for webhooks behind a load balancer that routes by virtual host.",
+					PropertyName: "host_header",
+				},
+				{
+					Label:        "SigV4 Region",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "AWS region to sign requests with AWS Signature Version 4, such as for API Gateway endpoints protected with IAM.",
+					Placeholder:  "us-east-1",
+					PropertyName: "sigv4_region",
+				},
+				{
+					Label:        "SigV4 Service",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "AWS service to sign requests for. Defaults to execute-api.",
+					Placeholder:  "execute-api",
+					PropertyName: "sigv4_service",
+				},
+				{
+					Label:        "SigV4 Access Key",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "AWS access key ID to sign requests with.",
+					PropertyName: "sigv4_access_key",
+				},
+				{
+					Label:        "SigV4 Secret Key",
+					Element:      ElementTypeInput,
+					InputType:    InputTypePassword,
+					Description:  "AWS secret access key to sign requests with.",
+					PropertyName: "sigv4_secret_key",
+					Secure:       true,
+				},
 			},
 		},
 		{
@@ -910,115 +1601,334 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					PropertyName: "basicAuthPassword",
 					Secure:       true,
 				},
-			},
-		},
-		{
-			Type:        "discord",
-			Name:        "Discord",
-			Heading:     "Discord settings",
-			Description: "Sends notifications to Discord",
-			Options: []NotifierOption{
 				{
-					Label:        "Title",
-					Description:  "Templated title of the message",
+					Label:        "Connect Timeout",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Placeholder:  channels.DefaultMessageTitleEmbed,
-					PropertyName: "title",
+					Description:  "Timeout for connecting to Alertmanager, for example 10s. Defaults to 30s.",
+					PropertyName: "connect_timeout",
 				},
 				{
-					Label:        "Message Content",
-					Description:  "Mention a group using @ or a user using <@ID> when notifying in a channel",
+					Label:        "Request Timeout",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Placeholder:  channels.DefaultMessageEmbed,
-					PropertyName: "message",
+					Description:  "Timeout for the entire request to Alertmanager, for example 1m. Defaults to 30s.",
+					PropertyName: "request_timeout",
 				},
 				{
-					Label:        "Webhook URL",
+					Label:        "Response Header Timeout",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Placeholder:  "Discord webhook URL",
-					PropertyName: "url",
-					Required:     true,
+					Description:  "Timeout for receiving the response headers from Alertmanager after sending the request, for example 5s. Defaults to 10s.",
+					PropertyName: "response_header_timeout",
 				},
 				{
-					Label:        "Avatar URL",
+					Label:        "Idle Connection Timeout",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					PropertyName: "avatar_url",
-				},
-				{
-					Label:        "Use Discord's Webhook Username",
-					Description:  "Use the username configured in Discord's webhook settings. Otherwise, the username will be 'Grafana'",
-					Element:      ElementTypeCheckbox,
-					PropertyName: "use_discord_username",
+					Description:  "Time after which idle connections to Alertmanager are closed, for example 30s. Defaults to 90s.",
+					PropertyName: "idle_conn_timeout",
 				},
-			},
-		},
-		{
-			Type:        "googlechat",
-			Name:        "Google Hangouts Chat",
-			Description: "Sends notifications to Google Hangouts Chat via webhooks based on the official JSON message format",
-			Heading:     "Google Hangouts Chat settings",
-			Options: []NotifierOption{
 				{
-					Label:        "URL",
+					Label:        "Expected Status Codes",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Placeholder:  "Google Hangouts Chat incoming webhook url",
-					PropertyName: "url",
-					Required:     true,
+					Description:  "Comma-separated list of status codes that indicate success, for example 200,204. Defaults to any 2xx status code.",
+					PropertyName: "expected_status_codes",
 				},
 				{
-					Label:        "Title",
-					Description:  "Templated title of the message",
+					Label:        "DNS Server",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Placeholder:  channels.DefaultMessageTitleEmbed,
-					PropertyName: "title",
+					Description:  "IP address and optional port of the DNS server used to resolve the Alertmanager host, for example 10.0.0.1:53. Defaults to the system resolver.",
+					PropertyName: "dns_server",
 				},
 				{
-					Label:        "Message",
-					Element:      ElementTypeTextArea,
-					Placeholder:  channels.DefaultMessageEmbed,
-					PropertyName: "message",
+					Label:        "Max Redirects",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Maximum number of redirects to follow, keeping the method and body of the request. Defaults to 0, which refuses redirects.",
+					Placeholder:  "0",
+					PropertyName: "max_redirects",
 				},
-			},
-		},
-		{
-			Type:        "LINE",
-			Name:        "LINE",
-			Description: "Send notifications to LINE notify",
-			Heading:     "LINE notify settings",
-			Options: []NotifierOption{
 				{
-					Label:        "Token",
+					Label:        "Redirect Host Allowlist",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Placeholder:  "LINE notify token key",
-					PropertyName: "token",
-					Required:     true,
-					Secure:       true,
+					Description:  "Comma-separated list of hosts that redirects are followed to, for example alertmanager.example.com,*.example.com. Defaults to the host of the URL.",
+					PropertyName: "max_redirects_follow_host_allowlist",
 				},
-				{ // New in 9.3
-					Label:        "Title",
+				{
+					Label:        "Host Header",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Description:  "Templated title of the message",
-					PropertyName: "title",
-					Placeholder:  channels.DefaultMessageTitleEmbed,
+					Description:  "Overrides the Host header of requests, for Alertmanagers behind a load balancer that routes by virtual host.",
+					PropertyName: "host_header",
 				},
-				{ // New in 9.3
-					Label:        "Description",
+				{
+					Label:        "Max Body Log Length",
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
-					Description:  "Templated description of the message",
-					PropertyName: "description",
-					Placeholder:  channels.DefaultMessageEmbed,
+					Description:  "Maximum number of bytes of the response body that are logged when a request fails. Longer bodies are truncated.",
+					Placeholder:  "256",
+					PropertyName: "max_body_log_len",
 				},
-			},
-		},
+				{
+					Label:        "TLS CA Certificate",
+					Element:      ElementTypeTextArea,
+					Description:  "CA certificate to verify the certificate of Alertmanager, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_ca_cert",
+				},
+				{
+					Label:        "TLS Client Certificate",
+					Element:      ElementTypeTextArea,
+					Description:  "Client certificate, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_client_cert",
+				},
+				{
+					Label:        "TLS Client Key",
+					Element:      ElementTypeTextArea,
+					Description:  "Client key, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_client_key",
+					Secure:       true,
+				},
+				{
+					Label:   "TLS Format",
+					Element: ElementTypeSelect,
+					SelectOptions: []SelectOption{
+						{
+							Value: "auto",
+							Label: "Auto-detect",
+						},
+						{
+							Value: "file",
+							Label: "File path",
+						},
+						{
+							Value: "base64",
+							Label: "Base64",
+						},
+					},
+					Description:  "Whether the TLS certificates and key are file paths or base64-encoded PEM. Defaults to auto-detect.",
+					PropertyName: "tls_format",
+				},
+				{
+					Label:        "SigV4 Region",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "AWS region to sign requests with AWS Signature Version 4, such as for API Gateway endpoints protected with IAM.",
+					Placeholder:  "us-east-1",
+					PropertyName: "sigv4_region",
+				},
+				{
+					Label:        "SigV4 Service",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "AWS service to sign requests for. Defaults to execute-api.",
+					Placeholder:  "execute-api",
+					PropertyName: "sigv4_service",
+				},
+				{
+					Label:        "SigV4 Access Key",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "AWS access key ID to sign requests with.",
+					PropertyName: "sigv4_access_key",
+				},
+				{
+					Label:        "SigV4 Secret Key",
+					Element:      ElementTypeInput,
+					InputType:    InputTypePassword,
+					Description:  "AWS secret access key to sign requests with.",
+					PropertyName: "sigv4_secret_key",
+					Secure:       true,
+				},
+			},
+		},
+		{
+			Type:        "discord",
+			Name:        "Discord",
+			Heading:     "Discord settings",
+			Description: "Sends notifications to Discord",
+			Options: []NotifierOption{
+				{
+					Label:        "Title",
+					Description:  "Templated title of the message",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "title",
+				},
+				{
+					Label:        "Message Content",
+					Description:  "Mention a group using @ or a user using <@ID> when notifying in a channel",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+				{
+					Label:        "Webhook URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "Discord webhook URL",
+					Description:  "Can reference the environment variables of the Grafana server that are allowed by notifier_environment_variables, such as ${env:DISCORD_WEBHOOK_URL}.",
+					PropertyName: "url",
+					Required:     true,
+				},
+				{
+					Label:        "Resolved webhook URL",
+					Description:  "Discord webhook URL to send notifications to when all alerts are resolved, instead of the webhook URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "resolved_url",
+				},
+				{
+					Label:        "Avatar URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "avatar_url",
+				},
+				{
+					Label:        "Avatar from image",
+					Element:      ElementTypeCheckbox,
+					Description:  "Use the image of the alerts as the avatar when it has a public URL. Falls back to the Avatar URL.",
+					PropertyName: "avatar_from_image",
+				},
+				{
+					Label:        "Use Discord's Webhook Username",
+					Description:  "Use the username configured in Discord's webhook settings. Otherwise, the username will be 'Grafana'",
+					Element:      ElementTypeCheckbox,
+					PropertyName: "use_discord_username",
+				},
+				{
+					Label:        "Buttons",
+					Description:  "Comma-separated list of link buttons to add to the message, up to 5 of: dashboard, panel, silence, alert_rules",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "dashboard,silence",
+					PropertyName: "buttons",
+				},
+				{
+					Label:        "Author name",
+					Description:  "Templated name of the author of the embed, such as the team that owns the alerts",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "author_name",
+				},
+				{
+					Label:        "Author URL",
+					Description:  "Templated URL of the author of the embed",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "author_url",
+				},
+				{
+					Label:        "Author icon URL",
+					Description:  "Templated URL of an image to use as the icon of the author of the embed",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "author_icon_url",
+				},
+				{
+					Label:        "Allowed Role Mentions",
+					Description:  "Comma-separated IDs of the roles that can be pinged by mentions in the message. Mentions of @everyone and @here never ping.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "allowed_mention_roles",
+				},
+				{
+					Label:        "Allowed User Mentions",
+					Description:  "Comma-separated IDs of the users that can be pinged by mentions in the message. Defaults to all users.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "allowed_mention_users",
+				},
+				{
+					Label:        "Silence duration",
+					Description:  "Duration pre-filled in the form of silence links, such as 1h",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "2h",
+					PropertyName: "silence_duration",
+				},
+			},
+		},
+		{
+			Type:        "googlechat",
+			Name:        "Google Hangouts Chat",
+			Description: "Sends notifications to Google Hangouts Chat via webhooks based on the official JSON message format",
+			Heading:     "Google Hangouts Chat settings",
+			Options: []NotifierOption{
+				{
+					Label:        "URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "Google Hangouts Chat incoming webhook url",
+					PropertyName: "url",
+					Required:     true,
+				},
+				{
+					Label:        "Title",
+					Description:  "Templated title of the message",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "title",
+				},
+				{
+					Label:        "Message",
+					Element:      ElementTypeTextArea,
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+			},
+		},
+		{
+			Type:        "LINE",
+			Name:        "LINE",
+			Description: "Send notifications to LINE notify",
+			Heading:     "LINE notify settings",
+			Options: []NotifierOption{
+				{
+					Label:        "Token",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Placeholder:  "LINE notify token key",
+					PropertyName: "token",
+					Required:     true,
+					Secure:       true,
+				},
+				{ // New in 9.3
+					Label:        "Title",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated title of the message",
+					PropertyName: "title",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+				},
+				{ // New in 9.3
+					Label:        "Description",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated description of the message",
+					PropertyName: "description",
+					Placeholder:  channels.DefaultMessageEmbed,
+				},
+			},
+		},
 		{
 			Type:        "threema",
 			Name:        "Threema Gateway",
@@ -1094,8 +2004,24 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					Element:      ElementTypeInput,
 					InputType:    InputTypeText,
 					Placeholder:  "https://api.opsgenie.com/v2/alerts",
+					Description:  "Overrides the API URL of the region",
 					PropertyName: "apiUrl",
-					Required:     true,
+				},
+				{
+					Label:   "Region",
+					Element: ElementTypeSelect,
+					SelectOptions: []SelectOption{
+						{
+							Value: "us",
+							Label: "US",
+						},
+						{
+							Value: "eu",
+							Label: "EU",
+						},
+					},
+					Description:  "Opsgenie region of the account",
+					PropertyName: "region",
 				},
 				{
 					Label:        "Message",
@@ -1142,6 +2068,30 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 					Description:  "Send the common annotations to Opsgenie as either Extra Properties, Tags or both",
 					PropertyName: "sendTagsAs",
 				},
+				{
+					Label:        "Close note",
+					Description:  "Templated note added to the alert when it is closed.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "close_note",
+					Placeholder:  "Resolved by Grafana",
+				},
+				{
+					Label:        "Close user",
+					Description:  "Templated user that closed the alert in the Opsgenie alert timeline.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "close_user",
+					Placeholder:  "Grafana",
+				},
+				{
+					Label:        "Close source",
+					Description:  "Templated source that closed the alert in the Opsgenie alert timeline.",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "close_source",
+					Placeholder:  "Grafana",
+				},
 			},
 		},
 		{
@@ -1188,5 +2138,366 @@ func GetAvailableNotifiers() []*NotifierPlugin {
 				},
 			},
 		},
+		{
+			Type:        "heartbeat",
+			Name:        "Heartbeat",
+			Description: "Pings a URL on each notification to keep a dead man's switch alive",
+			Heading:     "Heartbeat settings",
+			Info:        "Use with an always firing watchdog alert. The alerts are not included in the ping.",
+			Options: []NotifierOption{
+				{
+					Label:        "URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					PropertyName: "url",
+					Secure:       true,
+					Required:     true,
+				},
+				{
+					Label:   "HTTP Method",
+					Element: ElementTypeSelect,
+					SelectOptions: []SelectOption{
+						{
+							Value: "POST",
+							Label: "POST",
+						},
+						{
+							Value: "PUT",
+							Label: "PUT",
+						},
+						{
+							Value: "GET",
+							Label: "GET",
+						},
+					},
+					PropertyName: "httpMethod",
+				},
+				{
+					Label:        "Body",
+					Element:      ElementTypeTextArea,
+					Description:  "Body of the ping",
+					PropertyName: "body",
+				},
+			},
+		},
+		{
+			Type:        "squadcast",
+			Name:        "Squadcast",
+			Description: "Sends notifications to Squadcast",
+			Heading:     "Squadcast settings",
+			Options: []NotifierOption{
+				{
+					Label:        "Webhook URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Incident webhook URL of the Squadcast service",
+					Placeholder:  "https://api.squadcast.com/v2/incidents/api/<api key>",
+					PropertyName: "url",
+					Secure:       true,
+					Required:     true,
+				},
+				{
+					Label:        "Message",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated message of the Squadcast incident",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+				{
+					Label:        "Description",
+					Element:      ElementTypeTextArea,
+					Description:  "Templated description of the Squadcast incident",
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "description",
+				},
+				{
+					Label:        "Tags",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Comma-separated list of labels to add as tags to the Squadcast incident, for example severity,team",
+					PropertyName: "tags",
+				},
+			},
+		},
+		{
+			Type:        "rocketchat",
+			Name:        "Rocket.Chat",
+			Description: "Sends notifications to a Rocket.Chat incoming webhook",
+			Heading:     "Rocket.Chat settings",
+			Options: []NotifierOption{
+				{
+					Label:        "Webhook URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "URL of the Rocket.Chat incoming webhook",
+					Placeholder:  "https://chat.example.com/hooks/<token>",
+					PropertyName: "url",
+					Secure:       true,
+					Required:     true,
+				},
+				{
+					Label:        "Channel",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated channel or user to send the message to, overrides the channel of the webhook, for example #alerts or @user",
+					PropertyName: "channel",
+				},
+				{
+					Label:        "Alias",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated name to post as, overrides the name of the webhook",
+					PropertyName: "alias",
+				},
+				{
+					Label:        "Emoji",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Emoji to use as the avatar, for example :bell:",
+					PropertyName: "emoji",
+				},
+				{
+					Label:        "Avatar URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "URL of an image to use as the avatar",
+					PropertyName: "avatar_url",
+				},
+				{
+					Label:        "Title",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated title of the message",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "title",
+				},
+				{
+					Label:        "Text",
+					Element:      ElementTypeTextArea,
+					Description:  "Templated text of the message",
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "text",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+			},
+		},
+		{
+			Type:        "file",
+			Name:        "File",
+			Description: "Appends notifications as JSON lines to a local file",
+			Heading:     "File settings",
+			Options: []NotifierOption{
+				{
+					Label:        "Path",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Path of the file in the file notifier directory of the Grafana server, such as alerts.jsonl.",
+					Placeholder:  "alerts.jsonl",
+					PropertyName: "path",
+					Required:     true,
+				},
+				{
+					Label:        "Max Size",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Size in bytes after which the file is rotated",
+					Placeholder:  "104857600",
+					PropertyName: "max_size",
+				},
+				{
+					Label:        "Max Backups",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Number of rotated files that are kept, such as alerts.jsonl.1",
+					Placeholder:  "1",
+					PropertyName: "max_backups",
+				},
+				{
+					Label:        "Title",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated title of the message",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "title",
+				},
+				{
+					Label:        "Message",
+					Element:      ElementTypeTextArea,
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+			},
+		},
+		{
+			Type:        "syslog",
+			Name:        "Syslog",
+			Description: "Sends notifications as RFC 5424 syslog messages over TCP or TLS",
+			Heading:     "Syslog settings",
+			Options: []NotifierOption{
+				{
+					Label:        "Address",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Address of the syslog server",
+					Placeholder:  "syslog.example.com:6514",
+					PropertyName: "address",
+					Required:     true,
+				},
+				{
+					Label:   "Protocol",
+					Element: ElementTypeSelect,
+					SelectOptions: []SelectOption{
+						{
+							Value: "tcp",
+							Label: "TCP",
+						},
+						{
+							Value: "tls",
+							Label: "TLS",
+						},
+					},
+					Description:  "Protocol used to connect to the syslog server",
+					PropertyName: "protocol",
+				},
+				{
+					Label:        "Disable TLS certificate verification",
+					Element:      ElementTypeCheckbox,
+					Description:  "Do not verify the TLS certificate of the syslog server",
+					PropertyName: "insecureSkipVerify",
+				},
+				{
+					Label:        "TLS CA Certificate",
+					Element:      ElementTypeTextArea,
+					Description:  "CA certificate to verify the certificate of the syslog server, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_ca_cert",
+				},
+				{
+					Label:        "TLS Client Certificate",
+					Element:      ElementTypeTextArea,
+					Description:  "Client certificate, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_client_cert",
+				},
+				{
+					Label:        "TLS Client Key",
+					Element:      ElementTypeTextArea,
+					Description:  "Client key, as a file path or base64-encoded PEM.",
+					PropertyName: "tls_client_key",
+					Secure:       true,
+				},
+				{
+					Label:   "TLS Format",
+					Element: ElementTypeSelect,
+					SelectOptions: []SelectOption{
+						{
+							Value: "auto",
+							Label: "Auto-detect",
+						},
+						{
+							Value: "file",
+							Label: "File path",
+						},
+						{
+							Value: "base64",
+							Label: "Base64",
+						},
+					},
+					Description:  "Whether the TLS certificates and key are file paths or base64-encoded PEM. Defaults to auto-detect.",
+					PropertyName: "tls_format",
+				},
+				{
+					Label:        "Facility",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Syslog facility of the messages, for example daemon or local0",
+					Placeholder:  "local0",
+					PropertyName: "facility",
+				},
+				{
+					Label:        "Default Severity",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Syslog severity of the messages for alerts without a mapped severity label, for example warning",
+					Placeholder:  "warning",
+					PropertyName: "default_severity",
+				},
+				{
+					Label:        "Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Templated syslog message",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+			},
+		},
+		{
+			Type:        "zenduty",
+			Name:        "Zenduty",
+			Description: "Sends notifications to Zenduty",
+			Heading:     "Zenduty settings",
+			Options: []NotifierOption{
+				{
+					Label:        "Integration URL",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "URL of the Zenduty integration to send events to",
+					Placeholder:  "https://www.zenduty.com/api/events/<integration key>/",
+					PropertyName: "url",
+					Secure:       true,
+					Required:     true,
+				},
+				{
+					Label:        "Message",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated message of the Zenduty alert",
+					Placeholder:  channels.DefaultMessageTitleEmbed,
+					PropertyName: "message",
+				},
+				{
+					Label:        "Resolved Message",
+					Element:      ElementTypeTextArea,
+					Description:  "Optional message to use instead of the message when all alerts are resolved. You can use template variables.",
+					PropertyName: "resolved_message",
+				},
+				{
+					Label:        "Summary",
+					Element:      ElementTypeTextArea,
+					Description:  "Templated summary of the Zenduty alert",
+					Placeholder:  channels.DefaultMessageEmbed,
+					PropertyName: "summary",
+				},
+				{
+					Label:        "Entity ID",
+					Element:      ElementTypeInput,
+					InputType:    InputTypeText,
+					Description:  "Templated ID used by Zenduty to deduplicate and resolve alerts. Defaults to a hash of the alert group key.",
+					PropertyName: "entity_id",
+				},
+			},
+		},
 	}
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	ngchannels "github.com/grafana/grafana/pkg/services/ngalert/notifier/channels"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -58,6 +59,14 @@ func NewMultiOrgAlertmanager(cfg *setting.Cfg, configStore AlertingStore, orgSto
 	kvStore kvstore.KVStore, provStore provisioning.ProvisioningStore, decryptFn channels.GetDecryptedValueFn,
 	m *metrics.MultiOrgAlertmanager, ns notifications.Service, l log.Logger, s secrets.Service,
 ) (*MultiOrgAlertmanager, error) {
+	// Notifiers are created by the Alertmanagers of the organizations, so the options
+	// are set before.
+	ngchannels.SetOptions(ngchannels.Options{
+		FileDirectory:             cfg.UnifiedAlerting.FileNotifierDirectory,
+		WebhookTemplatesDirectory: cfg.UnifiedAlerting.WebhookTemplatesDirectory,
+		EnvironmentVariables:      cfg.UnifiedAlerting.NotifierEnvironmentVariables,
+	})
+
 	moa := &MultiOrgAlertmanager{
 		Crypto:    NewCrypto(s, configStore, l),
 		ProvStore: provStore,
@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/go-openapi/strfmt"
+	"github.com/grafana/alerting/alerting/notifier/channels"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	ngchannels "github.com/grafana/grafana/pkg/services/ngalert/notifier/channels"
 	"github.com/prometheus/alertmanager/api/v2/models"
 	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/types"
@@ -41,6 +43,8 @@ type TestReceiverConfigResult struct {
 	UID    string
 	Status string
 	Error  error
+	// ErrorCategory is the category of Error, if it could be classified.
+	ErrorCategory ngchannels.NotifyErrorCategory
 }
 
 type InvalidReceiverError struct {
@@ -78,7 +82,7 @@ func (am *Alertmanager) TestReceivers(ctx context.Context, c apimodels.TestRecei
 	type job struct {
 		Config       *apimodels.PostableGrafanaReceiver
 		ReceiverName string
-		Notifier     notify.Notifier
+		Notifier     channels.NotificationChannel
 	}
 
 	// result contains the receiver that was tested and an error that is non-nil if the test failed
@@ -101,14 +105,20 @@ func (am *Alertmanager) TestReceivers(ctx context.Context, c apimodels.TestRecei
 		for _, next := range results {
 			tmp := m[next.ReceiverName]
 			status := "ok"
+			var category ngchannels.NotifyErrorCategory
 			if next.Error != nil {
 				status = "failed"
+				var notifyErr ngchannels.NotifyError
+				if errors.As(next.Error, &notifyErr) {
+					category = notifyErr.Category
+				}
 			}
 			tmp.Configs = append(tmp.Configs, TestReceiverConfigResult{
-				Name:   next.Config.Name,
-				UID:    next.Config.UID,
-				Status: status,
-				Error:  processNotifierError(next.Config, next.Error),
+				Name:          next.Config.Name,
+				UID:           next.Config.UID,
+				Status:        status,
+				Error:         processNotifierError(next.Config, next.Error),
+				ErrorCategory: category,
 			})
 			m[next.ReceiverName] = tmp
 		}
@@ -183,6 +193,9 @@ func (am *Alertmanager) TestReceivers(ctx context.Context, c apimodels.TestRecei
 				if _, err := next.Notifier.Notify(ctx, &testAlert); err != nil {
 					v.Error = err
 				}
+				// The notifier is only used for the test notification, so connections
+				// to brokers are not kept open.
+				ngchannels.Close(next.Notifier)
 				resultCh <- v
 			}
 			return nil
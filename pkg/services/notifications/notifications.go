@@ -68,6 +68,13 @@ func ProvideService(bus bus.Bus, cfg *setting.Cfg, mailer Mailer, store TempUser
 		return nil, errors.New("invalid email address for SMTP from_address config")
 	}
 
+	if err := setWebhookConnectionLimits(cfg.UnifiedAlerting.WebhookMaxIdleConnsPerHost, cfg.UnifiedAlerting.WebhookMaxConnsPerHost); err != nil {
+		return nil, err
+	}
+	if err := setWebhookIdleConnTimeout(cfg.UnifiedAlerting.WebhookIdleConnTimeout); err != nil {
+		return nil, err
+	}
+
 	if cfg.EmailCodeValidMinutes == 0 {
 		cfg.EmailCodeValidMinutes = 120
 	}
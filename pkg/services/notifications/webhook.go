@@ -27,11 +27,25 @@ type Webhook struct {
 	Validation func(body []byte, statusCode int) error
 }
 
+// WebhookResponseError is returned when the response of a webhook does not have a 2xx status code.
+type WebhookResponseError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e WebhookResponseError) Error() string {
+	return fmt.Sprintf("webhook response status %v", e.Status)
+}
+
 // WebhookClient exists to mock the client in tests.
 type WebhookClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// defaultWebhookIdleConnTimeout is the time after which idle connections of the
+// transport that is shared by webhooks are closed.
+const defaultWebhookIdleConnTimeout = 90 * time.Second
+
 var netTransport = &http.Transport{
 	TLSClientConfig: &tls.Config{
 		Renegotiation: tls.RenegotiateFreelyAsClient,
@@ -41,12 +55,69 @@ var netTransport = &http.Transport{
 		Timeout: 30 * time.Second,
 	}).Dial,
 	TLSHandshakeTimeout: 5 * time.Second,
+	IdleConnTimeout:     defaultWebhookIdleConnTimeout,
 }
 var netClient WebhookClient = &http.Client{
 	Timeout:   time.Second * 30,
 	Transport: netTransport,
 }
 
+type webhookClientKey struct{}
+
+// WithWebhookClient returns a copy of ctx whose webhooks are sent with the client instead
+// of the client that is shared by webhooks, such as a client that restricts the hosts
+// that webhooks are sent to.
+func WithWebhookClient(ctx context.Context, client WebhookClient) context.Context {
+	return context.WithValue(ctx, webhookClientKey{}, client)
+}
+
+// webhookClient returns the client of ctx, or the shared client if it has none.
+func webhookClient(ctx context.Context) WebhookClient {
+	if client, ok := ctx.Value(webhookClientKey{}).(WebhookClient); ok {
+		return client
+	}
+	return netClient
+}
+
+// NewWebhookTransport returns a copy of the transport that is shared by webhooks, with
+// its connection limits and idle timeout, for clients that send webhooks instead of the
+// shared client, such as with WithWebhookClient.
+func NewWebhookTransport() *http.Transport {
+	return netTransport.Clone()
+}
+
+// setWebhookConnectionLimits sets the maximum number of idle connections and of
+// connections per host of the transport that is shared by webhooks. As in Go, 0 means
+// http.DefaultMaxIdleConnsPerHost idle connections and no limit on connections. It is
+// called by ProvideService at startup, as the transport must not be changed once
+// webhooks are sent.
+func setWebhookConnectionLimits(maxIdleConnsPerHost, maxConnsPerHost int) error {
+	if maxIdleConnsPerHost < 0 {
+		return fmt.Errorf("invalid value for webhook_max_idle_conns_per_host: %d, must be a non-negative integer", maxIdleConnsPerHost)
+	}
+	if maxConnsPerHost < 0 {
+		return fmt.Errorf("invalid value for webhook_max_conns_per_host: %d, must be a non-negative integer", maxConnsPerHost)
+	}
+	netTransport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	netTransport.MaxConnsPerHost = maxConnsPerHost
+	return nil
+}
+
+// setWebhookIdleConnTimeout sets the time after which idle connections of the transport
+// that is shared by webhooks are closed. 0 means defaultWebhookIdleConnTimeout. It is
+// called by ProvideService at startup, as the transport must not be changed once
+// webhooks are sent.
+func setWebhookIdleConnTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("invalid value for webhook_idle_conn_timeout: %s, must be a positive duration", timeout)
+	}
+	if timeout == 0 {
+		timeout = defaultWebhookIdleConnTimeout
+	}
+	netTransport.IdleConnTimeout = timeout
+	return nil
+}
+
 func (ns *NotificationService) sendWebRequestSync(ctx context.Context, webhook *Webhook) error {
 	if webhook.HttpMethod == "" {
 		webhook.HttpMethod = http.MethodPost
@@ -78,7 +149,7 @@ func (ns *NotificationService) sendWebRequestSync(ctx context.Context, webhook *
 		request.Header.Set(k, v)
 	}
 
-	resp, err := netClient.Do(request)
+	resp, err := webhookClient(ctx).Do(request)
 	if err != nil {
 		return err
 	}
@@ -107,5 +178,5 @@ func (ns *NotificationService) sendWebRequestSync(ctx context.Context, webhook *
 	}
 
 	ns.log.Debug("Webhook failed", "url", webhook.Url, "statuscode", resp.Status, "body", string(body))
-	return fmt.Errorf("webhook response status %v", resp.Status)
+	return WebhookResponseError{StatusCode: resp.StatusCode, Status: resp.Status}
 }
@@ -0,0 +1,125 @@
+package notifications
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookConnectionLimits(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, setWebhookConnectionLimits(0, 0))
+	})
+
+	// The defaults are the same as Go.
+	require.Equal(t, 0, netTransport.MaxIdleConnsPerHost)
+	require.Equal(t, 0, netTransport.MaxConnsPerHost)
+
+	require.NoError(t, setWebhookConnectionLimits(10, 100))
+	require.Equal(t, 10, netTransport.MaxIdleConnsPerHost)
+	require.Equal(t, 100, netTransport.MaxConnsPerHost)
+
+	require.EqualError(t, setWebhookConnectionLimits(-1, 100), "invalid value for webhook_max_idle_conns_per_host: -1, must be a non-negative integer")
+	require.EqualError(t, setWebhookConnectionLimits(10, -1), "invalid value for webhook_max_conns_per_host: -1, must be a non-negative integer")
+	// The limits are unchanged if they are invalid.
+	require.Equal(t, 10, netTransport.MaxIdleConnsPerHost)
+	require.Equal(t, 100, netTransport.MaxConnsPerHost)
+}
+
+func TestProvideService_WebhookConnectionLimits(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, setWebhookConnectionLimits(0, 0))
+	})
+
+	cfg := createSmtpConfig()
+	cfg.UnifiedAlerting.WebhookMaxIdleConnsPerHost = 10
+	cfg.UnifiedAlerting.WebhookMaxConnsPerHost = 100
+	_, _, err := createSutWithConfig(t, newBus(t), cfg)
+	require.NoError(t, err)
+	require.Equal(t, 10, netTransport.MaxIdleConnsPerHost)
+	require.Equal(t, 100, netTransport.MaxConnsPerHost)
+
+	cfg.UnifiedAlerting.WebhookMaxConnsPerHost = -1
+	_, _, err = createSutWithConfig(t, newBus(t), cfg)
+	require.EqualError(t, err, "invalid value for webhook_max_conns_per_host: -1, must be a non-negative integer")
+}
+
+func TestWebhookIdleConnTimeout(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, setWebhookIdleConnTimeout(0))
+	})
+
+	require.Equal(t, defaultWebhookIdleConnTimeout, netTransport.IdleConnTimeout)
+
+	require.NoError(t, setWebhookIdleConnTimeout(30*time.Second))
+	require.Equal(t, 30*time.Second, netTransport.IdleConnTimeout)
+
+	require.EqualError(t, setWebhookIdleConnTimeout(-time.Second), "invalid value for webhook_idle_conn_timeout: -1s, must be a positive duration")
+	// The timeout is unchanged if it is invalid.
+	require.Equal(t, 30*time.Second, netTransport.IdleConnTimeout)
+
+	// 0 means the default.
+	require.NoError(t, setWebhookIdleConnTimeout(0))
+	require.Equal(t, defaultWebhookIdleConnTimeout, netTransport.IdleConnTimeout)
+}
+
+func TestProvideService_WebhookIdleConnTimeout(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, setWebhookIdleConnTimeout(0))
+	})
+
+	cfg := createSmtpConfig()
+	cfg.UnifiedAlerting.WebhookIdleConnTimeout = 30 * time.Second
+	_, _, err := createSutWithConfig(t, newBus(t), cfg)
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, netTransport.IdleConnTimeout)
+}
+
+type recordingWebhookClient struct {
+	requests []*http.Request
+}
+
+func (c *recordingWebhookClient) Do(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, req)
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestNewWebhookTransport(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, setWebhookConnectionLimits(0, 0))
+		require.NoError(t, setWebhookIdleConnTimeout(0))
+	})
+
+	require.NoError(t, setWebhookConnectionLimits(10, 100))
+	require.NoError(t, setWebhookIdleConnTimeout(30*time.Second))
+	transport := NewWebhookTransport()
+	require.Equal(t, 10, transport.MaxIdleConnsPerHost)
+	require.Equal(t, 100, transport.MaxConnsPerHost)
+	require.Equal(t, 30*time.Second, transport.IdleConnTimeout)
+	require.NotSame(t, netTransport, transport)
+}
+
+func TestWithWebhookClient(t *testing.T) {
+	ns, _ := createSut(t, newBus(t))
+	shared := &recordingWebhookClient{}
+	orig := *NetClient
+	t.Cleanup(func() { SetWebhookClient(orig) })
+	SetWebhookClient(shared)
+
+	// Webhooks are sent with the client of the context.
+	client := &recordingWebhookClient{}
+	ctx := WithWebhookClient(context.Background(), client)
+	require.NoError(t, ns.sendWebRequestSync(ctx, &Webhook{Url: "http://localhost/test"}))
+	require.Len(t, client.requests, 1)
+	require.Empty(t, shared.requests)
+
+	// Otherwise they are sent with the shared client.
+	require.NoError(t, ns.sendWebRequestSync(context.Background(), &Webhook{Url: "http://localhost/test"}))
+	require.Len(t, client.requests, 1)
+	require.Len(t, shared.requests, 1)
+}
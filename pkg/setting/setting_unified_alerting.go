@@ -21,6 +21,7 @@ const (
 	alertmanagerDefaultGossipInterval     = cluster.DefaultGossipInterval
 	alertmanagerDefaultPushPullInterval   = cluster.DefaultPushPullInterval
 	alertmanagerDefaultConfigPollInterval = time.Minute
+	webhookDefaultIdleConnTimeout         = 90 * time.Second
 	// To start, the alertmanager needs at least one route defined.
 	// TODO: we should move this to Grafana settings and define this as the default.
 	alertmanagerDefaultConfiguration = `{
@@ -83,6 +84,26 @@ type UnifiedAlertingSettings struct {
 	DefaultRuleEvaluationInterval time.Duration
 	Screenshots                   UnifiedAlertingScreenshotSettings
 	ReservedLabels                UnifiedAlertingReservedLabelSettings
+	Destinations                  UnifiedAlertingDestinationSettings
+	// FileNotifierDirectory is the directory that file notifiers write to. File
+	// notifiers are disabled if it is empty.
+	FileNotifierDirectory string
+	// WebhookTemplatesDirectory is the directory of the template files of webhook
+	// notifiers. Template files are disabled if it is empty.
+	WebhookTemplatesDirectory string
+	// NotifierEnvironmentVariables are the environment variables that can be referenced
+	// in the settings of contact points. Names ending with "*" are prefixes.
+	NotifierEnvironmentVariables []string
+	// NotifierSecretsDirectory is the directory of the secrets that can be referenced
+	// in the settings of contact points. Secrets cannot be referenced if it is empty.
+	NotifierSecretsDirectory string
+	// WebhookMaxIdleConnsPerHost and WebhookMaxConnsPerHost limit the connections of
+	// the transport that is shared by webhooks. 0 means the defaults of Go.
+	WebhookMaxIdleConnsPerHost int
+	WebhookMaxConnsPerHost     int
+	// WebhookIdleConnTimeout is the time after which idle connections of the transport
+	// that is shared by webhooks are closed.
+	WebhookIdleConnTimeout time.Duration
 }
 
 type UnifiedAlertingScreenshotSettings struct {
@@ -95,6 +116,13 @@ type UnifiedAlertingReservedLabelSettings struct {
 	DisabledLabels map[string]struct{}
 }
 
+// UnifiedAlertingDestinationSettings are the hosts that notifications can be sent to.
+// Hosts are hostnames, IP addresses or CIDR ranges.
+type UnifiedAlertingDestinationSettings struct {
+	AllowedHosts []string
+	DeniedHosts  []string
+}
+
 // IsEnabled returns true if UnifiedAlertingSettings.Enabled is either nil or true.
 // It hides the implementation details of the Enabled and simplifies its usage.
 func (u *UnifiedAlertingSettings) IsEnabled() bool {
@@ -213,6 +241,17 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		}
 	}
 
+	uaCfg.FileNotifierDirectory = ua.Key("file_notifier_directory").MustString("")
+	uaCfg.WebhookTemplatesDirectory = ua.Key("webhook_templates_directory").MustString("")
+	uaCfg.NotifierEnvironmentVariables = util.SplitString(ua.Key("notifier_environment_variables").MustString(""))
+	uaCfg.NotifierSecretsDirectory = ua.Key("notifier_secrets_directory").MustString("")
+	uaCfg.WebhookMaxIdleConnsPerHost = ua.Key("webhook_max_idle_conns_per_host").MustInt(0)
+	uaCfg.WebhookMaxConnsPerHost = ua.Key("webhook_max_conns_per_host").MustInt(0)
+	uaCfg.WebhookIdleConnTimeout, err = gtime.ParseDuration(valueAsString(ua, "webhook_idle_conn_timeout", webhookDefaultIdleConnTimeout.String()))
+	if err != nil {
+		return err
+	}
+
 	// TODO load from ini file
 	uaCfg.DefaultConfiguration = alertmanagerDefaultConfiguration
 
@@ -294,6 +333,12 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 	}
 	uaCfg.ReservedLabels = uaCfgReservedLabels
 
+	destinations := iniFile.Section("unified_alerting.destinations")
+	uaCfg.Destinations = UnifiedAlertingDestinationSettings{
+		AllowedHosts: util.SplitString(destinations.Key("allowed_hosts").MustString("")),
+		DeniedHosts:  util.SplitString(destinations.Key("denied_hosts").MustString("")),
+	}
+
 	cfg.UnifiedAlerting = uaCfg
 	return nil
 }
@@ -39,6 +39,22 @@ func TestCfg_ReadUnifiedAlertingSettings(t *testing.T) {
 		require.Len(t, cfg.UnifiedAlerting.HAPeers, 3)
 		require.ElementsMatch(t, []string{"hostname1:9090", "hostname2:9090", "hostname3:9090"}, cfg.UnifiedAlerting.HAPeers)
 	}
+
+	// With destinations set, it correctly parses them.
+	{
+		require.Empty(t, cfg.UnifiedAlerting.Destinations.AllowedHosts)
+		require.Empty(t, cfg.UnifiedAlerting.Destinations.DeniedHosts)
+		s, err := cfg.Raw.NewSection("unified_alerting.destinations")
+		require.NoError(t, err)
+		_, err = s.NewKey("allowed_hosts", "*.example.com, 203.0.113.0/24")
+		require.NoError(t, err)
+		_, err = s.NewKey("denied_hosts", "169.254.169.254")
+		require.NoError(t, err)
+
+		require.NoError(t, cfg.ReadUnifiedAlertingSettings(cfg.Raw))
+		require.Equal(t, []string{"*.example.com", "203.0.113.0/24"}, cfg.UnifiedAlerting.Destinations.AllowedHosts)
+		require.Equal(t, []string{"169.254.169.254"}, cfg.UnifiedAlerting.Destinations.DeniedHosts)
+	}
 }
 
 func TestUnifiedAlertingSettings(t *testing.T) {
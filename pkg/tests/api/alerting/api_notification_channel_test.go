@@ -719,24 +719,25 @@ func TestIntegrationNotificationChannels(t *testing.T) {
 	mockChannel.responses["slack_recvX"] = `{"ok": true}`
 
 	// Overriding some URLs to send to the mock channel.
-	os, opa, ot, opu, ogb, ol, oth := ngchannels.SlackAPIEndpoint, channels.PagerdutyEventAPIURL,
-		channels.TelegramAPIURL, channels.PushoverEndpoint, channels.GetBoundary,
+	os, opa, ot, opu, ogb, ongb, ol, oth := ngchannels.SlackAPIEndpoint, channels.PagerdutyEventAPIURL,
+		ngchannels.TelegramAPIURL, channels.PushoverEndpoint, channels.GetBoundary, ngchannels.GetBoundary,
 		ngchannels.LineNotifyURL, channels.ThreemaGwBaseURL
 	originalTemplate := channels.DefaultTemplateString
 	t.Cleanup(func() {
 		ngchannels.SlackAPIEndpoint, channels.PagerdutyEventAPIURL,
-			channels.TelegramAPIURL, channels.PushoverEndpoint, channels.GetBoundary,
-			ngchannels.LineNotifyURL, channels.ThreemaGwBaseURL = os, opa, ot, opu, ogb, ol, oth
+			ngchannels.TelegramAPIURL, channels.PushoverEndpoint, channels.GetBoundary, ngchannels.GetBoundary,
+			ngchannels.LineNotifyURL, channels.ThreemaGwBaseURL = os, opa, ot, opu, ogb, ongb, ol, oth
 		channels.DefaultTemplateString = originalTemplate
 	})
 	channels.DefaultTemplateString = channels.TemplateForTestsString
 	ngchannels.SlackAPIEndpoint = fmt.Sprintf("http://%s/slack_recvX/slack_testX", mockChannel.server.Addr)
 	channels.PagerdutyEventAPIURL = fmt.Sprintf("http://%s/pagerduty_recvX/pagerduty_testX", mockChannel.server.Addr)
-	channels.TelegramAPIURL = fmt.Sprintf("http://%s/telegram_recv/bot%%s/%%s", mockChannel.server.Addr)
+	ngchannels.TelegramAPIURL = fmt.Sprintf("http://%s/telegram_recv/bot%%s/%%s", mockChannel.server.Addr)
 	channels.PushoverEndpoint = fmt.Sprintf("http://%s/pushover_recv/pushover_test", mockChannel.server.Addr)
 	ngchannels.LineNotifyURL = fmt.Sprintf("http://%s/line_recv/line_test", mockChannel.server.Addr)
 	channels.ThreemaGwBaseURL = fmt.Sprintf("http://%s/threema_recv/threema_test", mockChannel.server.Addr)
 	channels.GetBoundary = func() string { return "abcd" }
+	ngchannels.GetBoundary = func() string { return "abcd" }
 
 	env.NotificationService.EmailHandlerSync = mockEmail.sendEmailCommandHandlerSync
 	// As we are using a NotificationService mock here, but the test expects real NotificationService -
@@ -2340,7 +2341,14 @@ var expNonEmailNotifications = map[string][]string{
 			  "color": "#D63232",
 			  "ts": %s,
               "mrkdwn_in": ["pretext"],
-              "pretext": "<!here|here> <!subteam^group1><!subteam^group2> <@user1><@user2>"
+              "pretext": "<!here|here> <!subteam^group1><!subteam^group2> <@user1><@user2>",
+			  "actions": [
+				{
+				  "type": "button",
+				  "text": "View Source",
+				  "url": "http://localhost:3000/alerting/grafana/UID_SlackAlert1/view"
+				}
+			  ]
 			}
 		  ]
 		}`,
@@ -2360,7 +2368,14 @@ var expNonEmailNotifications = map[string][]string{
 			  "color": "#D63232",
 			  "ts": %s,
               "mrkdwn_in": ["pretext"],
-              "pretext": "<@user1><@user2>"
+              "pretext": "<@user1><@user2>",
+			  "actions": [
+				{
+				  "type": "button",
+				  "text": "View Source",
+				  "url": "http://localhost:3000/alerting/grafana/UID_SlackAlert2/view"
+				}
+			  ]
 			}
 		  ]
 		}`,
@@ -2422,12 +2437,27 @@ var expNonEmailNotifications = map[string][]string{
 				  	"text": "**Firing**\n\nValue: A=1\nLabels:\n - alertname = TeamsAlert\n - grafana_folder = default\nAnnotations:\nSource: http://localhost:3000/alerting/grafana/UID_TeamsAlert/view\nSilence: http://localhost:3000/alerting/silence/new?alertmanager=grafana\u0026matcher=alertname%3DTeamsAlert\u0026matcher=grafana_folder%3Ddefault\n",
 				  	"type": "TextBlock",
 				  	"wrap": true
+				  }, {
+				  	"facts": [
+				  	  {
+				  	  	"title": "alertname",
+				  	  	"value": "TeamsAlert"
+				  	  }, {
+				  	  	"title": "grafana_folder",
+				  	  	"value": "default"
+				  	  }
+				  	],
+				  	"type": "FactSet"
 				  }, {
 				  	"actions": [
 				  	  {
 				  	  	"title": "View URL",
 				  	  	"type": "Action.OpenUrl",
 				  	  	"url": "http://localhost:3000/alerting/list"
+				  	  }, {
+				  	  	"title": "View Source",
+				  	  	"type": "Action.OpenUrl",
+				  	  	"url": "http://localhost:3000/alerting/grafana/UID_TeamsAlert/view"
 				  	  }
 				  	],
 				  	"type": "ActionSet"
@@ -2489,6 +2519,9 @@ var expNonEmailNotifications = map[string][]string{
 	},
 	"discord_recv/discord_test": {
 		`{
+		  "allowed_mentions": {
+			"parse": ["users"]
+		  },
 		  "content": "**Firing**\n\nValue: A=1\nLabels:\n - alertname = DiscordAlert\n - grafana_folder = default\nAnnotations:\nSource: http://localhost:3000/alerting/grafana/UID_DiscordAlert/view\nSilence: http://localhost:3000/alerting/silence/new?alertmanager=grafana&matcher=alertname%3DDiscordAlert&matcher=grafana_folder%3Ddefault\n",
 		  "embeds": [
 			{
@@ -2502,6 +2535,19 @@ var expNonEmailNotifications = map[string][]string{
 			  "url": "http://localhost:3000/alerting/list"
 			}
 		  ],
+		  "components": [
+			{
+			  "type": 1,
+			  "components": [
+				{
+				  "type": 2,
+				  "style": 5,
+				  "label": "View Source",
+				  "url": "http://localhost:3000/alerting/grafana/UID_DiscordAlert/view"
+				}
+			  ]
+			}
+		  ],
 		  "username": "Grafana"
 		}`,
 	},
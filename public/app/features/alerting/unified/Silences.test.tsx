@@ -260,6 +260,17 @@ describe('Silence edit', () => {
     TEST_TIMEOUT
   );
 
+  it(
+    'prefills the duration field with the duration param',
+    async () => {
+      renderSilences(`${baseUrlPath}?duration=${encodeURIComponent('1d 12h')}`);
+      await waitFor(() => expect(ui.editor.durationField.query()).not.toBeNull());
+
+      expect(ui.editor.durationInput.get()).toHaveValue('1d 12h');
+    },
+    TEST_TIMEOUT
+  );
+
   it(
     'creates a new silence',
     async () => {
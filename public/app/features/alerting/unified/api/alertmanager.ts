@@ -14,6 +14,7 @@ import {
   Silence,
   SilenceCreatePayload,
   TestReceiversAlert,
+  TestReceiversErrorCategory,
   TestReceiversPayload,
   TestReceiversResult,
 } from 'app/plugins/datasource/alertmanager/types';
@@ -216,12 +217,24 @@ function isTestReceiversResult(data: any): data is TestReceiversResult {
   return false;
 }
 
+const errorCategoryHints: Record<TestReceiversErrorCategory, string> = {
+  auth: 'Authentication failed, check the credentials of the contact point',
+  rate_limited: 'The notification was rate limited, try again later',
+  network: 'Could not connect, check the URL of the contact point and the network',
+  bad_request: 'The notification was rejected, check the settings of the contact point',
+  server_error: 'The service returned an error, try again later',
+};
+
 function getReceiverResultError(receiversResult: TestReceiversResult) {
   return receiversResult.receivers
     .flatMap((receiver) =>
       receiver.grafana_managed_receiver_configs
         .filter((receiver) => receiver.status === 'failed')
-        .map((receiver) => receiver.error ?? 'Unknown error.')
+        .map((receiver) => {
+          const error = receiver.error ?? 'Unknown error.';
+          const hint = receiver.error_category && errorCategoryHints[receiver.error_category];
+          return hint ? `${hint}: ${error}` : error;
+        })
     )
     .join('; ');
 }
@@ -37,11 +37,12 @@ interface Props {
   alertManagerSourceName: string;
 }
 
-const defaultsFromQuery = (searchParams: URLSearchParams): Partial<SilenceFormFields> => {
+const defaultsFromQuery = (searchParams: URLSearchParams, now: Date): Partial<SilenceFormFields> => {
   const defaults: Partial<SilenceFormFields> = {};
 
   const comment = searchParams.get('comment');
   const matchers = searchParams.getAll('matcher');
+  const duration = searchParams.get('duration');
 
   const formMatchers = parseQueryParamMatchers(matchers);
   if (formMatchers.length) {
@@ -52,6 +53,11 @@ const defaultsFromQuery = (searchParams: URLSearchParams): Partial<SilenceFormFi
     defaults.comment = comment;
   }
 
+  if (duration && Object.keys(parseDuration(duration)).length > 0) {
+    defaults.duration = duration;
+    defaults.endsAt = addDurationToDate(now, parseDuration(duration)).toISOString();
+  }
+
   return defaults;
 };
 
@@ -92,7 +98,7 @@ const getDefaultFormValues = (searchParams: URLSearchParams, silence?: Silence):
       matcherName: '',
       matcherValue: '',
       timeZone: DefaultTimeZone,
-      ...defaultsFromQuery(searchParams),
+      ...defaultsFromQuery(searchParams, now),
     };
   }
 };
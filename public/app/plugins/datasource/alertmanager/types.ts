@@ -250,10 +250,13 @@ export interface TestReceiversPayload {
   alert?: TestReceiversAlert;
 }
 
+export type TestReceiversErrorCategory = 'auth' | 'rate_limited' | 'network' | 'bad_request' | 'server_error';
+
 interface TestReceiversResultGrafanaReceiverConfig {
   name: string;
   uid?: string;
   error?: string;
+  error_category?: TestReceiversErrorCategory;
   status: 'ok' | 'failed';
 }
 